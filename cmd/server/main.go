@@ -1,8 +1,14 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -11,12 +17,17 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/thenaveensharma/telehook/internal/database"
 	"github.com/thenaveensharma/telehook/internal/handlers"
+	"github.com/thenaveensharma/telehook/internal/metrics"
 	"github.com/thenaveensharma/telehook/internal/middleware"
 	"github.com/thenaveensharma/telehook/internal/queue"
+	"github.com/thenaveensharma/telehook/internal/scheduler"
 	"github.com/thenaveensharma/telehook/internal/telegram"
 )
 
 func main() {
+	migrateDown := flag.Bool("migrate-down", false, "roll back the last applied database migration (027 or later) and exit")
+	flag.Parse()
+
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, using environment variables")
@@ -29,6 +40,49 @@ func main() {
 	}
 	defer db.Close()
 
+	migrationsDir := os.Getenv("MIGRATIONS_DIR")
+	if migrationsDir == "" {
+		migrationsDir = "migrations"
+	}
+
+	// -migrate-down reverts the last applied migration and exits
+	if *migrateDown {
+		name, err := db.RollbackLastMigration(context.Background(), migrationsDir)
+		if err != nil {
+			log.Fatalf("Failed to roll back migration: %v", err)
+		}
+		log.Printf("Rolled back migration %s", name)
+		return
+	}
+
+	// Apply pending schema migrations; a failing one stops startup
+	if err := db.RunMigrations(context.Background(), migrationsDir); err != nil {
+		log.Fatalf("Failed to run database migrations: %v", err)
+	}
+
+	// Push queue metrics to a StatsD agent if configured
+	if err := metrics.ConfigureStatsD(); err != nil {
+		log.Fatalf("Invalid StatsD configuration: %v", err)
+	}
+
+	// Route Telegram API traffic through an outbound proxy if configured
+	if err := telegram.ConfigureProxy(); err != nil {
+		log.Fatalf("Invalid Telegram proxy configuration: %v", err)
+	}
+
+	// Apply the send rates users set on their bots and channels
+	if overrides, err := db.GetRateLimitOverrides(context.Background()); err != nil {
+		log.Printf("WARNING: Failed to load bot and channel rate limits: %v", err)
+	} else {
+		for _, o := range overrides {
+			if o.ChannelID == "" {
+				telegram.SetBotRateLimit(o.BotToken, o.Limit)
+			} else {
+				telegram.SetChannelRateLimit(o.BotToken, o.ChannelID, o.Limit)
+			}
+		}
+	}
+
 	// Initialize Telegram bot
 	bot, err := telegram.NewBot()
 	if err != nil {
@@ -56,27 +110,79 @@ func main() {
 	app.Use(logger.New())
 	app.Use(cors.New())
 
+	// Prometheus registry scraped at /metrics
+	prom := metrics.NewPrometheus()
+
 	// Initialize alert queue system
 	processor := queue.NewTelegramProcessor(bot, db)
+	processor.SetMetrics(prom)
 	processor.InitializeDefaultRules()
+	defer processor.FlushCoalesced()
 
-	// Alert queue sized to handle burst traffic:
-	// - 20 workers for concurrent processing
-	// - 15000 queue capacity to buffer stress test (12,000 alerts + headroom)
-	alertQueue := queue.NewAlertQueue(20, 15000, processor)
+	// Alert queue sized to handle burst traffic, overridable per deployment:
+	// - QUEUE_WORKERS concurrent workers (default 20)
+	// - QUEUE_CAPACITY buffered alerts (default 15000: stress test of 12,000 alerts + headroom)
+	// - PERSIST_QUEUE=true keeps unfinished alerts in the database across restarts
+	workers := envInt("QUEUE_WORKERS", 20)
+	capacity := envInt("QUEUE_CAPACITY", 15000)
+	var alertQueue *queue.AlertQueue
+	if os.Getenv("PERSIST_QUEUE") == "true" {
+		alertQueue = queue.NewPersistentAlertQueue(db, workers, capacity, processor)
+	} else {
+		alertQueue = queue.NewAlertQueue(workers, capacity, processor)
+	}
+	escalator := queue.NewEscalationDispatcher(db)
+	alertQueue.SetEscalator(escalator)
+	alertQueue.SetFailureRecorder(escalator)
+	alertQueue.SetRedactor(processor)
+	alertQueue.SetMetrics(prom)
+	// QUEUE_WARNING_NOTIFY=true posts queue capacity warnings to the system bot's channel
+	if os.Getenv("QUEUE_WARNING_NOTIFY") == "true" && bot != nil {
+		alertQueue.SetCapacityNotifier(func(message string) {
+			ctx, cancel := context.WithTimeout(context.Background(), queue.SendTimeoutFromEnv())
+			defer cancel()
+			if _, err := bot.SendMessageWithFormat(ctx, "⚠️ "+message, telegram.FormatPlain); err != nil {
+				log.Printf("Failed to send queue capacity warning: %v", err)
+			}
+		})
+	}
 	alertQueue.Start()
 	defer alertQueue.Stop()
 
-	log.Println("Alert queue system initialized (20 workers, 15k capacity)")
+	log.Printf("Alert queue system initialized (%d workers, %d capacity)", workers, capacity)
+
+	// Start scheduler for recurring messages
+	messageScheduler := scheduler.NewScheduler(db, alertQueue)
+	messageScheduler.Start()
+
+	// Start delivery receipt dispatcher
+	receiptDispatcher := scheduler.NewReceiptDispatcher(db)
+	receiptDispatcher.Start()
+	defer receiptDispatcher.Stop()
 
 	// Initialize rate limiter with high limits for webhook endpoint
 	rateLimiter := middleware.NewRateLimiter()
+	rateLimiter.SetLimitLookup(handlers.RateLimitLookup(db))
+
+	// Stricter limiter for auth endpoints, with lockout after failed logins
+	authRateLimiter := middleware.NewAuthRateLimiter()
 
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db)
-	webhookHandler := handlers.NewWebhookHandler(db, bot, alertQueue)
+	authHandler := handlers.NewAuthHandler(db, authRateLimiter)
+	webhookHandler := handlers.NewWebhookHandler(db, bot, alertQueue, processor)
+	webhookHandler.SetMetrics(prom)
 	telegramConfigHandler := handlers.NewTelegramConfigHandler(db)
 	analyticsHandler := handlers.NewAnalyticsHandler(db)
+	scheduleHandler := handlers.NewScheduleHandler(db)
+	receiptHandler := handlers.NewReceiptHandler(db)
+	transformHandler := handlers.NewTransformHandler(db)
+	fixtureHandler := handlers.NewFixtureHandler(db, webhookHandler)
+	ruleHandler := handlers.NewRuleHandler(db, processor)
+	quietHoursHandler := handlers.NewQuietHoursHandler(db, processor)
+	redactionHandler := handlers.NewRedactionHandler(db, processor)
+	userHandler := handlers.NewUserHandler(db, rateLimiter)
+	escalationHandler := handlers.NewEscalationHandler(db)
+	adminHandler := handlers.NewAdminHandler(db, processor, rateLimiter)
 
 	// Serve static files
 	app.Static("/static", "./web/static")
@@ -98,6 +204,14 @@ func main() {
 		return c.SendFile("./web/templates/dashboard.html")
 	})
 
+	// Password reset links (PASSWORD_RESET_URL) open this page
+	app.Get("/reset-password", func(c *fiber.Ctx) error {
+		return c.SendFile("./web/templates/reset-password.html")
+	})
+
+	// Prometheus metrics (bearer METRICS_TOKEN, disabled unless it is set)
+	app.Get("/metrics", middleware.MetricsMiddleware(), prom.Handler())
+
 	// API Routes
 	api := app.Group("/api")
 
@@ -110,37 +224,136 @@ func main() {
 	})
 
 	// Auth routes (public)
-	auth := api.Group("/auth")
+	auth := api.Group("/auth", authRateLimiter.Middleware())
 	auth.Post("/signup", authHandler.Signup)
 	auth.Post("/login", authHandler.Login)
+	auth.Post("/refresh", authHandler.Refresh)
+	auth.Post("/logout", authHandler.Logout)
+	auth.Post("/forgot-password", authHandler.ForgotPassword)
+	auth.Post("/reset-password", authHandler.ResetPassword)
 
 	// Protected routes
 	user := api.Group("/user", middleware.JWTMiddleware())
 	user.Get("/webhook-info", webhookHandler.GetWebhookInfo)
+	user.Get("/logs", webhookHandler.GetWebhookLogs)
+	user.Get("/logs/search", webhookHandler.SearchWebhookLogs)
+	user.Get("/logs/export", analyticsHandler.ExportLogs)
+	user.Put("/webhook-secret", webhookHandler.SetWebhookSecret)
+	user.Delete("/webhook-secret", webhookHandler.DeleteWebhookSecret)
 	user.Get("/queue-stats", webhookHandler.GetQueueStats)
+	user.Get("/whoami", userHandler.WhoAmI)
+	user.Post("/change-password", authRateLimiter.Middleware(), authHandler.ChangePassword)
+	user.Delete("/account", authRateLimiter.Middleware(), authHandler.DeleteAccount)
+	user.Get("/settings", userHandler.GetSettings)
+	user.Put("/settings", userHandler.UpdateSettings)
+	user.Get("/batches/:id", webhookHandler.GetBatchStatus)
 
 	// Telegram bot configuration routes (protected)
 	bots := user.Group("/bots")
 	bots.Post("/", telegramConfigHandler.CreateBot)
 	bots.Get("/", telegramConfigHandler.GetBots)
 	bots.Get("/with-channels", telegramConfigHandler.GetBotsWithChannels)
+	bots.Post("/validate", telegramConfigHandler.ValidateBotToken)
 	bots.Get("/:id", telegramConfigHandler.GetBot)
 	bots.Put("/:id", telegramConfigHandler.UpdateBot)
 	bots.Delete("/:id", telegramConfigHandler.DeleteBot)
 
+	// Whole bot and channel configuration, for keeping under version control
+	user.Get("/config/export", telegramConfigHandler.ExportConfig)
+	user.Post("/config/import", telegramConfigHandler.ImportConfig)
+
 	// Telegram channel configuration routes (protected)
 	channels := user.Group("/channels")
 	channels.Post("/", telegramConfigHandler.CreateChannel)
+	channels.Post("/bulk", telegramConfigHandler.BulkCreateChannels)
 	channels.Get("/", telegramConfigHandler.GetChannels)
 	channels.Get("/:id", telegramConfigHandler.GetChannel)
 	channels.Put("/:id", telegramConfigHandler.UpdateChannel)
 	channels.Delete("/:id", telegramConfigHandler.DeleteChannel)
+	channels.Post("/:id/preview", telegramConfigHandler.PreviewChannel)
+	channels.Post("/:id/test", telegramConfigHandler.TestChannel)
+	channels.Put("/:id/webhook-secret", telegramConfigHandler.SetChannelWebhookSecret)
+	channels.Delete("/:id/webhook-secret", telegramConfigHandler.DeleteChannelWebhookSecret)
+
+	// Scheduled message routes (protected)
+	schedules := user.Group("/schedules")
+	schedules.Post("/", scheduleHandler.CreateSchedule)
+	schedules.Get("/", scheduleHandler.GetSchedules)
+	schedules.Get("/:id", scheduleHandler.GetSchedule)
+	schedules.Put("/:id", scheduleHandler.UpdateSchedule)
+	schedules.Delete("/:id", scheduleHandler.DeleteSchedule)
+
+	// Alert rule routes (protected)
+	rules := user.Group("/rules")
+	rules.Post("/", ruleHandler.CreateRule)
+	rules.Get("/", ruleHandler.GetRules)
+	rules.Put("/:id", ruleHandler.UpdateRule)
+	rules.Delete("/:id", ruleHandler.DeleteRule)
+
+	// Delivery receipt webhook routes (protected)
+	user.Get("/receipts-webhook", receiptHandler.GetReceiptWebhook)
+	user.Put("/receipts-webhook", receiptHandler.UpsertReceiptWebhook)
+	user.Delete("/receipts-webhook", receiptHandler.DeleteReceiptWebhook)
+
+	// Webhook payload transform routes (protected)
+	user.Get("/payload-transform", transformHandler.GetPayloadTransform)
+	user.Put("/payload-transform", transformHandler.UpsertPayloadTransform)
+	user.Delete("/payload-transform", transformHandler.DeletePayloadTransform)
+
+	// Quiet hours routes (protected)
+	user.Get("/quiet-hours", quietHoursHandler.GetQuietHours)
+	user.Put("/quiet-hours", quietHoursHandler.UpsertQuietHours)
+	user.Delete("/quiet-hours", quietHoursHandler.DeleteQuietHours)
+
+	// Redaction rule routes (protected)
+	redactions := user.Group("/redactions")
+	redactions.Post("/", redactionHandler.CreateRedaction)
+	redactions.Get("/", redactionHandler.GetRedactions)
+	redactions.Put("/:id", redactionHandler.UpdateRedaction)
+	redactions.Delete("/:id", redactionHandler.DeleteRedaction)
+
+	// Webhook test fixture routes (protected)
+	fixtures := user.Group("/fixtures")
+	fixtures.Post("/", fixtureHandler.SaveFixture)
+	fixtures.Get("/", fixtureHandler.GetFixtures)
+	fixtures.Get("/:name", fixtureHandler.GetFixture)
+	fixtures.Delete("/:name", fixtureHandler.DeleteFixture)
+	fixtures.Post("/:name/fire", fixtureHandler.FireFixture)
+
+	// Escalation policy and dead-letter routes (protected)
+	user.Get("/escalation-policies", escalationHandler.GetEscalationPolicies)
+	user.Put("/escalation-policies", escalationHandler.UpsertEscalationPolicy)
+	user.Delete("/escalation-policies/:id", escalationHandler.DeleteEscalationPolicy)
+	user.Get("/dead-letters", escalationHandler.GetDeadLetters)
 
 	// Analytics routes (protected)
 	user.Get("/analytics", analyticsHandler.GetAnalytics)
+	user.Get("/analytics/export", analyticsHandler.ExportAnalytics)
+
+	// Admin routes (X-Admin-Key header, disabled unless ADMIN_API_KEY is set)
+	admin := api.Group("/admin", middleware.AdminMiddleware())
+	admin.Get("/maintenance-notices", adminHandler.GetMaintenanceNotices)
+	admin.Post("/maintenance-notices", adminHandler.CreateMaintenanceNotice)
+	admin.Delete("/maintenance-notices/:id", adminHandler.DeleteMaintenanceNotice)
+	admin.Post("/users/:id/pause", adminHandler.PauseUserProcessing)
+	admin.Post("/users/:id/resume", adminHandler.ResumeUserProcessing)
+	admin.Put("/users/:id/rate-limit", adminHandler.SetUserRateLimit)
+	admin.Post("/channels/purge-deleted", adminHandler.PurgeDeletedChannels)
 
-	// Webhook endpoint (uses webhook token, not JWT) - Rate limited to prevent abuse
-	api.Post("/webhook/:token", rateLimiter.Middleware(), webhookHandler.HandleWebhook)
+	// Webhook endpoints (use webhook token, not JWT) - Rate limited to prevent abuse
+	// Compressed (gzip/deflate) bodies are decoded with a size cap before parsing.
+	// /api/v1 is the original contract and stays unversioned under /api for
+	// existing integrations; /api/v2 responds with a uniform shape.
+	for _, v1 := range []fiber.Router{api, api.Group("/v1")} {
+		v1.Post("/webhook/:token", rateLimiter.Middleware(), middleware.DecompressBody(), webhookHandler.HandleWebhook)
+		v1.Post("/webhook/:token/batch", rateLimiter.Middleware(), middleware.DecompressBody(), webhookHandler.HandleWebhookBatch)
+
+		// Vendor adapters render Datadog and Opsgenie payloads before queuing
+		v1.Post("/webhook/:token/datadog", rateLimiter.Middleware(), middleware.DecompressBody(), webhookHandler.HandleDatadogWebhook)
+		v1.Post("/webhook/:token/opsgenie", rateLimiter.Middleware(), middleware.DecompressBody(), webhookHandler.HandleOpsgenieWebhook)
+	}
+	v2 := api.Group("/v2")
+	v2.Post("/webhook/:token", rateLimiter.Middleware(), middleware.DecompressBody(), webhookHandler.HandleWebhookV2)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -153,8 +366,48 @@ func main() {
 		host = "0.0.0.0"
 	}
 
+	// SIGINT/SIGTERM (Render sends SIGTERM on deploys) stop accepting
+	// requests, then the queued alerts are drained before the deferred
+	// Stop calls run
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		sig := <-signals
+		log.Printf("Received %s, shutting down", sig)
+		if err := app.ShutdownWithTimeout(envDuration("SHUTDOWN_TIMEOUT", 10*time.Second)); err != nil {
+			log.Printf("Error shutting down server: %v", err)
+		}
+	}()
+
 	log.Printf("Server starting on %s:%s", host, port)
 	if err := app.Listen(host + ":" + port); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
+
+	// Nothing more arrives over HTTP; stop scheduled sends from queuing
+	// more before waiting for the queue to empty
+	messageScheduler.Stop()
+	drainTimeout := envDuration("QUEUE_DRAIN_TIMEOUT", 30*time.Second)
+	log.Printf("Draining alert queue (timeout %s)", drainTimeout)
+	if alertQueue.Drain(drainTimeout) {
+		log.Println("Alert queue drained")
+	}
+}
+
+// envInt reads a positive integer from the environment, falling back to def
+// when it is unset or invalid
+func envInt(name string, def int) int {
+	if v, err := strconv.Atoi(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
+}
+
+// envDuration reads a positive duration ("30s") from the environment,
+// falling back to def when it is unset or invalid
+func envDuration(name string, def time.Duration) time.Duration {
+	if v, err := time.ParseDuration(os.Getenv(name)); err == nil && v > 0 {
+		return v
+	}
+	return def
 }
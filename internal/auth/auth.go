@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -29,10 +33,14 @@ func VerifyPassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
-func GenerateJWT(userID int, email, username string) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		return "", fmt.Errorf("JWT_SECRET not set in environment")
+// AccessTokenTTL is how long access tokens are valid, from JWT_TTL (a
+// duration such as "15m"). JWT_EXPIRY_HOURS is still honoured when JWT_TTL
+// is unset.
+func AccessTokenTTL() time.Duration {
+	if envTTL := os.Getenv("JWT_TTL"); envTTL != "" {
+		if ttl, err := time.ParseDuration(envTTL); err == nil && ttl > 0 {
+			return ttl
+		}
 	}
 
 	expiryHours := 24
@@ -41,13 +49,50 @@ func GenerateJWT(userID int, email, username string) (string, error) {
 			expiryHours = hours
 		}
 	}
+	return time.Duration(expiryHours) * time.Hour
+}
+
+// RefreshTokenTTL is how long refresh tokens are valid, from
+// JWT_REFRESH_TTL (default 30 days)
+func RefreshTokenTTL() time.Duration {
+	if envTTL := os.Getenv("JWT_REFRESH_TTL"); envTTL != "" {
+		if ttl, err := time.ParseDuration(envTTL); err == nil && ttl > 0 {
+			return ttl
+		}
+	}
+	return 30 * 24 * time.Hour
+}
+
+// GenerateRefreshToken returns a random refresh token and the hash to store
+// for it
+func GenerateRefreshToken() (token, hash string, err error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	token = hex.EncodeToString(buf)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken is what is stored for a refresh token
+func HashRefreshToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func GenerateJWT(userID int, email, username string) (string, error) {
+	jwtSecret := os.Getenv("JWT_SECRET")
+	if jwtSecret == "" {
+		return "", fmt.Errorf("JWT_SECRET not set in environment")
+	}
 
 	claims := Claims{
 		UserID:   userID,
 		Email:    email,
 		Username: username,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(expiryHours) * time.Hour)),
+			ID:        uuid.NewString(), // jti, so the token can be revoked
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
@@ -80,6 +125,9 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+		if IsTokenRevoked(claims.ID) {
+			return nil, fmt.Errorf("token has been revoked")
+		}
 		return claims, nil
 	}
 
@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// MinPassphraseLength is the shortest passphrase secrets may be sealed with
+const MinPassphraseLength = 12
+
+const passphraseSaltSize = 16
+
+// ErrWrongPassphrase is returned when a sealed secret can't be opened, either
+// because the passphrase differs or the secret was altered
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted secret")
+
+// SealWithPassphrase encrypts a secret with a key derived from passphrase,
+// returning base64 text that OpenWithPassphrase turns back into the secret
+func SealWithPassphrase(secret, passphrase string) (string, error) {
+	salt := make([]byte, passphraseSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := passphraseCipher(passphrase, salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := append(salt, nonce...)
+	sealed = gcm.Seal(sealed, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// OpenWithPassphrase decrypts a secret sealed by SealWithPassphrase
+func OpenWithPassphrase(sealed, passphrase string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(raw) < passphraseSaltSize {
+		return "", ErrWrongPassphrase
+	}
+
+	gcm, err := passphraseCipher(passphrase, raw[:passphraseSaltSize])
+	if err != nil {
+		return "", err
+	}
+
+	raw = raw[passphraseSaltSize:]
+	if len(raw) < gcm.NonceSize() {
+		return "", ErrWrongPassphrase
+	}
+	secret, err := gcm.Open(nil, raw[:gcm.NonceSize()], raw[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", ErrWrongPassphrase
+	}
+	return string(secret), nil
+}
+
+// passphraseCipher derives an AES-256-GCM cipher from a passphrase and salt
+func passphraseCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// bcryptMaxLength is the longest password bcrypt will hash
+const bcryptMaxLength = 72
+
+// PasswordPolicy describes the requirements a new password must meet
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// LoadPasswordPolicy reads the password policy from environment variables,
+// defaulting to a minimum length of 8 with no character class requirements
+func LoadPasswordPolicy() PasswordPolicy {
+	policy := PasswordPolicy{MinLength: 8}
+
+	if envMin := os.Getenv("PASSWORD_MIN_LENGTH"); envMin != "" {
+		if n, err := strconv.Atoi(envMin); err == nil && n > 0 {
+			policy.MinLength = n
+		}
+	}
+
+	policy.RequireUpper = envBool("PASSWORD_REQUIRE_UPPER")
+	policy.RequireLower = envBool("PASSWORD_REQUIRE_LOWER")
+	policy.RequireDigit = envBool("PASSWORD_REQUIRE_DIGIT")
+	policy.RequireSymbol = envBool("PASSWORD_REQUIRE_SYMBOL")
+
+	return policy
+}
+
+// Validate returns a description of each requirement the password fails to
+// meet, or nil if it satisfies the policy
+func (p PasswordPolicy) Validate(password string) []string {
+	var unmet []string
+
+	// The minimum counts characters, while bcrypt's limit is in bytes
+	if utf8.RuneCountInString(password) < p.MinLength {
+		unmet = append(unmet, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if len(password) > bcryptMaxLength {
+		unmet = append(unmet, fmt.Sprintf("must be at most %d bytes long", bcryptMaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		unmet = append(unmet, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		unmet = append(unmet, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		unmet = append(unmet, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		unmet = append(unmet, "must contain a symbol")
+	}
+
+	return unmet
+}
+
+// envBool reports whether an environment variable is set to a true value
+func envBool(key string) bool {
+	b, err := strconv.ParseBool(os.Getenv(key))
+	return err == nil && b
+}
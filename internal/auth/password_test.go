@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPasswordPolicyLengthBoundaries(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 8}
+
+	tests := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{"7 characters", "abcdefg", false},
+		{"8 characters", "abcdefgh", true},
+		{"72 bytes", strings.Repeat("a", 72), true},
+		{"73 bytes", strings.Repeat("a", 73), false},
+		{"7 two-byte characters", strings.Repeat("é", 7), false},
+		{"8 two-byte characters", strings.Repeat("é", 8), true},
+		{"4 four-byte characters", strings.Repeat("🔒", 4), false},
+		{"72 bytes of two-byte characters", strings.Repeat("é", 36), true},
+		{"37 two-byte characters over 72 bytes", strings.Repeat("é", 37), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unmet := policy.Validate(tt.password)
+			if valid := len(unmet) == 0; valid != tt.valid {
+				t.Fatalf("Validate(%q) = %v, want valid %v", tt.password, unmet, tt.valid)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyCharacterClasses(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:     8,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}
+
+	if unmet := policy.Validate("Ábcdéf1!"); len(unmet) != 0 {
+		t.Fatalf("multibyte password meeting every class was rejected: %v", unmet)
+	}
+	if unmet := policy.Validate("abcdefgh"); len(unmet) != 3 {
+		t.Fatalf("got %v, want the uppercase, digit and symbol requirements", unmet)
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// RevocationLoader returns the expiry of each revoked access token that
+// hasn't expired yet, keyed by jti
+type RevocationLoader func(ctx context.Context) (map[string]time.Time, error)
+
+// revokedTokens holds the IDs (jti) of access tokens revoked before they
+// expired, each kept until the token would have expired anyway. Tokens
+// revoked by other instances are picked up from load every refresh.
+var revokedTokens = struct {
+	sync.RWMutex
+	expiry   map[string]time.Time
+	load     RevocationLoader
+	refresh  time.Duration
+	loadedAt time.Time
+}{expiry: make(map[string]time.Time)}
+
+// SetRevocationLoader makes tokens revoked elsewhere, such as by a logout on
+// another replica, rejected here too. The revoked tokens are reloaded when
+// a token is checked and refresh has passed since the last load, so a
+// revocation applies everywhere within refresh.
+func SetRevocationLoader(load RevocationLoader, refresh time.Duration) {
+	revokedTokens.Lock()
+	defer revokedTokens.Unlock()
+	revokedTokens.load = load
+	revokedTokens.refresh = refresh
+	revokedTokens.loadedAt = time.Time{}
+}
+
+// RevokeToken rejects the access token with the given ID until expiresAt
+func RevokeToken(tokenID string, expiresAt time.Time) {
+	if tokenID == "" {
+		return
+	}
+
+	revokedTokens.Lock()
+	defer revokedTokens.Unlock()
+
+	now := time.Now()
+	pruneRevoked(now)
+	if expiresAt.After(now) {
+		revokedTokens.expiry[tokenID] = expiresAt
+	}
+}
+
+// IsTokenRevoked reports whether the access token with the given ID has
+// been revoked
+func IsTokenRevoked(tokenID string) bool {
+	now := time.Now()
+	reloadRevoked(now)
+
+	revokedTokens.RLock()
+	defer revokedTokens.RUnlock()
+
+	expiry, ok := revokedTokens.expiry[tokenID]
+	return ok && expiry.After(now)
+}
+
+// reloadRevoked merges in the tokens from the loader once the refresh
+// interval has passed. A failed load keeps the tokens already known.
+func reloadRevoked(now time.Time) {
+	revokedTokens.RLock()
+	due := revokedTokens.load != nil && now.Sub(revokedTokens.loadedAt) >= revokedTokens.refresh
+	revokedTokens.RUnlock()
+	if !due {
+		return
+	}
+
+	// Claimed under the write lock so only one request does the load
+	revokedTokens.Lock()
+	load := revokedTokens.load
+	if load == nil || now.Sub(revokedTokens.loadedAt) < revokedTokens.refresh {
+		revokedTokens.Unlock()
+		return
+	}
+	revokedTokens.loadedAt = now
+	revokedTokens.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tokens, err := load(ctx)
+	if err != nil {
+		log.Printf("Failed to reload revoked access tokens: %v", err)
+		return
+	}
+
+	revokedTokens.Lock()
+	defer revokedTokens.Unlock()
+	pruneRevoked(now)
+	for id, expiry := range tokens {
+		if expiry.After(now) {
+			revokedTokens.expiry[id] = expiry
+		}
+	}
+}
+
+// pruneRevoked drops tokens that have expired. Callers must hold the write
+// lock.
+func pruneRevoked(now time.Time) {
+	for id, expiry := range revokedTokens.expiry {
+		if !expiry.After(now) {
+			delete(revokedTokens.expiry, id)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRevocationsFromLoaderApply(t *testing.T) {
+	revokedTokens.Lock()
+	revokedTokens.expiry = make(map[string]time.Time)
+	revokedTokens.Unlock()
+
+	var mu sync.Mutex
+	stored := map[string]time.Time{}
+	loads := 0
+	SetRevocationLoader(func(ctx context.Context) (map[string]time.Time, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		loads++
+		tokens := make(map[string]time.Time, len(stored))
+		for id, expiry := range stored {
+			tokens[id] = expiry
+		}
+		return tokens, nil
+	}, 50*time.Millisecond)
+	defer SetRevocationLoader(nil, 0)
+
+	if IsTokenRevoked("other-replica") {
+		t.Fatal("token revoked before anything was stored")
+	}
+
+	// Revoked by another instance, straight into the database
+	mu.Lock()
+	stored["other-replica"] = time.Now().Add(time.Hour)
+	stored["already-expired"] = time.Now().Add(-time.Minute)
+	mu.Unlock()
+
+	if IsTokenRevoked("other-replica") {
+		t.Fatal("token reloaded before the refresh interval passed")
+	}
+	time.Sleep(60 * time.Millisecond)
+	if !IsTokenRevoked("other-replica") {
+		t.Fatal("token revoked in the database is still accepted after a refresh")
+	}
+	if IsTokenRevoked("already-expired") {
+		t.Fatal("expired token kept as revoked")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if loads != 2 {
+		t.Fatalf("got %d loads, want one per refresh interval", loads)
+	}
+}
+
+func TestRevokeTokenLocally(t *testing.T) {
+	RevokeToken("local", time.Now().Add(time.Hour))
+	if !IsTokenRevoked("local") {
+		t.Fatal("locally revoked token is accepted")
+	}
+	RevokeToken("expired", time.Now().Add(-time.Second))
+	if IsTokenRevoked("expired") {
+		t.Fatal("token revoked after it expired is kept")
+	}
+}
@@ -5,15 +5,71 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type DB struct {
 	Pool *pgxpool.Pool
+	// caseInsensitiveIdentifiers makes channel identifier lookups ignore case
+	caseInsensitiveIdentifiers bool
+	// identifiersPerBot lets each of a user's bots reuse the same channel
+	// identifier instead of requiring it to be unique across the user
+	identifiersPerBot bool
 }
 
 func NewDB() (*DB, error) {
+	config, err := pgxpool.ParseConfig(connectionString())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse database config: %w", err)
+	}
+
+	if err := applyPoolSettings(config); err != nil {
+		return nil, err
+	}
+	log.Printf("Database pool: max_conns=%d min_conns=%d max_conn_lifetime=%s max_conn_idle_time=%s",
+		config.MaxConns, config.MinConns, config.MaxConnLifetime, config.MaxConnIdleTime)
+
+	pool, err := pgxpool.NewWithConfig(context.Background(), config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	}
+
+	// Test the connection
+	if err := pool.Ping(context.Background()); err != nil {
+		return nil, fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	log.Println("Database connection established successfully")
+
+	caseInsensitive := true
+	if envCase := os.Getenv("IDENTIFIER_CASE_INSENSITIVE"); envCase != "" {
+		if v, err := strconv.ParseBool(envCase); err == nil {
+			caseInsensitive = v
+		}
+	}
+
+	perBot := false
+	switch scope := os.Getenv("IDENTIFIER_SCOPE"); scope {
+	case "", "user":
+	case "bot":
+		perBot = true
+	default:
+		log.Printf("Unknown IDENTIFIER_SCOPE %q, identifiers stay unique per user", scope)
+	}
+
+	return &DB{Pool: pool, caseInsensitiveIdentifiers: caseInsensitive, identifiersPerBot: perBot}, nil
+}
+
+// connectionString returns DATABASE_URL when set, so settings in it such as
+// sslmode apply as given, and otherwise builds one from the DB_* variables
+func connectionString() string {
+	if databaseURL := os.Getenv("DATABASE_URL"); databaseURL != "" {
+		return databaseURL
+	}
+
 	dbHost := os.Getenv("DB_HOST")
 	dbPort := os.Getenv("DB_PORT")
 	dbUser := os.Getenv("DB_USER")
@@ -21,29 +77,59 @@ func NewDB() (*DB, error) {
 	dbName := os.Getenv("DB_NAME")
 	dbSSLMode := os.Getenv("DB_SSLMODE")
 
-	connString := fmt.Sprintf(
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		dbHost, dbPort, dbUser, dbPassword, dbName, dbSSLMode,
 	)
+}
 
-	config, err := pgxpool.ParseConfig(connString)
-	if err != nil {
-		return nil, fmt.Errorf("unable to parse database config: %w", err)
+// applyPoolSettings overrides the pgxpool defaults with DB_MAX_CONNS,
+// DB_MIN_CONNS, DB_MAX_CONN_LIFETIME and DB_MAX_CONN_IDLE_TIME (durations
+// such as "30m"); unset variables keep the defaults
+func applyPoolSettings(config *pgxpool.Config) error {
+	if env := os.Getenv("DB_MAX_CONNS"); env != "" {
+		maxConns, err := strconv.ParseInt(env, 10, 32)
+		if err != nil || maxConns < 1 {
+			return fmt.Errorf("invalid DB_MAX_CONNS %q: must be a positive integer", env)
+		}
+		config.MaxConns = int32(maxConns)
 	}
 
-	pool, err := pgxpool.NewWithConfig(context.Background(), config)
-	if err != nil {
-		return nil, fmt.Errorf("unable to create connection pool: %w", err)
+	if env := os.Getenv("DB_MIN_CONNS"); env != "" {
+		minConns, err := strconv.ParseInt(env, 10, 32)
+		if err != nil || minConns < 0 {
+			return fmt.Errorf("invalid DB_MIN_CONNS %q: must be a non-negative integer", env)
+		}
+		config.MinConns = int32(minConns)
 	}
 
-	// Test the connection
-	if err := pool.Ping(context.Background()); err != nil {
-		return nil, fmt.Errorf("unable to ping database: %w", err)
+	if config.MinConns > config.MaxConns {
+		return fmt.Errorf("DB_MIN_CONNS (%d) must not exceed DB_MAX_CONNS (%d)", config.MinConns, config.MaxConns)
 	}
 
-	log.Println("Database connection established successfully")
+	if env := os.Getenv("DB_MAX_CONN_LIFETIME"); env != "" {
+		lifetime, err := time.ParseDuration(env)
+		if err != nil || lifetime <= 0 {
+			return fmt.Errorf("invalid DB_MAX_CONN_LIFETIME %q: must be a duration such as 1h", env)
+		}
+		config.MaxConnLifetime = lifetime
+	}
+
+	if env := os.Getenv("DB_MAX_CONN_IDLE_TIME"); env != "" {
+		idleTime, err := time.ParseDuration(env)
+		if err != nil || idleTime <= 0 {
+			return fmt.Errorf("invalid DB_MAX_CONN_IDLE_TIME %q: must be a duration such as 30m", env)
+		}
+		config.MaxConnIdleTime = idleTime
+	}
+
+	return nil
+}
 
-	return &DB{Pool: pool}, nil
+// IdentifiersPerBot reports whether channel identifiers only need to be
+// unique per bot (IDENTIFIER_SCOPE=bot) rather than per user
+func (db *DB) IdentifiersPerBot() bool {
+	return db.identifiersPerBot
 }
 
 func (db *DB) Close() {
@@ -0,0 +1,281 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// migrationFilePattern matches migration files such as 001_init_schema.sql
+// or 032_topics.up.sql, with optional 032_topics.down.sql rollbacks
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_.+?(\.up|\.down)?\.sql$`)
+
+// firstReversibleMigration is the earliest migration that can be rolled
+// back. Older ones predate .down.sql files; every migration from this one on
+// must come with one.
+const firstReversibleMigration = 27
+
+type migration struct {
+	version  int
+	name     string
+	path     string
+	downPath string // Empty when the migration can't be rolled back
+}
+
+// RunMigrations applies every migration in dir that hasn't been applied yet,
+// in version order, each in its own transaction, recording it in
+// schema_migrations. It stops at the first failing migration.
+//
+// A database set up before migrations were tracked has its schema but no
+// record of it; MIGRATIONS_BASELINE=<version> marks the migrations up to
+// that version as applied without running them.
+func (db *DB) RunMigrations(ctx context.Context, dir string) error {
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Pool.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := db.appliedMigrations(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		if err := db.baselineMigrations(ctx, migrations, applied); err != nil {
+			return err
+		}
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := db.applyMigration(ctx, m); err != nil {
+			return err
+		}
+		log.Printf("Applied migration %s", m.name)
+		count++
+	}
+
+	if count > 0 {
+		log.Printf("Applied %d migration(s)", count)
+	}
+	return nil
+}
+
+// loadMigrations lists the migration files in dir in version order
+func loadMigrations(dir string) ([]migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	downPaths := make(map[int]string)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		if match[2] == ".down" {
+			if _, ok := downPaths[version]; ok {
+				return nil, fmt.Errorf("more than one rollback for migration version %d", version)
+			}
+			downPaths[version] = filepath.Join(dir, entry.Name())
+			continue
+		}
+
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    entry.Name(),
+			path:    filepath.Join(dir, entry.Name()),
+		})
+	}
+
+	for version := range downPaths {
+		if _, ok := seen[version]; !ok {
+			return nil, fmt.Errorf("rollback for migration version %d has no migration", version)
+		}
+	}
+	for i := range migrations {
+		migrations[i].downPath = downPaths[migrations[i].version]
+		if migrations[i].downPath == "" && migrations[i].version >= firstReversibleMigration {
+			return nil, fmt.Errorf("migration %s has no .down.sql file; migrations from %03d on must have one", migrations[i].name, firstReversibleMigration)
+		}
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// appliedMigrations returns the versions recorded in schema_migrations
+func (db *DB) appliedMigrations(ctx context.Context) (map[int]bool, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan applied migration: %w", err)
+		}
+		applied[version] = true
+	}
+
+	return applied, rows.Err()
+}
+
+// baselineMigrations handles a database with no recorded migrations: with
+// MIGRATIONS_BASELINE set it records the migrations up to that version as
+// applied, and without it refuses to run over an existing schema
+func (db *DB) baselineMigrations(ctx context.Context, migrations []migration, applied map[int]bool) error {
+	baseline := os.Getenv("MIGRATIONS_BASELINE")
+	if baseline == "" {
+		var exists bool
+		if err := db.Pool.QueryRow(ctx, `SELECT to_regclass('users') IS NOT NULL`).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to inspect schema: %w", err)
+		}
+		if exists {
+			return fmt.Errorf("database has a schema but no recorded migrations; set MIGRATIONS_BASELINE to the last migration version already applied")
+		}
+		return nil
+	}
+
+	version, err := strconv.Atoi(baseline)
+	if err != nil {
+		return fmt.Errorf("invalid MIGRATIONS_BASELINE %q: %w", baseline, err)
+	}
+
+	for _, m := range migrations {
+		if m.version > version {
+			break
+		}
+		if _, err := db.Pool.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+			return fmt.Errorf("failed to record baseline migration %s: %w", m.name, err)
+		}
+		applied[m.version] = true
+	}
+
+	log.Printf("Recorded migrations up to version %d as already applied", version)
+	return nil
+}
+
+// applyMigration runs one migration file and records it, atomically
+func (db *DB) applyMigration(ctx context.Context, m migration) error {
+	sql, err := os.ReadFile(m.path)
+	if err != nil {
+		return fmt.Errorf("failed to read migration %s: %w", m.name, err)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, string(sql)); err != nil {
+		return fmt.Errorf("migration %s failed: %w", m.name, err)
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+		return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migration %s: %w", m.name, err)
+	}
+	return nil
+}
+
+// RollbackLastMigration reverts the most recently applied migration using
+// its .down.sql file and removes it from schema_migrations, atomically. It
+// returns the name of the migration rolled back. Only migrations from
+// firstReversibleMigration on can be rolled back.
+func (db *DB) RollbackLastMigration(ctx context.Context, dir string) (string, error) {
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		return "", err
+	}
+
+	var version int
+	err = db.Pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	if err != nil {
+		return "", fmt.Errorf("failed to get last applied migration: %w", err)
+	}
+	if version == 0 {
+		return "", fmt.Errorf("no migrations have been applied")
+	}
+
+	var last *migration
+	for i := range migrations {
+		if migrations[i].version == version {
+			last = &migrations[i]
+			break
+		}
+	}
+	if last == nil {
+		return "", fmt.Errorf("migration version %d is applied but has no file in %s", version, dir)
+	}
+	if last.version < firstReversibleMigration {
+		return "", fmt.Errorf("migration %s can't be rolled back: rollback is only supported for migrations %03d and later; restore a backup to go further back", last.name, firstReversibleMigration)
+	}
+	if last.downPath == "" {
+		return "", fmt.Errorf("migration %s has no .down.sql file to roll back with", last.name)
+	}
+
+	sql, err := os.ReadFile(last.downPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read rollback for %s: %w", last.name, err)
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, string(sql)); err != nil {
+		return "", fmt.Errorf("rollback of %s failed: %w", last.name, err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, version); err != nil {
+		return "", fmt.Errorf("failed to unrecord migration %s: %w", last.name, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("failed to commit rollback of %s: %w", last.name, err)
+	}
+	return last.name, nil
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMigrations(t *testing.T, names ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMigrationsRequiresDownFromFirstReversible(t *testing.T) {
+	dir := writeMigrations(t,
+		"026_forum_topics.sql",
+		"027_password_resets.sql",
+		"027_password_resets.down.sql",
+	)
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].downPath != "" || migrations[1].downPath == "" {
+		t.Fatalf("got %+v, want 026 without a rollback and 027 with one", migrations)
+	}
+
+	dir = writeMigrations(t, "027_password_resets.sql", "028_refresh_tokens.sql", "028_refresh_tokens.down.sql")
+	if _, err := loadMigrations(dir); err == nil || !strings.Contains(err.Error(), "027_password_resets.sql") {
+		t.Fatalf("got error %v, want one naming the migration without a rollback", err)
+	}
+}
+
+func TestMigrationsDirectoryLoads(t *testing.T) {
+	migrations, err := loadMigrations(filepath.Join("..", "..", "migrations"))
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	for _, m := range migrations {
+		if (m.version >= firstReversibleMigration) != (m.downPath != "") {
+			t.Errorf("migration %s: rollback %q", m.name, m.downPath)
+		}
+	}
+}
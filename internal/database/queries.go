@@ -3,10 +3,15 @@ package database
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/thenaveensharma/telehook/internal/models"
 )
 
@@ -37,7 +42,7 @@ func (db *DB) CreateUser(ctx context.Context, username, email, passwordHash stri
 func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
 	query := `
-		SELECT id, username, email, password_hash, webhook_token, created_at, updated_at
+		SELECT id, username, email, password_hash, webhook_token, processing_paused, webhook_secret, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -48,6 +53,8 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, e
 		&user.Email,
 		&user.PasswordHash,
 		&user.WebhookToken,
+		&user.ProcessingPaused,
+		&user.WebhookSecret,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -62,7 +69,7 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, e
 func (db *DB) GetUserByWebhookToken(ctx context.Context, token uuid.UUID) (*models.User, error) {
 	var user models.User
 	query := `
-		SELECT id, username, email, password_hash, webhook_token, created_at, updated_at
+		SELECT id, username, email, password_hash, webhook_token, processing_paused, webhook_secret, created_at, updated_at
 		FROM users
 		WHERE webhook_token = $1
 	`
@@ -73,6 +80,8 @@ func (db *DB) GetUserByWebhookToken(ctx context.Context, token uuid.UUID) (*mode
 		&user.Email,
 		&user.PasswordHash,
 		&user.WebhookToken,
+		&user.ProcessingPaused,
+		&user.WebhookSecret,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -84,18 +93,87 @@ func (db *DB) GetUserByWebhookToken(ctx context.Context, token uuid.UUID) (*mode
 	return &user, nil
 }
 
+func (db *DB) GetUserByID(ctx context.Context, userID int) (*models.User, error) {
+	var user models.User
+	query := `
+		SELECT id, username, email, password_hash, webhook_token, processing_paused, webhook_secret, created_at, updated_at
+		FROM users
+		WHERE id = $1
+	`
+
+	err := db.Pool.QueryRow(ctx, query, userID).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.WebhookToken,
+		&user.ProcessingPaused,
+		&user.WebhookSecret,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user by ID: %w", err)
+	}
+
+	return &user, nil
+}
+
+// GetUserResourceCounts returns how many bots, channels and schedules a user has
+func (db *DB) GetUserResourceCounts(ctx context.Context, userID int) (*models.UserResourceCounts, error) {
+	var counts models.UserResourceCounts
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM telegram_bots WHERE user_id = $1),
+			(SELECT COUNT(*) FROM telegram_channels WHERE user_id = $1 AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM telegram_channels WHERE user_id = $1 AND is_active = true AND deleted_at IS NULL),
+			(SELECT COUNT(*) FROM scheduled_messages WHERE user_id = $1)
+	`
+
+	err := db.Pool.QueryRow(ctx, query, userID).Scan(
+		&counts.Bots,
+		&counts.Channels,
+		&counts.ActiveChannels,
+		&counts.Schedules,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user resource counts: %w", err)
+	}
+
+	return &counts, nil
+}
+
 func (db *DB) CreateWebhookLog(ctx context.Context, userID int, payload map[string]interface{}, telegramResponse, status string) error {
+	return db.createWebhookLog(ctx, userID, payload, telegramResponse, status, nil, 0, "")
+}
+
+// CreateDeliveredWebhookLog logs a successful send along with how long the
+// alert took from being queued to being delivered
+func (db *DB) CreateDeliveredWebhookLog(ctx context.Context, userID int, payload map[string]interface{}, telegramResponse string, latency time.Duration) error {
+	latencyMs := int(latency.Milliseconds())
+	return db.createWebhookLog(ctx, userID, payload, telegramResponse, "success", &latencyMs, 0, "")
+}
+
+// CreateFailedWebhookLog logs a failed send with the Telegram error code (0
+// if there was none) and failure category
+func (db *DB) CreateFailedWebhookLog(ctx context.Context, userID int, payload map[string]interface{}, telegramResponse string, errorCode int, errorCategory string) error {
+	return db.createWebhookLog(ctx, userID, payload, telegramResponse, "failed", nil, errorCode, errorCategory)
+}
+
+func (db *DB) createWebhookLog(ctx context.Context, userID int, payload map[string]interface{}, telegramResponse, status string, latencyMs *int, errorCode int, errorCategory string) error {
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
 	query := `
-		INSERT INTO webhook_logs (user_id, payload, telegram_response, status)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO webhook_logs (user_id, payload, telegram_response, status, latency_ms, error_code, error_category)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0), NULLIF($7, ''))
 	`
 
-	_, err = db.Pool.Exec(ctx, query, userID, payloadJSON, telegramResponse, status)
+	_, err = db.Pool.Exec(ctx, query, userID, payloadJSON, telegramResponse, status, latencyMs, errorCode, errorCategory)
 	if err != nil {
 		return fmt.Errorf("failed to create webhook log: %w", err)
 	}
@@ -138,11 +216,137 @@ func (db *DB) GetUserWebhookLogs(ctx context.Context, userID int, limit int) ([]
 	return logs, nil
 }
 
+// GetUserWebhookLogsPaginated returns a page of a user's webhook logs, newest
+// first, along with the total number of matching logs. An empty
+// statusFilter matches every status.
+func (db *DB) GetUserWebhookLogsPaginated(ctx context.Context, userID, limit, offset int, statusFilter string) ([]models.WebhookLog, int, error) {
+	var total int
+	countQuery := `SELECT COUNT(*) FROM webhook_logs WHERE user_id = $1 AND ($2 = '' OR status = $2)`
+	if err := db.Pool.QueryRow(ctx, countQuery, userID, statusFilter).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook logs: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, payload, telegram_response, status, sent_at
+		FROM webhook_logs
+		WHERE user_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY sent_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID, statusFilter, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []models.WebhookLog{}
+	for rows.Next() {
+		var log models.WebhookLog
+		err := rows.Scan(
+			&log.ID,
+			&log.UserID,
+			&log.Payload,
+			&log.TelegramResponse,
+			&log.Status,
+			&log.SentAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan webhook log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, total, rows.Err()
+}
+
+// SearchUserWebhookLogs returns a page of a user's webhook logs whose message
+// contains search, case-insensitively, newest first, along with the total
+// number of matches
+func (db *DB) SearchUserWebhookLogs(ctx context.Context, userID int, search string, limit, offset int) ([]models.WebhookLog, int, error) {
+	// Match the search text literally rather than as a LIKE pattern
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search) + "%"
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM webhook_logs WHERE user_id = $1 AND payload->>'message' ILIKE $2`
+	if err := db.Pool.QueryRow(ctx, countQuery, userID, pattern).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook logs: %w", err)
+	}
+
+	query := `
+		SELECT id, user_id, payload, telegram_response, status, sent_at
+		FROM webhook_logs
+		WHERE user_id = $1 AND payload->>'message' ILIKE $2
+		ORDER BY sent_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID, pattern, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search webhook logs: %w", err)
+	}
+	defer rows.Close()
+
+	logs := []models.WebhookLog{}
+	for rows.Next() {
+		var log models.WebhookLog
+		err := rows.Scan(
+			&log.ID,
+			&log.UserID,
+			&log.Payload,
+			&log.TelegramResponse,
+			&log.Status,
+			&log.SentAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan webhook log: %w", err)
+		}
+		logs = append(logs, log)
+	}
+
+	return logs, total, rows.Err()
+}
+
+// ForEachWebhookLogExport calls fn for each of a user's webhook logs sent
+// between since and until, oldest first, with the message truncated to
+// maxMessageLength characters. Rows are streamed rather than loaded at once.
+func (db *DB) ForEachWebhookLogExport(ctx context.Context, userID int, since, until time.Time, maxMessageLength int, fn func(row models.WebhookLogExportRow) error) error {
+	query := `
+		SELECT
+			sent_at,
+			status,
+			COALESCE(payload->>'identifier', ''),
+			COALESCE((payload->>'priority')::INTEGER, 3),
+			LEFT(COALESCE(payload->>'message', ''), $4)
+		FROM webhook_logs
+		WHERE user_id = $1 AND sent_at >= $2 AND sent_at <= $3
+		ORDER BY sent_at ASC, id ASC
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID, since, until, maxMessageLength)
+	if err != nil {
+		return fmt.Errorf("failed to export webhook logs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row models.WebhookLogExportRow
+		if err := rows.Scan(&row.SentAt, &row.Status, &row.Identifier, &row.Priority, &row.Message); err != nil {
+			return fmt.Errorf("failed to scan webhook log: %w", err)
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 // ============================================================================
 // Telegram Bot CRUD Operations
 // ============================================================================
 
-func (db *DB) CreateTelegramBot(ctx context.Context, userID int, botToken, botUsername string, isDefault bool) (*models.TelegramBot, error) {
+func (db *DB) CreateTelegramBot(ctx context.Context, userID int, botToken, botUsername string, isDefault bool, messageTemplate string, rateLimitPerSecond *int) (*models.TelegramBot, error) {
 	var bot models.TelegramBot
 
 	// If this is set as default, unset other defaults for this user
@@ -154,17 +358,19 @@ func (db *DB) CreateTelegramBot(ctx context.Context, userID int, botToken, botUs
 	}
 
 	query := `
-		INSERT INTO telegram_bots (user_id, bot_token, bot_username, is_default)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, bot_token, bot_username, is_default, created_at, updated_at
+		INSERT INTO telegram_bots (user_id, bot_token, bot_username, is_default, message_template, rate_limit_per_second)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, user_id, bot_token, bot_username, is_default, message_template, rate_limit_per_second, created_at, updated_at
 	`
 
-	err := db.Pool.QueryRow(ctx, query, userID, botToken, botUsername, isDefault).Scan(
+	err := db.Pool.QueryRow(ctx, query, userID, botToken, botUsername, isDefault, messageTemplate, rateLimitPerSecond).Scan(
 		&bot.ID,
 		&bot.UserID,
 		&bot.BotToken,
 		&bot.BotUsername,
 		&bot.IsDefault,
+		&bot.MessageTemplate,
+		&bot.RateLimitPerSecond,
 		&bot.CreatedAt,
 		&bot.UpdatedAt,
 	)
@@ -179,7 +385,7 @@ func (db *DB) CreateTelegramBot(ctx context.Context, userID int, botToken, botUs
 func (db *DB) GetTelegramBot(ctx context.Context, botID, userID int) (*models.TelegramBot, error) {
 	var bot models.TelegramBot
 	query := `
-		SELECT id, user_id, bot_token, bot_username, is_default, created_at, updated_at
+		SELECT id, user_id, bot_token, bot_username, is_default, message_template, rate_limit_per_second, created_at, updated_at
 		FROM telegram_bots
 		WHERE id = $1 AND user_id = $2
 	`
@@ -190,6 +396,8 @@ func (db *DB) GetTelegramBot(ctx context.Context, botID, userID int) (*models.Te
 		&bot.BotToken,
 		&bot.BotUsername,
 		&bot.IsDefault,
+		&bot.MessageTemplate,
+		&bot.RateLimitPerSecond,
 		&bot.CreatedAt,
 		&bot.UpdatedAt,
 	)
@@ -203,7 +411,7 @@ func (db *DB) GetTelegramBot(ctx context.Context, botID, userID int) (*models.Te
 
 func (db *DB) GetUserTelegramBots(ctx context.Context, userID int) ([]models.TelegramBot, error) {
 	query := `
-		SELECT id, user_id, bot_token, bot_username, is_default, created_at, updated_at
+		SELECT id, user_id, bot_token, bot_username, is_default, message_template, rate_limit_per_second, created_at, updated_at
 		FROM telegram_bots
 		WHERE user_id = $1
 		ORDER BY is_default DESC, created_at DESC
@@ -224,6 +432,8 @@ func (db *DB) GetUserTelegramBots(ctx context.Context, userID int) ([]models.Tel
 			&bot.BotToken,
 			&bot.BotUsername,
 			&bot.IsDefault,
+			&bot.MessageTemplate,
+			&bot.RateLimitPerSecond,
 			&bot.CreatedAt,
 			&bot.UpdatedAt,
 		)
@@ -236,7 +446,10 @@ func (db *DB) GetUserTelegramBots(ctx context.Context, userID int) ([]models.Tel
 	return bots, nil
 }
 
-func (db *DB) UpdateTelegramBot(ctx context.Context, botID, userID int, botToken, botUsername string, isDefault bool) (*models.TelegramBot, error) {
+// UpdateTelegramBot updates a bot; a nil messageTemplate or
+// rateLimitPerSecond is left unchanged and a rateLimitPerSecond of 0 clears
+// the override
+func (db *DB) UpdateTelegramBot(ctx context.Context, botID, userID int, botToken, botUsername string, isDefault bool, messageTemplate *string, rateLimitPerSecond *int) (*models.TelegramBot, error) {
 	// If this is set as default, unset other defaults for this user
 	if isDefault {
 		_, err := db.Pool.Exec(ctx, `UPDATE telegram_bots SET is_default = false WHERE user_id = $1 AND id != $2`, userID, botID)
@@ -250,18 +463,26 @@ func (db *DB) UpdateTelegramBot(ctx context.Context, botID, userID int, botToken
 		SET bot_token = COALESCE(NULLIF($1, ''), bot_token),
 		    bot_username = COALESCE(NULLIF($2, ''), bot_username),
 		    is_default = $3,
+		    message_template = COALESCE($6, message_template),
+		    rate_limit_per_second = CASE
+		        WHEN $7::INTEGER IS NULL THEN rate_limit_per_second
+		        WHEN $7 = 0 THEN NULL
+		        ELSE $7
+		    END,
 		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = $4 AND user_id = $5
-		RETURNING id, user_id, bot_token, bot_username, is_default, created_at, updated_at
+		RETURNING id, user_id, bot_token, bot_username, is_default, message_template, rate_limit_per_second, created_at, updated_at
 	`
 
 	var bot models.TelegramBot
-	err := db.Pool.QueryRow(ctx, query, botToken, botUsername, isDefault, botID, userID).Scan(
+	err := db.Pool.QueryRow(ctx, query, botToken, botUsername, isDefault, botID, userID, messageTemplate, rateLimitPerSecond).Scan(
 		&bot.ID,
 		&bot.UserID,
 		&bot.BotToken,
 		&bot.BotUsername,
 		&bot.IsDefault,
+		&bot.MessageTemplate,
+		&bot.RateLimitPerSecond,
 		&bot.CreatedAt,
 		&bot.UpdatedAt,
 	)
@@ -291,15 +512,92 @@ func (db *DB) DeleteTelegramBot(ctx context.Context, botID, userID int) error {
 // Telegram Channel CRUD Operations
 // ============================================================================
 
-func (db *DB) CreateTelegramChannel(ctx context.Context, userID, botID int, identifier, channelID, channelName, description string) (*models.TelegramChannel, error) {
+// CreateTelegramChannel creates a channel. A channel created as the default
+// replaces the user's previous default.
+func (db *DB) CreateTelegramChannel(ctx context.Context, userID int, req models.CreateChannelRequest) (*models.TelegramChannel, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	channel, err := createTelegramChannel(ctx, tx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit telegram channel: %w", err)
+	}
+
+	return channel, nil
+}
+
+// CreateTelegramChannels creates several channels in one transaction,
+// returning them along with each one's error in request order. With
+// skipFailed a channel that fails is rolled back on its own and the rest are
+// still created; otherwise the first failure rolls back all of them and is
+// also returned as err.
+func (db *DB) CreateTelegramChannels(ctx context.Context, userID int, reqs []models.CreateChannelRequest, skipFailed bool) ([]*models.TelegramChannel, []error, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	channels := make([]*models.TelegramChannel, len(reqs))
+	errs := make([]error, len(reqs))
+	for i, req := range reqs {
+		if !skipFailed {
+			channels[i], errs[i] = createTelegramChannel(ctx, tx, userID, req)
+			if errs[i] != nil {
+				return nil, errs, errs[i]
+			}
+			continue
+		}
+
+		// A savepoint per channel, so a failure doesn't abort the others
+		savepoint, err := tx.Begin(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create savepoint: %w", err)
+		}
+		channels[i], errs[i] = createTelegramChannel(ctx, savepoint, userID, req)
+		if errs[i] != nil {
+			err = savepoint.Rollback(ctx)
+		} else {
+			err = savepoint.Commit(ctx)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to release savepoint: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit telegram channels: %w", err)
+	}
+
+	return channels, errs, nil
+}
+
+// createTelegramChannel inserts a channel within tx, first unsetting the
+// user's other default when it is the default
+func createTelegramChannel(ctx context.Context, tx pgx.Tx, userID int, req models.CreateChannelRequest) (*models.TelegramChannel, error) {
+	// If this is set as default, unset other defaults for this user
+	if req.IsDefault {
+		_, err := tx.Exec(ctx, `UPDATE telegram_channels SET is_default = false WHERE user_id = $1 AND is_default`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unset other defaults: %w", err)
+		}
+	}
+
 	var channel models.TelegramChannel
 	query := `
-		INSERT INTO telegram_channels (user_id, bot_id, identifier, channel_id, channel_name, description)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, user_id, bot_id, identifier, channel_id, channel_name, description, is_active, created_at, updated_at
+		INSERT INTO telegram_channels (user_id, bot_id, identifier, channel_id, channel_name, description, parse_mode, message_template, coalesce_window_seconds, dedup_window_seconds, thread_id, rate_limit_per_minute, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE(NULLIF($7, ''), 'markdown'), $8, $9, $10, $11, $12, $13)
+		RETURNING id, user_id, bot_id, identifier, channel_id, channel_name, description, parse_mode, message_template, coalesce_window_seconds, dedup_window_seconds, thread_id, rate_limit_per_minute, is_default, webhook_secret, is_active, created_at, updated_at
 	`
 
-	err := db.Pool.QueryRow(ctx, query, userID, botID, identifier, channelID, channelName, description).Scan(
+	err := tx.QueryRow(ctx, query, userID, req.BotID, req.Identifier, req.ChannelID, req.ChannelName, req.Description, req.ParseMode, req.MessageTemplate, req.CoalesceWindowSeconds, req.DedupWindowSeconds, req.ThreadID, req.RateLimitPerMinute, req.IsDefault).Scan(
 		&channel.ID,
 		&channel.UserID,
 		&channel.BotID,
@@ -307,6 +605,14 @@ func (db *DB) CreateTelegramChannel(ctx context.Context, userID, botID int, iden
 		&channel.ChannelID,
 		&channel.ChannelName,
 		&channel.Description,
+		&channel.ParseMode,
+		&channel.MessageTemplate,
+		&channel.CoalesceWindowSeconds,
+		&channel.DedupWindowSeconds,
+		&channel.ThreadID,
+		&channel.RateLimitPerMinute,
+		&channel.IsDefault,
+		&channel.WebhookSecret,
 		&channel.IsActive,
 		&channel.CreatedAt,
 		&channel.UpdatedAt,
@@ -322,9 +628,9 @@ func (db *DB) CreateTelegramChannel(ctx context.Context, userID, botID int, iden
 func (db *DB) GetTelegramChannel(ctx context.Context, channelID, userID int) (*models.TelegramChannel, error) {
 	var channel models.TelegramChannel
 	query := `
-		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, is_active, created_at, updated_at
+		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, parse_mode, message_template, coalesce_window_seconds, dedup_window_seconds, thread_id, rate_limit_per_minute, is_default, webhook_secret, is_active, created_at, updated_at
 		FROM telegram_channels
-		WHERE id = $1 AND user_id = $2
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 	`
 
 	err := db.Pool.QueryRow(ctx, query, channelID, userID).Scan(
@@ -335,6 +641,14 @@ func (db *DB) GetTelegramChannel(ctx context.Context, channelID, userID int) (*m
 		&channel.ChannelID,
 		&channel.ChannelName,
 		&channel.Description,
+		&channel.ParseMode,
+		&channel.MessageTemplate,
+		&channel.CoalesceWindowSeconds,
+		&channel.DedupWindowSeconds,
+		&channel.ThreadID,
+		&channel.RateLimitPerMinute,
+		&channel.IsDefault,
+		&channel.WebhookSecret,
 		&channel.IsActive,
 		&channel.CreatedAt,
 		&channel.UpdatedAt,
@@ -347,39 +661,106 @@ func (db *DB) GetTelegramChannel(ctx context.Context, channelID, userID int) (*m
 	return &channel, nil
 }
 
-func (db *DB) GetTelegramChannelByIdentifier(ctx context.Context, userID int, identifier string) (*models.TelegramChannel, error) {
-	var channel models.TelegramChannel
+// ErrAmbiguousIdentifier is returned when an identifier without a bot scope
+// matches active channels on more than one of the user's bots
+var ErrAmbiguousIdentifier = errors.New("identifier matches channels on more than one bot")
+
+// GetTelegramChannelByIdentifier finds an active channel by identifier,
+// ignoring case unless IDENTIFIER_CASE_INSENSITIVE is disabled. A non-empty
+// bot limits the match to the bot with that username (with or without the
+// leading @) or numeric ID. Without one, an identifier used on several bots
+// fails with ErrAmbiguousIdentifier rather than picking one of them.
+func (db *DB) GetTelegramChannelByIdentifier(ctx context.Context, userID int, identifier, bot string) (*models.TelegramChannel, error) {
 	query := `
-		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, is_active, created_at, updated_at
-		FROM telegram_channels
-		WHERE user_id = $1 AND identifier = $2 AND is_active = true
+		SELECT c.id, c.user_id, c.bot_id, c.identifier, c.channel_id, c.channel_name, c.description, c.parse_mode, c.message_template, c.coalesce_window_seconds, c.dedup_window_seconds, c.thread_id, c.rate_limit_per_minute, c.is_default, c.webhook_secret, c.is_active, c.created_at, c.updated_at,
+		       COALESCE(b.bot_username, '')
+		FROM telegram_channels c
+		JOIN telegram_bots b ON b.id = c.bot_id
+		WHERE c.user_id = $1 AND c.is_active = true AND c.deleted_at IS NULL
+		  AND (c.identifier = $2 OR ($3 AND c.identifier_normalized = LOWER($2)))
+		  AND ($4 = '' OR LOWER(b.bot_username) = LOWER(LTRIM($4, '@')) OR b.id::TEXT = $4)
+		ORDER BY c.id
 	`
 
-	err := db.Pool.QueryRow(ctx, query, userID, identifier).Scan(
-		&channel.ID,
-		&channel.UserID,
-		&channel.BotID,
-		&channel.Identifier,
-		&channel.ChannelID,
-		&channel.ChannelName,
-		&channel.Description,
-		&channel.IsActive,
-		&channel.CreatedAt,
-		&channel.UpdatedAt,
-	)
-
+	rows, err := db.Pool.Query(ctx, query, userID, identifier, db.caseInsensitiveIdentifiers, bot)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get telegram channel by identifier: %w", err)
 	}
+	defer rows.Close()
 
-	return &channel, nil
+	var channels []models.TelegramChannel
+	var bots []string
+	for rows.Next() {
+		var channel models.TelegramChannel
+		var botUsername string
+		err := rows.Scan(
+			&channel.ID,
+			&channel.UserID,
+			&channel.BotID,
+			&channel.Identifier,
+			&channel.ChannelID,
+			&channel.ChannelName,
+			&channel.Description,
+			&channel.ParseMode,
+			&channel.MessageTemplate,
+			&channel.CoalesceWindowSeconds,
+			&channel.DedupWindowSeconds,
+			&channel.ThreadID,
+			&channel.RateLimitPerMinute,
+			&channel.IsDefault,
+			&channel.WebhookSecret,
+			&channel.IsActive,
+			&channel.CreatedAt,
+			&channel.UpdatedAt,
+			&botUsername,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan telegram channel: %w", err)
+		}
+		if botUsername == "" {
+			botUsername = strconv.Itoa(channel.BotID)
+		}
+		channels = append(channels, channel)
+		bots = append(bots, botUsername)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get telegram channel by identifier: %w", err)
+	}
+
+	switch len(channels) {
+	case 0:
+		return nil, fmt.Errorf("failed to get telegram channel by identifier: %w", pgx.ErrNoRows)
+	case 1:
+		return &channels[0], nil
+	default:
+		return nil, fmt.Errorf("%w: use %s@<bot> with one of: %s", ErrAmbiguousIdentifier, identifier, strings.Join(bots, ", "))
+	}
+}
+
+// ChannelIdentifierInUse reports whether another of the user's channels, on
+// any bot, already uses identifier (ignoring case). excludeChannelID is the
+// channel being updated, or 0 when creating one.
+func (db *DB) ChannelIdentifierInUse(ctx context.Context, userID int, identifier string, excludeChannelID int) (bool, error) {
+	var exists bool
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM telegram_channels
+			WHERE user_id = $1 AND identifier_normalized = LOWER($2) AND id <> $3 AND deleted_at IS NULL
+		)
+	`
+
+	if err := db.Pool.QueryRow(ctx, query, userID, identifier, excludeChannelID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check channel identifier: %w", err)
+	}
+
+	return exists, nil
 }
 
 func (db *DB) GetUserTelegramChannels(ctx context.Context, userID int) ([]models.TelegramChannel, error) {
 	query := `
-		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, is_active, created_at, updated_at
+		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, parse_mode, message_template, coalesce_window_seconds, dedup_window_seconds, thread_id, rate_limit_per_minute, is_default, webhook_secret, is_active, created_at, updated_at
 		FROM telegram_channels
-		WHERE user_id = $1
+		WHERE user_id = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -400,6 +781,14 @@ func (db *DB) GetUserTelegramChannels(ctx context.Context, userID int) ([]models
 			&channel.ChannelID,
 			&channel.ChannelName,
 			&channel.Description,
+			&channel.ParseMode,
+			&channel.MessageTemplate,
+			&channel.CoalesceWindowSeconds,
+			&channel.DedupWindowSeconds,
+			&channel.ThreadID,
+			&channel.RateLimitPerMinute,
+			&channel.IsDefault,
+			&channel.WebhookSecret,
 			&channel.IsActive,
 			&channel.CreatedAt,
 			&channel.UpdatedAt,
@@ -415,9 +804,9 @@ func (db *DB) GetUserTelegramChannels(ctx context.Context, userID int) ([]models
 
 func (db *DB) GetBotChannels(ctx context.Context, botID, userID int) ([]models.TelegramChannel, error) {
 	query := `
-		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, is_active, created_at, updated_at
+		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, parse_mode, message_template, coalesce_window_seconds, dedup_window_seconds, thread_id, rate_limit_per_minute, is_default, webhook_secret, is_active, created_at, updated_at
 		FROM telegram_channels
-		WHERE bot_id = $1 AND user_id = $2
+		WHERE bot_id = $1 AND user_id = $2 AND deleted_at IS NULL
 		ORDER BY created_at DESC
 	`
 
@@ -438,6 +827,14 @@ func (db *DB) GetBotChannels(ctx context.Context, botID, userID int) ([]models.T
 			&channel.ChannelID,
 			&channel.ChannelName,
 			&channel.Description,
+			&channel.ParseMode,
+			&channel.MessageTemplate,
+			&channel.CoalesceWindowSeconds,
+			&channel.DedupWindowSeconds,
+			&channel.ThreadID,
+			&channel.RateLimitPerMinute,
+			&channel.IsDefault,
+			&channel.WebhookSecret,
 			&channel.IsActive,
 			&channel.CreatedAt,
 			&channel.UpdatedAt,
@@ -451,7 +848,23 @@ func (db *DB) GetBotChannels(ctx context.Context, botID, userID int) ([]models.T
 	return channels, nil
 }
 
+// UpdateTelegramChannel updates a channel. Making it the default replaces the
+// user's previous default.
 func (db *DB) UpdateTelegramChannel(ctx context.Context, channelID, userID int, req models.UpdateChannelRequest) (*models.TelegramChannel, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// If this is set as default, unset other defaults for this user
+	if req.IsDefault != nil && *req.IsDefault {
+		_, err := tx.Exec(ctx, `UPDATE telegram_channels SET is_default = false WHERE user_id = $1 AND id != $2 AND is_default`, userID, channelID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unset other defaults: %w", err)
+		}
+	}
+
 	query := `
 		UPDATE telegram_channels
 		SET bot_id = COALESCE(NULLIF($1, 0), bot_id),
@@ -459,14 +872,29 @@ func (db *DB) UpdateTelegramChannel(ctx context.Context, channelID, userID int,
 		    channel_id = COALESCE(NULLIF($3, ''), channel_id),
 		    channel_name = COALESCE(NULLIF($4, ''), channel_name),
 		    description = COALESCE(NULLIF($5, ''), description),
-		    is_active = COALESCE($6, is_active),
+		    parse_mode = COALESCE(NULLIF($6, ''), parse_mode),
+		    is_active = COALESCE($7, is_active),
+		    message_template = COALESCE($10, message_template),
+		    coalesce_window_seconds = COALESCE($11, coalesce_window_seconds),
+		    dedup_window_seconds = CASE
+		        WHEN $12::INTEGER IS NULL THEN dedup_window_seconds
+		        WHEN $12 < 0 THEN NULL
+		        ELSE $12
+		    END,
+		    thread_id = COALESCE($13, thread_id),
+		    rate_limit_per_minute = CASE
+		        WHEN $14::INTEGER IS NULL THEN rate_limit_per_minute
+		        WHEN $14 = 0 THEN NULL
+		        ELSE $14
+		    END,
+		    is_default = COALESCE($15, is_default),
 		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = $7 AND user_id = $8
-		RETURNING id, user_id, bot_id, identifier, channel_id, channel_name, description, is_active, created_at, updated_at
+		WHERE id = $8 AND user_id = $9 AND deleted_at IS NULL
+		RETURNING id, user_id, bot_id, identifier, channel_id, channel_name, description, parse_mode, message_template, coalesce_window_seconds, dedup_window_seconds, thread_id, rate_limit_per_minute, is_default, webhook_secret, is_active, created_at, updated_at
 	`
 
 	var channel models.TelegramChannel
-	err := db.Pool.QueryRow(ctx, query, req.BotID, req.Identifier, req.ChannelID, req.ChannelName, req.Description, req.IsActive, channelID, userID).Scan(
+	err = tx.QueryRow(ctx, query, req.BotID, req.Identifier, req.ChannelID, req.ChannelName, req.Description, req.ParseMode, req.IsActive, channelID, userID, req.MessageTemplate, req.CoalesceWindowSeconds, req.DedupWindowSeconds, req.ThreadID, req.RateLimitPerMinute, req.IsDefault).Scan(
 		&channel.ID,
 		&channel.UserID,
 		&channel.BotID,
@@ -474,6 +902,14 @@ func (db *DB) UpdateTelegramChannel(ctx context.Context, channelID, userID int,
 		&channel.ChannelID,
 		&channel.ChannelName,
 		&channel.Description,
+		&channel.ParseMode,
+		&channel.MessageTemplate,
+		&channel.CoalesceWindowSeconds,
+		&channel.DedupWindowSeconds,
+		&channel.ThreadID,
+		&channel.RateLimitPerMinute,
+		&channel.IsDefault,
+		&channel.WebhookSecret,
 		&channel.IsActive,
 		&channel.CreatedAt,
 		&channel.UpdatedAt,
@@ -483,12 +919,29 @@ func (db *DB) UpdateTelegramChannel(ctx context.Context, channelID, userID int,
 		return nil, fmt.Errorf("failed to update telegram channel: %w", err)
 	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit telegram channel: %w", err)
+	}
+
 	return &channel, nil
 }
 
+// DeleteTelegramChannel soft-deletes a channel: it disappears from every
+// lookup except analytics name resolution until PurgeDeletedChannels removes
+// it. Its schedules and escalation policies are deleted as before.
 func (db *DB) DeleteTelegramChannel(ctx context.Context, channelID, userID int) error {
-	query := `DELETE FROM telegram_channels WHERE id = $1 AND user_id = $2`
-	result, err := db.Pool.Exec(ctx, query, channelID, userID)
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE telegram_channels
+		SET deleted_at = CURRENT_TIMESTAMP, is_default = false, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+	`
+	result, err := tx.Exec(ctx, query, channelID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete telegram channel: %w", err)
 	}
@@ -497,14 +950,74 @@ func (db *DB) DeleteTelegramChannel(ctx context.Context, channelID, userID int)
 		return fmt.Errorf("channel not found or not owned by user")
 	}
 
+	// What the foreign keys did when the row itself was deleted
+	for _, query := range []string{
+		`DELETE FROM scheduled_messages WHERE channel_id = $1`,
+		`DELETE FROM escalation_policies WHERE channel_id = $1`,
+		`UPDATE escalation_policies SET failover_channel_id = NULL WHERE failover_channel_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, channelID); err != nil {
+			return fmt.Errorf("failed to delete channel data: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit channel deletion: %w", err)
+	}
+
 	return nil
 }
 
+// PurgeDeletedChannels permanently removes channels deleted before cutoff,
+// returning how many were removed. Logs that referenced them lose their
+// channel names in analytics.
+func (db *DB) PurgeDeletedChannels(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM telegram_channels WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	result, err := db.Pool.Exec(ctx, query, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge deleted channels: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// GetRateLimitOverrides returns the bots and channels with their own send
+// rates, for loading into the Telegram rate limiters at startup
+func (db *DB) GetRateLimitOverrides(ctx context.Context) ([]models.RateLimitOverride, error) {
+	query := `
+		SELECT bot_token, '', rate_limit_per_second
+		FROM telegram_bots
+		WHERE rate_limit_per_second IS NOT NULL
+		UNION ALL
+		SELECT b.bot_token, c.channel_id, c.rate_limit_per_minute
+		FROM telegram_channels c
+		JOIN telegram_bots b ON b.id = c.bot_id
+		WHERE c.rate_limit_per_minute IS NOT NULL AND c.deleted_at IS NULL
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limit overrides: %w", err)
+	}
+	defer rows.Close()
+
+	var overrides []models.RateLimitOverride
+	for rows.Next() {
+		var o models.RateLimitOverride
+		if err := rows.Scan(&o.BotToken, &o.ChannelID, &o.Limit); err != nil {
+			return nil, fmt.Errorf("failed to scan rate limit override: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+
+	return overrides, rows.Err()
+}
+
 // GetBotByID retrieves bot by ID for internal use
 func (db *DB) GetBotByID(ctx context.Context, botID int) (*models.TelegramBot, error) {
 	var bot models.TelegramBot
 	query := `
-		SELECT id, user_id, bot_token, bot_username, is_default, created_at, updated_at
+		SELECT id, user_id, bot_token, bot_username, is_default, message_template, rate_limit_per_second, created_at, updated_at
 		FROM telegram_bots
 		WHERE id = $1
 	`
@@ -515,6 +1028,8 @@ func (db *DB) GetBotByID(ctx context.Context, botID int) (*models.TelegramBot, e
 		&bot.BotToken,
 		&bot.BotUsername,
 		&bot.IsDefault,
+		&bot.MessageTemplate,
+		&bot.RateLimitPerSecond,
 		&bot.CreatedAt,
 		&bot.UpdatedAt,
 	)
@@ -526,14 +1041,15 @@ func (db *DB) GetBotByID(ctx context.Context, botID int) (*models.TelegramBot, e
 	return &bot, nil
 }
 
-// GetDefaultTelegramChannel retrieves the first active channel for a user
+// GetDefaultTelegramChannel retrieves the user's default channel, or their
+// oldest active channel when none is marked as default or it is inactive
 func (db *DB) GetDefaultTelegramChannel(ctx context.Context, userID int) (*models.TelegramChannel, error) {
 	var channel models.TelegramChannel
 	query := `
-		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, is_active, created_at, updated_at
+		SELECT id, user_id, bot_id, identifier, channel_id, channel_name, description, parse_mode, message_template, coalesce_window_seconds, dedup_window_seconds, thread_id, rate_limit_per_minute, is_default, webhook_secret, is_active, created_at, updated_at
 		FROM telegram_channels
-		WHERE user_id = $1 AND is_active = true
-		ORDER BY created_at ASC
+		WHERE user_id = $1 AND is_active = true AND deleted_at IS NULL
+		ORDER BY is_default DESC, created_at ASC
 		LIMIT 1
 	`
 
@@ -545,6 +1061,14 @@ func (db *DB) GetDefaultTelegramChannel(ctx context.Context, userID int) (*model
 		&channel.ChannelID,
 		&channel.ChannelName,
 		&channel.Description,
+		&channel.ParseMode,
+		&channel.MessageTemplate,
+		&channel.CoalesceWindowSeconds,
+		&channel.DedupWindowSeconds,
+		&channel.ThreadID,
+		&channel.RateLimitPerMinute,
+		&channel.IsDefault,
+		&channel.WebhookSecret,
 		&channel.IsActive,
 		&channel.CreatedAt,
 		&channel.UpdatedAt,
@@ -558,19 +1082,1933 @@ func (db *DB) GetDefaultTelegramChannel(ctx context.Context, userID int) (*model
 }
 
 // ============================================================================
-// Analytics Queries
+// Scheduled Message CRUD Operations
 // ============================================================================
 
-// GetAnalytics retrieves comprehensive analytics for a user within a time range
-func (db *DB) GetAnalytics(ctx context.Context, userID int, timeRange string) (*models.AnalyticsResponse, error) {
-	var response models.AnalyticsResponse
-	response.TimeRange = timeRange
+func (db *DB) CreateScheduledMessage(ctx context.Context, schedule *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	query := `
+		INSERT INTO scheduled_messages (user_id, channel_id, name, cron_expression, timezone, message, priority, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, user_id, channel_id, name, cron_expression, timezone, message, priority, is_active, next_run_at, last_run_at, created_at, updated_at
+	`
 
-	// Calculate time boundaries
-	var since time.Time
-	now := time.Now()
+	var created models.ScheduledMessage
+	err := db.Pool.QueryRow(ctx, query,
+		schedule.UserID,
+		schedule.ChannelID,
+		schedule.Name,
+		schedule.CronExpression,
+		schedule.Timezone,
+		schedule.Message,
+		schedule.Priority,
+		schedule.NextRunAt.UTC(),
+	).Scan(
+		&created.ID,
+		&created.UserID,
+		&created.ChannelID,
+		&created.Name,
+		&created.CronExpression,
+		&created.Timezone,
+		&created.Message,
+		&created.Priority,
+		&created.IsActive,
+		&created.NextRunAt,
+		&created.LastRunAt,
+		&created.CreatedAt,
+		&created.UpdatedAt,
+	)
 
-	switch timeRange {
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scheduled message: %w", err)
+	}
+
+	return &created, nil
+}
+
+func (db *DB) GetScheduledMessage(ctx context.Context, scheduleID, userID int) (*models.ScheduledMessage, error) {
+	var schedule models.ScheduledMessage
+	query := `
+		SELECT id, user_id, channel_id, name, cron_expression, timezone, message, priority, is_active, next_run_at, last_run_at, created_at, updated_at
+		FROM scheduled_messages
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := db.Pool.QueryRow(ctx, query, scheduleID, userID).Scan(
+		&schedule.ID,
+		&schedule.UserID,
+		&schedule.ChannelID,
+		&schedule.Name,
+		&schedule.CronExpression,
+		&schedule.Timezone,
+		&schedule.Message,
+		&schedule.Priority,
+		&schedule.IsActive,
+		&schedule.NextRunAt,
+		&schedule.LastRunAt,
+		&schedule.CreatedAt,
+		&schedule.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled message: %w", err)
+	}
+
+	return &schedule, nil
+}
+
+func (db *DB) GetUserScheduledMessages(ctx context.Context, userID int) ([]models.ScheduledMessage, error) {
+	query := `
+		SELECT id, user_id, channel_id, name, cron_expression, timezone, message, priority, is_active, next_run_at, last_run_at, created_at, updated_at
+		FROM scheduled_messages
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled messages: %w", err)
+	}
+	defer rows.Close()
+
+	var schedules []models.ScheduledMessage
+	for rows.Next() {
+		var schedule models.ScheduledMessage
+		err := rows.Scan(
+			&schedule.ID,
+			&schedule.UserID,
+			&schedule.ChannelID,
+			&schedule.Name,
+			&schedule.CronExpression,
+			&schedule.Timezone,
+			&schedule.Message,
+			&schedule.Priority,
+			&schedule.IsActive,
+			&schedule.NextRunAt,
+			&schedule.LastRunAt,
+			&schedule.CreatedAt,
+			&schedule.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled message: %w", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, nil
+}
+
+// UpdateScheduledMessage saves all mutable fields of a scheduled message
+func (db *DB) UpdateScheduledMessage(ctx context.Context, schedule *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	query := `
+		UPDATE scheduled_messages
+		SET channel_id = $1,
+		    name = $2,
+		    cron_expression = $3,
+		    timezone = $4,
+		    message = $5,
+		    priority = $6,
+		    is_active = $7,
+		    next_run_at = $8,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $9 AND user_id = $10
+		RETURNING id, user_id, channel_id, name, cron_expression, timezone, message, priority, is_active, next_run_at, last_run_at, created_at, updated_at
+	`
+
+	var updated models.ScheduledMessage
+	err := db.Pool.QueryRow(ctx, query,
+		schedule.ChannelID,
+		schedule.Name,
+		schedule.CronExpression,
+		schedule.Timezone,
+		schedule.Message,
+		schedule.Priority,
+		schedule.IsActive,
+		schedule.NextRunAt.UTC(),
+		schedule.ID,
+		schedule.UserID,
+	).Scan(
+		&updated.ID,
+		&updated.UserID,
+		&updated.ChannelID,
+		&updated.Name,
+		&updated.CronExpression,
+		&updated.Timezone,
+		&updated.Message,
+		&updated.Priority,
+		&updated.IsActive,
+		&updated.NextRunAt,
+		&updated.LastRunAt,
+		&updated.CreatedAt,
+		&updated.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update scheduled message: %w", err)
+	}
+
+	return &updated, nil
+}
+
+func (db *DB) DeleteScheduledMessage(ctx context.Context, scheduleID, userID int) error {
+	query := `DELETE FROM scheduled_messages WHERE id = $1 AND user_id = $2`
+	result, err := db.Pool.Exec(ctx, query, scheduleID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled message: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("scheduled message not found or not owned by user")
+	}
+
+	return nil
+}
+
+// GetDueScheduledMessages returns active schedules whose next run is at or
+// before now, along with the bot and channel needed to deliver them
+func (db *DB) GetDueScheduledMessages(ctx context.Context, now time.Time) ([]models.DueScheduledMessage, error) {
+	query := `
+		SELECT s.id, s.user_id, s.channel_id, s.name, s.cron_expression, s.timezone, s.message, s.priority,
+		       s.is_active, s.next_run_at, s.last_run_at, s.created_at, s.updated_at,
+		       u.username, b.bot_token, c.channel_id, c.identifier, c.parse_mode,
+		       c.message_template, b.message_template
+		FROM scheduled_messages s
+		JOIN users u ON u.id = s.user_id
+		JOIN telegram_channels c ON c.id = s.channel_id
+		JOIN telegram_bots b ON b.id = c.bot_id
+		WHERE s.is_active = true AND c.is_active = true AND s.next_run_at <= $1
+		ORDER BY s.next_run_at ASC
+	`
+
+	rows, err := db.Pool.Query(ctx, query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due scheduled messages: %w", err)
+	}
+	defer rows.Close()
+
+	var due []models.DueScheduledMessage
+	for rows.Next() {
+		var schedule models.DueScheduledMessage
+		err := rows.Scan(
+			&schedule.ID,
+			&schedule.UserID,
+			&schedule.ChannelID,
+			&schedule.Name,
+			&schedule.CronExpression,
+			&schedule.Timezone,
+			&schedule.Message,
+			&schedule.Priority,
+			&schedule.IsActive,
+			&schedule.NextRunAt,
+			&schedule.LastRunAt,
+			&schedule.CreatedAt,
+			&schedule.UpdatedAt,
+			&schedule.Username,
+			&schedule.BotToken,
+			&schedule.TelegramChannelID,
+			&schedule.Identifier,
+			&schedule.ParseMode,
+			&schedule.ChannelTemplate,
+			&schedule.BotTemplate,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan due scheduled message: %w", err)
+		}
+		due = append(due, schedule)
+	}
+
+	return due, nil
+}
+
+// ClaimScheduledRun advances a schedule from dueAt to nextRunAt. The update only
+// succeeds if next_run_at still equals dueAt, so when several replicas see the
+// same due schedule exactly one of them claims (and fires) the occurrence.
+func (db *DB) ClaimScheduledRun(ctx context.Context, scheduleID int, dueAt, nextRunAt time.Time) (bool, error) {
+	query := `
+		UPDATE scheduled_messages
+		SET next_run_at = $1, last_run_at = $3
+		WHERE id = $2 AND next_run_at = $3
+	`
+
+	result, err := db.Pool.Exec(ctx, query, nextRunAt.UTC(), scheduleID, dueAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduled run: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// ============================================================================
+// Receipt Webhook Operations
+// ============================================================================
+
+// UpsertReceiptWebhook creates or replaces the user's receipt webhook
+func (db *DB) UpsertReceiptWebhook(ctx context.Context, webhook *models.ReceiptWebhook) (*models.ReceiptWebhook, error) {
+	query := `
+		INSERT INTO receipt_webhooks (user_id, url, secret, interval_minutes, is_active, next_send_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET url = EXCLUDED.url,
+		    secret = EXCLUDED.secret,
+		    interval_minutes = EXCLUDED.interval_minutes,
+		    is_active = EXCLUDED.is_active,
+		    next_send_at = EXCLUDED.next_send_at,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id, user_id, url, secret, interval_minutes, is_active, next_send_at, last_window_end, created_at, updated_at
+	`
+
+	var saved models.ReceiptWebhook
+	err := db.Pool.QueryRow(ctx, query,
+		webhook.UserID,
+		webhook.URL,
+		webhook.Secret,
+		webhook.IntervalMinutes,
+		webhook.IsActive,
+		webhook.NextSendAt.UTC(),
+	).Scan(
+		&saved.ID,
+		&saved.UserID,
+		&saved.URL,
+		&saved.Secret,
+		&saved.IntervalMinutes,
+		&saved.IsActive,
+		&saved.NextSendAt,
+		&saved.LastWindowEnd,
+		&saved.CreatedAt,
+		&saved.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save receipt webhook: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func (db *DB) GetReceiptWebhook(ctx context.Context, userID int) (*models.ReceiptWebhook, error) {
+	var webhook models.ReceiptWebhook
+	query := `
+		SELECT id, user_id, url, secret, interval_minutes, is_active, next_send_at, last_window_end, created_at, updated_at
+		FROM receipt_webhooks
+		WHERE user_id = $1
+	`
+
+	err := db.Pool.QueryRow(ctx, query, userID).Scan(
+		&webhook.ID,
+		&webhook.UserID,
+		&webhook.URL,
+		&webhook.Secret,
+		&webhook.IntervalMinutes,
+		&webhook.IsActive,
+		&webhook.NextSendAt,
+		&webhook.LastWindowEnd,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get receipt webhook: %w", err)
+	}
+
+	return &webhook, nil
+}
+
+func (db *DB) DeleteReceiptWebhook(ctx context.Context, userID int) error {
+	query := `DELETE FROM receipt_webhooks WHERE user_id = $1`
+	result, err := db.Pool.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete receipt webhook: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("receipt webhook not found")
+	}
+
+	return nil
+}
+
+// GetDueReceiptWebhooks returns active receipt webhooks whose next send is due
+func (db *DB) GetDueReceiptWebhooks(ctx context.Context, now time.Time) ([]models.DueReceiptWebhook, error) {
+	query := `
+		SELECT r.id, r.user_id, r.url, r.secret, r.interval_minutes, r.is_active, r.next_send_at, r.last_window_end,
+		       r.created_at, r.updated_at, u.webhook_token
+		FROM receipt_webhooks r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.is_active = true AND r.next_send_at <= $1
+		ORDER BY r.next_send_at ASC
+	`
+
+	rows, err := db.Pool.Query(ctx, query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due receipt webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var due []models.DueReceiptWebhook
+	for rows.Next() {
+		var webhook models.DueReceiptWebhook
+		err := rows.Scan(
+			&webhook.ID,
+			&webhook.UserID,
+			&webhook.URL,
+			&webhook.Secret,
+			&webhook.IntervalMinutes,
+			&webhook.IsActive,
+			&webhook.NextSendAt,
+			&webhook.LastWindowEnd,
+			&webhook.CreatedAt,
+			&webhook.UpdatedAt,
+			&webhook.WebhookToken,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan receipt webhook: %w", err)
+		}
+		due = append(due, webhook)
+	}
+
+	return due, nil
+}
+
+// ClaimReceiptWindow advances a receipt webhook past dueAt, recording windowEnd
+// as the end of the reported window. Like ClaimScheduledRun it only succeeds
+// for the one replica that still sees next_send_at = dueAt.
+func (db *DB) ClaimReceiptWindow(ctx context.Context, webhookID int, dueAt, nextSendAt, windowEnd time.Time) (bool, error) {
+	query := `
+		UPDATE receipt_webhooks
+		SET next_send_at = $1, last_window_end = $2
+		WHERE id = $3 AND next_send_at = $4
+	`
+
+	result, err := db.Pool.Exec(ctx, query, nextSendAt.UTC(), windowEnd.UTC(), webhookID, dueAt.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim receipt window: %w", err)
+	}
+
+	return result.RowsAffected() == 1, nil
+}
+
+// ============================================================================
+// Escalation Policy and Dead-Letter Operations
+// ============================================================================
+
+// UpsertEscalationPolicy creates or replaces the policy for the policy's scope
+// (the user default when ChannelID is nil, otherwise that channel)
+func (db *DB) UpsertEscalationPolicy(ctx context.Context, policy *models.EscalationPolicy) (*models.EscalationPolicy, error) {
+	query := `
+		INSERT INTO escalation_policies (user_id, channel_id, failover_channel_id, mirror_url, dead_letter, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, (COALESCE(channel_id, 0))) DO UPDATE
+		SET failover_channel_id = EXCLUDED.failover_channel_id,
+		    mirror_url = EXCLUDED.mirror_url,
+		    dead_letter = EXCLUDED.dead_letter,
+		    is_active = EXCLUDED.is_active,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id, user_id, channel_id, failover_channel_id, mirror_url, dead_letter, is_active, created_at, updated_at
+	`
+
+	var saved models.EscalationPolicy
+	err := db.Pool.QueryRow(ctx, query,
+		policy.UserID,
+		policy.ChannelID,
+		policy.FailoverChannelID,
+		policy.MirrorURL,
+		policy.DeadLetter,
+		policy.IsActive,
+	).Scan(
+		&saved.ID,
+		&saved.UserID,
+		&saved.ChannelID,
+		&saved.FailoverChannelID,
+		&saved.MirrorURL,
+		&saved.DeadLetter,
+		&saved.IsActive,
+		&saved.CreatedAt,
+		&saved.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save escalation policy: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func (db *DB) GetUserEscalationPolicies(ctx context.Context, userID int) ([]models.EscalationPolicy, error) {
+	query := `
+		SELECT id, user_id, channel_id, failover_channel_id, mirror_url, dead_letter, is_active, created_at, updated_at
+		FROM escalation_policies
+		WHERE user_id = $1
+		ORDER BY channel_id NULLS FIRST, id ASC
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get escalation policies: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []models.EscalationPolicy
+	for rows.Next() {
+		var policy models.EscalationPolicy
+		err := rows.Scan(
+			&policy.ID,
+			&policy.UserID,
+			&policy.ChannelID,
+			&policy.FailoverChannelID,
+			&policy.MirrorURL,
+			&policy.DeadLetter,
+			&policy.IsActive,
+			&policy.CreatedAt,
+			&policy.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan escalation policy: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
+
+// GetEffectiveEscalationPolicy returns the active policy that applies to a
+// channel: the channel's own policy, falling back to the user default
+func (db *DB) GetEffectiveEscalationPolicy(ctx context.Context, userID, channelID int) (*models.EscalationPolicy, error) {
+	var policy models.EscalationPolicy
+	query := `
+		SELECT id, user_id, channel_id, failover_channel_id, mirror_url, dead_letter, is_active, created_at, updated_at
+		FROM escalation_policies
+		WHERE user_id = $1 AND is_active = true AND (channel_id = $2 OR channel_id IS NULL)
+		ORDER BY channel_id NULLS LAST
+		LIMIT 1
+	`
+
+	err := db.Pool.QueryRow(ctx, query, userID, channelID).Scan(
+		&policy.ID,
+		&policy.UserID,
+		&policy.ChannelID,
+		&policy.FailoverChannelID,
+		&policy.MirrorURL,
+		&policy.DeadLetter,
+		&policy.IsActive,
+		&policy.CreatedAt,
+		&policy.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get escalation policy: %w", err)
+	}
+
+	return &policy, nil
+}
+
+func (db *DB) DeleteEscalationPolicy(ctx context.Context, policyID, userID int) error {
+	query := `DELETE FROM escalation_policies WHERE id = $1 AND user_id = $2`
+	result, err := db.Pool.Exec(ctx, query, policyID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete escalation policy: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("escalation policy not found or not owned by user")
+	}
+
+	return nil
+}
+
+func (db *DB) CreateDeadLetterAlert(ctx context.Context, alert *models.DeadLetterAlert) error {
+	payloadJSON, err := json.Marshal(alert.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO dead_letter_alerts (user_id, alert_id, channel_id, payload, priority, retries, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = db.Pool.Exec(ctx, query,
+		alert.UserID,
+		alert.AlertID,
+		alert.ChannelID,
+		payloadJSON,
+		alert.Priority,
+		alert.Retries,
+		alert.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create dead-letter alert: %w", err)
+	}
+
+	return nil
+}
+
+func (db *DB) GetUserDeadLetterAlerts(ctx context.Context, userID int, limit int) ([]models.DeadLetterAlert, error) {
+	query := `
+		SELECT id, user_id, alert_id, channel_id, payload, priority, retries, error, created_at
+		FROM dead_letter_alerts
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dead-letter alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.DeadLetterAlert
+	for rows.Next() {
+		var alert models.DeadLetterAlert
+		var payloadJSON []byte
+		err := rows.Scan(
+			&alert.ID,
+			&alert.UserID,
+			&alert.AlertID,
+			&alert.ChannelID,
+			&payloadJSON,
+			&alert.Priority,
+			&alert.Retries,
+			&alert.Error,
+			&alert.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dead-letter alert: %w", err)
+		}
+
+		if err := json.Unmarshal(payloadJSON, &alert.Payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// ============================================================================
+// Maintenance Notice Operations
+// ============================================================================
+
+func (db *DB) CreateMaintenanceNotice(ctx context.Context, notice *models.MaintenanceNotice) (*models.MaintenanceNotice, error) {
+	query := `
+		INSERT INTO maintenance_notices (user_id, message, starts_at, ends_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, message, starts_at, ends_at, created_at
+	`
+
+	var saved models.MaintenanceNotice
+	err := db.Pool.QueryRow(ctx, query, notice.UserID, notice.Message, notice.StartsAt.UTC(), notice.EndsAt.UTC()).Scan(
+		&saved.ID,
+		&saved.UserID,
+		&saved.Message,
+		&saved.StartsAt,
+		&saved.EndsAt,
+		&saved.CreatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create maintenance notice: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// GetMaintenanceNotices returns notices that have not yet ended at now,
+// including ones scheduled to start later
+func (db *DB) GetMaintenanceNotices(ctx context.Context, now time.Time) ([]models.MaintenanceNotice, error) {
+	query := `
+		SELECT id, user_id, message, starts_at, ends_at, created_at
+		FROM maintenance_notices
+		WHERE ends_at > $1
+		ORDER BY starts_at ASC
+	`
+
+	rows, err := db.Pool.Query(ctx, query, now.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get maintenance notices: %w", err)
+	}
+	defer rows.Close()
+
+	var notices []models.MaintenanceNotice
+	for rows.Next() {
+		var notice models.MaintenanceNotice
+		err := rows.Scan(
+			&notice.ID,
+			&notice.UserID,
+			&notice.Message,
+			&notice.StartsAt,
+			&notice.EndsAt,
+			&notice.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan maintenance notice: %w", err)
+		}
+		notices = append(notices, notice)
+	}
+
+	return notices, nil
+}
+
+func (db *DB) DeleteMaintenanceNotice(ctx context.Context, noticeID int) error {
+	query := `DELETE FROM maintenance_notices WHERE id = $1`
+	result, err := db.Pool.Exec(ctx, query, noticeID)
+	if err != nil {
+		return fmt.Errorf("failed to delete maintenance notice: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("maintenance notice not found")
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Batch Submission Operations
+// ============================================================================
+
+// CreateAlertBatch records the alerts of a batch submission as queued
+func (db *DB) CreateAlertBatch(ctx context.Context, batchID string, userID int, alertIDs []string) error {
+	query := `
+		INSERT INTO batch_alerts (alert_id, batch_id, user_id)
+		SELECT alert_id::uuid, $2, $3
+		FROM unnest($1::text[]) AS alert_id
+	`
+
+	_, err := db.Pool.Exec(ctx, query, alertIDs, batchID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to create alert batch: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateBatchAlertStatus records the outcome of an alert in a batch
+func (db *DB) UpdateBatchAlertStatus(ctx context.Context, alertID, status, errMsg string) error {
+	query := `
+		UPDATE batch_alerts
+		SET status = $2, error = $3, updated_at = $4
+		WHERE alert_id = $1
+	`
+
+	_, err := db.Pool.Exec(ctx, query, alertID, status, errMsg, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update batch alert status: %w", err)
+	}
+
+	return nil
+}
+
+// GetAlertBatch returns the per-alert statuses of a user's batch submission
+func (db *DB) GetAlertBatch(ctx context.Context, batchID string, userID int) (*models.AlertBatch, error) {
+	query := `
+		SELECT alert_id::text, status, error, updated_at, created_at
+		FROM batch_alerts
+		WHERE batch_id = $1 AND user_id = $2
+		ORDER BY created_at, alert_id
+	`
+
+	rows, err := db.Pool.Query(ctx, query, batchID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert batch: %w", err)
+	}
+	defer rows.Close()
+
+	batch := &models.AlertBatch{
+		BatchID: batchID,
+		Counts:  map[string]int{"queued": 0, "completed": 0, "failed": 0},
+		Alerts:  make([]models.BatchAlertStatus, 0),
+	}
+	for rows.Next() {
+		var alert models.BatchAlertStatus
+		if err := rows.Scan(&alert.AlertID, &alert.Status, &alert.Error, &alert.UpdatedAt, &batch.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan batch alert: %w", err)
+		}
+		batch.Counts[alert.Status]++
+		batch.Alerts = append(batch.Alerts, alert)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get alert batch: %w", err)
+	}
+
+	if len(batch.Alerts) == 0 {
+		return nil, fmt.Errorf("alert batch not found")
+	}
+
+	batch.Total = len(batch.Alerts)
+	batch.Done = batch.Counts["queued"] == 0
+
+	return batch, nil
+}
+
+// ============================================================================
+// Payload Transform Operations
+// ============================================================================
+
+// UpsertPayloadTransform creates or replaces the user's payload transform
+func (db *DB) UpsertPayloadTransform(ctx context.Context, transform *models.PayloadTransform) (*models.PayloadTransform, error) {
+	query := `
+		INSERT INTO payload_transforms (user_id, expression, is_active)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET expression = EXCLUDED.expression,
+		    is_active = EXCLUDED.is_active,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id, user_id, expression, is_active, created_at, updated_at
+	`
+
+	var saved models.PayloadTransform
+	err := db.Pool.QueryRow(ctx, query,
+		transform.UserID,
+		transform.Expression,
+		transform.IsActive,
+	).Scan(
+		&saved.ID,
+		&saved.UserID,
+		&saved.Expression,
+		&saved.IsActive,
+		&saved.CreatedAt,
+		&saved.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save payload transform: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func (db *DB) GetPayloadTransform(ctx context.Context, userID int) (*models.PayloadTransform, error) {
+	var transform models.PayloadTransform
+	query := `
+		SELECT id, user_id, expression, is_active, created_at, updated_at
+		FROM payload_transforms
+		WHERE user_id = $1
+	`
+
+	err := db.Pool.QueryRow(ctx, query, userID).Scan(
+		&transform.ID,
+		&transform.UserID,
+		&transform.Expression,
+		&transform.IsActive,
+		&transform.CreatedAt,
+		&transform.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payload transform: %w", err)
+	}
+
+	return &transform, nil
+}
+
+func (db *DB) DeletePayloadTransform(ctx context.Context, userID int) error {
+	query := `DELETE FROM payload_transforms WHERE user_id = $1`
+	result, err := db.Pool.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete payload transform: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("payload transform not found")
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Quiet Hours Operations
+// ============================================================================
+
+// UpsertQuietHours creates or replaces the user's quiet hours
+func (db *DB) UpsertQuietHours(ctx context.Context, quiet *models.QuietHours) (*models.QuietHours, error) {
+	query := `
+		INSERT INTO quiet_hours (user_id, start_hour, end_hour, timezone, is_active)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET start_hour = EXCLUDED.start_hour,
+		    end_hour = EXCLUDED.end_hour,
+		    timezone = EXCLUDED.timezone,
+		    is_active = EXCLUDED.is_active,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING user_id, start_hour, end_hour, timezone, is_active, created_at, updated_at
+	`
+
+	var saved models.QuietHours
+	err := db.Pool.QueryRow(ctx, query,
+		quiet.UserID,
+		quiet.StartHour,
+		quiet.EndHour,
+		quiet.Timezone,
+		quiet.IsActive,
+	).Scan(
+		&saved.UserID,
+		&saved.StartHour,
+		&saved.EndHour,
+		&saved.Timezone,
+		&saved.IsActive,
+		&saved.CreatedAt,
+		&saved.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save quiet hours: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func (db *DB) GetQuietHours(ctx context.Context, userID int) (*models.QuietHours, error) {
+	var quiet models.QuietHours
+	query := `
+		SELECT user_id, start_hour, end_hour, timezone, is_active, created_at, updated_at
+		FROM quiet_hours
+		WHERE user_id = $1
+	`
+
+	err := db.Pool.QueryRow(ctx, query, userID).Scan(
+		&quiet.UserID,
+		&quiet.StartHour,
+		&quiet.EndHour,
+		&quiet.Timezone,
+		&quiet.IsActive,
+		&quiet.CreatedAt,
+		&quiet.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiet hours: %w", err)
+	}
+
+	return &quiet, nil
+}
+
+// GetActiveQuietHours returns every user's active quiet hours, for the rule
+// engine's cache
+func (db *DB) GetActiveQuietHours(ctx context.Context) ([]models.QuietHours, error) {
+	query := `
+		SELECT user_id, start_hour, end_hour, timezone, is_active, created_at, updated_at
+		FROM quiet_hours
+		WHERE is_active = true
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quiet hours: %w", err)
+	}
+	defer rows.Close()
+
+	var all []models.QuietHours
+	for rows.Next() {
+		var quiet models.QuietHours
+		err := rows.Scan(
+			&quiet.UserID,
+			&quiet.StartHour,
+			&quiet.EndHour,
+			&quiet.Timezone,
+			&quiet.IsActive,
+			&quiet.CreatedAt,
+			&quiet.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan quiet hours: %w", err)
+		}
+		all = append(all, quiet)
+	}
+
+	return all, rows.Err()
+}
+
+func (db *DB) DeleteQuietHours(ctx context.Context, userID int) error {
+	query := `DELETE FROM quiet_hours WHERE user_id = $1`
+	result, err := db.Pool.Exec(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete quiet hours: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("quiet hours not found")
+	}
+
+	return nil
+}
+
+// ============================================================================
+// User Settings Operations
+// ============================================================================
+
+// UpsertUserSettings creates or replaces the user's settings
+func (db *DB) UpsertUserSettings(ctx context.Context, settings *models.UserSettings) (*models.UserSettings, error) {
+	query := `
+		INSERT INTO user_settings (user_id, default_max_retries, backoff_base_seconds, backoff_max_seconds, dedup_window_seconds, dedup_across_channels)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE
+		SET default_max_retries = EXCLUDED.default_max_retries,
+		    backoff_base_seconds = EXCLUDED.backoff_base_seconds,
+		    backoff_max_seconds = EXCLUDED.backoff_max_seconds,
+		    dedup_window_seconds = EXCLUDED.dedup_window_seconds,
+		    dedup_across_channels = EXCLUDED.dedup_across_channels,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING user_id, default_max_retries, backoff_base_seconds, backoff_max_seconds, dedup_window_seconds, dedup_across_channels, updated_at
+	`
+
+	var saved models.UserSettings
+	err := db.Pool.QueryRow(ctx, query,
+		settings.UserID,
+		settings.DefaultMaxRetries,
+		settings.BackoffBaseSeconds,
+		settings.BackoffMaxSeconds,
+		settings.DedupWindowSeconds,
+		settings.DedupAcrossChannels,
+	).Scan(
+		&saved.UserID,
+		&saved.DefaultMaxRetries,
+		&saved.BackoffBaseSeconds,
+		&saved.BackoffMaxSeconds,
+		&saved.DedupWindowSeconds,
+		&saved.DedupAcrossChannels,
+		&saved.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to save user settings: %w", err)
+	}
+
+	return &saved, nil
+}
+
+func (db *DB) GetUserSettings(ctx context.Context, userID int) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	query := `
+		SELECT user_id, default_max_retries, backoff_base_seconds, backoff_max_seconds, dedup_window_seconds, dedup_across_channels, updated_at
+		FROM user_settings
+		WHERE user_id = $1
+	`
+
+	err := db.Pool.QueryRow(ctx, query, userID).Scan(
+		&settings.UserID,
+		&settings.DefaultMaxRetries,
+		&settings.BackoffBaseSeconds,
+		&settings.BackoffMaxSeconds,
+		&settings.DedupWindowSeconds,
+		&settings.DedupAcrossChannels,
+		&settings.UpdatedAt,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+
+	return &settings, nil
+}
+
+// ============================================================================
+// Queued Alert Operations
+// ============================================================================
+
+// SaveQueuedAlert records an alert as pending, updating its retry state if it
+// is already stored
+func (db *DB) SaveQueuedAlert(ctx context.Context, alert *models.QueuedAlert) error {
+	payloadJSON, err := json.Marshal(alert.Payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO queued_alerts (
+			id, user_id, username, payload, priority, retries, max_retries, scheduled_at,
+			bot_token, telegram_channel_id, channel_id, format, channel_template, bot_template,
+			correlation_id, coalesce_window_ms, batch_id, backoff_base_ms, backoff_max_ms, dedup_window_ms, dedup_across_channels,
+			silent, thread_id, created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25)
+		ON CONFLICT (id) DO UPDATE
+		SET retries = EXCLUDED.retries,
+		    scheduled_at = EXCLUDED.scheduled_at,
+		    status = 'pending',
+		    updated_at = EXCLUDED.updated_at
+	`
+
+	_, err = db.Pool.Exec(ctx, query,
+		alert.ID,
+		alert.UserID,
+		alert.Username,
+		payloadJSON,
+		alert.Priority,
+		alert.Retries,
+		alert.MaxRetries,
+		alert.ScheduledAt.UTC(),
+		alert.BotToken,
+		alert.TelegramChannelID,
+		alert.ChannelID,
+		alert.Format,
+		alert.ChannelTemplate,
+		alert.BotTemplate,
+		alert.CorrelationID,
+		alert.CoalesceWindowMs,
+		alert.BatchID,
+		alert.BackoffBaseMs,
+		alert.BackoffMaxMs,
+		alert.DedupWindowMs,
+		alert.DedupAcrossChannels,
+		alert.Silent,
+		alert.ThreadID,
+		alert.CreatedAt.UTC(),
+		time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save queued alert: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateQueuedAlertStatus marks a queued alert as processed or failed
+func (db *DB) UpdateQueuedAlertStatus(ctx context.Context, alertID, status string) error {
+	query := `
+		UPDATE queued_alerts
+		SET status = $2, updated_at = $3
+		WHERE id = $1
+	`
+
+	_, err := db.Pool.Exec(ctx, query, alertID, status, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to update queued alert status: %w", err)
+	}
+
+	return nil
+}
+
+// GetPendingQueuedAlerts returns unfinished alerts, most urgent and oldest first
+func (db *DB) GetPendingQueuedAlerts(ctx context.Context) ([]models.QueuedAlert, error) {
+	query := `
+		SELECT id::text, user_id, username, payload, priority, retries, max_retries, scheduled_at,
+		       bot_token, telegram_channel_id, channel_id, format, channel_template, bot_template,
+		       correlation_id, coalesce_window_ms, batch_id::text, backoff_base_ms, backoff_max_ms, dedup_window_ms, dedup_across_channels, silent, thread_id, created_at
+		FROM queued_alerts
+		WHERE status = 'pending'
+		ORDER BY priority, created_at
+	`
+
+	rows, err := db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending queued alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var alerts []models.QueuedAlert
+	for rows.Next() {
+		var alert models.QueuedAlert
+		var payloadJSON []byte
+		err := rows.Scan(
+			&alert.ID,
+			&alert.UserID,
+			&alert.Username,
+			&payloadJSON,
+			&alert.Priority,
+			&alert.Retries,
+			&alert.MaxRetries,
+			&alert.ScheduledAt,
+			&alert.BotToken,
+			&alert.TelegramChannelID,
+			&alert.ChannelID,
+			&alert.Format,
+			&alert.ChannelTemplate,
+			&alert.BotTemplate,
+			&alert.CorrelationID,
+			&alert.CoalesceWindowMs,
+			&alert.BatchID,
+			&alert.BackoffBaseMs,
+			&alert.BackoffMaxMs,
+			&alert.DedupWindowMs,
+			&alert.DedupAcrossChannels,
+			&alert.Silent,
+			&alert.ThreadID,
+			&alert.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan queued alert: %w", err)
+		}
+
+		if err := json.Unmarshal(payloadJSON, &alert.Payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, nil
+}
+
+// DeleteFinishedQueuedAlerts removes processed and failed alerts last updated
+// before cutoff
+func (db *DB) DeleteFinishedQueuedAlerts(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM queued_alerts WHERE status <> 'pending' AND updated_at < $1`
+	result, err := db.Pool.Exec(ctx, query, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished queued alerts: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ============================================================================
+// Idempotency Key Operations
+// ============================================================================
+
+// ClaimIdempotencyKey records alertID as the result of a user's idempotency
+// key until expiresAt. If the key is already held by an unexpired request,
+// nothing changes and that request's alert ID is returned with false.
+func (db *DB) ClaimIdempotencyKey(ctx context.Context, userID int, key, alertID string, now, expiresAt time.Time) (string, bool, error) {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, alert_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET alert_id = EXCLUDED.alert_id,
+		    created_at = EXCLUDED.created_at,
+		    expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+		RETURNING alert_id
+	`
+
+	var claimed string
+	err := db.Pool.QueryRow(ctx, query, userID, key, alertID, now.UTC(), expiresAt.UTC()).Scan(&claimed)
+	if err == nil {
+		return claimed, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return "", false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	// The key is held by an earlier request
+	var existing string
+	err = db.Pool.QueryRow(ctx,
+		`SELECT alert_id FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`,
+		userID, key,
+	).Scan(&existing)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return existing, false, nil
+}
+
+// ReleaseIdempotencyKey forgets a key whose request queued nothing, so the
+// sender's retry is processed
+func (db *DB) ReleaseIdempotencyKey(ctx context.Context, userID int, key string) error {
+	query := `DELETE FROM idempotency_keys WHERE user_id = $1 AND idempotency_key = $2`
+	if _, err := db.Pool.Exec(ctx, query, userID, key); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys removes keys that expired before cutoff
+func (db *DB) DeleteExpiredIdempotencyKeys(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM idempotency_keys WHERE expires_at < $1`
+	result, err := db.Pool.Exec(ctx, query, cutoff.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// ============================================================================
+// Webhook Fixture Operations
+// ============================================================================
+
+// SaveWebhookFixture creates a fixture, or replaces the payload of the user's
+// fixture with the same name
+func (db *DB) SaveWebhookFixture(ctx context.Context, fixture *models.WebhookFixture) (*models.WebhookFixture, error) {
+	payloadJSON, err := json.Marshal(fixture.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fixture payload: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_fixtures (user_id, name, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, name) DO UPDATE
+		SET payload = EXCLUDED.payload,
+		    updated_at = CURRENT_TIMESTAMP
+		RETURNING id, user_id, name, payload, created_at, updated_at
+	`
+
+	var saved models.WebhookFixture
+	var savedPayload []byte
+	err = db.Pool.QueryRow(ctx, query, fixture.UserID, fixture.Name, payloadJSON).Scan(
+		&saved.ID,
+		&saved.UserID,
+		&saved.Name,
+		&savedPayload,
+		&saved.CreatedAt,
+		&saved.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save webhook fixture: %w", err)
+	}
+
+	if err := json.Unmarshal(savedPayload, &saved.Payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fixture payload: %w", err)
+	}
+
+	return &saved, nil
+}
+
+// GetWebhookFixtures lists the user's fixtures by name
+func (db *DB) GetWebhookFixtures(ctx context.Context, userID int) ([]models.WebhookFixture, error) {
+	query := `
+		SELECT id, user_id, name, payload, created_at, updated_at
+		FROM webhook_fixtures
+		WHERE user_id = $1
+		ORDER BY name
+	`
+
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook fixtures: %w", err)
+	}
+	defer rows.Close()
+
+	fixtures := []models.WebhookFixture{}
+	for rows.Next() {
+		var fixture models.WebhookFixture
+		var payloadJSON []byte
+		err := rows.Scan(
+			&fixture.ID,
+			&fixture.UserID,
+			&fixture.Name,
+			&payloadJSON,
+			&fixture.CreatedAt,
+			&fixture.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook fixture: %w", err)
+		}
+		if err := json.Unmarshal(payloadJSON, &fixture.Payload); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal fixture payload: %w", err)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+
+	return fixtures, nil
+}
+
+// GetWebhookFixture returns one of the user's fixtures by name
+func (db *DB) GetWebhookFixture(ctx context.Context, userID int, name string) (*models.WebhookFixture, error) {
+	query := `
+		SELECT id, user_id, name, payload, created_at, updated_at
+		FROM webhook_fixtures
+		WHERE user_id = $1 AND name = $2
+	`
+
+	var fixture models.WebhookFixture
+	var payloadJSON []byte
+	err := db.Pool.QueryRow(ctx, query, userID, name).Scan(
+		&fixture.ID,
+		&fixture.UserID,
+		&fixture.Name,
+		&payloadJSON,
+		&fixture.CreatedAt,
+		&fixture.UpdatedAt,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook fixture: %w", err)
+	}
+
+	if err := json.Unmarshal(payloadJSON, &fixture.Payload); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal fixture payload: %w", err)
+	}
+
+	return &fixture, nil
+}
+
+func (db *DB) DeleteWebhookFixture(ctx context.Context, userID int, name string) error {
+	query := `DELETE FROM webhook_fixtures WHERE user_id = $1 AND name = $2`
+	result, err := db.Pool.Exec(ctx, query, userID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook fixture: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("webhook fixture not found")
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Alert Rule Operations
+// ============================================================================
+
+const alertRuleColumns = `id, user_id, name, kind, rule_type, keywords, value, window_seconds, is_enabled, created_at, updated_at`
+
+func scanAlertRule(row pgx.Row) (*models.UserAlertRule, error) {
+	var rule models.UserAlertRule
+	err := row.Scan(
+		&rule.ID,
+		&rule.UserID,
+		&rule.Name,
+		&rule.Kind,
+		&rule.RuleType,
+		&rule.Keywords,
+		&rule.Value,
+		&rule.WindowSeconds,
+		&rule.IsEnabled,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (db *DB) CreateUserAlertRule(ctx context.Context, rule *models.UserAlertRule) (*models.UserAlertRule, error) {
+	query := `
+		INSERT INTO alert_rules (user_id, name, kind, rule_type, keywords, value, window_seconds, is_enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + alertRuleColumns
+
+	saved, err := scanAlertRule(db.Pool.QueryRow(ctx, query,
+		rule.UserID, rule.Name, rule.Kind, rule.RuleType, rule.Keywords, rule.Value, rule.WindowSeconds, rule.IsEnabled))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert rule: %w", err)
+	}
+
+	return saved, nil
+}
+
+// UpdateUserAlertRule replaces every field of one of the user's rules
+func (db *DB) UpdateUserAlertRule(ctx context.Context, rule *models.UserAlertRule) (*models.UserAlertRule, error) {
+	query := `
+		UPDATE alert_rules
+		SET name = $3, kind = $4, rule_type = $5, keywords = $6, value = $7,
+		    window_seconds = $8, is_enabled = $9, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + alertRuleColumns
+
+	saved, err := scanAlertRule(db.Pool.QueryRow(ctx, query,
+		rule.ID, rule.UserID, rule.Name, rule.Kind, rule.RuleType, rule.Keywords, rule.Value, rule.WindowSeconds, rule.IsEnabled))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update alert rule: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (db *DB) GetUserAlertRules(ctx context.Context, userID int) ([]models.UserAlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE user_id = $1 ORDER BY id`
+	return db.queryAlertRules(ctx, query, userID)
+}
+
+func (db *DB) GetUserAlertRule(ctx context.Context, ruleID, userID int) (*models.UserAlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE id = $1 AND user_id = $2`
+
+	rule, err := scanAlertRule(db.Pool.QueryRow(ctx, query, ruleID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+// GetEnabledAlertRules returns every user's enabled rules, for the rule
+// engine's cache
+func (db *DB) GetEnabledAlertRules(ctx context.Context) ([]models.UserAlertRule, error) {
+	query := `SELECT ` + alertRuleColumns + ` FROM alert_rules WHERE is_enabled = true ORDER BY user_id, id`
+	return db.queryAlertRules(ctx, query)
+}
+
+func (db *DB) queryAlertRules(ctx context.Context, query string, args ...interface{}) ([]models.UserAlertRule, error) {
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.UserAlertRule{}
+	for rows.Next() {
+		rule, err := scanAlertRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (db *DB) DeleteUserAlertRule(ctx context.Context, ruleID, userID int) error {
+	query := `DELETE FROM alert_rules WHERE id = $1 AND user_id = $2`
+	result, err := db.Pool.Exec(ctx, query, ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete alert rule: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("alert rule not found")
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Webhook Secret Operations
+// ============================================================================
+
+// SetUserWebhookSecret sets the key incoming webhooks must be signed with;
+// an empty secret turns signature verification off
+func (db *DB) SetUserWebhookSecret(ctx context.Context, userID int, secret string) error {
+	query := `UPDATE users SET webhook_secret = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	result, err := db.Pool.Exec(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook secret: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// SetChannelWebhookSecret sets the key webhooks routed to a channel must be
+// signed with; an empty secret turns signature verification off
+func (db *DB) SetChannelWebhookSecret(ctx context.Context, channelID, userID int, secret string) error {
+	query := `UPDATE telegram_channels SET webhook_secret = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+	result, err := db.Pool.Exec(ctx, query, channelID, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to update channel webhook secret: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("channel not found")
+	}
+
+	return nil
+}
+
+// GetChannelWebhookSecrets returns the distinct webhook secrets set on a
+// user's channels
+func (db *DB) GetChannelWebhookSecrets(ctx context.Context, userID int) ([]string, error) {
+	query := `
+		SELECT DISTINCT webhook_secret FROM telegram_channels
+		WHERE user_id = $1 AND webhook_secret <> '' AND deleted_at IS NULL
+	`
+	rows, err := db.Pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get channel webhook secrets: %w", err)
+	}
+	defer rows.Close()
+
+	var secrets []string
+	for rows.Next() {
+		var secret string
+		if err := rows.Scan(&secret); err != nil {
+			return nil, fmt.Errorf("failed to scan channel webhook secret: %w", err)
+		}
+		secrets = append(secrets, secret)
+	}
+
+	return secrets, rows.Err()
+}
+
+// UpdateUserPassword replaces a user's password hash
+func (db *DB) UpdateUserPassword(ctx context.Context, userID int, passwordHash string) error {
+	query := `UPDATE users SET password_hash = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	result, err := db.Pool.Exec(ctx, query, userID, passwordHash)
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// DeleteUserCascade deletes a user along with their bots, channels, webhook
+// logs and everything else that belongs to them, in one transaction
+func (db *DB) DeleteUserCascade(ctx context.Context, userID int) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The remaining tables cascade from users; these are removed explicitly
+	// as they hold the bulk of a user's data
+	for _, query := range []string{
+		`DELETE FROM webhook_logs WHERE user_id = $1`,
+		`DELETE FROM telegram_channels WHERE user_id = $1`,
+		`DELETE FROM telegram_bots WHERE user_id = $1`,
+	} {
+		if _, err := tx.Exec(ctx, query, userID); err != nil {
+			return fmt.Errorf("failed to delete user data: %w", err)
+		}
+	}
+
+	result, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit user deletion: %w", err)
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Password Reset Operations
+// ============================================================================
+
+// CreatePasswordReset stores the hash of a reset token for a user
+func (db *DB) CreatePasswordReset(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := db.Pool.Exec(ctx, query, userID, tokenHash, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumePasswordReset marks an unused, unexpired reset token as used and
+// returns its user. Claiming and checking in one statement keeps a token
+// from being used twice.
+func (db *DB) ConsumePasswordReset(ctx context.Context, tokenHash string, now time.Time) (int, error) {
+	query := `
+		UPDATE password_resets
+		SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING user_id
+	`
+
+	var userID int
+	if err := db.Pool.QueryRow(ctx, query, tokenHash, now.UTC()).Scan(&userID); err != nil {
+		return 0, fmt.Errorf("failed to consume password reset: %w", err)
+	}
+
+	return userID, nil
+}
+
+// DeletePasswordResets removes a user's outstanding reset tokens, and any
+// that expired before cutoff for every user
+func (db *DB) DeletePasswordResets(ctx context.Context, userID int, cutoff time.Time) error {
+	query := `DELETE FROM password_resets WHERE (user_id = $1 AND used_at IS NULL) OR expires_at < $2`
+	if _, err := db.Pool.Exec(ctx, query, userID, cutoff.UTC()); err != nil {
+		return fmt.Errorf("failed to delete password resets: %w", err)
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Refresh Token Operations
+// ============================================================================
+
+// CreateRefreshToken stores the hash of a refresh token for a user
+func (db *DB) CreateRefreshToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	query := `INSERT INTO refresh_tokens (user_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := db.Pool.Exec(ctx, query, userID, tokenHash, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeRefreshToken revokes a live refresh token and returns its user.
+// Checking and revoking in one statement keeps a token from being used
+// twice.
+func (db *DB) RevokeRefreshToken(ctx context.Context, tokenHash string, now time.Time) (int, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = $2
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > $2
+		RETURNING user_id
+	`
+
+	var userID int
+	if err := db.Pool.QueryRow(ctx, query, tokenHash, now.UTC()).Scan(&userID); err != nil {
+		return 0, fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+
+	return userID, nil
+}
+
+// RevokeUserRefreshTokens revokes all of a user's refresh tokens and
+// removes those that expired before now
+func (db *DB) RevokeUserRefreshTokens(ctx context.Context, userID int, now time.Time) error {
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at < $2`, userID, now.UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired refresh tokens: %w", err)
+	}
+
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+	if _, err := db.Pool.Exec(ctx, query, userID, now.UTC()); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeAccessToken records an access token's jti as revoked until it
+// expires, and drops entries for tokens that have since expired
+func (db *DB) RevokeAccessToken(ctx context.Context, jti string, userID int, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING
+	`
+	if _, err := db.Pool.Exec(ctx, query, jti, userID, expiresAt.UTC()); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	if _, err := db.Pool.Exec(ctx, `DELETE FROM revoked_tokens WHERE expires_at < $1`, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to delete expired revoked tokens: %w", err)
+	}
+
+	return nil
+}
+
+// GetRevokedAccessTokens returns the expiry of each revoked access token
+// that hasn't expired yet, keyed by jti
+func (db *DB) GetRevokedAccessTokens(ctx context.Context) (map[string]time.Time, error) {
+	query := `SELECT jti, expires_at FROM revoked_tokens WHERE expires_at > $1`
+	rows, err := db.Pool.Query(ctx, query, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revoked access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make(map[string]time.Time)
+	for rows.Next() {
+		var jti string
+		var expiresAt time.Time
+		if err := rows.Scan(&jti, &expiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked access token: %w", err)
+		}
+		tokens[jti] = expiresAt
+	}
+
+	return tokens, rows.Err()
+}
+
+// ============================================================================
+// User Processing Pause Operations
+// ============================================================================
+
+// SetUserProcessingPaused pauses or resumes delivery of a user's alerts
+func (db *DB) SetUserProcessingPaused(ctx context.Context, userID int, paused bool) error {
+	query := `UPDATE users SET processing_paused = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	result, err := db.Pool.Exec(ctx, query, userID, paused)
+	if err != nil {
+		return fmt.Errorf("failed to update user processing pause: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// GetPausedUserIDs returns the users whose alert processing is paused
+func (db *DB) GetPausedUserIDs(ctx context.Context) ([]int, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT id FROM users WHERE processing_paused ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paused users: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan paused user: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// ============================================================================
+// User Rate Limit Operations
+// ============================================================================
+
+// SetUserRateLimit overrides a user's webhook rate limit; nil reverts to the
+// global default
+func (db *DB) SetUserRateLimit(ctx context.Context, userID int, limit *int) error {
+	query := `UPDATE users SET rate_limit = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	result, err := db.Pool.Exec(ctx, query, userID, limit)
+	if err != nil {
+		return fmt.Errorf("failed to update user rate limit: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// GetUserRateLimit returns a user's webhook rate limit override, 0 when the
+// user has none
+func (db *DB) GetUserRateLimit(ctx context.Context, userID int) (int, error) {
+	var limit int
+	err := db.Pool.QueryRow(ctx, `SELECT COALESCE(rate_limit, 0) FROM users WHERE id = $1`, userID).Scan(&limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user rate limit: %w", err)
+	}
+	return limit, nil
+}
+
+// GetWebhookTokenRateLimit returns the user a webhook token belongs to and
+// their rate limit override, 0 when the user has none
+func (db *DB) GetWebhookTokenRateLimit(ctx context.Context, token uuid.UUID) (int, int, error) {
+	var userID, limit int
+	err := db.Pool.QueryRow(ctx, `SELECT id, COALESCE(rate_limit, 0) FROM users WHERE webhook_token = $1`, token).Scan(&userID, &limit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get webhook token rate limit: %w", err)
+	}
+	return userID, limit, nil
+}
+
+// ============================================================================
+// Redaction Rule Operations
+// ============================================================================
+
+const redactionRuleColumns = `id, user_id, name, pattern, replacement, is_enabled, created_at, updated_at`
+
+func scanRedactionRule(row pgx.Row) (*models.RedactionRule, error) {
+	var rule models.RedactionRule
+	err := row.Scan(
+		&rule.ID,
+		&rule.UserID,
+		&rule.Name,
+		&rule.Pattern,
+		&rule.Replacement,
+		&rule.IsEnabled,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (db *DB) CreateRedactionRule(ctx context.Context, rule *models.RedactionRule) (*models.RedactionRule, error) {
+	query := `
+		INSERT INTO redaction_rules (user_id, name, pattern, replacement, is_enabled)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + redactionRuleColumns
+
+	saved, err := scanRedactionRule(db.Pool.QueryRow(ctx, query,
+		rule.UserID, rule.Name, rule.Pattern, rule.Replacement, rule.IsEnabled))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redaction rule: %w", err)
+	}
+
+	return saved, nil
+}
+
+// UpdateRedactionRule replaces every field of one of the user's rules
+func (db *DB) UpdateRedactionRule(ctx context.Context, rule *models.RedactionRule) (*models.RedactionRule, error) {
+	query := `
+		UPDATE redaction_rules
+		SET name = $3, pattern = $4, replacement = $5, is_enabled = $6, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + redactionRuleColumns
+
+	saved, err := scanRedactionRule(db.Pool.QueryRow(ctx, query,
+		rule.ID, rule.UserID, rule.Name, rule.Pattern, rule.Replacement, rule.IsEnabled))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update redaction rule: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (db *DB) GetRedactionRule(ctx context.Context, ruleID, userID int) (*models.RedactionRule, error) {
+	query := `SELECT ` + redactionRuleColumns + ` FROM redaction_rules WHERE id = $1 AND user_id = $2`
+
+	rule, err := scanRedactionRule(db.Pool.QueryRow(ctx, query, ruleID, userID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redaction rule: %w", err)
+	}
+
+	return rule, nil
+}
+
+func (db *DB) GetUserRedactionRules(ctx context.Context, userID int) ([]models.RedactionRule, error) {
+	query := `SELECT ` + redactionRuleColumns + ` FROM redaction_rules WHERE user_id = $1 ORDER BY id`
+	return db.queryRedactionRules(ctx, query, userID)
+}
+
+// GetEnabledRedactionRules returns every user's enabled rules, for the
+// processor's cache
+func (db *DB) GetEnabledRedactionRules(ctx context.Context) ([]models.RedactionRule, error) {
+	query := `SELECT ` + redactionRuleColumns + ` FROM redaction_rules WHERE is_enabled = true ORDER BY user_id, id`
+	return db.queryRedactionRules(ctx, query)
+}
+
+func (db *DB) queryRedactionRules(ctx context.Context, query string, args ...interface{}) ([]models.RedactionRule, error) {
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get redaction rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.RedactionRule{}
+	for rows.Next() {
+		rule, err := scanRedactionRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan redaction rule: %w", err)
+		}
+		rules = append(rules, *rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (db *DB) DeleteRedactionRule(ctx context.Context, ruleID, userID int) error {
+	query := `DELETE FROM redaction_rules WHERE id = $1 AND user_id = $2`
+	result, err := db.Pool.Exec(ctx, query, ruleID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete redaction rule: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("redaction rule not found")
+	}
+
+	return nil
+}
+
+// ============================================================================
+// Analytics Queries
+// ============================================================================
+
+// analyticsSectionCount is the number of independently queried sections in
+// an AnalyticsResponse
+const analyticsSectionCount = 6
+
+// GetAnalytics retrieves comprehensive analytics for a user within a time range
+func (db *DB) GetAnalytics(ctx context.Context, userID int, timeRange string) (*models.AnalyticsResponse, error) {
+	// Calculate time boundaries
+	var since time.Time
+	now := time.Now()
+
+	switch timeRange {
 	case "24h":
 		since = now.Add(-24 * time.Hour)
 	case "7d":
@@ -579,77 +3017,141 @@ func (db *DB) GetAnalytics(ctx context.Context, userID int, timeRange string) (*
 		since = now.Add(-30 * 24 * time.Hour)
 	default:
 		since = now.Add(-24 * time.Hour)
-		response.TimeRange = "24h"
+		timeRange = "24h"
+	}
+
+	return db.GetAnalyticsBetween(ctx, userID, since, now, timeRange, models.AnalyticsFilter{})
+}
+
+// GetAnalyticsBetween retrieves comprehensive analytics for a user between
+// since and until, narrowed by filter. timeRange labels the window in the
+// response.
+func (db *DB) GetAnalyticsBetween(ctx context.Context, userID int, since, until time.Time, timeRange string, filter models.AnalyticsFilter) (*models.AnalyticsResponse, error) {
+	var response models.AnalyticsResponse
+	response.TimeRange = timeRange
+	response.From = since.UTC()
+	response.To = until.UTC()
+	now := until
+
+	// Each section is queried independently so one failing query degrades
+	// the response instead of failing it outright
+	var failed []string
+	sectionFailed := func(section string, err error) {
+		log.Printf("Analytics section %s failed for user %d: %v", section, userID, err)
+		failed = append(failed, section)
 	}
 
 	// Get summary statistics
-	summary, err := db.getAnalyticsSummary(ctx, userID, since, now)
-	if err != nil {
-		return nil, err
+	if summary, err := db.getAnalyticsSummary(ctx, userID, since, now, filter); err != nil {
+		sectionFailed("summary", err)
+	} else {
+		response.Summary = *summary
 	}
-	response.Summary = *summary
 
 	// Get timeline data
-	timeline, err := db.getAnalyticsTimeline(ctx, userID, since, now, timeRange)
-	if err != nil {
-		return nil, err
+	if timeline, err := db.getAnalyticsTimeline(ctx, userID, since, now, filter); err != nil {
+		sectionFailed("timeline", err)
+	} else {
+		response.Timeline = timeline
 	}
-	response.Timeline = timeline
 
 	// Get status distribution
-	statusDist, err := db.getAnalyticsByStatus(ctx, userID, since)
-	if err != nil {
-		return nil, err
+	if statusDist, err := db.getAnalyticsByStatus(ctx, userID, since, now, filter); err != nil {
+		sectionFailed("status_distribution", err)
+	} else {
+		response.StatusDistribution = statusDist
 	}
-	response.StatusDistribution = statusDist
 
 	// Get channel distribution
-	channelDist, err := db.getAnalyticsByChannel(ctx, userID, since)
-	if err != nil {
-		return nil, err
+	if channelDist, err := db.getAnalyticsByChannel(ctx, userID, since, now, filter); err != nil {
+		sectionFailed("channel_distribution", err)
+	} else {
+		response.ChannelDistribution = channelDist
 	}
-	response.ChannelDistribution = channelDist
 
 	// Get priority distribution
-	priorityDist, err := db.getAnalyticsByPriority(ctx, userID, since)
-	if err != nil {
-		return nil, err
+	if priorityDist, err := db.getAnalyticsByPriority(ctx, userID, since, now, filter); err != nil {
+		sectionFailed("priority_distribution", err)
+	} else {
+		response.PriorityDistribution = priorityDist
+	}
+
+	// Get failure reason distribution
+	if failureReasons, err := db.getAnalyticsByFailureReason(ctx, userID, since, now, filter); err != nil {
+		sectionFailed("failure_reasons", err)
+	} else {
+		response.FailureReasons = failureReasons
+	}
+
+	if len(failed) == analyticsSectionCount {
+		return nil, fmt.Errorf("failed to get analytics: all sections failed")
+	}
+	if len(failed) > 0 {
+		response.Partial = true
+		response.FailedSections = failed
 	}
-	response.PriorityDistribution = priorityDist
 
 	return &response, nil
 }
 
+// GetAnalyticsSummary calculates overall statistics for an arbitrary window
+func (db *DB) GetAnalyticsSummary(ctx context.Context, userID int, since, until time.Time) (*models.AnalyticsSummary, error) {
+	return db.getAnalyticsSummary(ctx, userID, since, until, models.AnalyticsFilter{})
+}
+
 // getAnalyticsSummary calculates overall statistics
-func (db *DB) getAnalyticsSummary(ctx context.Context, userID int, since, until time.Time) (*models.AnalyticsSummary, error) {
+func (db *DB) getAnalyticsSummary(ctx context.Context, userID int, since, until time.Time, filter models.AnalyticsFilter) (*models.AnalyticsSummary, error) {
+	where, args := analyticsWhere(userID, since, until, filter)
+
 	var summary models.AnalyticsSummary
 
-	// Get total counts by status
+	// Get total count and latest message
 	query := `
-		SELECT
-			COUNT(*) as total,
-			COALESCE(SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END), 0) as success,
-			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) as failed,
-			COALESCE(SUM(CASE WHEN status = 'filtered' THEN 1 ELSE 0 END), 0) as filtered,
-			COALESCE(SUM(CASE WHEN status = 'pending' THEN 1 ELSE 0 END), 0) as pending,
-			MAX(sent_at) as last_message
+		SELECT COUNT(*) as total, MAX(sent_at) as last_message
 		FROM webhook_logs
-		WHERE user_id = $1 AND sent_at >= $2 AND sent_at <= $3
+		WHERE ` + where + `
 	`
 
 	var lastMsg *time.Time
-	err := db.Pool.QueryRow(ctx, query, userID, since, until).Scan(
-		&summary.TotalMessages,
-		&summary.SuccessCount,
-		&summary.FailedCount,
-		&summary.FilteredCount,
-		&summary.PendingCount,
-		&lastMsg,
-	)
+	err := db.Pool.QueryRow(ctx, query, args...).Scan(&summary.TotalMessages, &lastMsg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get analytics summary: %w", err)
 	}
 
+	// Count every status present, so statuses added by new features show up
+	// and the counts always add up to the total
+	statusQuery := `
+		SELECT COALESCE(status, 'unknown') as status, COUNT(*) as count
+		FROM webhook_logs
+		WHERE ` + where + `
+		GROUP BY 1
+	`
+
+	rows, err := db.Pool.Query(ctx, statusQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status counts: %w", err)
+	}
+	defer rows.Close()
+
+	summary.StatusCounts = make(map[string]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan status count: %w", err)
+		}
+		summary.StatusCounts[status] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get status counts: %w", err)
+	}
+
+	// The common statuses keep their own fields
+	summary.SuccessCount = summary.StatusCounts["success"]
+	summary.FailedCount = summary.StatusCounts["failed"]
+	summary.FilteredCount = summary.StatusCounts["filtered"]
+	summary.PendingCount = summary.StatusCounts["pending"]
+
 	summary.LastMessageAt = lastMsg
 
 	// Calculate success rate
@@ -670,13 +3172,13 @@ func (db *DB) getAnalyticsSummary(ctx context.Context, userID int, since, until
 			EXTRACT(HOUR FROM sent_at)::INTEGER as hour,
 			COUNT(*) as count
 		FROM webhook_logs
-		WHERE user_id = $1 AND sent_at >= $2 AND sent_at <= $3
+		WHERE ` + where + `
 		GROUP BY hour
 		ORDER BY count DESC
 		LIMIT 1
 	`
 
-	err = db.Pool.QueryRow(ctx, peakQuery, userID, since, until).Scan(&summary.PeakHour, &summary.PeakHourCount)
+	err = db.Pool.QueryRow(ctx, peakQuery, args...).Scan(&summary.PeakHour, &summary.PeakHourCount)
 	if err != nil && err.Error() != "no rows in result set" {
 		// If no data, just leave peak values as 0
 		if err.Error() != "no rows in result set" {
@@ -684,43 +3186,56 @@ func (db *DB) getAnalyticsSummary(ctx context.Context, userID int, since, until
 		}
 	}
 
+	// Delivery latency of successful sends; logs from before latency was
+	// recorded have none
+	latencyQuery := `
+		SELECT
+			AVG(latency_ms)::DOUBLE PRECISION,
+			PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY latency_ms),
+			PERCENTILE_CONT(0.95) WITHIN GROUP (ORDER BY latency_ms),
+			PERCENTILE_CONT(0.99) WITHIN GROUP (ORDER BY latency_ms)
+		FROM webhook_logs
+		WHERE ` + where + ` AND latency_ms IS NOT NULL
+	`
+
+	var avg, p50, p95, p99 *float64
+	if err := db.Pool.QueryRow(ctx, latencyQuery, args...).Scan(&avg, &p50, &p95, &p99); err != nil {
+		return nil, fmt.Errorf("failed to get delivery latency: %w", err)
+	}
+	if avg != nil {
+		summary.LatencyAvgMs = *avg
+		summary.LatencyP50Ms = *p50
+		summary.LatencyP95Ms = *p95
+		summary.LatencyP99Ms = *p99
+	}
+
 	return &summary, nil
 }
 
 // getAnalyticsTimeline returns time-series data for charting
-func (db *DB) getAnalyticsTimeline(ctx context.Context, userID int, since, until time.Time, timeRange string) ([]models.TimelineDataPoint, error) {
-	// Determine grouping interval based on time range
-	var interval string
-	switch timeRange {
-	case "24h":
-		interval = "1 hour"
-	case "7d":
-		interval = "6 hours"
-	case "30d":
-		interval = "1 day"
-	default:
-		interval = "1 hour"
-	}
+func (db *DB) getAnalyticsTimeline(ctx context.Context, userID int, since, until time.Time, filter models.AnalyticsFilter) ([]models.TimelineDataPoint, error) {
+	where, args := analyticsWhere(userID, since, until, filter)
 
-	query := fmt.Sprintf(`
+	bucket := timelineBucket(until.Sub(since))
+	args = append(args, int(bucket.Seconds()))
+	bucketParam := fmt.Sprintf("$%d", len(args))
+
+	// Buckets are aligned to the Unix epoch, so they fall on whole hours
+	// and days in UTC
+	query := `
 		SELECT
-			date_trunc('hour', sent_at) +
-			(EXTRACT(HOUR FROM sent_at)::INTEGER / CASE
-				WHEN $4 = '24h' THEN 1
-				WHEN $4 = '7d' THEN 6
-				ELSE 24
-			END) * INTERVAL '%s' as timestamp,
+			TIMESTAMP 'epoch' + (FLOOR(EXTRACT(EPOCH FROM sent_at) / ` + bucketParam + `::INTEGER) * ` + bucketParam + `::INTEGER)::DOUBLE PRECISION * INTERVAL '1 second' as timestamp,
 			COALESCE(SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END), 0) as success_count,
 			COALESCE(SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END), 0) as failed_count,
 			COALESCE(SUM(CASE WHEN status = 'filtered' THEN 1 ELSE 0 END), 0) as filtered_count,
 			COUNT(*) as total_count
 		FROM webhook_logs
-		WHERE user_id = $1 AND sent_at >= $2 AND sent_at <= $3
+		WHERE ` + where + `
 		GROUP BY timestamp
 		ORDER BY timestamp ASC
-	`, interval)
+	`
 
-	rows, err := db.Pool.Query(ctx, query, userID, since, until, timeRange)
+	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get timeline data: %w", err)
 	}
@@ -745,20 +3260,61 @@ func (db *DB) getAnalyticsTimeline(ctx context.Context, userID int, since, until
 	return timeline, nil
 }
 
+// analyticsWhere builds the WHERE condition shared by the analytics
+// queries, with its arguments as $1 onwards. Filtering by bot matches the
+// logs addressed to one of the bot's channels by identifier.
+func analyticsWhere(userID int, since, until time.Time, filter models.AnalyticsFilter) (string, []interface{}) {
+	where := "user_id = $1 AND sent_at >= $2 AND sent_at <= $3"
+	args := []interface{}{userID, since, until}
+
+	if filter.BotID != 0 {
+		args = append(args, filter.BotID)
+		where += fmt.Sprintf(` AND LOWER(payload->>'identifier') IN (
+			SELECT identifier_normalized FROM telegram_channels WHERE user_id = $1 AND bot_id = $%d
+		)`, len(args))
+	}
+
+	// Logs without an identifier went to the default channel, which the
+	// channel distribution reports as "default"
+	if filter.Identifier != "" {
+		args = append(args, filter.Identifier)
+		where += fmt.Sprintf(" AND LOWER(COALESCE(payload->>'identifier', 'default')) = LOWER($%d)", len(args))
+	}
+
+	return where, args
+}
+
+// timelineBucket picks the timeline interval for a window of the given
+// span, keeping charts to roughly 24 to 120 points
+func timelineBucket(span time.Duration) time.Duration {
+	switch {
+	case span <= 2*24*time.Hour:
+		return time.Hour
+	case span <= 7*24*time.Hour:
+		return 6 * time.Hour
+	case span <= 30*24*time.Hour:
+		return 12 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
 // getAnalyticsByStatus returns distribution of messages by status
-func (db *DB) getAnalyticsByStatus(ctx context.Context, userID int, since time.Time) ([]models.StatusDistribution, error) {
+func (db *DB) getAnalyticsByStatus(ctx context.Context, userID int, since, until time.Time, filter models.AnalyticsFilter) ([]models.StatusDistribution, error) {
+	where, args := analyticsWhere(userID, since, until, filter)
+
 	query := `
 		SELECT
 			status,
 			COUNT(*) as count,
 			(COUNT(*) * 100.0 / SUM(COUNT(*)) OVER ()) as percentage
 		FROM webhook_logs
-		WHERE user_id = $1 AND sent_at >= $2
+		WHERE ` + where + `
 		GROUP BY status
 		ORDER BY count DESC
 	`
 
-	rows, err := db.Pool.Query(ctx, query, userID, since)
+	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get status distribution: %w", err)
 	}
@@ -778,7 +3334,9 @@ func (db *DB) getAnalyticsByStatus(ctx context.Context, userID int, since time.T
 }
 
 // getAnalyticsByChannel returns distribution of messages by channel
-func (db *DB) getAnalyticsByChannel(ctx context.Context, userID int, since time.Time) ([]models.ChannelDistribution, error) {
+func (db *DB) getAnalyticsByChannel(ctx context.Context, userID int, since, until time.Time, filter models.AnalyticsFilter) ([]models.ChannelDistribution, error) {
+	where, args := analyticsWhere(userID, since, until, filter)
+
 	query := `
 		SELECT
 			COALESCE(
@@ -788,13 +3346,13 @@ func (db *DB) getAnalyticsByChannel(ctx context.Context, userID int, since time.
 			COUNT(*) as count,
 			(COUNT(*) * 100.0 / SUM(COUNT(*)) OVER ()) as percentage
 		FROM webhook_logs
-		WHERE user_id = $1 AND sent_at >= $2
+		WHERE ` + where + `
 		GROUP BY identifier
 		ORDER BY count DESC
 		LIMIT 10
 	`
 
-	rows, err := db.Pool.Query(ctx, query, userID, since)
+	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get channel distribution: %w", err)
 	}
@@ -808,12 +3366,15 @@ func (db *DB) getAnalyticsByChannel(ctx context.Context, userID int, since time.
 			return nil, fmt.Errorf("failed to scan channel distribution: %w", err)
 		}
 
-		// Get channel name from telegram_channels table if available
+		// Get channel name from telegram_channels table if available,
+		// including deleted channels so older logs keep their label; a live
+		// channel wins over deleted ones with the same identifier
 		var channelName string
 		nameQuery := `
 			SELECT channel_name
 			FROM telegram_channels
-			WHERE user_id = $1 AND identifier = $2 AND is_active = true
+			WHERE user_id = $1 AND identifier = $2 AND (is_active = true OR deleted_at IS NOT NULL)
+			ORDER BY deleted_at DESC NULLS FIRST
 			LIMIT 1
 		`
 		err = db.Pool.QueryRow(ctx, nameQuery, userID, dist.ChannelIdentifier).Scan(&channelName)
@@ -828,19 +3389,21 @@ func (db *DB) getAnalyticsByChannel(ctx context.Context, userID int, since time.
 }
 
 // getAnalyticsByPriority returns distribution of messages by priority
-func (db *DB) getAnalyticsByPriority(ctx context.Context, userID int, since time.Time) ([]models.PriorityDistribution, error) {
+func (db *DB) getAnalyticsByPriority(ctx context.Context, userID int, since, until time.Time, filter models.AnalyticsFilter) ([]models.PriorityDistribution, error) {
+	where, args := analyticsWhere(userID, since, until, filter)
+
 	query := `
 		SELECT
 			COALESCE((payload->>'priority')::INTEGER, 3) as priority,
 			COUNT(*) as count,
 			(COUNT(*) * 100.0 / SUM(COUNT(*)) OVER ()) as percentage
 		FROM webhook_logs
-		WHERE user_id = $1 AND sent_at >= $2
+		WHERE ` + where + `
 		GROUP BY priority
 		ORDER BY priority ASC
 	`
 
-	rows, err := db.Pool.Query(ctx, query, userID, since)
+	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get priority distribution: %w", err)
 	}
@@ -867,6 +3430,41 @@ func (db *DB) getAnalyticsByPriority(ctx context.Context, userID int, since time
 	return distribution, nil
 }
 
+// getAnalyticsByFailureReason returns distribution of failed sends by
+// category. Failures logged before categories were recorded count as
+// unknown.
+func (db *DB) getAnalyticsByFailureReason(ctx context.Context, userID int, since, until time.Time, filter models.AnalyticsFilter) ([]models.FailureReasonDistribution, error) {
+	where, args := analyticsWhere(userID, since, until, filter)
+
+	query := `
+		SELECT
+			COALESCE(error_category, 'unknown') as category,
+			COUNT(*) as count,
+			(COUNT(*) * 100.0 / SUM(COUNT(*)) OVER ()) as percentage
+		FROM webhook_logs
+		WHERE ` + where + ` AND status = 'failed'
+		GROUP BY category
+		ORDER BY count DESC
+	`
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get failure reason distribution: %w", err)
+	}
+	defer rows.Close()
+
+	var distribution []models.FailureReasonDistribution
+	for rows.Next() {
+		var dist models.FailureReasonDistribution
+		if err := rows.Scan(&dist.Category, &dist.Count, &dist.Percentage); err != nil {
+			return nil, fmt.Errorf("failed to scan failure reason distribution: %w", err)
+		}
+		distribution = append(distribution, dist)
+	}
+
+	return distribution, nil
+}
+
 // Helper function to split message and extract identifier
 func splitMessage(message string) []string {
 	parts := make([]string, 2)
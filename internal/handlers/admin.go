@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/middleware"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/queue"
+)
+
+// AdminHandler serves operator endpoints guarded by middleware.AdminMiddleware
+type AdminHandler struct {
+	db          *database.DB
+	processor   *queue.TelegramProcessor
+	rateLimiter *middleware.RateLimiter
+}
+
+func NewAdminHandler(db *database.DB, processor *queue.TelegramProcessor, rateLimiter *middleware.RateLimiter) *AdminHandler {
+	return &AdminHandler{
+		db:          db,
+		processor:   processor,
+		rateLimiter: rateLimiter,
+	}
+}
+
+// CreateMaintenanceNotice schedules a notice prepended to outgoing messages
+// POST /api/admin/maintenance-notices
+func (h *AdminHandler) CreateMaintenanceNotice(c *fiber.Ctx) error {
+	var req models.CreateMaintenanceNoticeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" || req.EndsAt.IsZero() {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "message and ends_at are required",
+		})
+	}
+
+	startsAt := time.Now()
+	if req.StartsAt != nil {
+		startsAt = *req.StartsAt
+	}
+
+	if !req.EndsAt.After(startsAt) || !req.EndsAt.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "ends_at must be in the future and after starts_at",
+		})
+	}
+
+	if req.UserID != nil {
+		if _, err := h.db.GetUserByID(context.Background(), *req.UserID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+	}
+
+	notice, err := h.db.CreateMaintenanceNotice(context.Background(), &models.MaintenanceNotice{
+		UserID:   req.UserID,
+		Message:  req.Message,
+		StartsAt: startsAt,
+		EndsAt:   req.EndsAt,
+	})
+	if err != nil {
+		log.Printf("Error creating maintenance notice: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create maintenance notice",
+		})
+	}
+
+	h.processor.InvalidateNotices()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"notice":  notice,
+	})
+}
+
+// GetMaintenanceNotices lists active and upcoming notices
+// GET /api/admin/maintenance-notices
+func (h *AdminHandler) GetMaintenanceNotices(c *fiber.Ctx) error {
+	notices, err := h.db.GetMaintenanceNotices(context.Background(), time.Now())
+	if err != nil {
+		log.Printf("Error getting maintenance notices: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve maintenance notices",
+		})
+	}
+
+	if notices == nil {
+		notices = []models.MaintenanceNotice{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"notices": notices,
+	})
+}
+
+// DeleteMaintenanceNotice ends a notice early
+// DELETE /api/admin/maintenance-notices/:id
+func (h *AdminHandler) DeleteMaintenanceNotice(c *fiber.Ctx) error {
+	noticeID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid notice ID",
+		})
+	}
+
+	if err := h.db.DeleteMaintenanceNotice(context.Background(), noticeID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "maintenance notice not found",
+		})
+	}
+
+	h.processor.InvalidateNotices()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "maintenance notice deleted successfully",
+	})
+}
+
+// PauseUserProcessing stops delivering a user's alerts without affecting
+// other users. Alerts are held or dropped according to PAUSED_USER_ACTION.
+// POST /api/admin/users/:id/pause
+func (h *AdminHandler) PauseUserProcessing(c *fiber.Ctx) error {
+	return h.setUserProcessingPaused(c, true)
+}
+
+// ResumeUserProcessing resumes delivery of a paused user's alerts; held
+// alerts are released shortly after
+// POST /api/admin/users/:id/resume
+func (h *AdminHandler) ResumeUserProcessing(c *fiber.Ctx) error {
+	return h.setUserProcessingPaused(c, false)
+}
+
+func (h *AdminHandler) setUserProcessingPaused(c *fiber.Ctx, paused bool) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user ID",
+		})
+	}
+
+	if err := h.db.SetUserProcessingPaused(context.Background(), userID, paused); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	h.processor.InvalidatePausedUsers()
+
+	log.Printf("Admin set processing_paused=%t for user %d", paused, userID)
+
+	return c.JSON(fiber.Map{
+		"success":           true,
+		"user_id":           userID,
+		"processing_paused": paused,
+	})
+}
+
+// SetUserRateLimit overrides a user's webhook requests per minute; null or 0
+// reverts to RATE_LIMIT. It takes effect immediately.
+// PUT /api/admin/users/:id/rate-limit
+func (h *AdminHandler) SetUserRateLimit(c *fiber.Ctx) error {
+	userID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user ID",
+		})
+	}
+
+	var req models.SetUserRateLimitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.RateLimit != nil && *req.RateLimit < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "rate_limit must not be negative",
+		})
+	}
+	if req.RateLimit != nil && *req.RateLimit == 0 {
+		req.RateLimit = nil
+	}
+
+	if err := h.db.SetUserRateLimit(context.Background(), userID, req.RateLimit); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	limit := 0
+	if req.RateLimit != nil {
+		limit = *req.RateLimit
+	}
+	h.rateLimiter.SetUserLimit(userID, limit)
+
+	log.Printf("Admin set rate_limit=%d for user %d", limit, userID)
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"user_id":    userID,
+		"rate_limit": req.RateLimit,
+	})
+}
+
+// PurgeDeletedChannels permanently removes channels deleted more than
+// older_than ago (a duration such as 720h; default 0, every deleted channel).
+// Analytics stop showing those channels' names.
+// POST /api/admin/channels/purge-deleted?older_than=720h
+func (h *AdminHandler) PurgeDeletedChannels(c *fiber.Ctx) error {
+	var olderThan time.Duration
+	if value := c.Query("older_than"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "older_than must be a non-negative duration such as 720h",
+			})
+		}
+		olderThan = d
+	}
+
+	purged, err := h.db.PurgeDeletedChannels(context.Background(), time.Now().Add(-olderThan))
+	if err != nil {
+		log.Printf("Error purging deleted channels: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to purge deleted channels",
+		})
+	}
+
+	log.Printf("Admin purged %d deleted channels older than %s", purged, olderThan)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"purged":  purged,
+	})
+}
@@ -1,8 +1,14 @@
 package handlers
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
 )
 
 type AnalyticsHandler struct {
@@ -13,8 +19,14 @@ func NewAnalyticsHandler(db *database.DB) *AnalyticsHandler {
 	return &AnalyticsHandler{db: db}
 }
 
-// GetAnalytics returns comprehensive analytics data for the authenticated user
+// maxAnalyticsSpan caps custom from/to analytics windows
+const maxAnalyticsSpan = 90 * 24 * time.Hour
+
+// GetAnalytics returns comprehensive analytics data for the authenticated user,
+// either for a preset range or for a custom from/to window (RFC3339)
 // GET /api/user/analytics?range=24h|7d|30d
+// GET /api/user/analytics?from=2026-01-02T15:00:00Z&to=2026-01-03T03:00:00Z
+// Either form accepts bot_id and identifier to narrow the data.
 func (h *AnalyticsHandler) GetAnalytics(c *fiber.Ctx) error {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := c.Locals("user_id").(int)
@@ -24,24 +36,22 @@ func (h *AnalyticsHandler) GetAnalytics(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get time range from query parameter (default: 24h)
-	timeRange := c.Query("range", "24h")
-
-	// Validate time range
-	validRanges := map[string]bool{
-		"24h": true,
-		"7d":  true,
-		"30d": true,
+	from, to, timeRange, err := analyticsWindow(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	if !validRanges[timeRange] {
+	filter, err := h.analyticsFilter(c, userID)
+	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "invalid time range. Must be 24h, 7d, or 30d",
+			"error": err.Error(),
 		})
 	}
 
 	// Get analytics from database
-	analytics, err := h.db.GetAnalytics(c.Context(), userID, timeRange)
+	analytics, err := h.db.GetAnalyticsBetween(c.Context(), userID, from, to, timeRange, filter)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to fetch analytics",
@@ -50,3 +60,76 @@ func (h *AnalyticsHandler) GetAnalytics(c *fiber.Ctx) error {
 
 	return c.JSON(analytics)
 }
+
+// analyticsFilter reads the optional bot_id and identifier query
+// parameters; bot_id must be one of the user's bots
+func (h *AnalyticsHandler) analyticsFilter(c *fiber.Ctx, userID int) (models.AnalyticsFilter, error) {
+	filter := models.AnalyticsFilter{
+		Identifier: strings.TrimSpace(c.Query("identifier")),
+	}
+
+	if botParam := c.Query("bot_id"); botParam != "" {
+		botID, err := strconv.Atoi(botParam)
+		if err != nil || botID < 1 {
+			return filter, fmt.Errorf("invalid bot_id")
+		}
+		if _, err := h.db.GetTelegramBot(c.Context(), botID, userID); err != nil {
+			return filter, fmt.Errorf("bot not found")
+		}
+		filter.BotID = botID
+	}
+
+	return filter, nil
+}
+
+// analyticsWindow resolves the requested window: a custom from/to, or the
+// preset range (default 24h) ending now. timeRange labels it.
+func analyticsWindow(c *fiber.Ctx) (from, to time.Time, timeRange string, err error) {
+	if c.Query("from") != "" || c.Query("to") != "" {
+		from, to, err = parseAnalyticsWindow(c.Query("from"), c.Query("to"))
+		return from, to, "custom", err
+	}
+
+	// Get time range from query parameter (default: 24h)
+	timeRange = c.Query("range", "24h")
+
+	presets := map[string]time.Duration{
+		"24h": 24 * time.Hour,
+		"7d":  7 * 24 * time.Hour,
+		"30d": 30 * 24 * time.Hour,
+	}
+
+	span, ok := presets[timeRange]
+	if !ok {
+		return time.Time{}, time.Time{}, "", fmt.Errorf("invalid time range. Must be 24h, 7d, or 30d")
+	}
+
+	to = time.Now().UTC()
+	return to.Add(-span), to, timeRange, nil
+}
+
+// parseAnalyticsWindow validates a custom from/to analytics window
+func parseAnalyticsWindow(fromParam, toParam string) (time.Time, time.Time, error) {
+	if fromParam == "" || toParam == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("from and to must be given together")
+	}
+
+	from, err := time.Parse(time.RFC3339, fromParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid from: must be an RFC3339 timestamp")
+	}
+	to, err := time.Parse(time.RFC3339, toParam)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid to: must be an RFC3339 timestamp")
+	}
+
+	if !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from) > maxAnalyticsSpan {
+		return time.Time{}, time.Time{}, fmt.Errorf("window must not span more than %d days", int(maxAnalyticsSpan.Hours()/24))
+	}
+
+	// Timestamps are stored in UTC
+	return from.UTC(), to.UTC(), nil
+}
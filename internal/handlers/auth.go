@@ -2,20 +2,84 @@ package handlers
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/thenaveensharma/telehook/internal/auth"
 	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/mailer"
+	"github.com/thenaveensharma/telehook/internal/middleware"
 	"github.com/thenaveensharma/telehook/internal/models"
 )
 
 type AuthHandler struct {
-	db *database.DB
+	db             *database.DB
+	passwordPolicy auth.PasswordPolicy
+	rateLimiter    *middleware.AuthRateLimiter
+	mailer         mailer.Mailer
+	// resetTTL is how long a password reset link stays valid
+	resetTTL time.Duration
+	// resetURL is the page reset links point to; the token is appended as
+	// a query parameter. Password reset is disabled when it is empty.
+	resetURL string
 }
 
-func NewAuthHandler(db *database.DB) *AuthHandler {
-	return &AuthHandler{db: db}
+func NewAuthHandler(db *database.DB, rateLimiter *middleware.AuthRateLimiter) *AuthHandler {
+	resetTTL := 30 * time.Minute
+	if envTTL := os.Getenv("PASSWORD_RESET_TTL_MINUTES"); envTTL != "" {
+		if minutes, err := strconv.Atoi(envTTL); err == nil && minutes > 0 {
+			resetTTL = time.Duration(minutes) * time.Minute
+		}
+	}
+
+	// Access tokens revoked before a restart or by another instance stay
+	// revoked here too, picked up every REVOKED_TOKENS_REFRESH (default 10s)
+	revokedRefresh := 10 * time.Second
+	if d, err := time.ParseDuration(os.Getenv("REVOKED_TOKENS_REFRESH")); err == nil && d > 0 {
+		revokedRefresh = d
+	}
+	auth.SetRevocationLoader(db.GetRevokedAccessTokens, revokedRefresh)
+
+	return &AuthHandler{
+		db:             db,
+		passwordPolicy: auth.LoadPasswordPolicy(),
+		rateLimiter:    rateLimiter,
+		mailer:         mailer.FromEnv(),
+		resetTTL:       resetTTL,
+		resetURL:       resetURLFromEnv(),
+	}
+}
+
+// resetURLFromEnv reads the page password reset links point to from
+// PASSWORD_RESET_URL. It has to be configured: deriving it from the request
+// would let anyone who can set the Host header send a victim a link to
+// their own server and capture the token.
+func resetURLFromEnv() string {
+	resetURL := os.Getenv("PASSWORD_RESET_URL")
+	if resetURL == "" {
+		log.Println("PASSWORD_RESET_URL is not set; password reset is disabled")
+		return ""
+	}
+
+	u, err := url.Parse(resetURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		log.Printf("Invalid PASSWORD_RESET_URL %q, expected an absolute http(s) URL; password reset is disabled", resetURL)
+		return ""
+	}
+	return resetURL
+}
+
+// SetMailer replaces the mailer password reset links are sent with
+func (h *AuthHandler) SetMailer(m mailer.Mailer) {
+	h.mailer = m
 }
 
 func (h *AuthHandler) Signup(c *fiber.Ctx) error {
@@ -33,6 +97,14 @@ func (h *AuthHandler) Signup(c *fiber.Ctx) error {
 		})
 	}
 
+	// Enforce password policy
+	if unmet := h.passwordPolicy.Validate(req.Password); len(unmet) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":        "password does not meet requirements",
+			"requirements": unmet,
+		})
+	}
+
 	// Hash password
 	passwordHash, err := auth.HashPassword(req.Password)
 	if err != nil {
@@ -51,20 +123,16 @@ func (h *AuthHandler) Signup(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate JWT
-	token, err := auth.GenerateJWT(user.ID, user.Email, user.Username)
+	// Generate access and refresh tokens
+	response, err := h.issueTokens(context.Background(), user)
 	if err != nil {
-		log.Printf("Error generating JWT: %v", err)
+		log.Printf("Error generating tokens: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to generate token",
 		})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(models.LoginResponse{
-		Token:        token,
-		User:         *user,
-		WebhookToken: user.WebhookToken,
-	})
+	return c.Status(fiber.StatusCreated).JSON(response)
 }
 
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
@@ -85,6 +153,7 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	// Get user by email
 	user, err := h.db.GetUserByEmail(context.Background(), req.Email)
 	if err != nil {
+		h.rateLimiter.RecordLoginFailure(req.Email)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "invalid email or password",
 		})
@@ -92,23 +161,427 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 
 	// Verify password
 	if err := auth.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		h.rateLimiter.RecordLoginFailure(req.Email)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "invalid email or password",
 		})
 	}
 
-	// Generate JWT
-	token, err := auth.GenerateJWT(user.ID, user.Email, user.Username)
+	h.rateLimiter.RecordLoginSuccess(req.Email)
+
+	// Generate access and refresh tokens
+	response, err := h.issueTokens(context.Background(), user)
 	if err != nil {
-		log.Printf("Error generating JWT: %v", err)
+		log.Printf("Error generating tokens: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to generate token",
 		})
 	}
 
-	return c.JSON(models.LoginResponse{
+	return c.JSON(response)
+}
+
+// Refresh exchanges a refresh token for a new access token. The refresh
+// token is rotated: the one presented is revoked and a new one returned.
+// POST /api/auth/refresh
+func (h *AuthHandler) Refresh(c *fiber.Ctx) error {
+	var req models.RefreshRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "refresh_token is required",
+		})
+	}
+
+	userID, err := h.db.RevokeRefreshToken(context.Background(), auth.HashRefreshToken(req.RefreshToken), time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid or expired refresh token",
+		})
+	}
+
+	user, err := h.db.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid or expired refresh token",
+		})
+	}
+
+	response, err := h.issueTokens(context.Background(), user)
+	if err != nil {
+		log.Printf("Error generating tokens: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to generate token",
+		})
+	}
+
+	return c.JSON(response)
+}
+
+// Logout ends a session: the access token in the Authorization header is
+// revoked until it expires, as is the refresh token in the body. With "all",
+// every refresh token of the user is revoked too.
+// POST /api/auth/logout
+func (h *AuthHandler) Logout(c *fiber.Ctx) error {
+	var req models.LogoutRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+	}
+
+	// An expired access token can be ignored if there's a refresh token to
+	// revoke
+	var claims *auth.Claims
+	if token, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer "); ok {
+		var err error
+		if claims, err = auth.ValidateJWT(token); err != nil && req.RefreshToken == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid or expired token",
+			})
+		}
+	}
+
+	if claims == nil && req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "an access token or refresh_token is required",
+		})
+	}
+
+	now := time.Now()
+	userID := 0
+
+	if claims != nil {
+		userID = claims.UserID
+		if claims.ID != "" && claims.ExpiresAt != nil {
+			if err := h.db.RevokeAccessToken(context.Background(), claims.ID, claims.UserID, claims.ExpiresAt.Time); err != nil {
+				log.Printf("Error revoking access token for user %d: %v", claims.UserID, err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "failed to revoke token",
+				})
+			}
+			auth.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+		}
+	}
+
+	if req.RefreshToken != "" {
+		// An already revoked or expired refresh token needs no further action
+		if tokenUserID, err := h.db.RevokeRefreshToken(context.Background(), auth.HashRefreshToken(req.RefreshToken), now); err == nil {
+			if userID == 0 {
+				userID = tokenUserID
+			}
+		}
+	}
+
+	if req.All && userID != 0 {
+		if err := h.db.RevokeUserRefreshTokens(context.Background(), userID, now); err != nil {
+			log.Printf("Error revoking refresh tokens for user %d: %v", userID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to revoke sessions",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "logged out successfully",
+	})
+}
+
+// issueTokens creates an access token and a stored refresh token for user
+func (h *AuthHandler) issueTokens(ctx context.Context, user *models.User) (models.LoginResponse, error) {
+	token, err := auth.GenerateJWT(user.ID, user.Email, user.Username)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	refreshToken, refreshHash, err := auth.GenerateRefreshToken()
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+	if err := h.db.CreateRefreshToken(ctx, user.ID, refreshHash, time.Now().Add(auth.RefreshTokenTTL())); err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	return models.LoginResponse{
 		Token:        token,
+		ExpiresIn:    int(auth.AccessTokenTTL().Seconds()),
+		RefreshToken: refreshToken,
 		User:         *user,
 		WebhookToken: user.WebhookToken,
+	}, nil
+}
+
+// ChangePassword replaces the user's password after checking the current one
+// POST /api/user/change-password
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "current_password and new_password are required",
+		})
+	}
+
+	user, err := h.db.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	// Wrong current passwords count towards the same lockout as logins
+	if wait := h.rateLimiter.LockoutRemaining(user.Email); wait > 0 {
+		return middleware.TooManyRequests(c, wait, "too many attempts, please try again later")
+	}
+	if err := auth.VerifyPassword(user.PasswordHash, req.CurrentPassword); err != nil {
+		h.rateLimiter.RecordLoginFailure(user.Email)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "current password is incorrect",
+		})
+	}
+	h.rateLimiter.RecordLoginSuccess(user.Email)
+
+	if req.NewPassword == req.CurrentPassword {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "new password must differ from the current password",
+		})
+	}
+
+	// Enforce password policy
+	if unmet := h.passwordPolicy.Validate(req.NewPassword); len(unmet) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":        "password does not meet requirements",
+			"requirements": unmet,
+		})
+	}
+
+	passwordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to process password",
+		})
+	}
+
+	if err := h.db.UpdateUserPassword(context.Background(), userID, passwordHash); err != nil {
+		log.Printf("Error updating password for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update password",
+		})
+	}
+
+	// Sign out other sessions
+	if err := h.db.RevokeUserRefreshTokens(context.Background(), userID, time.Now()); err != nil {
+		log.Printf("Error revoking refresh tokens for user %d: %v", userID, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "password changed successfully",
+	})
+}
+
+// DeleteAccount permanently deletes the user and all their data after
+// checking their password. Their webhook token stops working immediately.
+// DELETE /api/user/account
+func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.DeleteAccountRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.Password == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "password is required",
+		})
+	}
+
+	user, err := h.db.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	// Wrong passwords count towards the same lockout as logins
+	if wait := h.rateLimiter.LockoutRemaining(user.Email); wait > 0 {
+		return middleware.TooManyRequests(c, wait, "too many attempts, please try again later")
+	}
+	if err := auth.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		h.rateLimiter.RecordLoginFailure(user.Email)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "password is incorrect",
+		})
+	}
+	h.rateLimiter.RecordLoginSuccess(user.Email)
+
+	if err := h.db.DeleteUserCascade(context.Background(), userID); err != nil {
+		log.Printf("Error deleting user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete account",
+		})
+	}
+
+	log.Printf("User %d deleted their account", userID)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "account deleted successfully",
+	})
+}
+
+// ForgotPassword emails a single-use password reset link. The response is
+// the same whether or not the email belongs to an account, so it can't be
+// used to discover accounts.
+// POST /api/auth/forgot-password
+func (h *AuthHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req models.ForgotPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.Email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "email is required",
+		})
+	}
+
+	if h.resetURL == "" {
+		log.Println("Password reset requested but PASSWORD_RESET_URL is not configured")
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "password reset is not available",
+		})
+	}
+
+	response := fiber.Map{
+		"success": true,
+		"message": "if an account exists for this email, a reset link has been sent",
+	}
+
+	user, err := h.db.GetUserByEmail(context.Background(), req.Email)
+	if err != nil {
+		return c.JSON(response)
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Error generating reset token: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create reset token",
+		})
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := h.db.CreatePasswordReset(context.Background(), user.ID, hashResetToken(token), time.Now().Add(h.resetTTL)); err != nil {
+		log.Printf("Error creating password reset for user %d: %v", user.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create reset token",
+		})
+	}
+
+	link := h.resetURL + "?token=" + url.QueryEscape(token)
+	body := "Someone asked to reset the password for your account " + user.Username + ".\n\n" +
+		"To choose a new password, open this link within " + h.resetTTL.String() + ":\n\n" + link + "\n\n" +
+		"If you didn't ask for this, you can ignore this email."
+
+	// Sent in the background so the response time doesn't reveal whether
+	// the account exists
+	go func() {
+		if err := h.mailer.Send(context.Background(), user.Email, "Reset your password", body); err != nil {
+			log.Printf("Error sending password reset email to user %d: %v", user.ID, err)
+		}
+	}()
+
+	return c.JSON(response)
+}
+
+// ResetPassword sets a new password using a token from ForgotPassword. The
+// token is consumed, and any other outstanding tokens for the user are
+// discarded.
+// POST /api/auth/reset-password
+func (h *AuthHandler) ResetPassword(c *fiber.Ctx) error {
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.Token == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "token and new_password are required",
+		})
+	}
+
+	// Checked before the token is consumed so a weak password doesn't use
+	// it up
+	if unmet := h.passwordPolicy.Validate(req.NewPassword); len(unmet) > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":        "password does not meet requirements",
+			"requirements": unmet,
+		})
+	}
+
+	passwordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to process password",
+		})
+	}
+
+	now := time.Now()
+	userID, err := h.db.ConsumePasswordReset(context.Background(), hashResetToken(req.Token), now)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid or expired reset token",
+		})
+	}
+
+	if err := h.db.UpdateUserPassword(context.Background(), userID, passwordHash); err != nil {
+		log.Printf("Error resetting password for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update password",
+		})
+	}
+
+	if err := h.db.DeletePasswordResets(context.Background(), userID, now); err != nil {
+		log.Printf("Error clearing password resets for user %d: %v", userID, err)
+	}
+	if err := h.db.RevokeUserRefreshTokens(context.Background(), userID, now); err != nil {
+		log.Printf("Error revoking refresh tokens for user %d: %v", userID, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "password reset successfully",
 	})
 }
+
+// hashResetToken is what is stored for a reset token, so a leaked table
+// can't be used to reset passwords
+func hashResetToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/auth"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/telegram"
+)
+
+// configExportVersion is the format version of config exports; imports of
+// other versions are refused
+const configExportVersion = 1
+
+// configPassphraseHeader carries the passphrase bot tokens are encrypted with
+// in a config export, and decrypted with on import
+const configPassphraseHeader = "X-Config-Passphrase"
+
+// ExportConfig returns all of the user's bots and channels as one JSON
+// document that ImportConfig accepts. Bot tokens are masked, or encrypted
+// when a passphrase is sent in the X-Config-Passphrase header. Channel
+// webhook secrets are not exported.
+// GET /api/user/config/export
+func (h *TelegramConfigHandler) ExportConfig(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	passphrase := c.Get(configPassphraseHeader)
+	if passphrase != "" && len(passphrase) < auth.MinPassphraseLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("passphrase must be at least %d characters", auth.MinPassphraseLength),
+		})
+	}
+
+	bots, err := h.db.GetUserTelegramBots(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting bots: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to export configuration",
+		})
+	}
+
+	doc := models.ConfigExport{
+		Version:         configExportVersion,
+		ExportedAt:      time.Now().UTC(),
+		TokensEncrypted: passphrase != "",
+		Bots:            make([]models.BotWithChannels, 0, len(bots)),
+	}
+	for _, bot := range bots {
+		// A partial export would silently drop channels on import
+		channels, err := h.db.GetBotChannels(context.Background(), bot.ID, userID)
+		if err != nil {
+			log.Printf("Error getting channels for bot %d: %v", bot.ID, err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to export configuration",
+			})
+		}
+		if channels == nil {
+			channels = []models.TelegramChannel{}
+		}
+
+		response := bot.Response()
+		if passphrase != "" {
+			if response.BotToken, err = auth.SealWithPassphrase(bot.BotToken, passphrase); err != nil {
+				log.Printf("Error encrypting token of bot %d: %v", bot.ID, err)
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "failed to export configuration",
+				})
+			}
+		}
+
+		doc.Bots = append(doc.Bots, models.BotWithChannels{Bot: response, Channels: channels})
+	}
+
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="telehook-config-%s.json"`, doc.ExportedAt.Format("20060102")))
+	return c.JSON(doc)
+}
+
+// ImportConfig recreates the bots and channels of a config export. Bots
+// already configured are matched by username and kept as they are; others
+// are created from their encrypted token, which needs the export's
+// passphrase in the X-Config-Passphrase header. Channels whose identifier is
+// already in use are skipped, so importing the same export twice is safe.
+// Imported channels start out active.
+// POST /api/user/config/import
+func (h *TelegramConfigHandler) ImportConfig(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var doc models.ConfigExport
+	if err := c.BodyParser(&doc); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if doc.Version != configExportVersion {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("unsupported config version %d, expected %d", doc.Version, configExportVersion),
+		})
+	}
+
+	passphrase := c.Get(configPassphraseHeader)
+	if doc.TokensEncrypted && passphrase == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bot tokens are encrypted, send the export's passphrase in the " + configPassphraseHeader + " header",
+		})
+	}
+
+	existing, err := h.db.GetUserTelegramBots(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting bots: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to import configuration",
+		})
+	}
+	byUsername := make(map[string]*models.TelegramBot, len(existing))
+	for i := range existing {
+		byUsername[strings.ToLower(existing[i].BotUsername)] = &existing[i]
+	}
+
+	botResults := make([]fiber.Map, 0, len(doc.Bots))
+	channelResults := make([]fiber.Map, 0)
+	bots := make(map[int]*models.TelegramBot)
+	var reqs []models.CreateChannelRequest
+	var pending []fiber.Map // Results of the channels in reqs, filled in once created
+	failed := 0
+
+	for _, entry := range doc.Bots {
+		bot, created, err := h.importBot(userID, entry.Bot, doc.TokensEncrypted, passphrase, byUsername)
+		if err != nil {
+			botResults = append(botResults, fiber.Map{"bot_username": entry.Bot.BotUsername, "status": "failed", "error": err.Error()})
+			failed++
+			for _, channel := range entry.Channels {
+				channelResults = append(channelResults, fiber.Map{"identifier": channel.Identifier, "status": "failed", "error": "bot was not imported"})
+				failed++
+			}
+			continue
+		}
+		status := "existing"
+		if created {
+			status = "created"
+		}
+		botResults = append(botResults, fiber.Map{"bot_username": bot.BotUsername, "status": status})
+		bots[bot.ID] = bot
+
+		for _, channel := range entry.Channels {
+			result := fiber.Map{"identifier": channel.Identifier, "bot_username": bot.BotUsername}
+			channelResults = append(channelResults, result)
+
+			req := channelRequestFromExport(channel, bot.ID)
+			if err := validateChannelRequest(&req); err != nil {
+				result["status"], result["error"] = "failed", err.Error()
+				failed++
+				continue
+			}
+			if taken, err := h.identifierTaken(userID, req.Identifier, 0); err != nil {
+				log.Printf("Error checking channel identifier: %v", err)
+				result["status"], result["error"] = "failed", "failed to check identifier"
+				failed++
+				continue
+			} else if taken {
+				result["status"] = "skipped"
+				continue
+			}
+
+			reqs = append(reqs, req)
+			pending = append(pending, result)
+		}
+	}
+
+	if len(reqs) > 0 {
+		channels, errs, err := h.db.CreateTelegramChannels(context.Background(), userID, reqs, true)
+		if err != nil {
+			log.Printf("Error importing channels: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to import channels",
+				"bots":  botResults,
+			})
+		}
+
+		for i, result := range pending {
+			switch {
+			case errs[i] == nil:
+				applyChannelRateLimit(bots[channels[i].BotID].BotToken, channels[i])
+				result["status"] = "created"
+			case strings.Contains(errs[i].Error(), "duplicate"):
+				// Identifiers unique per bot have no earlier check
+				result["status"] = "skipped"
+			default:
+				log.Printf("Error importing channel %s: %v", reqs[i].Identifier, errs[i])
+				result["status"], result["error"] = "failed", "failed to create channel"
+				failed++
+			}
+		}
+	}
+
+	status := fiber.StatusOK
+	if failed > 0 {
+		status = fiber.StatusMultiStatus
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success":  failed == 0,
+		"bots":     botResults,
+		"channels": channelResults,
+	})
+}
+
+// importBot finds the user's bot matching an exported one, or creates it from
+// its encrypted token, reporting whether it was created. byUsername holds the
+// user's bots by lowercased username and is updated with created bots.
+func (h *TelegramConfigHandler) importBot(userID int, exported models.TelegramBotResponse, encrypted bool, passphrase string, byUsername map[string]*models.TelegramBot) (*models.TelegramBot, bool, error) {
+	if bot, ok := byUsername[strings.ToLower(exported.BotUsername)]; ok {
+		return bot, false, nil
+	}
+	if !encrypted {
+		return nil, false, fmt.Errorf("bot token is masked and @%s is not configured: add the bot first, or export with a passphrase", exported.BotUsername)
+	}
+
+	token, err := auth.OpenWithPassphrase(exported.BotToken, passphrase)
+	if errors.Is(err, auth.ErrWrongPassphrase) {
+		return nil, false, fmt.Errorf("could not decrypt bot token: wrong passphrase or altered export")
+	}
+	if err != nil {
+		log.Printf("Error decrypting imported bot token: %v", err)
+		return nil, false, fmt.Errorf("could not decrypt bot token")
+	}
+
+	if err := telegram.ValidateTemplate(exported.MessageTemplate); err != nil {
+		return nil, false, err
+	}
+	rateLimit := exported.RateLimitPerSecond
+	if rateLimit != nil && (*rateLimit < 1 || *rateLimit > maxBotRatePerSecond) {
+		return nil, false, fmt.Errorf("rate_limit_per_second must be between 1 and %d", maxBotRatePerSecond)
+	}
+
+	// The username is looked up again as the token decides which bot it is
+	username, err := telegram.GetBotUsername(token)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid bot token or cannot connect to Telegram API")
+	}
+	if bot, ok := byUsername[strings.ToLower(username)]; ok {
+		return bot, false, nil
+	}
+
+	bot, err := h.db.CreateTelegramBot(context.Background(), userID, token, username, exported.IsDefault, exported.MessageTemplate, rateLimit)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return nil, false, fmt.Errorf("bot token already exists")
+		}
+		log.Printf("Error importing bot: %v", err)
+		return nil, false, fmt.Errorf("failed to create bot")
+	}
+	applyBotRateLimit(bot)
+
+	byUsername[strings.ToLower(bot.BotUsername)] = bot
+	return bot, true, nil
+}
+
+// channelRequestFromExport turns an exported channel into a request creating
+// it under botID. The bot's access was checked when the channel was first
+// saved, so it isn't checked again.
+func channelRequestFromExport(channel models.TelegramChannel, botID int) models.CreateChannelRequest {
+	return models.CreateChannelRequest{
+		BotID:                 botID,
+		Identifier:            channel.Identifier,
+		ChannelID:             channel.ChannelID,
+		ChannelName:           channel.ChannelName,
+		Description:           channel.Description,
+		ParseMode:             channel.ParseMode,
+		MessageTemplate:       channel.MessageTemplate,
+		CoalesceWindowSeconds: channel.CoalesceWindowSeconds,
+		DedupWindowSeconds:    channel.DedupWindowSeconds,
+		ThreadID:              channel.ThreadID,
+		RateLimitPerMinute:    channel.RateLimitPerMinute,
+		IsDefault:             channel.IsDefault,
+		SkipValidation:        true,
+	}
+}
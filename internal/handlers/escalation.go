@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/outbound"
+)
+
+type EscalationHandler struct {
+	db *database.DB
+}
+
+func NewEscalationHandler(db *database.DB) *EscalationHandler {
+	return &EscalationHandler{db: db}
+}
+
+// GetEscalationPolicies lists the user's escalation policies
+// GET /api/user/escalation-policies
+func (h *EscalationHandler) GetEscalationPolicies(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	policies, err := h.db.GetUserEscalationPolicies(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting escalation policies: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve escalation policies",
+		})
+	}
+
+	if policies == nil {
+		policies = []models.EscalationPolicy{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"policies": policies,
+	})
+}
+
+// UpsertEscalationPolicy creates or replaces the user's default policy, or a
+// channel's policy when channel_id is given
+// PUT /api/user/escalation-policies
+func (h *EscalationHandler) UpsertEscalationPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.UpsertEscalationPolicyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.MirrorURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err := outbound.ValidateURL(ctx, req.MirrorURL)
+		cancel()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "mirror_url: " + err.Error(),
+			})
+		}
+	}
+
+	// Verify referenced channels belong to user
+	for _, channelID := range []*int{req.ChannelID, req.FailoverChannelID} {
+		if channelID == nil {
+			continue
+		}
+		if _, err := h.db.GetTelegramChannel(context.Background(), *channelID, userID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "channel not found or not owned by user",
+			})
+		}
+	}
+
+	if req.ChannelID != nil && req.FailoverChannelID != nil && *req.ChannelID == *req.FailoverChannelID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "failover_channel_id must differ from channel_id",
+		})
+	}
+
+	policy := &models.EscalationPolicy{
+		UserID:            userID,
+		ChannelID:         req.ChannelID,
+		FailoverChannelID: req.FailoverChannelID,
+		MirrorURL:         req.MirrorURL,
+		DeadLetter:        true,
+		IsActive:          true,
+	}
+	if req.DeadLetter != nil {
+		policy.DeadLetter = *req.DeadLetter
+	}
+	if req.IsActive != nil {
+		policy.IsActive = *req.IsActive
+	}
+
+	saved, err := h.db.UpsertEscalationPolicy(context.Background(), policy)
+	if err != nil {
+		log.Printf("Error saving escalation policy: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save escalation policy",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"policy":  saved,
+	})
+}
+
+// DeleteEscalationPolicy removes an escalation policy
+// DELETE /api/user/escalation-policies/:id
+func (h *EscalationHandler) DeleteEscalationPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	policyID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid policy ID",
+		})
+	}
+
+	if err := h.db.DeleteEscalationPolicy(context.Background(), policyID, userID); err != nil {
+		log.Printf("Error deleting escalation policy: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete escalation policy",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "escalation policy deleted successfully",
+	})
+}
+
+// GetDeadLetters lists the user's most recent dead-lettered alerts
+// GET /api/user/dead-letters?limit=50
+func (h *EscalationHandler) GetDeadLetters(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+
+	alerts, err := h.db.GetUserDeadLetterAlerts(context.Background(), userID, limit)
+	if err != nil {
+		log.Printf("Error getting dead-letter alerts: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve dead-letter alerts",
+		})
+	}
+
+	if alerts == nil {
+		alerts = []models.DeadLetterAlert{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success":      true,
+		"dead_letters": alerts,
+	})
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+const (
+	// exportMessageLength is how much of each message a log export keeps
+	exportMessageLength = 200
+	// exportFlushRows is how many rows are written between flushes
+	exportFlushRows = 500
+)
+
+// ExportLogs streams the user's webhook logs for a window as CSV. Rows are
+// written as they are read, so large exports aren't held in memory.
+// GET /api/user/logs/export?range=24h|7d|30d or ?from=&to=
+func (h *AnalyticsHandler) ExportLogs(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	from, to, _, err := analyticsWindow(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	setCSVHeaders(c, fmt.Sprintf("webhook-logs-%s.csv", from.Format("20060102")))
+
+	// The writer runs after the handler returns, so it can't use c
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		out := csv.NewWriter(w)
+		_ = out.Write([]string{"timestamp", "status", "identifier", "priority", "message"})
+
+		rows := 0
+		err := h.db.ForEachWebhookLogExport(context.Background(), userID, from, to, exportMessageLength, func(row models.WebhookLogExportRow) error {
+			if err := out.Write([]string{
+				row.SentAt.UTC().Format(time.RFC3339),
+				row.Status,
+				csvSafe(row.Identifier),
+				strconv.Itoa(row.Priority),
+				csvSafe(row.Message),
+			}); err != nil {
+				return err
+			}
+
+			rows++
+			if rows%exportFlushRows == 0 {
+				out.Flush()
+				if err := out.Error(); err != nil {
+					return err
+				}
+				// Fails once the client has gone away, ending the export
+				return w.Flush()
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("Error exporting webhook logs for user %d: %v", userID, err)
+		}
+
+		out.Flush()
+		_ = w.Flush()
+	})
+
+	return nil
+}
+
+// ExportAnalytics returns the analytics timeline for a window as CSV, or the
+// summary with section=summary
+// GET /api/user/analytics/export?range=24h|7d|30d or ?from=&to=&section=timeline|summary&bot_id=&identifier=
+func (h *AnalyticsHandler) ExportAnalytics(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	from, to, timeRange, err := analyticsWindow(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filter, err := h.analyticsFilter(c, userID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	section := c.Query("section", "timeline")
+	if section != "timeline" && section != "summary" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "section must be timeline or summary",
+		})
+	}
+
+	analytics, err := h.db.GetAnalyticsBetween(c.Context(), userID, from, to, timeRange, filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to fetch analytics",
+		})
+	}
+
+	var records [][]string
+	if section == "summary" {
+		summary := analytics.Summary
+		records = [][]string{
+			{"metric", "value"},
+			{"from", from.Format(time.RFC3339)},
+			{"to", to.Format(time.RFC3339)},
+			{"total_messages", strconv.Itoa(summary.TotalMessages)},
+			{"success_count", strconv.Itoa(summary.SuccessCount)},
+			{"failed_count", strconv.Itoa(summary.FailedCount)},
+			{"filtered_count", strconv.Itoa(summary.FilteredCount)},
+			{"success_rate", strconv.FormatFloat(summary.SuccessRate, 'f', 2, 64)},
+			{"avg_per_hour", strconv.FormatFloat(summary.AvgPerHour, 'f', 2, 64)},
+			{"avg_per_day", strconv.FormatFloat(summary.AvgPerDay, 'f', 2, 64)},
+			{"peak_hour", strconv.Itoa(summary.PeakHour)},
+			{"peak_hour_count", strconv.Itoa(summary.PeakHourCount)},
+			{"latency_avg_ms", strconv.FormatFloat(summary.LatencyAvgMs, 'f', 0, 64)},
+			{"latency_p50_ms", strconv.FormatFloat(summary.LatencyP50Ms, 'f', 0, 64)},
+			{"latency_p95_ms", strconv.FormatFloat(summary.LatencyP95Ms, 'f', 0, 64)},
+			{"latency_p99_ms", strconv.FormatFloat(summary.LatencyP99Ms, 'f', 0, 64)},
+		}
+	} else {
+		records = [][]string{{"timestamp", "total_count", "success_count", "failed_count", "filtered_count"}}
+		for _, point := range analytics.Timeline {
+			records = append(records, []string{
+				point.Timestamp.UTC().Format(time.RFC3339),
+				strconv.Itoa(point.TotalCount),
+				strconv.Itoa(point.SuccessCount),
+				strconv.Itoa(point.FailedCount),
+				strconv.Itoa(point.FilteredCount),
+			})
+		}
+	}
+
+	var body strings.Builder
+	out := csv.NewWriter(&body)
+	if err := out.WriteAll(records); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to write CSV",
+		})
+	}
+
+	setCSVHeaders(c, fmt.Sprintf("analytics-%s-%s.csv", section, from.Format("20060102")))
+	return c.SendString(body.String())
+}
+
+// setCSVHeaders marks the response as a CSV download named filename
+func setCSVHeaders(c *fiber.Ctx, filename string) {
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+}
+
+// csvSafe keeps user-supplied text from being run as a formula when the
+// export is opened in a spreadsheet
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
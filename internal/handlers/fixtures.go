@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+// maxFixtureNameLength and maxFixturePayloadBytes bound a saved fixture
+const (
+	maxFixtureNameLength   = 100
+	maxFixturePayloadBytes = 64 * 1024
+)
+
+// fixtureNamePattern keeps fixture names safe to use in URLs
+var fixtureNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+type FixtureHandler struct {
+	db      *database.DB
+	webhook *WebhookHandler
+}
+
+func NewFixtureHandler(db *database.DB, webhook *WebhookHandler) *FixtureHandler {
+	return &FixtureHandler{db: db, webhook: webhook}
+}
+
+// SaveFixture creates a named sample payload, replacing any fixture with the
+// same name
+// POST /api/user/fixtures
+func (h *FixtureHandler) SaveFixture(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.SaveWebhookFixtureRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if len(req.Name) > maxFixtureNameLength || !fixtureNamePattern.MatchString(req.Name) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("name must be 1-%d characters of letters, digits, dots, dashes and underscores, starting with a letter or digit", maxFixtureNameLength),
+		})
+	}
+
+	if req.Payload == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "payload is required and must be a JSON object",
+		})
+	}
+	if encoded, err := json.Marshal(req.Payload); err != nil || len(encoded) > maxFixturePayloadBytes {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("payload must be at most %d bytes", maxFixturePayloadBytes),
+		})
+	}
+
+	fixture, err := h.db.SaveWebhookFixture(context.Background(), &models.WebhookFixture{
+		UserID:  userID,
+		Name:    req.Name,
+		Payload: req.Payload,
+	})
+	if err != nil {
+		log.Printf("Error saving webhook fixture: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save fixture",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"fixture": fixture,
+	})
+}
+
+// GetFixtures lists the user's saved fixtures
+// GET /api/user/fixtures
+func (h *FixtureHandler) GetFixtures(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	fixtures, err := h.db.GetWebhookFixtures(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting webhook fixtures: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve fixtures",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"fixtures": fixtures,
+	})
+}
+
+// GetFixture returns a saved fixture by name
+// GET /api/user/fixtures/:name
+func (h *FixtureHandler) GetFixture(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	fixture, err := h.db.GetWebhookFixture(context.Background(), userID, c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "fixture not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"fixture": fixture,
+	})
+}
+
+// DeleteFixture removes a saved fixture by name
+// DELETE /api/user/fixtures/:name
+func (h *FixtureHandler) DeleteFixture(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	if err := h.db.DeleteWebhookFixture(context.Background(), userID, c.Params("name")); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "fixture not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "fixture deleted successfully",
+	})
+}
+
+// FireFixture sends a saved fixture through the webhook path exactly as if it
+// had been posted to the user's webhook URL, including the payload transform.
+// With ?dry_run=true the routing is reported without sending.
+// POST /api/user/fixtures/:name/fire
+func (h *FixtureHandler) FireFixture(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	fixture, err := h.db.GetWebhookFixture(context.Background(), userID, c.Params("name"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "fixture not found",
+		})
+	}
+
+	user, err := h.db.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	body, err := json.Marshal(fixture.Payload)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to encode fixture payload",
+		})
+	}
+
+	payload, werr := h.webhook.decodePayload(body, userID, func(out interface{}) error {
+		return json.Unmarshal(body, out)
+	})
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	// Fired by the signed-in user, so channels' webhook secrets don't apply
+	return h.webhook.dispatch(c, user, payload, body, webhookSignature{trusted: true})
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+// inflightRegistry tracks webhook requests whose alerts are still being
+// processed, so an identical request arriving meanwhile (e.g. a sender
+// retrying on timeout) can be answered with the original alert instead of
+// enqueuing another copy
+type inflightRegistry struct {
+	byKey   map[string]inflightEntry // request hash -> alert
+	byAlert map[string]string        // alert ID -> request hash
+	maxAge  time.Duration
+	mu      sync.Mutex
+}
+
+type inflightEntry struct {
+	alertID   string
+	createdAt time.Time
+}
+
+func newInflightRegistry(maxAge time.Duration) *inflightRegistry {
+	return &inflightRegistry{
+		byKey:   make(map[string]inflightEntry),
+		byAlert: make(map[string]string),
+		maxAge:  maxAge,
+	}
+}
+
+// Register records alertID as in flight for key. If an identical request is
+// already in flight, it returns that request's alert ID and false instead.
+func (r *inflightRegistry) Register(key, alertID string) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if entry, exists := r.byKey[key]; exists {
+		// Entries whose alert never reported completion (e.g. lost on
+		// shutdown) expire rather than blocking the request forever
+		if time.Since(entry.createdAt) < r.maxAge {
+			return entry.alertID, false
+		}
+		delete(r.byAlert, entry.alertID)
+	}
+
+	r.byKey[key] = inflightEntry{alertID: alertID, createdAt: time.Now()}
+	r.byAlert[alertID] = key
+	return alertID, true
+}
+
+// Complete removes an alert from the registry
+func (r *inflightRegistry) Complete(alertID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, exists := r.byAlert[alertID]; exists {
+		delete(r.byKey, key)
+		delete(r.byAlert, alertID)
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/queue"
+)
+
+type QuietHoursHandler struct {
+	db        *database.DB
+	processor *queue.TelegramProcessor
+}
+
+func NewQuietHoursHandler(db *database.DB, processor *queue.TelegramProcessor) *QuietHoursHandler {
+	return &QuietHoursHandler{db: db, processor: processor}
+}
+
+// GetQuietHours returns the user's quiet hours
+// GET /api/user/quiet-hours
+func (h *QuietHoursHandler) GetQuietHours(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	quiet, err := h.db.GetQuietHours(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "quiet hours not configured",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"quiet_hours": quiet,
+	})
+}
+
+// UpsertQuietHours sets the daily window in which normal and low priority
+// alerts are filtered. end_hour at or before start_hour crosses midnight.
+// PUT /api/user/quiet-hours
+func (h *QuietHoursHandler) UpsertQuietHours(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.UpsertQuietHoursRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.StartHour == nil || req.EndHour == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "start_hour and end_hour are required",
+		})
+	}
+	if *req.StartHour < 0 || *req.StartHour > 23 || *req.EndHour < 0 || *req.EndHour > 23 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "start_hour and end_hour must be between 0 and 23",
+		})
+	}
+	if *req.StartHour == *req.EndHour {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "start_hour and end_hour must differ",
+		})
+	}
+
+	timezone := req.Timezone
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid timezone, use an IANA name such as Europe/Berlin",
+		})
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	quiet, err := h.db.UpsertQuietHours(context.Background(), &models.QuietHours{
+		UserID:    userID,
+		StartHour: *req.StartHour,
+		EndHour:   *req.EndHour,
+		Timezone:  timezone,
+		IsActive:  isActive,
+	})
+	if err != nil {
+		log.Printf("Error saving quiet hours: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save quiet hours",
+		})
+	}
+
+	h.processor.InvalidateQuietHours()
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"quiet_hours": quiet,
+	})
+}
+
+// DeleteQuietHours removes the user's quiet hours
+// DELETE /api/user/quiet-hours
+func (h *QuietHoursHandler) DeleteQuietHours(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	if err := h.db.DeleteQuietHours(context.Background(), userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "quiet hours not configured",
+		})
+	}
+
+	h.processor.InvalidateQuietHours()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "quiet hours deleted successfully",
+	})
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/outbound"
+)
+
+type ReceiptHandler struct {
+	db *database.DB
+}
+
+func NewReceiptHandler(db *database.DB) *ReceiptHandler {
+	return &ReceiptHandler{db: db}
+}
+
+// GetReceiptWebhook returns the user's delivery receipt webhook
+// GET /api/user/receipts-webhook
+func (h *ReceiptHandler) GetReceiptWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	webhook, err := h.db.GetReceiptWebhook(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "receipt webhook not configured",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":         true,
+		"receipt_webhook": webhook,
+	})
+}
+
+// UpsertReceiptWebhook creates or replaces the user's delivery receipt webhook
+// PUT /api/user/receipts-webhook
+func (h *ReceiptHandler) UpsertReceiptWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.UpsertReceiptWebhookRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	err := outbound.ValidateURL(ctx, req.URL)
+	cancel()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	interval := req.IntervalMinutes
+	if interval == 0 {
+		interval = 60
+	}
+	if interval < 5 || interval > 1440 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "interval_minutes must be between 5 and 1440",
+		})
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		// Generate a signing secret if the user didn't supply one
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Printf("Error generating receipt secret: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to generate signing secret",
+			})
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	webhook, err := h.db.UpsertReceiptWebhook(context.Background(), &models.ReceiptWebhook{
+		UserID:          userID,
+		URL:             req.URL,
+		Secret:          secret,
+		IntervalMinutes: interval,
+		IsActive:        isActive,
+		NextSendAt:      time.Now().Add(time.Duration(interval) * time.Minute),
+	})
+	if err != nil {
+		log.Printf("Error saving receipt webhook: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save receipt webhook",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":         true,
+		"receipt_webhook": webhook,
+	})
+}
+
+// DeleteReceiptWebhook removes the user's delivery receipt webhook
+// DELETE /api/user/receipts-webhook
+func (h *ReceiptHandler) DeleteReceiptWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	if err := h.db.DeleteReceiptWebhook(context.Background(), userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "receipt webhook not configured",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "receipt webhook deleted successfully",
+	})
+}
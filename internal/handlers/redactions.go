@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/queue"
+)
+
+const (
+	maxRedactionRules         = 50
+	maxRedactionPatternLength = 500
+	defaultRedaction          = "[REDACTED]"
+)
+
+type RedactionHandler struct {
+	db        *database.DB
+	processor *queue.TelegramProcessor
+}
+
+func NewRedactionHandler(db *database.DB, processor *queue.TelegramProcessor) *RedactionHandler {
+	return &RedactionHandler{db: db, processor: processor}
+}
+
+// CreateRedaction adds a redaction rule, applied to the message and data of
+// the user's alerts before they are sent or logged
+// POST /api/user/redactions
+func (h *RedactionHandler) CreateRedaction(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.RedactionRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	existing, err := h.db.GetUserRedactionRules(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting redaction rules: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create redaction rule",
+		})
+	}
+	if len(existing) >= maxRedactionRules {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("a user can have at most %d redaction rules", maxRedactionRules),
+		})
+	}
+
+	rule := &models.RedactionRule{UserID: userID, Replacement: defaultRedaction, IsEnabled: true}
+	if err := applyRedactionRequest(rule, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	created, err := h.db.CreateRedactionRule(context.Background(), rule)
+	if err != nil {
+		log.Printf("Error creating redaction rule: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create redaction rule",
+		})
+	}
+
+	h.processor.InvalidateRedactions()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success":   true,
+		"redaction": created,
+	})
+}
+
+// GetRedactions lists the user's redaction rules
+// GET /api/user/redactions
+func (h *RedactionHandler) GetRedactions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	rules, err := h.db.GetUserRedactionRules(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting redaction rules: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve redaction rules",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":    true,
+		"redactions": rules,
+	})
+}
+
+// UpdateRedaction replaces a redaction rule. replacement and is_enabled keep
+// their current values when omitted.
+// PUT /api/user/redactions/:id
+func (h *RedactionHandler) UpdateRedaction(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	ruleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid redaction ID",
+		})
+	}
+
+	var req models.RedactionRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	rule, err := h.db.GetRedactionRule(context.Background(), ruleID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "redaction rule not found",
+		})
+	}
+
+	if err := applyRedactionRequest(rule, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updated, err := h.db.UpdateRedactionRule(context.Background(), rule)
+	if err != nil {
+		log.Printf("Error updating redaction rule: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update redaction rule",
+		})
+	}
+
+	h.processor.InvalidateRedactions()
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"redaction": updated,
+	})
+}
+
+// DeleteRedaction removes a redaction rule
+// DELETE /api/user/redactions/:id
+func (h *RedactionHandler) DeleteRedaction(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	ruleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid redaction ID",
+		})
+	}
+
+	if err := h.db.DeleteRedactionRule(context.Background(), ruleID, userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "redaction rule not found",
+		})
+	}
+
+	h.processor.InvalidateRedactions()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "redaction rule deleted successfully",
+	})
+}
+
+// applyRedactionRequest validates a request and copies it onto rule. The
+// pattern must compile as Go (RE2) regexp syntax.
+func applyRedactionRequest(rule *models.RedactionRule, req *models.RedactionRuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || req.Pattern == "" {
+		return fmt.Errorf("name and pattern are required")
+	}
+	if len(name) > maxRuleNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxRuleNameLength)
+	}
+	if len(req.Pattern) > maxRedactionPatternLength {
+		return fmt.Errorf("pattern must be at most %d characters", maxRedactionPatternLength)
+	}
+	if _, err := regexp.Compile(req.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %v", err)
+	}
+
+	rule.Name = name
+	rule.Pattern = req.Pattern
+	if req.Replacement != nil {
+		rule.Replacement = *req.Replacement
+	}
+	if req.IsEnabled != nil {
+		rule.IsEnabled = *req.IsEnabled
+	}
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/queue"
+)
+
+const (
+	maxRuleKeywords      = 50
+	maxRuleNameLength    = 100
+	maxRuleWindowSeconds = 86400
+)
+
+// ruleKinds lists the kinds each rule type supports. max_length can either
+// drop long alerts or truncate them.
+var ruleKinds = map[string][]string{
+	models.RuleTypeBlockKeywords:   {models.RuleKindFilter},
+	models.RuleTypeRequireKeywords: {models.RuleKindFilter},
+	models.RuleTypeMinLength:       {models.RuleKindFilter},
+	models.RuleTypeMaxLength:       {models.RuleKindFilter, models.RuleKindTransform},
+	models.RuleTypeThrottle:        {models.RuleKindFilter},
+	models.RuleTypeRedactKeywords:  {models.RuleKindTransform},
+}
+
+type RuleHandler struct {
+	db        *database.DB
+	processor *queue.TelegramProcessor
+}
+
+func NewRuleHandler(db *database.DB, processor *queue.TelegramProcessor) *RuleHandler {
+	return &RuleHandler{db: db, processor: processor}
+}
+
+// CreateRule adds an alert rule, applied in addition to the global defaults
+// POST /api/user/rules
+func (h *RuleHandler) CreateRule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.UserAlertRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	rule := &models.UserAlertRule{UserID: userID, IsEnabled: true}
+	if err := applyRuleRequest(rule, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	created, err := h.db.CreateUserAlertRule(context.Background(), rule)
+	if err != nil {
+		log.Printf("Error creating alert rule: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create alert rule",
+		})
+	}
+
+	h.processor.InvalidateUserRules()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success": true,
+		"rule":    created,
+	})
+}
+
+// GetRules lists the user's alert rules
+// GET /api/user/rules
+func (h *RuleHandler) GetRules(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	rules, err := h.db.GetUserAlertRules(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting alert rules: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve alert rules",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"rules":   rules,
+	})
+}
+
+// UpdateRule replaces an alert rule. is_enabled keeps its current value when
+// omitted.
+// PUT /api/user/rules/:id
+func (h *RuleHandler) UpdateRule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	ruleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid rule ID",
+		})
+	}
+
+	var req models.UserAlertRuleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	rule, err := h.db.GetUserAlertRule(context.Background(), ruleID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "alert rule not found",
+		})
+	}
+
+	if err := applyRuleRequest(rule, &req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updated, err := h.db.UpdateUserAlertRule(context.Background(), rule)
+	if err != nil {
+		log.Printf("Error updating alert rule: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update alert rule",
+		})
+	}
+
+	h.processor.InvalidateUserRules()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"rule":    updated,
+	})
+}
+
+// DeleteRule removes an alert rule
+// DELETE /api/user/rules/:id
+func (h *RuleHandler) DeleteRule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	ruleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid rule ID",
+		})
+	}
+
+	if err := h.db.DeleteUserAlertRule(context.Background(), ruleID, userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "alert rule not found",
+		})
+	}
+
+	h.processor.InvalidateUserRules()
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "alert rule deleted successfully",
+	})
+}
+
+// applyRuleRequest validates a request and copies it onto rule, clearing
+// fields the rule type doesn't use
+func applyRuleRequest(rule *models.UserAlertRule, req *models.UserAlertRuleRequest) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || req.Kind == "" || req.RuleType == "" {
+		return fmt.Errorf("name, kind, and rule_type are required")
+	}
+	if len(name) > maxRuleNameLength {
+		return fmt.Errorf("name must be at most %d characters", maxRuleNameLength)
+	}
+
+	kinds, ok := ruleKinds[req.RuleType]
+	if !ok {
+		return fmt.Errorf("unknown rule_type %q", req.RuleType)
+	}
+	supported := false
+	for _, kind := range kinds {
+		if kind == req.Kind {
+			supported = true
+		}
+	}
+	if !supported {
+		return fmt.Errorf("rule_type %s must be of kind %s", req.RuleType, strings.Join(kinds, " or "))
+	}
+
+	var keywords []string
+	var value, window *int
+
+	switch req.RuleType {
+	case models.RuleTypeBlockKeywords, models.RuleTypeRequireKeywords, models.RuleTypeRedactKeywords:
+		for _, keyword := range req.Keywords {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				keywords = append(keywords, keyword)
+			}
+		}
+		if len(keywords) == 0 {
+			return fmt.Errorf("%s requires at least one keyword", req.RuleType)
+		}
+		if len(keywords) > maxRuleKeywords {
+			return fmt.Errorf("a rule can have at most %d keywords", maxRuleKeywords)
+		}
+	case models.RuleTypeMinLength, models.RuleTypeMaxLength:
+		if req.Value == nil || *req.Value <= 0 {
+			return fmt.Errorf("%s requires a positive value", req.RuleType)
+		}
+		value = req.Value
+	case models.RuleTypeThrottle:
+		if req.Value == nil || *req.Value <= 0 {
+			return fmt.Errorf("throttle requires a positive value (alerts per window)")
+		}
+		if req.WindowSeconds == nil || *req.WindowSeconds <= 0 || *req.WindowSeconds > maxRuleWindowSeconds {
+			return fmt.Errorf("throttle requires window_seconds between 1 and %d", maxRuleWindowSeconds)
+		}
+		value = req.Value
+		window = req.WindowSeconds
+	}
+
+	if keywords == nil {
+		keywords = []string{}
+	}
+
+	rule.Name = name
+	rule.Kind = req.Kind
+	rule.RuleType = req.RuleType
+	rule.Keywords = keywords
+	rule.Value = value
+	rule.WindowSeconds = window
+	if req.IsEnabled != nil {
+		rule.IsEnabled = *req.IsEnabled
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/scheduler"
+)
+
+type ScheduleHandler struct {
+	db *database.DB
+}
+
+func NewScheduleHandler(db *database.DB) *ScheduleHandler {
+	return &ScheduleHandler{db: db}
+}
+
+func (h *ScheduleHandler) CreateSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.CreateScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.ChannelID == 0 || req.Name == "" || req.CronExpression == "" || req.Message == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "channel_id, name, cron_expression, and message are required",
+		})
+	}
+
+	schedule := &models.ScheduledMessage{
+		UserID:         userID,
+		ChannelID:      req.ChannelID,
+		Name:           req.Name,
+		CronExpression: req.CronExpression,
+		Timezone:       req.Timezone,
+		Message:        req.Message,
+		Priority:       req.Priority,
+		IsActive:       true,
+	}
+
+	if err := h.prepareSchedule(schedule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	created, err := h.db.CreateScheduledMessage(context.Background(), schedule)
+	if err != nil {
+		log.Printf("Error creating schedule: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create schedule",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"success":  true,
+		"schedule": created,
+	})
+}
+
+func (h *ScheduleHandler) GetSchedules(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	schedules, err := h.db.GetUserScheduledMessages(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting schedules: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve schedules",
+		})
+	}
+
+	if schedules == nil {
+		schedules = []models.ScheduledMessage{}
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"schedules": schedules,
+	})
+}
+
+func (h *ScheduleHandler) GetSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	scheduleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid schedule ID",
+		})
+	}
+
+	schedule, err := h.db.GetScheduledMessage(context.Background(), scheduleID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "schedule not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"schedule": schedule,
+	})
+}
+
+func (h *ScheduleHandler) UpdateSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	scheduleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid schedule ID",
+		})
+	}
+
+	var req models.UpdateScheduleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	schedule, err := h.db.GetScheduledMessage(context.Background(), scheduleID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "schedule not found",
+		})
+	}
+
+	// Merge provided fields over the stored schedule
+	if req.ChannelID != 0 {
+		schedule.ChannelID = req.ChannelID
+	}
+	if req.Name != "" {
+		schedule.Name = req.Name
+	}
+	if req.CronExpression != "" {
+		schedule.CronExpression = req.CronExpression
+	}
+	if req.Timezone != "" {
+		schedule.Timezone = req.Timezone
+	}
+	if req.Message != "" {
+		schedule.Message = req.Message
+	}
+	if req.Priority != 0 {
+		schedule.Priority = req.Priority
+	}
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+
+	if err := h.prepareSchedule(schedule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updated, err := h.db.UpdateScheduledMessage(context.Background(), schedule)
+	if err != nil {
+		log.Printf("Error updating schedule: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to update schedule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"schedule": updated,
+	})
+}
+
+func (h *ScheduleHandler) DeleteSchedule(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	scheduleID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid schedule ID",
+		})
+	}
+
+	err = h.db.DeleteScheduledMessage(context.Background(), scheduleID, userID)
+	if err != nil {
+		log.Printf("Error deleting schedule: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete schedule",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "schedule deleted successfully",
+	})
+}
+
+// prepareSchedule applies defaults, validates the schedule and computes its
+// next run
+func (h *ScheduleHandler) prepareSchedule(schedule *models.ScheduledMessage) error {
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if schedule.Priority == 0 {
+		schedule.Priority = 3
+	}
+
+	if schedule.Priority < 1 || schedule.Priority > 4 {
+		return fmt.Errorf("priority must be between 1 (urgent) and 4 (low)")
+	}
+
+	// Verify channel belongs to user
+	if _, err := h.db.GetTelegramChannel(context.Background(), schedule.ChannelID, schedule.UserID); err != nil {
+		return fmt.Errorf("channel not found or not owned by user")
+	}
+
+	next, err := scheduler.NextRun(schedule.CronExpression, schedule.Timezone, time.Now())
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = next
+
+	if _, err := scheduler.RenderMessage(schedule.Message, schedule.Name, schedule.Timezone, time.Now()); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -2,15 +2,40 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/thenaveensharma/telehook/internal/database"
 	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/queue"
 	"github.com/thenaveensharma/telehook/internal/telegram"
 )
 
+// maxCoalesceWindowSeconds caps how long updates can be held back
+const maxCoalesceWindowSeconds = 300
+
+// maxDedupWindowSeconds caps channel and user deduplication windows
+const maxDedupWindowSeconds = 86400
+
+// maxBulkChannels caps the channels created in one bulk request
+const maxBulkChannels = 100
+
+// How a bulk channel request handles channels that fail
+const (
+	bulkOnErrorAbort = "abort" // Create nothing
+	bulkOnErrorSkip  = "skip"  // Create the rest
+)
+
+// Caps on user-set send rates; Telegram grants bots higher limits on request
+const (
+	maxBotRatePerSecond     = 1000
+	maxChannelRatePerMinute = 600
+)
+
 type TelegramConfigHandler struct {
 	db *database.DB
 }
@@ -41,6 +66,21 @@ func (h *TelegramConfigHandler) CreateBot(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := telegram.ValidateTemplate(req.MessageTemplate); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.RateLimitPerSecond != nil && (*req.RateLimitPerSecond < 0 || *req.RateLimitPerSecond > maxBotRatePerSecond) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("rate_limit_per_second must be between 1 and %d, or 0 for the default", maxBotRatePerSecond),
+		})
+	}
+	if req.RateLimitPerSecond != nil && *req.RateLimitPerSecond == 0 {
+		req.RateLimitPerSecond = nil
+	}
+
 	// Validate bot token by attempting to get bot username
 	botUsername, err := telegram.GetBotUsername(req.BotToken)
 	if err != nil {
@@ -50,7 +90,7 @@ func (h *TelegramConfigHandler) CreateBot(c *fiber.Ctx) error {
 	}
 
 	// Create bot in database
-	bot, err := h.db.CreateTelegramBot(context.Background(), userID, req.BotToken, botUsername, req.IsDefault)
+	bot, err := h.db.CreateTelegramBot(context.Background(), userID, req.BotToken, botUsername, req.IsDefault, req.MessageTemplate, req.RateLimitPerSecond)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate") {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
@@ -63,15 +103,57 @@ func (h *TelegramConfigHandler) CreateBot(c *fiber.Ctx) error {
 		})
 	}
 
+	applyBotRateLimit(bot)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"success": true,
-		"bot":     bot,
+		"bot":     bot.Response(),
 	})
 }
 
+// ValidateBotToken checks a bot token with Telegram without saving it, so
+// the dashboard can give feedback as a token is pasted
+// POST /api/user/bots/validate
+func (h *TelegramConfigHandler) ValidateBotToken(c *fiber.Ctx) error {
+	var req models.ValidateBotTokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	token := strings.TrimSpace(req.BotToken)
+	if token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "bot_token is required",
+		})
+	}
+
+	info, err := telegram.ValidateToken(token)
+	if err != nil {
+		log.Printf("Error validating bot token: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "cannot connect to Telegram API",
+		})
+	}
+
+	// A rejected token is still a successful check, reported as valid: false
+	return c.JSON(struct {
+		Success bool `json:"success"`
+		telegram.TokenInfo
+	}{true, info})
+}
+
 func (h *TelegramConfigHandler) GetBots(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(int)
 
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	bots, err := h.db.GetUserTelegramBots(context.Background(), userID)
 	if err != nil {
 		log.Printf("Error getting bots: %v", err)
@@ -80,13 +162,15 @@ func (h *TelegramConfigHandler) GetBots(c *fiber.Ctx) error {
 		})
 	}
 
-	if bots == nil {
-		bots = []models.TelegramBot{}
+	response := make([]models.TelegramBotResponse, 0, len(bots))
+	for i := range bots {
+		localizeTimes(loc, &bots[i].CreatedAt, &bots[i].UpdatedAt)
+		response = append(response, bots[i].Response())
 	}
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"bots":    bots,
+		"bots":    response,
 	})
 }
 
@@ -99,16 +183,24 @@ func (h *TelegramConfigHandler) GetBot(c *fiber.Ctx) error {
 		})
 	}
 
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	bot, err := h.db.GetTelegramBot(context.Background(), botID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "bot not found",
 		})
 	}
+	localizeTimes(loc, &bot.CreatedAt, &bot.UpdatedAt)
 
 	return c.JSON(fiber.Map{
 		"success": true,
-		"bot":     bot,
+		"bot":     bot.Response(),
 	})
 }
 
@@ -128,6 +220,20 @@ func (h *TelegramConfigHandler) UpdateBot(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.MessageTemplate != nil {
+		if err := telegram.ValidateTemplate(*req.MessageTemplate); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.RateLimitPerSecond != nil && (*req.RateLimitPerSecond < 0 || *req.RateLimitPerSecond > maxBotRatePerSecond) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("rate_limit_per_second must be between 1 and %d, or 0 for the default", maxBotRatePerSecond),
+		})
+	}
+
 	// If token is being updated, validate it
 	botUsername := ""
 	if req.BotToken != "" {
@@ -140,7 +246,7 @@ func (h *TelegramConfigHandler) UpdateBot(c *fiber.Ctx) error {
 		botUsername = username
 	}
 
-	bot, err := h.db.UpdateTelegramBot(context.Background(), botID, userID, req.BotToken, botUsername, req.IsDefault)
+	bot, err := h.db.UpdateTelegramBot(context.Background(), botID, userID, req.BotToken, botUsername, req.IsDefault, req.MessageTemplate, req.RateLimitPerSecond)
 	if err != nil {
 		log.Printf("Error updating bot: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -148,9 +254,11 @@ func (h *TelegramConfigHandler) UpdateBot(c *fiber.Ctx) error {
 		})
 	}
 
+	applyBotRateLimit(bot)
+
 	return c.JSON(fiber.Map{
 		"success": true,
-		"bot":     bot,
+		"bot":     bot.Response(),
 	})
 }
 
@@ -191,34 +299,45 @@ func (h *TelegramConfigHandler) CreateChannel(c *fiber.Ctx) error {
 		})
 	}
 
-	if req.BotID == 0 || req.Identifier == "" || req.ChannelID == "" {
+	if err := validateChannelRequest(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "bot_id, identifier, and channel_id are required",
+			"error": err.Error(),
 		})
 	}
 
 	// Verify bot belongs to user
-	_, err := h.db.GetTelegramBot(context.Background(), req.BotID, userID)
+	bot, err := h.db.GetTelegramBot(context.Background(), req.BotID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "bot not found or not owned by user",
 		})
 	}
 
+	if !req.SkipValidation {
+		if err := checkChannelAccess(bot.BotToken, req.ChannelID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if taken, err := h.identifierTaken(userID, req.Identifier, 0); err != nil {
+		log.Printf("Error checking channel identifier: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create channel",
+		})
+	} else if taken {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": h.duplicateIdentifierMessage(),
+		})
+	}
+
 	// Create channel
-	channel, err := h.db.CreateTelegramChannel(
-		context.Background(),
-		userID,
-		req.BotID,
-		req.Identifier,
-		req.ChannelID,
-		req.ChannelName,
-		req.Description,
-	)
+	channel, err := h.db.CreateTelegramChannel(context.Background(), userID, req)
 	if err != nil {
 		if strings.Contains(err.Error(), "duplicate") {
 			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-				"error": "identifier already exists for this user",
+				"error": h.duplicateIdentifierMessage(),
 			})
 		}
 		log.Printf("Error creating channel: %v", err)
@@ -227,15 +346,185 @@ func (h *TelegramConfigHandler) CreateChannel(c *fiber.Ctx) error {
 		})
 	}
 
+	applyChannelRateLimit(bot.BotToken, channel)
+
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"success": true,
 		"channel": channel,
 	})
 }
 
+// BulkCreateChannels creates many channels in one transaction, checking each
+// like CreateChannel and reporting per-channel results in request order
+// POST /api/user/channels/bulk
+func (h *TelegramConfigHandler) BulkCreateChannels(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.BulkCreateChannelsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.OnError == "" {
+		req.OnError = bulkOnErrorAbort
+	}
+	if req.OnError != bulkOnErrorAbort && req.OnError != bulkOnErrorSkip {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid on_error, must be one of: abort, skip",
+		})
+	}
+	if len(req.Channels) == 0 || len(req.Channels) > maxBulkChannels {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("channels must contain between 1 and %d channels", maxBulkChannels),
+		})
+	}
+
+	results := make([]fiber.Map, len(req.Channels))
+	bots := make(map[int]*models.TelegramBot)
+	seen := make(map[string]int) // Identifier key -> index of its first use
+	defaultIndex := -1
+	var valid []int
+	for i := range req.Channels {
+		channelReq := &req.Channels[i]
+		err := h.checkBulkChannel(userID, channelReq, bots)
+		if err == nil {
+			key := strings.ToLower(channelReq.Identifier)
+			if h.db.IdentifiersPerBot() {
+				key = fmt.Sprintf("%d|%s", channelReq.BotID, key)
+			}
+			if first, ok := seen[key]; ok {
+				err = fmt.Errorf("identifier %q is also used by channel %d in this request", channelReq.Identifier, first)
+			} else {
+				seen[key] = i
+			}
+		}
+		if err == nil && channelReq.IsDefault {
+			if defaultIndex >= 0 {
+				err = fmt.Errorf("channel %d in this request is already the default", defaultIndex)
+			} else {
+				defaultIndex = i
+			}
+		}
+
+		if err != nil {
+			results[i] = fiber.Map{"index": i, "success": false, "error": err.Error()}
+			continue
+		}
+		valid = append(valid, i)
+	}
+
+	if len(valid) < len(req.Channels) && (req.OnError == bulkOnErrorAbort || len(valid) == 0) {
+		failed := make([]fiber.Map, 0, len(req.Channels)-len(valid))
+		for _, result := range results {
+			if result != nil {
+				failed = append(failed, result)
+			}
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "some channels are invalid, none were created",
+			"results": failed,
+		})
+	}
+
+	reqs := make([]models.CreateChannelRequest, len(valid))
+	for j, i := range valid {
+		reqs[j] = req.Channels[i]
+	}
+	channels, errs, err := h.db.CreateTelegramChannels(context.Background(), userID, reqs, req.OnError == bulkOnErrorSkip)
+	if err != nil {
+		log.Printf("Error creating channels in bulk: %v", err)
+		// In abort mode errs names the channel that failed
+		for j, channelErr := range errs {
+			if channelErr != nil && strings.Contains(channelErr.Error(), "duplicate") {
+				return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+					"error": fmt.Sprintf("channel %d: %s, none were created", valid[j], h.duplicateIdentifierMessage()),
+				})
+			}
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create channels",
+		})
+	}
+
+	created := 0
+	for j, i := range valid {
+		if errs[j] != nil {
+			log.Printf("Error creating channel %d in bulk: %v", i, errs[j])
+			message := "failed to create channel"
+			if strings.Contains(errs[j].Error(), "duplicate") {
+				message = h.duplicateIdentifierMessage()
+			}
+			results[i] = fiber.Map{"index": i, "success": false, "error": message}
+			continue
+		}
+
+		applyChannelRateLimit(bots[channels[j].BotID].BotToken, channels[j])
+		results[i] = fiber.Map{"index": i, "success": true, "channel": channels[j]}
+		created++
+	}
+
+	status := fiber.StatusCreated
+	if created < len(req.Channels) {
+		status = fiber.StatusMultiStatus
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": created == len(req.Channels),
+		"created": created,
+		"failed":  len(req.Channels) - created,
+		"results": results,
+	})
+}
+
+// checkBulkChannel validates one channel of a bulk request as CreateChannel
+// would, caching the user's bots by ID in bots
+func (h *TelegramConfigHandler) checkBulkChannel(userID int, req *models.CreateChannelRequest, bots map[int]*models.TelegramBot) error {
+	if err := validateChannelRequest(req); err != nil {
+		return err
+	}
+
+	bot, ok := bots[req.BotID]
+	if !ok {
+		var err error
+		if bot, err = h.db.GetTelegramBot(context.Background(), req.BotID, userID); err != nil {
+			bot = nil
+		}
+		bots[req.BotID] = bot
+	}
+	if bot == nil {
+		return fmt.Errorf("bot not found or not owned by user")
+	}
+
+	if !req.SkipValidation {
+		if err := checkChannelAccess(bot.BotToken, req.ChannelID); err != nil {
+			return err
+		}
+	}
+
+	taken, err := h.identifierTaken(userID, req.Identifier, 0)
+	if err != nil {
+		log.Printf("Error checking channel identifier: %v", err)
+		return fmt.Errorf("failed to check identifier")
+	}
+	if taken {
+		return errors.New(h.duplicateIdentifierMessage())
+	}
+
+	return nil
+}
+
 func (h *TelegramConfigHandler) GetChannels(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(int)
 
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	channels, err := h.db.GetUserTelegramChannels(context.Background(), userID)
 	if err != nil {
 		log.Printf("Error getting channels: %v", err)
@@ -247,6 +536,9 @@ func (h *TelegramConfigHandler) GetChannels(c *fiber.Ctx) error {
 	if channels == nil {
 		channels = []models.TelegramChannel{}
 	}
+	for i := range channels {
+		localizeTimes(loc, &channels[i].CreatedAt, &channels[i].UpdatedAt)
+	}
 
 	return c.JSON(fiber.Map{
 		"success":  true,
@@ -263,12 +555,20 @@ func (h *TelegramConfigHandler) GetChannel(c *fiber.Ctx) error {
 		})
 	}
 
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	channel, err := h.db.GetTelegramChannel(context.Background(), channelID, userID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "channel not found",
 		})
 	}
+	localizeTimes(loc, &channel.CreatedAt, &channel.UpdatedAt)
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -292,6 +592,61 @@ func (h *TelegramConfigHandler) UpdateChannel(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.ParseMode != "" && !telegram.IsValidFormat(req.ParseMode) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid parse_mode, must be one of: html, markdown, markdownv2, plain",
+		})
+	}
+
+	if req.MessageTemplate != nil {
+		if err := telegram.ValidateTemplate(*req.MessageTemplate); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.Identifier != "" {
+		if err := validateIdentifier(req.Identifier); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.ChannelID != "" {
+		if err := telegram.ValidateChatID(req.ChannelID); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	if req.CoalesceWindowSeconds != nil && (*req.CoalesceWindowSeconds < 0 || *req.CoalesceWindowSeconds > maxCoalesceWindowSeconds) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("coalesce_window_seconds must be between 0 and %d", maxCoalesceWindowSeconds),
+		})
+	}
+
+	// -1 clears the override so the channel follows the user's setting again
+	if req.DedupWindowSeconds != nil && (*req.DedupWindowSeconds < -1 || *req.DedupWindowSeconds > maxDedupWindowSeconds) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("dedup_window_seconds must be between 0 and %d, or -1 to use the user setting", maxDedupWindowSeconds),
+		})
+	}
+
+	if req.ThreadID != nil && *req.ThreadID < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "thread_id must be a forum topic ID, or 0 for the main chat",
+		})
+	}
+
+	if req.RateLimitPerMinute != nil && (*req.RateLimitPerMinute < 0 || *req.RateLimitPerMinute > maxChannelRatePerMinute) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("rate_limit_per_minute must be between 1 and %d, or 0 for the default", maxChannelRatePerMinute),
+		})
+	}
+
 	// If bot_id is being updated, verify it belongs to user
 	if req.BotID != 0 {
 		_, err := h.db.GetTelegramBot(context.Background(), req.BotID, userID)
@@ -302,14 +657,71 @@ func (h *TelegramConfigHandler) UpdateChannel(c *fiber.Ctx) error {
 		}
 	}
 
+	// Moving the channel to another chat or bot is checked like a new channel
+	if (req.BotID != 0 || req.ChannelID != "") && !req.SkipValidation {
+		existing, err := h.db.GetTelegramChannel(context.Background(), channelID, userID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "channel not found",
+			})
+		}
+
+		botID, chatID := existing.BotID, existing.ChannelID
+		if req.BotID != 0 {
+			botID = req.BotID
+		}
+		if req.ChannelID != "" {
+			chatID = req.ChannelID
+		}
+
+		if botID != existing.BotID || chatID != existing.ChannelID {
+			bot, err := h.db.GetTelegramBot(context.Background(), botID, userID)
+			if err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "bot not found or not owned by user",
+				})
+			}
+			if err := checkChannelAccess(bot.BotToken, chatID); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+		}
+	}
+
+	if req.Identifier != "" {
+		if taken, err := h.identifierTaken(userID, req.Identifier, channelID); err != nil {
+			log.Printf("Error checking channel identifier: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "failed to update channel",
+			})
+		} else if taken {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": h.duplicateIdentifierMessage(),
+			})
+		}
+	}
+
 	channel, err := h.db.UpdateTelegramChannel(context.Background(), channelID, userID, req)
 	if err != nil {
+		if strings.Contains(err.Error(), "duplicate") {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": h.duplicateIdentifierMessage(),
+			})
+		}
 		log.Printf("Error updating channel: %v", err)
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to update channel",
 		})
 	}
 
+	// The override follows the channel to its new chat or bot
+	if req.RateLimitPerMinute != nil || req.BotID != 0 || req.ChannelID != "" {
+		if bot, err := h.db.GetTelegramBot(context.Background(), channel.BotID, userID); err == nil {
+			applyChannelRateLimit(bot.BotToken, channel)
+		}
+	}
+
 	return c.JSON(fiber.Map{
 		"success": true,
 		"channel": channel,
@@ -339,10 +751,166 @@ func (h *TelegramConfigHandler) DeleteChannel(c *fiber.Ctx) error {
 	})
 }
 
+// TestChannel sends a fixed test message straight to the channel, bypassing
+// the queue so configuration problems are reported immediately
+// POST /api/user/channels/:id/test
+func (h *TelegramConfigHandler) TestChannel(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	channelID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel ID",
+		})
+	}
+
+	channel, err := h.db.GetTelegramChannel(context.Background(), channelID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "channel not found",
+		})
+	}
+
+	bot, err := h.db.GetTelegramBot(context.Background(), channel.BotID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "bot configuration not found",
+		})
+	}
+
+	botInstance, err := telegram.NewBotWithToken(bot.BotToken, channel.ChannelID)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": telegram.DescribeError(err),
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), queue.SendTimeoutFromEnv())
+	defer cancel()
+	response, err := botInstance.SendMessage(ctx, "✅ Telehook test message")
+	if err != nil {
+		log.Printf("Test message to channel %d failed: %v", channel.ID, err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": telegram.DescribeError(err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":           true,
+		"message":           "test message sent",
+		"telegram_response": json.RawMessage(response),
+	})
+}
+
+// PreviewChannel renders a sample payload exactly as the channel would send it
+// POST /api/user/channels/:id/preview
+func (h *TelegramConfigHandler) PreviewChannel(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	username, _ := c.Locals("username").(string)
+	channelID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel ID",
+		})
+	}
+
+	var req models.ChannelPreviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	channel, err := h.db.GetTelegramChannel(context.Background(), channelID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "channel not found",
+		})
+	}
+
+	bot, err := h.db.GetTelegramBot(context.Background(), channel.BotID, userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "bot not found",
+		})
+	}
+
+	// Unsaved settings take precedence over the stored channel
+	if req.ParseMode != nil {
+		channel.ParseMode = *req.ParseMode
+	}
+	if req.MessageTemplate != nil {
+		channel.MessageTemplate = *req.MessageTemplate
+	}
+
+	format := channel.ParseMode
+	if req.Payload.Format != "" {
+		format = req.Payload.Format
+	}
+	if !telegram.IsValidFormat(format) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid parse_mode, must be one of: html, markdown, markdownv2, plain",
+		})
+	}
+
+	// Resolve the template the same way the processor does
+	template, templateSource := channel.MessageTemplate, "channel"
+	if template == "" {
+		template, templateSource = bot.MessageTemplate, "bot"
+	}
+	if template == "" {
+		templateSource = "default"
+	}
+
+	identifier, content := parseMessageWithIdentifier(req.Payload.Message)
+	priority := 3
+	if req.Payload.Priority > 0 {
+		priority = req.Payload.Priority
+	}
+
+	payload := map[string]interface{}{
+		"message":  content,
+		"priority": priority,
+	}
+	if identifier != "" {
+		payload["identifier"] = identifier
+	}
+	if req.Payload.Data != nil {
+		payload["data"] = req.Payload.Data
+	}
+
+	warnings := []string{}
+	text, err := telegram.BuildMessage(username, payload, telegram.MessageOptions{
+		Format:   format,
+		Template: template,
+	})
+	if err != nil {
+		warnings = append(warnings, err.Error()+"; the default layout would be used")
+	}
+	warnings = append(warnings, telegram.FormattingWarnings(text, format)...)
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"preview": fiber.Map{
+			"text":            text,
+			"format":          format,
+			"template_source": templateSource,
+			"length":          len([]rune(text)),
+			"warnings":        warnings,
+		},
+	})
+}
+
 // GetBotsWithChannels returns all bots with their associated channels
 func (h *TelegramConfigHandler) GetBotsWithChannels(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(int)
 
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	bots, err := h.db.GetUserTelegramBots(context.Background(), userID)
 	if err != nil {
 		log.Printf("Error getting bots: %v", err)
@@ -359,9 +927,13 @@ func (h *TelegramConfigHandler) GetBotsWithChannels(c *fiber.Ctx) error {
 			log.Printf("Error getting channels for bot %d: %v", bot.ID, err)
 			channels = []models.TelegramChannel{}
 		}
+		localizeTimes(loc, &bot.CreatedAt, &bot.UpdatedAt)
+		for i := range channels {
+			localizeTimes(loc, &channels[i].CreatedAt, &channels[i].UpdatedAt)
+		}
 
 		result = append(result, models.BotWithChannels{
-			Bot:      bot,
+			Bot:      bot.Response(),
 			Channels: channels,
 		})
 	}
@@ -371,3 +943,98 @@ func (h *TelegramConfigHandler) GetBotsWithChannels(c *fiber.Ctx) error {
 		"data":    result,
 	})
 }
+
+// validateChannelRequest checks a new channel's fields, clearing a rate limit
+// of 0 so the channel uses the default
+func validateChannelRequest(req *models.CreateChannelRequest) error {
+	if req.BotID == 0 || req.Identifier == "" || req.ChannelID == "" {
+		return fmt.Errorf("bot_id, identifier, and channel_id are required")
+	}
+
+	if err := validateIdentifier(req.Identifier); err != nil {
+		return err
+	}
+
+	if err := telegram.ValidateChatID(req.ChannelID); err != nil {
+		return err
+	}
+
+	if req.ParseMode != "" && !telegram.IsValidFormat(req.ParseMode) {
+		return fmt.Errorf("invalid parse_mode, must be one of: html, markdown, markdownv2, plain")
+	}
+
+	if err := telegram.ValidateTemplate(req.MessageTemplate); err != nil {
+		return err
+	}
+
+	if req.CoalesceWindowSeconds < 0 || req.CoalesceWindowSeconds > maxCoalesceWindowSeconds {
+		return fmt.Errorf("coalesce_window_seconds must be between 0 and %d", maxCoalesceWindowSeconds)
+	}
+
+	if req.DedupWindowSeconds != nil && (*req.DedupWindowSeconds < 0 || *req.DedupWindowSeconds > maxDedupWindowSeconds) {
+		return fmt.Errorf("dedup_window_seconds must be between 0 and %d", maxDedupWindowSeconds)
+	}
+
+	if req.ThreadID < 0 {
+		return fmt.Errorf("thread_id must be a forum topic ID, or 0 for the main chat")
+	}
+
+	if req.RateLimitPerMinute != nil && (*req.RateLimitPerMinute < 0 || *req.RateLimitPerMinute > maxChannelRatePerMinute) {
+		return fmt.Errorf("rate_limit_per_minute must be between 1 and %d, or 0 for the default", maxChannelRatePerMinute)
+	}
+	if req.RateLimitPerMinute != nil && *req.RateLimitPerMinute == 0 {
+		req.RateLimitPerMinute = nil
+	}
+
+	return nil
+}
+
+// applyBotRateLimit updates the bot's send rate limiter to its saved setting
+func applyBotRateLimit(bot *models.TelegramBot) {
+	perSecond := 0
+	if bot.RateLimitPerSecond != nil {
+		perSecond = *bot.RateLimitPerSecond
+	}
+	telegram.SetBotRateLimit(bot.BotToken, perSecond)
+}
+
+// applyChannelRateLimit updates the channel's send rate limiter to its saved
+// setting
+func applyChannelRateLimit(botToken string, channel *models.TelegramChannel) {
+	perMinute := 0
+	if channel.RateLimitPerMinute != nil {
+		perMinute = *channel.RateLimitPerMinute
+	}
+	telegram.SetChannelRateLimit(botToken, channel.ChannelID, perMinute)
+}
+
+// checkChannelAccess verifies a bot can post to a chat, describing what to
+// fix if it can't
+func checkChannelAccess(botToken, chatID string) error {
+	botInstance, err := telegram.NewBotWithToken(botToken, chatID)
+	if err == nil {
+		err = botInstance.CheckAccess()
+	}
+	if err != nil {
+		return fmt.Errorf("bot cannot post to channel %s: %s (set skip_validation to save it anyway)", chatID, telegram.DescribeError(err))
+	}
+	return nil
+}
+
+// identifierTaken reports whether identifier is already used by another of
+// the user's channels when identifiers are unique per user. With per-bot
+// identifiers the database's unique index is the only check needed.
+func (h *TelegramConfigHandler) identifierTaken(userID int, identifier string, channelID int) (bool, error) {
+	if h.db.IdentifiersPerBot() {
+		return false, nil
+	}
+	return h.db.ChannelIdentifierInUse(context.Background(), userID, identifier, channelID)
+}
+
+// duplicateIdentifierMessage explains the identifier uniqueness rule in force
+func (h *TelegramConfigHandler) duplicateIdentifierMessage() string {
+	if h.db.IdentifiersPerBot() {
+		return "identifier already exists for this bot (identifiers are case-insensitive)"
+	}
+	return "identifier already exists for this user (identifiers are case-insensitive)"
+}
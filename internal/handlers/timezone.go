@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+	_ "time/tzdata" // Resolve ?tz= on hosts without a system zoneinfo database
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// requestLocation reads the optional ?tz= IANA timezone (e.g.
+// "Europe/Berlin") that read endpoints convert timestamps to. Without it
+// timestamps are returned in UTC, so they always serialize as RFC3339 with
+// an explicit offset regardless of how the database returned them.
+func requestLocation(c *fiber.Ctx) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tz %q: must be an IANA timezone such as Europe/Berlin", tz)
+	}
+	return loc, nil
+}
+
+// localizeTimes converts timestamps in place to loc
+func localizeTimes(loc *time.Location, times ...*time.Time) {
+	for _, t := range times {
+		if !t.IsZero() {
+			*t = t.In(loc)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"context"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/transform"
+)
+
+type TransformHandler struct {
+	db *database.DB
+}
+
+func NewTransformHandler(db *database.DB) *TransformHandler {
+	return &TransformHandler{db: db}
+}
+
+// GetPayloadTransform returns the user's webhook payload transform
+// GET /api/user/payload-transform
+func (h *TransformHandler) GetPayloadTransform(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	t, err := h.db.GetPayloadTransform(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "payload transform not configured",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":           true,
+		"payload_transform": t,
+	})
+}
+
+// UpsertPayloadTransform validates and saves the user's payload transform
+// PUT /api/user/payload-transform
+func (h *TransformHandler) UpsertPayloadTransform(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.UpsertPayloadTransformRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if _, err := transform.Compile(req.Expression); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid expression: " + err.Error(),
+		})
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	t, err := h.db.UpsertPayloadTransform(context.Background(), &models.PayloadTransform{
+		UserID:     userID,
+		Expression: req.Expression,
+		IsActive:   isActive,
+	})
+	if err != nil {
+		log.Printf("Error saving payload transform: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save payload transform",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":           true,
+		"payload_transform": t,
+	})
+}
+
+// DeletePayloadTransform removes the user's payload transform
+// DELETE /api/user/payload-transform
+func (h *TransformHandler) DeletePayloadTransform(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	if err := h.db.DeletePayloadTransform(context.Background(), userID); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "payload transform not configured",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "payload transform deleted successfully",
+	})
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/middleware"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/queue"
+)
+
+type UserHandler struct {
+	db          *database.DB
+	rateLimiter *middleware.RateLimiter
+}
+
+func NewUserHandler(db *database.DB, rateLimiter *middleware.RateLimiter) *UserHandler {
+	return &UserHandler{
+		db:          db,
+		rateLimiter: rateLimiter,
+	}
+}
+
+// RateLimitLookup resolves webhook token and user rate limit keys to the
+// user's limit override for middleware.RateLimiter
+func RateLimitLookup(db *database.DB) middleware.LimitLookup {
+	return func(ctx context.Context, key string) (int, int, error) {
+		kind, value, _ := strings.Cut(key, ":")
+		switch kind {
+		case "token":
+			token, err := uuid.Parse(value)
+			if err != nil {
+				return 0, 0, nil
+			}
+			userID, limit, err := db.GetWebhookTokenRateLimit(ctx, token)
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Unknown tokens are rejected by the handler
+				return 0, 0, nil
+			}
+			return userID, limit, err
+		case "user":
+			userID, err := strconv.Atoi(value)
+			if err != nil {
+				return 0, 0, nil
+			}
+			limit, err := db.GetUserRateLimit(ctx, userID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return userID, 0, nil
+			}
+			return userID, limit, err
+		}
+		return 0, 0, nil
+	}
+}
+
+// WhoAmI returns the authenticated user's profile, webhook URL, resource
+// counts and limits in a single call
+// GET /api/user/whoami
+func (h *UserHandler) WhoAmI(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	user, err := h.db.GetUserByID(context.Background(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+
+	counts, err := h.db.GetUserResourceCounts(context.Background(), userID)
+	if err != nil {
+		log.Printf("Error getting resource counts for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve user information",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"user":          user,
+		"role":          "user",
+		"webhook_url":   c.BaseURL() + "/api/webhook/" + user.WebhookToken.String(),
+		"webhook_token": user.WebhookToken,
+		"counts":        counts,
+		"limits": fiber.Map{
+			"webhook_requests_per_window": h.rateLimiter.LimitFor(middleware.RateLimitKey(c)),
+			"webhook_window_seconds":      int(h.rateLimiter.Window().Seconds()),
+		},
+	})
+}
+
+// GetSettings returns the user's account-level webhook defaults
+// GET /api/user/settings
+func (h *UserHandler) GetSettings(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	settings, err := h.db.GetUserSettings(context.Background(), userID)
+	if err != nil {
+		// No settings saved yet; everything uses the queue defaults
+		settings = &models.UserSettings{UserID: userID}
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"settings": settings,
+		"defaults": fiber.Map{
+			"max_retries":          queue.DefaultMaxRetries,
+			"backoff_base_seconds": int(queue.DefaultBackoffBase.Seconds()),
+			"dedup_window_seconds": int(queue.DedupWindowFromEnv().Seconds()),
+		},
+	})
+}
+
+// UpdateSettings replaces the user's account-level webhook defaults. Retries
+// resolve as: the payload's max_retries, then default_max_retries, then the
+// queue default.
+// PUT /api/user/settings
+func (h *UserHandler) UpdateSettings(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	var req models.UpdateUserSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if limit := webhookMaxRetriesLimit(); req.DefaultMaxRetries != nil &&
+		(*req.DefaultMaxRetries < 0 || *req.DefaultMaxRetries > limit) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("default_max_retries must be between 0 and %d", limit),
+		})
+	}
+	if req.BackoffBaseSeconds != nil && (*req.BackoffBaseSeconds < 1 || *req.BackoffBaseSeconds > 3600) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "backoff_base_seconds must be between 1 and 3600",
+		})
+	}
+	if limit := int(queue.MaxBackoff.Seconds()); req.BackoffMaxSeconds != nil &&
+		(*req.BackoffMaxSeconds < 1 || *req.BackoffMaxSeconds > limit) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("backoff_max_seconds must be between 1 and %d", limit),
+		})
+	}
+	if req.BackoffBaseSeconds != nil && req.BackoffMaxSeconds != nil && *req.BackoffMaxSeconds < *req.BackoffBaseSeconds {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "backoff_max_seconds must not be less than backoff_base_seconds",
+		})
+	}
+	if req.DedupWindowSeconds != nil && (*req.DedupWindowSeconds < 0 || *req.DedupWindowSeconds > maxDedupWindowSeconds) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("dedup_window_seconds must be between 0 and %d", maxDedupWindowSeconds),
+		})
+	}
+
+	settings, err := h.db.UpsertUserSettings(context.Background(), &models.UserSettings{
+		UserID:              userID,
+		DefaultMaxRetries:   req.DefaultMaxRetries,
+		BackoffBaseSeconds:  req.BackoffBaseSeconds,
+		BackoffMaxSeconds:   req.BackoffMaxSeconds,
+		DedupWindowSeconds:  req.DedupWindowSeconds,
+		DedupAcrossChannels: req.DedupAcrossChannels,
+	})
+	if err != nil {
+		log.Printf("Error saving settings for user %d: %v", userID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save settings",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":  true,
+		"settings": settings,
+	})
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/vendors"
+)
+
+// HandleDatadogWebhook accepts a Datadog webhook integration payload. Datadog
+// does not sign webhooks; the webhook token in the URL authenticates them.
+// An optional ?identifier= routes the alert like the "----" message suffix.
+// POST /api/webhook/:token/datadog
+func (h *WebhookHandler) HandleDatadogWebhook(c *fiber.Ctx) error {
+	return h.handleVendorWebhook(c, vendors.ParseDatadog)
+}
+
+// HandleOpsgenieWebhook accepts an Opsgenie outgoing webhook payload. Opsgenie
+// does not sign webhooks; the webhook token in the URL authenticates them.
+// An optional ?identifier= routes the alert like the "----" message suffix.
+// POST /api/webhook/:token/opsgenie
+func (h *WebhookHandler) HandleOpsgenieWebhook(c *fiber.Ctx) error {
+	return h.handleVendorWebhook(c, vendors.ParseOpsgenie)
+}
+
+// handleVendorWebhook converts a vendor payload with parse and sends it down
+// the regular webhook path. Payload transforms don't apply: the adapter
+// already produces the message.
+func (h *WebhookHandler) handleVendorWebhook(c *fiber.Ctx, parse func(body []byte) (*models.WebhookPayload, error)) error {
+	user, werr := h.webhookUser(c)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	sig, werr := h.verifyRequest(c, user)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	payload, err := parse(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if identifier := strings.TrimSpace(c.Query("identifier")); identifier != "" {
+		payload.Message += "\n----\n" + identifier
+	}
+
+	return h.dispatch(c, user, payload, c.Body(), sig)
+}
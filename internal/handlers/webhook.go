@@ -2,77 +2,736 @@ package handlers
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/metrics"
 	"github.com/thenaveensharma/telehook/internal/models"
 	"github.com/thenaveensharma/telehook/internal/queue"
 	"github.com/thenaveensharma/telehook/internal/telegram"
+	"github.com/thenaveensharma/telehook/internal/transform"
 )
 
 type WebhookHandler struct {
-	db    *database.DB
-	bot   *telegram.Bot
-	queue *queue.AlertQueue
+	db        *database.DB
+	bot       *telegram.Bot
+	queue     *queue.AlertQueue
+	processor *queue.TelegramProcessor
+	inflight  *inflightRegistry
+	// idempotencyTTL is how long an idempotency key returns its original
+	// alert
+	idempotencyTTL time.Duration
+	// maxRetriesLimit is the highest max_retries a webhook may request
+	maxRetriesLimit int
+	// rejectPaused turns away webhooks from users whose processing is
+	// paused instead of queuing their alerts
+	rejectPaused bool
+	// legacyFallback routes users without channels of their own through the
+	// env-configured bot and channel (LEGACY_CHANNEL_FALLBACK)
+	legacyFallback bool
+	// signatureTolerance is how far a signed request's timestamp may be from
+	// now, limiting how long a captured request can be replayed
+	signatureTolerance time.Duration
+	prom               *metrics.Prometheus
 }
 
-func NewWebhookHandler(db *database.DB, bot *telegram.Bot, alertQueue *queue.AlertQueue) *WebhookHandler {
-	return &WebhookHandler{
-		db:    db,
-		bot:   bot,
-		queue: alertQueue,
+// webhookMaxRetriesLimit reads the highest max_retries a webhook or user
+// default may set from WEBHOOK_MAX_RETRIES_LIMIT (default 10)
+func webhookMaxRetriesLimit() int {
+	maxRetriesLimit := 10
+	if envLimit := os.Getenv("WEBHOOK_MAX_RETRIES_LIMIT"); envLimit != "" {
+		if l, err := strconv.Atoi(envLimit); err == nil && l >= 0 {
+			maxRetriesLimit = l
+		}
 	}
+	return maxRetriesLimit
 }
 
+// idempotencyTTLFromEnv reads how long idempotency keys are remembered from
+// IDEMPOTENCY_KEY_TTL_HOURS (default 24)
+func idempotencyTTLFromEnv() time.Duration {
+	hours := 24
+	if envHours := os.Getenv("IDEMPOTENCY_KEY_TTL_HOURS"); envHours != "" {
+		if h, err := strconv.Atoi(envHours); err == nil && h > 0 {
+			hours = h
+		}
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+func NewWebhookHandler(db *database.DB, bot *telegram.Bot, alertQueue *queue.AlertQueue, processor *queue.TelegramProcessor) *WebhookHandler {
+	h := &WebhookHandler{
+		db:              db,
+		bot:             bot,
+		queue:           alertQueue,
+		processor:       processor,
+		inflight:        newInflightRegistry(10 * time.Minute),
+		idempotencyTTL:  idempotencyTTLFromEnv(),
+		maxRetriesLimit: webhookMaxRetriesLimit(),
+		rejectPaused:    os.Getenv("PAUSED_USER_WEBHOOKS") == "reject",
+		legacyFallback:  os.Getenv("LEGACY_CHANNEL_FALLBACK") == "true",
+
+		signatureTolerance: signatureToleranceFromEnv(),
+	}
+
+	if purged, err := db.DeleteExpiredIdempotencyKeys(context.Background(), time.Now()); err != nil {
+		log.Printf("Failed to purge expired idempotency keys: %v", err)
+	} else if purged > 0 {
+		log.Printf("Purged %d expired idempotency keys", purged)
+	}
+
+	// Release in-flight request entries once their alert is finished, and
+	// record the outcome of alerts submitted in a batch
+	alertQueue.AddCompletionHook(func(alert *queue.Alert, err error) {
+		h.inflight.Complete(alert.ID)
+
+		if alert.BatchID == "" {
+			return
+		}
+		status, errMsg := "completed", ""
+		if err != nil {
+			status, errMsg = "failed", err.Error()
+		}
+		if err := db.UpdateBatchAlertStatus(context.Background(), alert.ID, status, errMsg); err != nil {
+			log.Printf("Failed to record batch status for alert %s: %v", alert.ID, err)
+		}
+	})
+
+	return h
+}
+
+// SetMetrics counts webhook requests per user in a Prometheus registry
+func (h *WebhookHandler) SetMetrics(prom *metrics.Prometheus) {
+	h.prom = prom
+}
+
+// webhookError is a rejected webhook alert along with the HTTP status and
+// JSON body to respond with
+type webhookError struct {
+	status int
+	body   fiber.Map
+}
+
+// idempotencyKeyHeader names a request so the sender's retries of it are not
+// queued again
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength matches the idempotency_keys column
+const maxIdempotencyKeyLength = 255
+
+// maxWebhookBatchSize caps the number of alerts in one batch submission
+const maxWebhookBatchSize = 100
+
+// HandleWebhook queues an alert and responds in the v1 format
+// POST /api/v1/webhook/:token (also served unversioned at /api/webhook/:token)
 func (h *WebhookHandler) HandleWebhook(c *fiber.Ctx) error {
+	user, werr := h.webhookUser(c)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	sig, werr := h.verifyRequest(c, user)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	payload, werr := h.parsePayload(c, user.ID)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	return h.dispatch(c, user, payload, c.Body(), sig)
+}
+
+// dispatchResult is the outcome of submitting a webhook payload, formatted
+// into a response by the API version that received it
+type dispatchResult struct {
+	single      bool // One identifier routed to one channel
+	dryRun      bool
+	debug       bool
+	duplicateOf string        // Alert ID of an identical request still in flight
+	replayed    bool          // duplicateOf was found by idempotency key
+	routes      []routedAlert // Queued routes, or every route on a dry run
+	failed      []fiber.Map
+}
+
+// submit routes a parsed payload and queues its alerts, or only resolves the
+// routing when dry_run is set. body is the raw payload, used to detect
+// identical requests still in flight; sig is how the request was verified.
+func (h *WebhookHandler) submit(c *fiber.Ctx, user *models.User, payload *models.WebhookPayload, body []byte, sig webhookSignature) (*dispatchResult, *webhookError) {
+	routes, failed, werr := h.buildAlerts(user, payload)
+	if werr != nil {
+		return nil, werr
+	}
+	if werr := verifyRoutes(user, routes, sig); werr != nil {
+		return nil, werr
+	}
+
+	// Debug output is requested in the payload or query string; a dry run
+	// always includes it since it is the only result
+	dryRun := payload.DryRun || c.QueryBool("dry_run")
+	result := &dispatchResult{
+		single: len(routes) == 1 && len(failed) == 0,
+		dryRun: dryRun,
+		debug:  dryRun || payload.Debug || c.QueryBool("debug"),
+		failed: failed,
+	}
+
+	if dryRun {
+		result.routes = routes
+		return result, nil
+	}
+
+	// A repeat of an idempotency key is answered with the original alert,
+	// however the payload differs
+	idempotencyKey := c.Get(idempotencyKeyHeader)
+	if idempotencyKey == "" {
+		idempotencyKey = payload.IdempotencyKey
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": fmt.Sprintf("idempotency key must be at most %d characters", maxIdempotencyKeyLength),
+		}}
+	}
+	if idempotencyKey != "" {
+		now := time.Now()
+		existingID, claimed, err := h.db.ClaimIdempotencyKey(context.Background(), user.ID, idempotencyKey, routes[0].alert.ID, now, now.Add(h.idempotencyTTL))
+		if err != nil {
+			// Accept the alert rather than fail the sender on a lookup error
+			log.Printf("Error claiming idempotency key for user %d: %v", user.ID, err)
+			idempotencyKey = ""
+		} else if !claimed {
+			result.duplicateOf = existingID
+			result.replayed = true
+			return result, nil
+		}
+	}
+
+	// The request is tracked by its first alert; a retry while that alert is
+	// in flight is answered without queuing again
+	requestKey := requestHash(user.ID, body)
+	if existingID, registered := h.inflight.Register(requestKey, routes[0].alert.ID); !registered {
+		h.releaseIdempotencyKey(user.ID, idempotencyKey)
+		result.duplicateOf = existingID
+		return result, nil
+	}
+
+	for _, route := range routes {
+		if err := h.queue.Enqueue(route.alert); err != nil {
+			log.Printf("Error enqueuing alert for identifier '%s': %v", route.identifier, err)
+			result.failed = append(result.failed, fiber.Map{
+				"identifier": route.identifier,
+				"error":      "alert queue is full, please try again later",
+			})
+			continue
+		}
+		result.routes = append(result.routes, route)
+	}
+
+	if len(result.routes) == 0 {
+		h.inflight.Complete(routes[0].alert.ID)
+		h.releaseIdempotencyKey(user.ID, idempotencyKey)
+		body := fiber.Map{"error": "alert queue is full, please try again later"}
+		if !result.single {
+			body["failed"] = result.failed
+		}
+		return nil, &webhookError{fiber.StatusServiceUnavailable, body}
+	}
+
+	return result, nil
+}
+
+// releaseIdempotencyKey forgets a key claimed by a request that queued
+// nothing, so the sender's retry isn't answered with an alert that was never
+// sent
+func (h *WebhookHandler) releaseIdempotencyKey(userID int, key string) {
+	if key == "" {
+		return
+	}
+	if err := h.db.ReleaseIdempotencyKey(context.Background(), userID, key); err != nil {
+		log.Printf("Error releasing idempotency key for user %d: %v", userID, err)
+	}
+}
+
+// dispatch submits a parsed payload and responds in the v1 format
+func (h *WebhookHandler) dispatch(c *fiber.Ctx, user *models.User, payload *models.WebhookPayload, body []byte, sig webhookSignature) error {
+	result, werr := h.submit(c, user, payload, body, sig)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	if result.duplicateOf != "" {
+		message := "identical request already in progress"
+		if result.replayed {
+			message = "request with this idempotency key already accepted"
+		}
+		return c.JSON(fiber.Map{
+			"success":   true,
+			"message":   message,
+			"alert_id":  result.duplicateOf,
+			"duplicate": true,
+		})
+	}
+
+	// A single identifier keeps the original single-alert response shape
+	if result.single {
+		route := result.routes[0]
+
+		if result.dryRun {
+			return c.JSON(fiber.Map{
+				"success": true,
+				"message": "dry run, alert not sent",
+				"dry_run": true,
+				"debug":   h.debugInfo(route.alert, route.bot, route.channel),
+			})
+		}
+
+		response := fiber.Map{
+			"success":  true,
+			"message":  "alert queued successfully",
+			"alert_id": route.alert.ID,
+			"channel":  route.channel.ChannelName,
+		}
+		if route.identifier != "" {
+			response["identifier"] = route.identifier
+		}
+		if !route.scheduledAt.IsZero() {
+			response["scheduled_at"] = route.scheduledAt
+		}
+		if result.debug {
+			response["debug"] = h.debugInfo(route.alert, route.bot, route.channel)
+		}
+
+		return c.JSON(response)
+	}
+
+	// Fan out to several channels, reporting each identifier's outcome
+	if result.dryRun {
+		debugRoutes := make([]fiber.Map, 0, len(result.routes))
+		for _, route := range result.routes {
+			debugRoutes = append(debugRoutes, h.debugInfo(route.alert, route.bot, route.channel))
+		}
+		return c.JSON(fiber.Map{
+			"success": true,
+			"message": "dry run, alerts not sent",
+			"dry_run": true,
+			"debug":   debugRoutes,
+			"failed":  result.failed,
+		})
+	}
+
+	queued := make([]fiber.Map, 0, len(result.routes))
+	for _, route := range result.routes {
+		queued = append(queued, h.routeEntry(route, result.debug))
+	}
+
+	status := fiber.StatusOK
+	if len(result.failed) > 0 {
+		status = fiber.StatusMultiStatus
+	}
+
+	return c.Status(status).JSON(fiber.Map{
+		"success": len(result.failed) == 0,
+		"message": fmt.Sprintf("alert queued to %d of %d channels", len(queued), len(queued)+len(result.failed)),
+		"queued":  queued,
+		"failed":  result.failed,
+	})
+}
+
+// routeEntry describes one queued route in a response
+func (h *WebhookHandler) routeEntry(route routedAlert, debug bool) fiber.Map {
+	entry := fiber.Map{
+		"identifier": route.identifier,
+		"alert_id":   route.alert.ID,
+		"channel":    route.channel.ChannelName,
+	}
+	if !route.scheduledAt.IsZero() {
+		entry["scheduled_at"] = route.scheduledAt
+	}
+	if debug {
+		entry["debug"] = h.debugInfo(route.alert, route.bot, route.channel)
+	}
+	return entry
+}
+
+// HandleWebhookBatch queues several alerts under one batch id whose combined
+// status can be polled with GetBatchStatus
+// POST /api/webhook/:token/batch
+func (h *WebhookHandler) HandleWebhookBatch(c *fiber.Ctx) error {
+	user, werr := h.webhookUser(c)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	sig, werr := h.verifyRequest(c, user)
+	if werr != nil {
+		return c.Status(werr.status).JSON(werr.body)
+	}
+
+	var req models.WebhookBatchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid JSON payload",
+		})
+	}
+
+	if len(req.Alerts) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "alerts must contain at least one alert",
+		})
+	}
+	if len(req.Alerts) > maxWebhookBatchSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("a batch may contain at most %d alerts", maxWebhookBatchSize),
+		})
+	}
+
+	// Invalid alerts are reported back rather than failing the whole batch
+	batchID := uuid.New().String()
+	alerts := make([]*queue.Alert, 0, len(req.Alerts))
+	alertIDs := make([]string, 0, len(req.Alerts))
+	rejected := make([]fiber.Map, 0)
+	for i := range req.Alerts {
+		routes, failed, werr := h.buildAlerts(user, &req.Alerts[i])
+		if werr != nil {
+			rejected = append(rejected, fiber.Map{
+				"index": i,
+				"error": werr.body["error"],
+			})
+			continue
+		}
+		// A request not signed for every route is untrusted as a whole, so
+		// it isn't partially queued
+		if werr := verifyRoutes(user, routes, sig); werr != nil {
+			return c.Status(werr.status).JSON(werr.body)
+		}
+		for _, failure := range failed {
+			failure["index"] = i
+			rejected = append(rejected, failure)
+		}
+		for _, route := range routes {
+			route.alert.BatchID = batchID
+			alerts = append(alerts, route.alert)
+			alertIDs = append(alertIDs, route.alert.ID)
+		}
+	}
+
+	if len(alerts) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":    "no valid alerts in batch",
+			"rejected": rejected,
+		})
+	}
+
+	// Record the batch before queuing so no completion can precede it
+	if err := h.db.CreateAlertBatch(context.Background(), batchID, user.ID, alertIDs); err != nil {
+		log.Printf("Error creating alert batch: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create batch",
+		})
+	}
+
+	if err := h.queue.EnqueueBatch(alerts); err != nil {
+		log.Printf("Error enqueuing batch %s: %v", batchID, err)
+		for _, alertID := range alertIDs {
+			_ = h.db.UpdateBatchAlertStatus(context.Background(), alertID, "failed", err.Error())
+		}
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "alert queue is full, please try again later",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":   true,
+		"message":   "batch queued successfully",
+		"batch_id":  batchID,
+		"alert_ids": alertIDs,
+		"rejected":  rejected,
+	})
+}
+
+// GetBatchStatus returns the aggregated delivery status of a batch submission
+// GET /api/user/batches/:id
+func (h *WebhookHandler) GetBatchStatus(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	batchID, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid batch ID",
+		})
+	}
+
+	batch, err := h.db.GetAlertBatch(context.Background(), batchID.String(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "batch not found",
+		})
+	}
+
+	return c.JSON(batch)
+}
+
+// webhookUser resolves the user owning the webhook token in the URL
+func (h *WebhookHandler) webhookUser(c *fiber.Ctx) (*models.User, *webhookError) {
 	// Get webhook token from URL parameter
 	tokenStr := c.Params("token")
 	if tokenStr == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
 			"error": "webhook token is required",
-		})
+		}}
 	}
 
 	// Parse token as UUID
 	token, err := uuid.Parse(tokenStr)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
 			"error": "invalid webhook token format",
-		})
+		}}
 	}
 
 	// Get user by webhook token
 	user, err := h.db.GetUserByWebhookToken(context.Background(), token)
 	if err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		return nil, &webhookError{fiber.StatusUnauthorized, fiber.Map{
 			"error": "invalid webhook token",
-		})
+		}}
 	}
 
-	// Parse JSON payload
+	if user.ProcessingPaused && h.rejectPaused {
+		return nil, &webhookError{fiber.StatusServiceUnavailable, fiber.Map{
+			"error": "alert processing is paused for this account",
+		}}
+	}
+
+	h.prom.ObserveWebhookRequest(user.ID)
+	return user, nil
+}
+
+// parsePayload decodes the request body, first reshaping it with the user's
+// payload transform when one is active
+func (h *WebhookHandler) parsePayload(c *fiber.Ctx, userID int) (*models.WebhookPayload, *webhookError) {
+	return h.decodePayload(c.Body(), userID, c.BodyParser)
+}
+
+// decodePayload is parsePayload for a raw body; bodyParser decodes it when no
+// transform is active
+func (h *WebhookHandler) decodePayload(body []byte, userID int, bodyParser func(out interface{}) error) (*models.WebhookPayload, *webhookError) {
 	var payload models.WebhookPayload
-	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+
+	t, err := h.db.GetPayloadTransform(context.Background(), userID)
+	if err != nil || !t.IsActive {
+		if err := bodyParser(&payload); err != nil {
+			return nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+				"error": "invalid JSON payload",
+			}}
+		}
+		return &payload, nil
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
 			"error": "invalid JSON payload",
-		})
+		}}
+	}
+
+	// Expressions are validated when saved, but compiling here keeps a
+	// transform saved by an older version from slipping through unchecked
+	expr, err := transform.Compile(t.Expression)
+	if err != nil {
+		return nil, &webhookError{fiber.StatusInternalServerError, fiber.Map{
+			"error": "payload transform is invalid: " + err.Error(),
+		}}
+	}
+
+	result, err := expr.Apply(raw)
+	if err != nil {
+		return nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": "payload transform failed: " + err.Error(),
+		}}
+	}
+
+	// Round-trip through JSON so the result gets the same decoding rules as
+	// an untransformed payload
+	encoded, err := json.Marshal(result)
+	if err == nil {
+		err = json.Unmarshal(encoded, &payload)
+	}
+	if err != nil {
+		return nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": "payload transform produced an invalid payload: " + err.Error(),
+		}}
+	}
+
+	return &payload, nil
+}
+
+// routedAlert is an alert together with the channel and bot it resolved to
+type routedAlert struct {
+	alert      *queue.Alert
+	channel    *models.TelegramChannel
+	bot        *models.TelegramBot
+	identifier string
+	threadID   int // Forum topic from an "identifier:topic" token; 0 if none
+	// Delivery time asked for with send_at or delay_seconds; zero to send
+	// right away
+	scheduledAt time.Time
+}
+
+// maxFanOutIdentifiers caps the channels one webhook message can target
+const maxFanOutIdentifiers = 10
+
+// Limits on a payload's inline keyboard buttons
+const (
+	maxWebhookButtons   = 10
+	maxButtonTextLength = 64
+)
+
+// validateButtons checks that every button has text and an absolute http,
+// https or tg URL
+func validateButtons(buttons []models.WebhookButton) error {
+	if len(buttons) > maxWebhookButtons {
+		return fmt.Errorf("a message can have at most %d buttons", maxWebhookButtons)
+	}
+
+	for i, button := range buttons {
+		if strings.TrimSpace(button.Text) == "" {
+			return fmt.Errorf("buttons[%d].text is required", i)
+		}
+		if utf8.RuneCountInString(button.Text) > maxButtonTextLength {
+			return fmt.Errorf("buttons[%d].text must be at most %d characters", i, maxButtonTextLength)
+		}
+
+		if !absoluteURL(button.URL, "http", "https", "tg") {
+			return fmt.Errorf("buttons[%d].url must be an absolute http, https or tg URL", i)
+		}
+	}
+
+	return nil
+}
+
+// validateMedia checks a payload's photo or document URL, which Telegram
+// downloads itself and so must be public http or https
+func validateMedia(payload *models.WebhookPayload) error {
+	if payload.PhotoURL != "" && payload.DocumentURL != "" {
+		return fmt.Errorf("only one of photo_url and document_url may be set")
+	}
+	if payload.PhotoURL != "" && !absoluteURL(payload.PhotoURL, "http", "https") {
+		return fmt.Errorf("photo_url must be an absolute http or https URL")
 	}
+	if payload.DocumentURL != "" && !absoluteURL(payload.DocumentURL, "http", "https") {
+		return fmt.Errorf("document_url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// absoluteURL reports whether raw parses as a URL with a host and one of
+// the given schemes
+func absoluteURL(raw string, schemes ...string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return true
+		}
+	}
+	return false
+}
 
+// buildAlerts validates a webhook payload and resolves it into one queue
+// alert per target channel. A message may name several comma-separated
+// channel identifiers; those that cannot be resolved are returned as failures
+// rather than rejecting the request, unless none resolve at all.
+func (h *WebhookHandler) buildAlerts(user *models.User, payload *models.WebhookPayload) ([]routedAlert, []fiber.Map, *webhookError) {
 	// Ensure message is not empty
 	if payload.Message == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
 			"error": "message field is required",
-		})
+		}}
+	}
+
+	// Validate optional per-message format override
+	if payload.Format != "" && !telegram.IsValidFormat(payload.Format) {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": "invalid format, must be one of: html, markdown, markdownv2, plain",
+		}}
 	}
 
-	// Parse message to extract optional channel identifier
+	// parse_mode takes Telegram's names for the same formats; format wins if
+	// both are given
+	if payload.ParseMode != "" {
+		format, ok := telegram.FormatForParseMode(payload.ParseMode)
+		if !ok {
+			return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+				"error": "invalid parse_mode, must be one of: HTML, Markdown, MarkdownV2, none",
+			}}
+		}
+		if payload.Format == "" {
+			payload.Format = format
+		}
+	}
+
+	// Validate optional per-message retry budget
+	if payload.MaxRetries != nil {
+		if *payload.MaxRetries < 0 || *payload.MaxRetries > h.maxRetriesLimit {
+			return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+				"error": fmt.Sprintf("max_retries must be between 0 and %d", h.maxRetriesLimit),
+			}}
+		}
+	}
+
+	if err := validateButtons(payload.Buttons); err != nil {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": err.Error(),
+		}}
+	}
+	if err := validateMedia(payload); err != nil {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": err.Error(),
+		}}
+	}
+	if payload.ThreadID != nil && *payload.ThreadID < 0 {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": "thread_id must be a forum topic ID, or 0 for the main chat",
+		}}
+	}
+	scheduledAt, err := deliveryTime(payload, time.Now())
+	if err != nil {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": err.Error(),
+		}}
+	}
+
+	// Parse message to extract optional channel identifiers
 	channelIdentifier, messageContent := parseMessageWithIdentifier(payload.Message)
 	log.Printf("[Webhook] User: %d, Original msg len: %d, Cleaned msg len: %d, Identifier: '%s'",
 		user.ID, len(payload.Message), len(messageContent), channelIdentifier)
 
+	// Raw messages are sent verbatim, so they can't be split safely
+	if payload.Raw && utf8.RuneCountInString(messageContent) > telegram.MaxMessageLength {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": fmt.Sprintf("raw messages must be at most %d characters", telegram.MaxMessageLength),
+		}}
+	}
+
 	// Log preview of cleaned message
 	previewLen := 100
 	if len(messageContent) < previewLen {
@@ -80,39 +739,176 @@ func (h *WebhookHandler) HandleWebhook(c *fiber.Ctx) error {
 	}
 	log.Printf("[Webhook] Cleaned message preview: %s", messageContent[:previewLen])
 
-	var channel *models.TelegramChannel
+	// Account-level retry defaults; a missing settings row means none
+	settings, err := h.db.GetUserSettings(context.Background(), user.ID)
+	if err != nil {
+		settings = &models.UserSettings{UserID: user.ID}
+	}
 
-	// If identifier provided, use specific channel; otherwise use default
-	if channelIdentifier != "" {
-		// Look up channel by identifier
-		channel, err = h.db.GetTelegramChannelByIdentifier(context.Background(), user.ID, channelIdentifier)
-		if err != nil {
-			log.Printf("Channel identifier '%s' not found for user %d: %v", channelIdentifier, user.ID, err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":      "channel identifier not found or inactive",
-				"identifier": channelIdentifier,
-				"hint":       "Please configure this channel identifier in your dashboard",
-			})
+	identifiers := splitIdentifiers(channelIdentifier)
+	if len(identifiers) > maxFanOutIdentifiers {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error": fmt.Sprintf("a message may target at most %d channel identifiers", maxFanOutIdentifiers),
+		}}
+	}
+
+	// Users without any configured channels fall back to the server's legacy
+	// bot and channel from the environment, when the operator opted in
+	if legacyChannel, legacyBot, ok := h.legacyRoute(user.ID); ok {
+		if channelIdentifier != "" {
+			log.Printf("[Webhook] User %d has no channels, ignoring identifier '%s' in legacy mode", user.ID, channelIdentifier)
 		}
-	} else {
+		route := routedAlert{channel: legacyChannel, bot: legacyBot}
+		route.scheduledAt = scheduledAt
+		route.alert = newWebhookAlert(user, payload, settings, messageContent, route)
+		return []routedAlert{route}, nil, nil
+	}
+
+	if len(identifiers) == 0 {
 		// Use default channel (first active channel)
-		channel, err = h.db.GetDefaultTelegramChannel(context.Background(), user.ID)
+		channel, err := h.db.GetDefaultTelegramChannel(context.Background(), user.ID)
 		if err != nil {
 			log.Printf("No active channel found for user %d: %v", user.ID, err)
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
 				"error": "no active channel configured",
 				"hint":  "Please configure a Telegram channel in your dashboard",
-			})
+			}}
 		}
+		route, werr := h.routeChannel(channel, "")
+		if werr != nil {
+			return nil, nil, werr
+		}
+		route.scheduledAt = scheduledAt
+		route.alert = newWebhookAlert(user, payload, settings, messageContent, route)
+		return []routedAlert{route}, nil, nil
 	}
 
+	var routes []routedAlert
+	var failures []*webhookError
+	for _, identifier := range identifiers {
+		// Look up channel by identifier, optionally scoped to one bot and
+		// naming a forum topic
+		name, bot := splitBotScope(identifier)
+		name, threadID, ok := splitThread(name)
+		if !ok {
+			failures = append(failures, &webhookError{fiber.StatusBadRequest, fiber.Map{
+				"error":      "invalid forum topic, use identifier:topic with a numeric topic ID",
+				"identifier": identifier,
+			}})
+			continue
+		}
+		channel, err := h.db.GetTelegramChannelByIdentifier(context.Background(), user.ID, name, bot)
+		if errors.Is(err, database.ErrAmbiguousIdentifier) {
+			log.Printf("Channel identifier '%s' is ambiguous for user %d: %v", identifier, user.ID, err)
+			failures = append(failures, &webhookError{fiber.StatusConflict, fiber.Map{
+				"error":      "channel identifier is used on more than one bot",
+				"identifier": identifier,
+				"hint":       err.Error(),
+			}})
+			continue
+		}
+		if err != nil {
+			log.Printf("Channel identifier '%s' not found for user %d: %v", identifier, user.ID, err)
+			failures = append(failures, &webhookError{fiber.StatusBadRequest, fiber.Map{
+				"error":      "channel identifier not found or inactive",
+				"identifier": identifier,
+				"hint":       "Please configure this channel identifier in your dashboard",
+			}})
+			continue
+		}
+
+		route, werr := h.routeChannel(channel, identifier)
+		if werr != nil {
+			werr.body["identifier"] = identifier
+			failures = append(failures, werr)
+			continue
+		}
+		route.threadID = threadID
+		route.scheduledAt = scheduledAt
+		route.alert = newWebhookAlert(user, payload, settings, messageContent, route)
+		routes = append(routes, route)
+	}
+
+	// A single unresolved identifier fails exactly as it always has
+	if len(routes) == 0 && len(failures) == 1 {
+		return nil, nil, failures[0]
+	}
+
+	failed := make([]fiber.Map, 0, len(failures))
+	for _, failure := range failures {
+		failed = append(failed, failure.body)
+	}
+
+	if len(routes) == 0 {
+		return nil, nil, &webhookError{fiber.StatusBadRequest, fiber.Map{
+			"error":  "none of the channel identifiers could be resolved",
+			"failed": failed,
+		}}
+	}
+
+	return routes, failed, nil
+}
+
+// routeChannel pairs a channel with its bot
+func (h *WebhookHandler) routeChannel(channel *models.TelegramChannel, identifier string) (routedAlert, *webhookError) {
 	// Get bot token for this channel
 	bot, err := h.db.GetBotByID(context.Background(), channel.BotID)
 	if err != nil {
 		log.Printf("Bot not found for channel %d: %v", channel.ID, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		return routedAlert{}, &webhookError{fiber.StatusInternalServerError, fiber.Map{
 			"error": "bot configuration not found",
-		})
+		}}
+	}
+
+	return routedAlert{channel: channel, bot: bot, identifier: identifier}, nil
+}
+
+// maxSendDelay is the furthest ahead send_at and delay_seconds may schedule
+// an alert; later times are clamped to it
+const maxSendDelay = 24 * time.Hour
+
+// deliveryTime returns when a payload asks to be delivered, or the zero time
+// to send it right away
+func deliveryTime(payload *models.WebhookPayload, now time.Time) (time.Time, error) {
+	if payload.SendAt != "" && payload.DelaySeconds != nil {
+		return time.Time{}, fmt.Errorf("only one of send_at and delay_seconds may be set")
+	}
+
+	var at time.Time
+	switch {
+	case payload.SendAt != "":
+		sendAt, err := time.Parse(time.RFC3339, payload.SendAt)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("send_at must be an RFC 3339 timestamp, such as 2026-01-02T15:04:05Z")
+		}
+		if sendAt.Before(now) {
+			return time.Time{}, fmt.Errorf("send_at is in the past")
+		}
+		at = sendAt
+	case payload.DelaySeconds != nil:
+		if *payload.DelaySeconds < 0 {
+			return time.Time{}, fmt.Errorf("delay_seconds must not be negative")
+		}
+		if *payload.DelaySeconds == 0 {
+			return time.Time{}, nil
+		}
+		at = now.Add(time.Duration(min(*payload.DelaySeconds, int(maxSendDelay/time.Second))) * time.Second)
+	default:
+		return time.Time{}, nil
+	}
+
+	if latest := now.Add(maxSendDelay); at.After(latest) {
+		at = latest
+	}
+	return at, nil
+}
+
+// newWebhookAlert creates the queue alert for a validated payload on a route
+func newWebhookAlert(user *models.User, payload *models.WebhookPayload, settings *models.UserSettings, messageContent string, route routedAlert) *queue.Alert {
+	// Per-message format overrides the channel's default
+	format := route.channel.ParseMode
+	if payload.Format != "" {
+		format = payload.Format
 	}
 
 	// Get priority from payload (default to normal)
@@ -121,51 +917,168 @@ func (h *WebhookHandler) HandleWebhook(c *fiber.Ctx) error {
 		priority = payload.Priority
 	}
 
+	// Retry precedence: the request, then the user's default, then the queue
+	maxRetries := queue.DefaultMaxRetries
+	if payload.MaxRetries != nil {
+		maxRetries = *payload.MaxRetries
+	} else if settings.DefaultMaxRetries != nil {
+		maxRetries = *settings.DefaultMaxRetries
+	}
+
+	var backoffBase, backoffMax time.Duration
+	if settings.BackoffBaseSeconds != nil {
+		backoffBase = time.Duration(*settings.BackoffBaseSeconds) * time.Second
+	}
+	if settings.BackoffMaxSeconds != nil {
+		backoffMax = time.Duration(*settings.BackoffMaxSeconds) * time.Second
+	}
+
+	// Dedup window precedence: the channel, then the user, then the server
+	var dedupWindow *time.Duration
+	dedupSeconds := route.channel.DedupWindowSeconds
+	if dedupSeconds == nil {
+		dedupSeconds = settings.DedupWindowSeconds
+	}
+	if dedupSeconds != nil {
+		window := time.Duration(*dedupSeconds) * time.Second
+		dedupWindow = &window
+	}
+
+	// Forum topic precedence: the identifier, then the payload, then the
+	// channel; 0 posts to the main chat
+	threadID := route.channel.ThreadID
+	if payload.ThreadID != nil {
+		threadID = *payload.ThreadID
+	}
+	if route.threadID != 0 {
+		threadID = route.threadID
+	}
+
 	// Create payload map for alert
 	payloadMap := map[string]interface{}{
 		"message":  messageContent,
 		"priority": priority,
 	}
-	if channelIdentifier != "" {
-		payloadMap["identifier"] = channelIdentifier
+	if route.identifier != "" {
+		payloadMap["identifier"] = route.identifier
 	}
 	if payload.Data != nil {
 		payloadMap["data"] = payload.Data
 	}
+	if payload.CorrelationID != "" {
+		payloadMap["correlation_id"] = payload.CorrelationID
+	}
+	if payload.Raw {
+		payloadMap["raw"] = true
+	}
+	if payload.PhotoURL != "" {
+		payloadMap["photo_url"] = payload.PhotoURL
+	}
+	if payload.DocumentURL != "" {
+		payloadMap["document_url"] = payload.DocumentURL
+	}
+	if len(payload.Buttons) > 0 {
+		// Stored in the shape it decodes to when a persisted alert is restored
+		buttons := make([]interface{}, 0, len(payload.Buttons))
+		for _, button := range payload.Buttons {
+			buttons = append(buttons, map[string]interface{}{"text": button.Text, "url": button.URL})
+		}
+		payloadMap["buttons"] = buttons
+	}
 
 	// Create alert with channel routing information
-	alert := &queue.Alert{
+	return &queue.Alert{
 		ID:          uuid.New().String(),
 		UserID:      user.ID,
 		Username:    user.Username,
 		Payload:     payloadMap,
 		Priority:    priority,
-		MaxRetries:  3,
+		MaxRetries:  maxRetries,
 		CreatedAt:   time.Now(),
-		BotToken:    bot.BotToken,
-		ChannelID:   channel.ChannelID,
-		DBChannelID: channel.ID,
+		ScheduledAt: route.scheduledAt,
+		BotToken:    route.bot.BotToken,
+		ChannelID:   route.channel.ChannelID,
+		DBChannelID: route.channel.ID,
+		Format:      format,
+		// Template resolution (channel, then bot) happens in the processor
+		ChannelTemplate:     route.channel.MessageTemplate,
+		BotTemplate:         route.bot.MessageTemplate,
+		CorrelationID:       payload.CorrelationID,
+		CoalesceWindow:      time.Duration(route.channel.CoalesceWindowSeconds) * time.Second,
+		BackoffBase:         backoffBase,
+		BackoffMax:          backoffMax,
+		DedupWindow:         dedupWindow,
+		DedupAcrossChannels: settings.DedupAcrossChannels,
+		Silent:              payload.Silent,
+		ThreadID:            threadID,
 	}
+}
 
-	// Enqueue the alert
-	if err := h.queue.Enqueue(alert); err != nil {
-		log.Printf("Error enqueuing alert: %v", err)
-		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-			"error": "alert queue is full, please try again later",
-		})
+// legacyRoute returns a synthetic channel and bot for the env-configured legacy
+// bot when the user has no channels of their own. The returned bot has no
+// token, so the processor delivers through the legacy bot. It is off unless
+// LEGACY_CHANNEL_FALLBACK is set, since it lets any account post to the
+// operator's channel.
+func (h *WebhookHandler) legacyRoute(userID int) (*models.TelegramChannel, *models.TelegramBot, bool) {
+	if !h.legacyFallback || h.bot == nil {
+		return nil, nil, false
 	}
 
-	response := fiber.Map{
-		"success":  true,
-		"message":  "alert queued successfully",
-		"alert_id": alert.ID,
-		"channel":  channel.ChannelName,
+	counts, err := h.db.GetUserResourceCounts(context.Background(), userID)
+	if err != nil || counts.Channels > 0 {
+		return nil, nil, false
 	}
-	if channelIdentifier != "" {
-		response["identifier"] = channelIdentifier
+
+	channel := &models.TelegramChannel{
+		UserID:      userID,
+		Identifier:  "legacy",
+		ChannelID:   h.bot.ChannelID(),
+		ChannelName: "legacy",
+		ParseMode:   telegram.FormatMarkdown,
+		IsActive:    true,
+	}
+	bot := &models.TelegramBot{
+		UserID:      userID,
+		BotUsername: h.bot.Username(),
 	}
 
-	return c.JSON(response)
+	return channel, bot, true
+}
+
+// debugInfo describes how an alert was routed and how the rules treat it
+func (h *WebhookHandler) debugInfo(alert *queue.Alert, bot *models.TelegramBot, channel *models.TelegramChannel) fiber.Map {
+	templateSource := "default"
+	if channel.MessageTemplate != "" {
+		templateSource = "channel"
+	} else if bot.MessageTemplate != "" {
+		templateSource = "bot"
+	}
+
+	allowed, reason, rules := h.processor.EvaluateRules(alert)
+
+	return fiber.Map{
+		"bot": fiber.Map{
+			"id":       bot.ID,
+			"username": bot.BotUsername,
+			"token":    bot.MaskedToken(),
+		},
+		"channel": fiber.Map{
+			"id":         channel.ID,
+			"channel_id": channel.ChannelID,
+			"name":       channel.ChannelName,
+			"identifier": channel.Identifier,
+			"parse_mode": channel.ParseMode,
+		},
+		"priority":        alert.Priority,
+		"format":          alert.Format,
+		"max_retries":     alert.MaxRetries,
+		"template_source": templateSource,
+		"rules": fiber.Map{
+			"checked": rules,
+			"allowed": allowed,
+			"reason":  reason,
+		},
+	}
 }
 
 func (h *WebhookHandler) GetQueueStats(c *fiber.Ctx) error {
@@ -177,6 +1090,13 @@ func (h *WebhookHandler) GetWebhookInfo(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(int)
 	username := c.Locals("username").(string)
 
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Get user to retrieve webhook token
 	user, err := h.db.GetUserByEmail(context.Background(), c.Locals("email").(string))
 	if err != nil {
@@ -191,19 +1111,259 @@ func (h *WebhookHandler) GetWebhookInfo(c *fiber.Ctx) error {
 		log.Printf("Error getting webhook logs: %v", err)
 		logs = make([]models.WebhookLog, 0)
 	}
+	for i := range logs {
+		localizeTimes(loc, &logs[i].SentAt)
+	}
 
 	webhookURL := c.BaseURL() + "/api/webhook/" + user.WebhookToken.String()
+	webhookURLV2 := c.BaseURL() + "/api/v2/webhook/" + user.WebhookToken.String()
+
+	return c.JSON(fiber.Map{
+		"username":           username,
+		"webhook_url":        webhookURL,
+		"webhook_url_v2":     webhookURLV2,
+		"webhook_token":      user.WebhookToken,
+		"signature_required": user.WebhookSecret != "",
+		"signature_headers":  []string{signatureHeader, signatureTimestampHeader},
+		"recent_logs":        logs,
+	})
+}
+
+// webhookLogStatuses are the statuses webhook logs are recorded with
+var webhookLogStatuses = map[string]bool{
+	"success":   true,
+	"failed":    true,
+	"filtered":  true,
+	"paused":    true,
+	"escalated": true,
+}
+
+// GetWebhookLogs pages through the user's webhook logs, newest first,
+// optionally filtered by status
+// GET /api/user/logs?limit=&offset=&status=
+func (h *WebhookHandler) GetWebhookLogs(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	status := c.Query("status")
+	if status != "" && !webhookLogStatuses[status] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "status must be one of success, failed, filtered, paused or escalated",
+		})
+	}
+
+	logs, total, err := h.db.GetUserWebhookLogsPaginated(context.Background(), userID, limit, offset, status)
+	if err != nil {
+		log.Printf("Error getting webhook logs: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to retrieve webhook logs",
+		})
+	}
+	for i := range logs {
+		localizeTimes(loc, &logs[i].SentAt)
+	}
 
 	return c.JSON(fiber.Map{
-		"username":      username,
-		"webhook_url":   webhookURL,
-		"webhook_token": user.WebhookToken,
-		"recent_logs":   logs,
+		"success": true,
+		"logs":    logs,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
 	})
 }
 
+// minLogSearchLength keeps log searches from matching nearly everything
+const minLogSearchLength = 3
+
+// SearchWebhookLogs finds the user's webhook logs whose message contains q,
+// case-insensitively, newest first
+// GET /api/user/logs/search?q=&limit=&offset=
+func (h *WebhookHandler) SearchWebhookLogs(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	loc, err := requestLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	search := strings.TrimSpace(c.Query("q"))
+	if len([]rune(search)) < minLogSearchLength {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": fmt.Sprintf("q must be at least %d characters", minLogSearchLength),
+		})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	offset := c.QueryInt("offset", 0)
+	if offset < 0 {
+		offset = 0
+	}
+
+	logs, total, err := h.db.SearchUserWebhookLogs(context.Background(), userID, search, limit, offset)
+	if err != nil {
+		log.Printf("Error searching webhook logs: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to search webhook logs",
+		})
+	}
+	for i := range logs {
+		localizeTimes(loc, &logs[i].SentAt)
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"logs":    logs,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// requestHash identifies a webhook request by user and exact body
+func requestHash(userID int, body []byte) string {
+	hash := sha256.Sum256(append([]byte(fmt.Sprintf("%d:", userID)), body...))
+	return hex.EncodeToString(hash[:])
+}
+
+// Signed webhooks carry the HMAC-SHA256 of "<timestamp>.<raw body>", as
+// "sha256=<hex>" or bare hex, along with the Unix time in seconds it was
+// signed at. Covering the timestamp keeps a captured request from being
+// replayed once it falls outside the tolerance.
+const (
+	signatureHeader          = "X-Telehook-Signature"
+	signatureTimestampHeader = "X-Telehook-Timestamp"
+)
+
+// signatureToleranceFromEnv reads how far a signature's timestamp may be
+// from now from WEBHOOK_SIGNATURE_TOLERANCE (default 5m)
+func signatureToleranceFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("WEBHOOK_SIGNATURE_TOLERANCE")); err == nil && d > 0 {
+		return d
+	}
+	return 5 * time.Minute
+}
+
+// webhookSignature records how a webhook request was authenticated
+type webhookSignature struct {
+	secret  string // The secret the request was signed with; "" when unsigned
+	trusted bool   // Authenticated another way, such as a fixture fired from the dashboard
+}
+
+// invalidSignature is the error for a request that isn't signed as required
+func invalidSignature() *webhookError {
+	return &webhookError{fiber.StatusUnauthorized, fiber.Map{
+		"error": "missing or invalid " + signatureHeader + " header",
+	}}
+}
+
+// verifyRequest checks a webhook's signature before anything else in the
+// request is looked at, so a request that isn't signed can't tell from the
+// routing errors which identifiers exist. A signature must be recent and
+// made with the user's secret or one of their channels'. Unsigned requests
+// are turned away when the user has a secret; otherwise they can only reach
+// channels without one, which verifyRoutes checks once they are routed.
+func (h *WebhookHandler) verifyRequest(c *fiber.Ctx, user *models.User) (webhookSignature, *webhookError) {
+	signature := c.Get(signatureHeader)
+	if signature == "" {
+		if user.WebhookSecret != "" {
+			return webhookSignature{}, invalidSignature()
+		}
+		return webhookSignature{}, nil
+	}
+
+	secrets, err := h.db.GetChannelWebhookSecrets(context.Background(), user.ID)
+	if err != nil {
+		log.Printf("Error loading channel webhook secrets for user %d: %v", user.ID, err)
+		return webhookSignature{}, &webhookError{fiber.StatusInternalServerError, fiber.Map{
+			"error": "failed to verify signature",
+		}}
+	}
+	if user.WebhookSecret != "" {
+		secrets = append(secrets, user.WebhookSecret)
+	}
+	if len(secrets) == 0 {
+		// Nothing requires a signature, so one sent anyway is ignored
+		return webhookSignature{}, nil
+	}
+
+	timestamp := c.Get(signatureTimestampHeader)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return webhookSignature{}, &webhookError{fiber.StatusUnauthorized, fiber.Map{
+			"error": "missing or invalid " + signatureTimestampHeader + " header",
+		}}
+	}
+	if age := time.Since(time.Unix(signedAt, 0)); age > h.signatureTolerance || age < -h.signatureTolerance {
+		return webhookSignature{}, &webhookError{fiber.StatusUnauthorized, fiber.Map{
+			"error": fmt.Sprintf("signature timestamp must be within %s of the current time", h.signatureTolerance),
+		}}
+	}
+
+	for _, secret := range secrets {
+		if validSignature(secret, signature, timestamp, c.Body()) {
+			return webhookSignature{secret: secret}, nil
+		}
+	}
+	return webhookSignature{}, invalidSignature()
+}
+
+// verifyRoutes checks that the request was signed with the secret of every
+// route: the channel's secret when it has one, otherwise the user's. Routes
+// without either need no signature, so existing webhooks keep working.
+func verifyRoutes(user *models.User, routes []routedAlert, sig webhookSignature) *webhookError {
+	if sig.trusted {
+		return nil
+	}
+	for _, route := range routes {
+		secret := user.WebhookSecret
+		if route.channel.WebhookSecret != "" {
+			secret = route.channel.WebhookSecret
+		}
+		if secret != "" && !hmac.Equal([]byte(secret), []byte(sig.secret)) {
+			return invalidSignature()
+		}
+	}
+	return nil
+}
+
+// validSignature reports whether signature is the HMAC-SHA256 of
+// "<timestamp>.<body>" keyed by secret, compared in constant time
+func validSignature(secret, signature, timestamp string, body []byte) bool {
+	given, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil || len(given) == 0 {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hmac.Equal(given, mac.Sum(nil))
+}
+
 // parseMessageWithIdentifier parses a message in the format:
-// "content\n----\nidentifier"
+// "content\n----\nidentifier", where identifier may be a comma-separated
+// list such as "alerts,vip,ops" to send to several channels. Each may name
+// a forum topic, as in "alerts:42".
 // Returns the identifier and the content (without the separator and identifier)
 // If no identifier found, returns empty string and the original message
 func parseMessageWithIdentifier(message string) (identifier string, content string) {
@@ -222,12 +1382,96 @@ func parseMessageWithIdentifier(message string) (identifier string, content stri
 	// Identifier is everything after the separator (trimmed)
 	identifier = strings.TrimSpace(message[idx+len(separator):])
 
-	// Validate identifier (should be a single word/token, not multiple lines)
-	if strings.Contains(identifier, "\n") || len(identifier) > 50 {
+	// Validate identifier (a single line of one or more comma-separated
+	// tokens, each at most maxIdentifierLength characters plus an optional
+	// :topic and @bot scope)
+	if strings.Contains(identifier, "\n") || len(identifier) > (maxIdentifierLength+1+maxThreadIDLength+1+maxBotScopeLength)*maxFanOutIdentifiers {
 		// If identifier contains newlines or is too long, it's probably not an identifier
 		// Return the full message instead
 		return "", message
 	}
+	for _, part := range strings.Split(identifier, ",") {
+		name, bot := splitBotScope(strings.TrimSpace(part))
+		name, topic, _ := strings.Cut(name, ":")
+		if len(name) > maxIdentifierLength || len(topic) > maxThreadIDLength || len(bot) > maxBotScopeLength {
+			return "", message
+		}
+	}
 
 	return identifier, content
 }
+
+// maxIdentifierLength is the longest channel identifier, both when creating a
+// channel and when parsing one from a message
+const maxIdentifierLength = 50
+
+// maxBotScopeLength is the longest bot scope accepted after an identifier's
+// "@": a Telegram bot username (at most 32 characters) or a bot ID
+const maxBotScopeLength = 32
+
+// maxThreadIDLength is the most digits accepted for a forum topic after an
+// identifier's ":"
+const maxThreadIDLength = 10
+
+// identifierPattern is the allowed identifier format: letters, digits and
+// dashes, starting with a letter or digit
+var identifierPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9-]*$`)
+
+// reservedIdentifiers collide with routing keywords and can't be used as
+// channel identifiers
+var reservedIdentifiers = []string{"default", "all", "none", "legacy"}
+
+// validateIdentifier checks a channel identifier against the naming rules
+func validateIdentifier(identifier string) error {
+	rules := fmt.Sprintf("identifiers must be 1-%d characters of letters, digits and dashes, start with a letter or digit, and not be one of: %s",
+		maxIdentifierLength, strings.Join(reservedIdentifiers, ", "))
+
+	if len(identifier) > maxIdentifierLength || !identifierPattern.MatchString(identifier) {
+		return fmt.Errorf("invalid identifier %q: %s", identifier, rules)
+	}
+	for _, reserved := range reservedIdentifiers {
+		if strings.EqualFold(identifier, reserved) {
+			return fmt.Errorf("identifier %q is reserved: %s", identifier, rules)
+		}
+	}
+	return nil
+}
+
+// splitBotScope splits an "identifier@bot" routing token into the identifier
+// and the bot username or ID it is scoped to, which is empty when unscoped
+func splitBotScope(token string) (identifier, bot string) {
+	identifier, bot, _ = strings.Cut(token, "@")
+	return strings.TrimSpace(identifier), strings.TrimSpace(bot)
+}
+
+// splitThread splits an "identifier:topic" routing token into the identifier
+// and its forum topic ID, which is 0 when none is given. ok is false if the
+// topic isn't a positive number.
+func splitThread(token string) (identifier string, threadID int, ok bool) {
+	identifier, topic, found := strings.Cut(token, ":")
+	if !found {
+		return identifier, 0, true
+	}
+
+	threadID, err := strconv.Atoi(strings.TrimSpace(topic))
+	if err != nil || threadID <= 0 {
+		return identifier, 0, false
+	}
+	return strings.TrimSpace(identifier), threadID, true
+}
+
+// splitIdentifiers splits a comma-separated identifier list, dropping blanks
+// and repeats
+func splitIdentifiers(identifier string) []string {
+	var identifiers []string
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(identifier, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" || seen[part] {
+			continue
+		}
+		seen[part] = true
+		identifiers = append(identifiers, part)
+	}
+	return identifiers
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 128
+)
+
+type webhookSecretRequest struct {
+	Secret string `json:"secret"` // Generated when empty
+}
+
+// parseWebhookSecret reads the secret from a request body, generating one
+// if the body is empty or doesn't supply it
+func parseWebhookSecret(c *fiber.Ctx) (string, error) {
+	var req webhookSecretRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return "", fmt.Errorf("invalid request body")
+		}
+	}
+
+	if req.Secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			log.Printf("Error generating webhook secret: %v", err)
+			return "", fmt.Errorf("failed to generate webhook secret")
+		}
+		return hex.EncodeToString(buf), nil
+	}
+
+	if len(req.Secret) < minWebhookSecretLength || len(req.Secret) > maxWebhookSecretLength {
+		return "", fmt.Errorf("secret must be between %d and %d characters", minWebhookSecretLength, maxWebhookSecretLength)
+	}
+	return req.Secret, nil
+}
+
+// SetWebhookSecret requires webhooks to carry an X-Telehook-Signature made
+// with the returned secret, along with the X-Telehook-Timestamp it covers.
+// The secret is only shown in this response.
+// PUT /api/user/webhook-secret
+func (h *WebhookHandler) SetWebhookSecret(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	secret, err := parseWebhookSecret(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.db.SetUserWebhookSecret(context.Background(), userID, secret); err != nil {
+		log.Printf("Error saving webhook secret: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to save webhook secret",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":          true,
+		"secret":           secret,
+		"header":           signatureHeader,
+		"timestamp_header": signatureTimestampHeader,
+	})
+}
+
+// DeleteWebhookSecret stops requiring signed webhooks, except for channels
+// with their own secret
+// DELETE /api/user/webhook-secret
+func (h *WebhookHandler) DeleteWebhookSecret(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+
+	if err := h.db.SetUserWebhookSecret(context.Background(), userID, ""); err != nil {
+		log.Printf("Error removing webhook secret: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to remove webhook secret",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "webhook secret removed successfully",
+	})
+}
+
+// SetChannelWebhookSecret requires webhooks routed to a channel to be signed
+// with the returned secret, which takes precedence over the user's secret.
+// The secret is only shown in this response.
+// PUT /api/user/channels/:id/webhook-secret
+func (h *TelegramConfigHandler) SetChannelWebhookSecret(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	channelID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel ID",
+		})
+	}
+
+	secret, err := parseWebhookSecret(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := h.db.SetChannelWebhookSecret(context.Background(), channelID, userID, secret); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "channel not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success":          true,
+		"secret":           secret,
+		"header":           signatureHeader,
+		"timestamp_header": signatureTimestampHeader,
+	})
+}
+
+// DeleteChannelWebhookSecret removes a channel's secret; webhooks routed to
+// it then fall back to the user's secret, if any
+// DELETE /api/user/channels/:id/webhook-secret
+func (h *TelegramConfigHandler) DeleteChannelWebhookSecret(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(int)
+	channelID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid channel ID",
+		})
+	}
+
+	if err := h.db.SetChannelWebhookSecret(context.Background(), channelID, userID, ""); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "channel not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"message": "channel webhook secret removed successfully",
+	})
+}
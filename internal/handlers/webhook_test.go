@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+func sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestValidSignatureCoversTimestamp(t *testing.T) {
+	body := []byte(`{"message":"disk full"}`)
+	signature := sign("s3cret-s3cret-s3cret", "1700000000", body)
+
+	if !validSignature("s3cret-s3cret-s3cret", signature, "1700000000", body) {
+		t.Fatal("valid signature rejected")
+	}
+	if validSignature("s3cret-s3cret-s3cret", signature, "1700000600", body) {
+		t.Fatal("signature accepted with a different timestamp")
+	}
+	if validSignature("s3cret-s3cret-s3cret", signature, "1700000000", []byte(`{"message":"all clear"}`)) {
+		t.Fatal("signature accepted for a different body")
+	}
+	if validSignature("another-secret-value", signature, "1700000000", body) {
+		t.Fatal("signature accepted with a different secret")
+	}
+}
+
+func TestVerifyRoutesRequiresEachRouteSecret(t *testing.T) {
+	user := &models.User{WebhookSecret: "user-secret"}
+	route := func(channelSecret string) routedAlert {
+		return routedAlert{channel: &models.TelegramChannel{WebhookSecret: channelSecret}}
+	}
+
+	tests := []struct {
+		name   string
+		user   *models.User
+		routes []routedAlert
+		sig    webhookSignature
+		ok     bool
+	}{
+		{"user secret", user, []routedAlert{route("")}, webhookSignature{secret: "user-secret"}, true},
+		{"channel secret overrides user", user, []routedAlert{route("chan-secret")}, webhookSignature{secret: "user-secret"}, false},
+		{"channel secret", user, []routedAlert{route("chan-secret")}, webhookSignature{secret: "chan-secret"}, true},
+		{"mixed routes", user, []routedAlert{route(""), route("chan-secret")}, webhookSignature{secret: "chan-secret"}, false},
+		{"unsigned to open channel", &models.User{}, []routedAlert{route("")}, webhookSignature{}, true},
+		{"unsigned to secured channel", &models.User{}, []routedAlert{route("chan-secret")}, webhookSignature{}, false},
+		{"trusted", user, []routedAlert{route("chan-secret")}, webhookSignature{trusted: true}, true},
+	}
+
+	for _, tt := range tests {
+		if werr := verifyRoutes(tt.user, tt.routes, tt.sig); (werr == nil) != tt.ok {
+			t.Errorf("%s: got error %v, want ok %v", tt.name, werr, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+// The v2 webhook contract accepts the same payload as v1 but responds with a
+// single shape however many channels an alert fans out to:
+//
+//	{"success": true, "status": "queued", "alerts": [...], "failed": [...]}
+//
+// status is "queued", "partial", "duplicate" or "dry_run". Queued alerts are
+// answered with 202 Accepted since delivery happens asynchronously. Errors
+// carry a machine-readable code next to the message:
+//
+//	{"success": false, "error": {"code": "invalid_request", "message": "..."}}
+
+// Status values of a v2 webhook response
+const (
+	webhookStatusQueued    = "queued"
+	webhookStatusPartial   = "partial"
+	webhookStatusDuplicate = "duplicate"
+	webhookStatusDryRun    = "dry_run"
+)
+
+// HandleWebhookV2 queues an alert and responds in the v2 format
+// POST /api/v2/webhook/:token
+func (h *WebhookHandler) HandleWebhookV2(c *fiber.Ctx) error {
+	user, werr := h.webhookUser(c)
+	if werr != nil {
+		return writeWebhookErrorV2(c, werr)
+	}
+
+	sig, werr := h.verifyRequest(c, user)
+	if werr != nil {
+		return writeWebhookErrorV2(c, werr)
+	}
+
+	payload, werr := h.parsePayload(c, user.ID)
+	if werr != nil {
+		return writeWebhookErrorV2(c, werr)
+	}
+
+	result, werr := h.submit(c, user, payload, c.Body(), sig)
+	if werr != nil {
+		return writeWebhookErrorV2(c, werr)
+	}
+
+	failed := result.failed
+	if failed == nil {
+		failed = []fiber.Map{}
+	}
+
+	alerts := make([]fiber.Map, 0, len(result.routes))
+	for _, route := range result.routes {
+		if result.dryRun {
+			alerts = append(alerts, fiber.Map{
+				"identifier": route.identifier,
+				"channel":    route.channel.ChannelName,
+				"debug":      h.debugInfo(route.alert, route.bot, route.channel),
+			})
+			continue
+		}
+		alerts = append(alerts, h.routeEntry(route, result.debug))
+	}
+
+	response := fiber.Map{
+		"success": len(failed) == 0,
+		"alerts":  alerts,
+		"failed":  failed,
+	}
+
+	status := fiber.StatusAccepted
+	switch {
+	case result.duplicateOf != "":
+		status = fiber.StatusOK
+		response["status"] = webhookStatusDuplicate
+		response["duplicate_of"] = result.duplicateOf
+		response["idempotent_replay"] = result.replayed
+		response["success"] = true
+	case result.dryRun:
+		status = fiber.StatusOK
+		response["status"] = webhookStatusDryRun
+	case len(failed) > 0:
+		status = fiber.StatusMultiStatus
+		response["status"] = webhookStatusPartial
+	default:
+		response["status"] = webhookStatusQueued
+	}
+
+	return c.Status(status).JSON(response)
+}
+
+// writeWebhookErrorV2 responds with a rejected webhook in the v2 format,
+// nesting the message under a code and keeping any extra fields
+func writeWebhookErrorV2(c *fiber.Ctx, werr *webhookError) error {
+	message, _ := werr.body["error"].(string)
+
+	body := fiber.Map{"success": false}
+	for key, value := range werr.body {
+		if key != "error" {
+			body[key] = value
+		}
+	}
+	body["error"] = fiber.Map{
+		"code":    webhookErrorCode(werr.status),
+		"message": message,
+	}
+
+	return c.Status(werr.status).JSON(body)
+}
+
+// webhookErrorCode names the class of a rejected webhook from its status
+func webhookErrorCode(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return "invalid_request"
+	case fiber.StatusUnauthorized:
+		return "invalid_token"
+	case fiber.StatusNotFound:
+		return "not_found"
+	case fiber.StatusConflict:
+		return "conflict"
+	case fiber.StatusRequestEntityTooLarge:
+		return "payload_too_large"
+	case fiber.StatusTooManyRequests:
+		return "rate_limited"
+	case fiber.StatusServiceUnavailable:
+		return "unavailable"
+	default:
+		return "internal_error"
+	}
+}
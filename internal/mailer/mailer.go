@@ -0,0 +1,89 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// Mailer sends plain-text email
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// FromEnv returns an SMTP mailer when SMTP_HOST is set, otherwise one that
+// only logs that an email would have been sent
+func FromEnv() Mailer {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return LogMailer{}
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USERNAME")
+	}
+
+	return &SMTPMailer{
+		Addr:     net.JoinHostPort(host, port),
+		Host:     host,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     from,
+	}
+}
+
+// SMTPMailer sends email through an SMTP server, upgrading to TLS when the
+// server supports STARTTLS
+type SMTPMailer struct {
+	Addr     string // host:port
+	Host     string
+	Username string // Empty skips authentication
+	Password string
+	From     string
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, to, subject, body string) error {
+	// Line breaks in a header would let the value inject further headers
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid email header value")
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(m.Addr, auth, m.From, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// LogMailer logs the recipient and subject of each email instead of sending
+// it. The body is not logged since it may carry secrets such as reset links.
+type LogMailer struct{}
+
+func (LogMailer) Send(ctx context.Context, to, subject, body string) error {
+	log.Printf("SMTP not configured, not sending %q to %s", subject, to)
+	return nil
+}
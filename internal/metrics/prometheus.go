@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+// Prometheus holds the registry scraped at /metrics. A nil *Prometheus is
+// valid and records nothing, so components work without one.
+type Prometheus struct {
+	registry        *prometheus.Registry
+	webhookRequests *prometheus.CounterVec
+	sendLatency     prometheus.Histogram
+}
+
+// NewPrometheus creates a registry with the Go runtime and process collectors
+// plus telehook's webhook and Telegram metrics
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		webhookRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "telehook_webhook_requests_total",
+			Help: "Webhook requests accepted per user.",
+		}, []string{"user_id"}),
+		sendLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "telehook_telegram_send_duration_seconds",
+			Help:    "Time taken to send a message to the Telegram API.",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}),
+	}
+
+	p.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		p.webhookRequests,
+		p.sendLatency,
+	)
+
+	return p
+}
+
+// RegisterQueue exports an alert queue's statistics, read from stats on
+// every scrape
+func (p *Prometheus) RegisterQueue(stats func() models.QueueStats) {
+	if p == nil {
+		return
+	}
+
+	counter := func(name, help string, value func(models.QueueStats) int64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, func() float64 {
+			return float64(value(stats()))
+		})
+	}
+
+	p.registry.MustRegister(
+		counter("telehook_queue_processed_total", "Alerts delivered or filtered.",
+			func(s models.QueueStats) int64 { return s.Processed }),
+		counter("telehook_queue_failed_total", "Failed delivery attempts.",
+			func(s models.QueueStats) int64 { return s.Failed }),
+		counter("telehook_queue_retried_total", "Alerts scheduled for retry.",
+			func(s models.QueueStats) int64 { return s.Retried }),
+		counter("telehook_queue_batched_total", "Alerts delivered as part of a batch.",
+			func(s models.QueueStats) int64 { return s.Batched }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "telehook_queue_current_size",
+			Help: "Alerts waiting in the queue.",
+		}, func() float64 {
+			return float64(stats().CurrentSize)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "telehook_queue_fill_percent",
+			Help: "Fill of the fullest priority queue, in percent of its capacity.",
+		}, func() float64 {
+			return stats().FillPercent
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "telehook_queue_capacity_warning",
+			Help: "Highest queue warning threshold crossed, in percent, or 0.",
+		}, func() float64 {
+			return stats().CapacityWarning
+		}),
+	)
+
+	depths := map[string]func(models.QueueStats) int{
+		"urgent": func(s models.QueueStats) int { return s.UrgentSize },
+		"high":   func(s models.QueueStats) int { return s.HighSize },
+		"normal": func(s models.QueueStats) int { return s.NormalSize },
+		"low":    func(s models.QueueStats) int { return s.LowSize },
+	}
+	for priority, depth := range depths {
+		p.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name:        "telehook_queue_priority_size",
+			Help:        "Alerts waiting in the queue by priority.",
+			ConstLabels: prometheus.Labels{"priority": priority},
+		}, func() float64 {
+			return float64(depth(stats()))
+		}))
+	}
+}
+
+// ObserveWebhookRequest counts a webhook request accepted for a user
+func (p *Prometheus) ObserveWebhookRequest(userID int) {
+	if p == nil {
+		return
+	}
+	p.webhookRequests.WithLabelValues(strconv.Itoa(userID)).Inc()
+}
+
+// ObserveTelegramSend records how long a Telegram send took
+func (p *Prometheus) ObserveTelegramSend(d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.sendLatency.Observe(d.Seconds())
+}
+
+// Handler serves the registry in the Prometheus text format
+func (p *Prometheus) Handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
+}
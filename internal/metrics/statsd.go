@@ -0,0 +1,94 @@
+// Package metrics pushes queue metrics to a StatsD or DogStatsD agent and
+// exposes them for Prometheus to scrape.
+package metrics
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// client is nil until ConfigureStatsD succeeds, making every emit a no-op
+var client *statsdClient
+
+type statsdClient struct {
+	conn    net.Conn
+	prefix  string
+	tags    string // DogStatsD "|#k:v,..." suffix, or ""
+	packets chan string
+}
+
+// ConfigureStatsD starts emitting to STATSD_ADDR (host:port) over UDP when it
+// is set. STATSD_PREFIX (default "telehook.") is prepended to metric names and
+// STATSD_TAGS ("env:prod,region:eu") adds DogStatsD tags to every metric.
+func ConfigureStatsD() error {
+	addr := os.Getenv("STATSD_ADDR")
+	if addr == "" {
+		return nil
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return fmt.Errorf("invalid STATSD_ADDR: %w", err)
+	}
+
+	prefix := "telehook."
+	if envPrefix, ok := os.LookupEnv("STATSD_PREFIX"); ok {
+		prefix = envPrefix
+	}
+
+	tags := ""
+	if envTags := strings.TrimSpace(os.Getenv("STATSD_TAGS")); envTags != "" {
+		tags = "|#" + envTags
+	}
+
+	client = &statsdClient{
+		conn:    conn,
+		prefix:  prefix,
+		tags:    tags,
+		packets: make(chan string, 1000),
+	}
+	go client.send()
+
+	log.Printf("StatsD metrics enabled: %s (prefix %q)", addr, prefix)
+	return nil
+}
+
+// Count adds delta to a counter
+func Count(name string, delta int64) {
+	emit(name, fmt.Sprintf("%d|c", delta))
+}
+
+// Gauge sets a gauge to value
+func Gauge(name string, value int64) {
+	emit(name, fmt.Sprintf("%d|g", value))
+}
+
+// Timing records a duration in milliseconds
+func Timing(name string, d time.Duration) {
+	emit(name, fmt.Sprintf("%d|ms", d.Milliseconds()))
+}
+
+// emit queues a metric without blocking; metrics are dropped when the agent
+// can't keep up rather than slowing alert processing
+func emit(name, value string) {
+	if client == nil {
+		return
+	}
+
+	select {
+	case client.packets <- client.prefix + name + ":" + value + client.tags:
+	default:
+	}
+}
+
+// send writes queued metrics to the agent; UDP write errors (e.g. no agent
+// listening) are ignored
+func (c *statsdClient) send() {
+	for packet := range c.packets {
+		_, _ = c.conn.Write([]byte(packet))
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"os"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminMiddleware guards operator endpoints with the X-Admin-Key header,
+// compared against ADMIN_API_KEY. Admin routes are disabled when it is unset.
+func AdminMiddleware() fiber.Handler {
+	adminKey := os.Getenv("ADMIN_API_KEY")
+
+	return func(c *fiber.Ctx) error {
+		if adminKey == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "admin API is disabled",
+			})
+		}
+
+		key := c.Get("X-Admin-Key")
+		if key == "" || subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid admin key",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,182 @@
+package middleware
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AuthRateLimiter protects the auth endpoints against credential stuffing and
+// signup spam. Requests are limited per IP and per email, and repeated failed
+// logins for the same email trigger an exponentially growing lockout.
+type AuthRateLimiter struct {
+	ipLimiter    *RateLimiter
+	emailLimiter *RateLimiter
+
+	failures         map[string]*loginFailures
+	lockoutThreshold int
+	lockoutBase      time.Duration
+	lockoutMax       time.Duration
+	mu               sync.Mutex
+}
+
+type loginFailures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+func NewAuthRateLimiter() *AuthRateLimiter {
+	ipLimit := 20
+	if envLimit := os.Getenv("AUTH_RATE_LIMIT"); envLimit != "" {
+		if l, err := strconv.Atoi(envLimit); err == nil && l > 0 {
+			ipLimit = l
+		}
+	}
+
+	emailLimit := 5
+	if envLimit := os.Getenv("AUTH_EMAIL_RATE_LIMIT"); envLimit != "" {
+		if l, err := strconv.Atoi(envLimit); err == nil && l > 0 {
+			emailLimit = l
+		}
+	}
+
+	threshold := 5
+	if envThreshold := os.Getenv("AUTH_LOCKOUT_THRESHOLD"); envThreshold != "" {
+		if t, err := strconv.Atoi(envThreshold); err == nil && t > 0 {
+			threshold = t
+		}
+	}
+
+	arl := &AuthRateLimiter{
+		ipLimiter:        newRateLimiter(ipLimit, time.Minute),
+		emailLimiter:     newRateLimiter(emailLimit, time.Minute),
+		failures:         make(map[string]*loginFailures),
+		lockoutThreshold: threshold,
+		lockoutBase:      30 * time.Second,
+		lockoutMax:       time.Hour,
+	}
+
+	go arl.cleanup()
+
+	return arl
+}
+
+func (arl *AuthRateLimiter) cleanup() {
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		arl.mu.Lock()
+		now := time.Now()
+		for email, f := range arl.failures {
+			if now.After(f.lockedUntil) && now.Sub(f.lastFailure) > arl.lockoutMax {
+				delete(arl.failures, email)
+			}
+		}
+		arl.mu.Unlock()
+	}
+}
+
+// RecordLoginFailure counts a failed login and locks the email out once the
+// threshold is reached, doubling the lockout with each further failure
+func (arl *AuthRateLimiter) RecordLoginFailure(email string) {
+	email = normalizeEmail(email)
+
+	arl.mu.Lock()
+	defer arl.mu.Unlock()
+
+	f, exists := arl.failures[email]
+	if !exists {
+		f = &loginFailures{}
+		arl.failures[email] = f
+	}
+
+	now := time.Now()
+	f.count++
+	f.lastFailure = now
+
+	if f.count >= arl.lockoutThreshold {
+		exponent := float64(f.count - arl.lockoutThreshold)
+		lockout := time.Duration(float64(arl.lockoutBase) * math.Pow(2, exponent))
+		if lockout > arl.lockoutMax || lockout <= 0 {
+			lockout = arl.lockoutMax
+		}
+		f.lockedUntil = now.Add(lockout)
+	}
+}
+
+// RecordLoginSuccess clears the failure history for an email
+func (arl *AuthRateLimiter) RecordLoginSuccess(email string) {
+	arl.mu.Lock()
+	defer arl.mu.Unlock()
+	delete(arl.failures, normalizeEmail(email))
+}
+
+// LockoutRemaining returns how much longer an email is locked out after
+// repeated failures, for handlers that check credentials outside Middleware
+func (arl *AuthRateLimiter) LockoutRemaining(email string) time.Duration {
+	return arl.lockedFor(normalizeEmail(email))
+}
+
+// lockedFor returns how much longer an email is locked out
+func (arl *AuthRateLimiter) lockedFor(email string) time.Duration {
+	arl.mu.Lock()
+	defer arl.mu.Unlock()
+
+	f, exists := arl.failures[email]
+	if !exists {
+		return 0
+	}
+	return time.Until(f.lockedUntil)
+}
+
+func (arl *AuthRateLimiter) Middleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ipKey := "ip:" + c.IP()
+		if !arl.ipLimiter.Allow(ipKey) {
+			return TooManyRequests(c, arl.ipLimiter.RetryAfter(ipKey), "too many attempts, please try again later")
+		}
+
+		var body struct {
+			Email string `json:"email"`
+		}
+		if err := c.BodyParser(&body); err == nil && body.Email != "" {
+			email := normalizeEmail(body.Email)
+
+			if wait := arl.lockedFor(email); wait > 0 {
+				return TooManyRequests(c, wait, "too many attempts, please try again later")
+			}
+
+			emailKey := "email:" + email
+			if !arl.emailLimiter.Allow(emailKey) {
+				return TooManyRequests(c, arl.emailLimiter.RetryAfter(emailKey), "too many attempts, please try again later")
+			}
+		}
+
+		return c.Next()
+	}
+}
+
+// TooManyRequests responds with 429 and a Retry-After header in whole seconds
+func TooManyRequests(c *fiber.Ctx, wait time.Duration, message string) error {
+	seconds := int(math.Ceil(wait.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       message,
+		"retry_after": seconds,
+	})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
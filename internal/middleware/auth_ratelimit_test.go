@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTooManyRequestsSetsRetryAfter(t *testing.T) {
+	tests := []struct {
+		wait time.Duration
+		want string
+	}{
+		{1500 * time.Millisecond, "2"},
+		{0, "1"},
+		{10 * time.Minute, "600"},
+	}
+
+	for _, tt := range tests {
+		app := fiber.New()
+		app.Post("/", func(c *fiber.Ctx) error {
+			return TooManyRequests(c, tt.wait, "too many attempts, please try again later")
+		})
+
+		resp, err := app.Test(httptest.NewRequest("POST", "/", nil))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		if resp.StatusCode != fiber.StatusTooManyRequests {
+			t.Fatalf("wait %s: got status %d, want 429", tt.wait, resp.StatusCode)
+		}
+		if got := resp.Header.Get(fiber.HeaderRetryAfter); got != tt.want {
+			t.Errorf("wait %s: Retry-After %q, want %q", tt.wait, got, tt.want)
+		}
+
+		var body struct {
+			RetryAfter int `json:"retry_after"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		if got := strconv.Itoa(body.RetryAfter); got != tt.want {
+			t.Errorf("wait %s: body retry_after %s, want %s", tt.wait, got, tt.want)
+		}
+	}
+}
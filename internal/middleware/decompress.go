@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// DecompressBody decodes gzip and deflate request bodies according to the
+// Content-Encoding header, capping the decompressed size at
+// MAX_WEBHOOK_BODY_BYTES (default 1 MiB) so small compressed payloads cannot
+// expand into huge ones. Downstream handlers see a plain body.
+func DecompressBody() fiber.Handler {
+	maxSize := int64(1 << 20)
+	if envMax := os.Getenv("MAX_WEBHOOK_BODY_BYTES"); envMax != "" {
+		if m, err := strconv.ParseInt(envMax, 10, 64); err == nil && m > 0 {
+			maxSize = m
+		}
+	}
+
+	return func(c *fiber.Ctx) error {
+		encoding := strings.ToLower(strings.TrimSpace(c.Get(fiber.HeaderContentEncoding)))
+		if encoding == "" || encoding == "identity" {
+			return c.Next()
+		}
+
+		body, err := decompress(encoding, c.Request().Body(), maxSize)
+		if err != nil {
+			status := fiber.StatusBadRequest
+			if err == errBodyTooLarge {
+				status = fiber.StatusRequestEntityTooLarge
+			}
+			return c.Status(status).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		// Replace the body and drop the header so Fiber doesn't decode again
+		c.Request().SetBody(body)
+		c.Request().Header.Del(fiber.HeaderContentEncoding)
+
+		return c.Next()
+	}
+}
+
+var errBodyTooLarge = fmt.Errorf("decompressed body exceeds size limit")
+
+// decompress decodes body, reading at most maxSize decompressed bytes
+func decompress(encoding string, body []byte, maxSize int64) ([]byte, error) {
+	var reader io.ReadCloser
+	var err error
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(bytes.NewReader(body))
+	case "deflate":
+		// "deflate" is meant to be zlib-wrapped, but many clients send raw
+		// DEFLATE; accept both
+		reader, err = zlib.NewReader(bytes.NewReader(body))
+		if err != nil {
+			reader, err = flate.NewReader(bytes.NewReader(body)), nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported Content-Encoding %q (use gzip or deflate)", encoding)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %v", encoding, err)
+	}
+	defer reader.Close()
+
+	// Read one byte past the limit to detect oversized bodies
+	decoded, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %v", encoding, err)
+	}
+	if int64(len(decoded)) > maxSize {
+		return nil, errBodyTooLarge
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"os"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// MetricsMiddleware guards the Prometheus endpoint, which exposes per-user
+// queue and send metrics, with a bearer token compared against
+// METRICS_TOKEN. The endpoint is disabled when it is unset.
+func MetricsMiddleware() fiber.Handler {
+	metricsToken := os.Getenv("METRICS_TOKEN")
+
+	return func(c *fiber.Ctx) error {
+		if metricsToken == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "metrics endpoint is disabled",
+			})
+		}
+
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(metricsToken)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid metrics token",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -1,8 +1,11 @@
 package middleware
 
 import (
+	"context"
+	"log"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,11 +17,37 @@ type RateLimiter struct {
 	mu       sync.RWMutex
 	limit    int
 	window   time.Duration
+
+	// Per-user overrides, resolved through lookup and cached per key
+	lookup   LimitLookup
+	limits   map[string]*keyLimit
+	limitTTL time.Duration
+	limitsMu sync.Mutex
 }
 
+// LimitLookup resolves a rate limit key (see RateLimitKey) to the user it
+// belongs to and that user's limit override. It returns a limit of 0 when
+// the user has no override and a user ID of 0 for keys that don't belong to
+// a user, such as IPs and unknown webhook tokens.
+type LimitLookup func(ctx context.Context, key string) (userID, limit int, err error)
+
+type keyLimit struct {
+	userID    int
+	limit     int // 0 uses the default
+	expiresAt time.Time
+}
+
+// Visitor counts a key's requests in the current fixed window and the one
+// before it. The limit is checked against the current count plus the
+// previous count weighted by how much of the previous window still overlaps
+// the last window-long span, so a burst straddling the boundary between two
+// windows can't get through twice the limit, while each key only costs two
+// counters however high its limit is.
 type Visitor struct {
-	lastSeen time.Time
-	count    int
+	lastSeen    time.Time
+	windowStart time.Time
+	current     int
+	previous    int
 }
 
 func NewRateLimiter() *RateLimiter {
@@ -29,10 +58,21 @@ func NewRateLimiter() *RateLimiter {
 		}
 	}
 
+	rl := newRateLimiter(limit, time.Minute)
+	if ttl, err := time.ParseDuration(os.Getenv("RATE_LIMIT_CACHE_TTL")); err == nil && ttl > 0 {
+		rl.limitTTL = ttl
+	}
+	return rl
+}
+
+// newRateLimiter creates a rate limiter allowing limit requests per window
+func newRateLimiter(limit int, window time.Duration) *RateLimiter {
 	rl := &RateLimiter{
 		visitors: make(map[string]*Visitor),
 		limit:    limit,
-		window:   time.Minute,
+		window:   window,
+		limits:   make(map[string]*keyLimit),
+		limitTTL: time.Minute,
 	}
 
 	// Cleanup old visitors every 5 minutes
@@ -41,6 +81,89 @@ func NewRateLimiter() *RateLimiter {
 	return rl
 }
 
+// Limit returns the default number of requests allowed per window
+func (rl *RateLimiter) Limit() int {
+	return rl.limit
+}
+
+// SetLimitLookup enables per-user limits. Lookups are cached per key for
+// RATE_LIMIT_CACHE_TTL (default 1m) so most requests don't reach the
+// database.
+func (rl *RateLimiter) SetLimitLookup(lookup LimitLookup) {
+	rl.limitsMu.Lock()
+	defer rl.limitsMu.Unlock()
+	rl.lookup = lookup
+	rl.limits = make(map[string]*keyLimit)
+}
+
+// SetUserLimit applies a changed limit override to the user's cached keys
+// immediately instead of after the cache expires. A limit of 0 reverts to
+// the default.
+func (rl *RateLimiter) SetUserLimit(userID, limit int) {
+	rl.limitsMu.Lock()
+	defer rl.limitsMu.Unlock()
+	for _, kl := range rl.limits {
+		if kl.userID == userID {
+			kl.limit = limit
+		}
+	}
+}
+
+// LimitFor returns the number of requests per window allowed for a key
+func (rl *RateLimiter) LimitFor(identifier string) int {
+	kl := rl.resolve(identifier)
+
+	rl.limitsMu.Lock()
+	defer rl.limitsMu.Unlock()
+	if kl != nil && kl.limit > 0 {
+		return kl.limit
+	}
+	return rl.limit
+}
+
+// resolve returns the user and limit override for a key, looking them up
+// once the cached entry is missing or expired. It returns nil when there is
+// no lookup, or the lookup failed and nothing was cached.
+func (rl *RateLimiter) resolve(identifier string) *keyLimit {
+	rl.limitsMu.Lock()
+	lookup := rl.lookup
+	kl, cached := rl.limits[identifier]
+	rl.limitsMu.Unlock()
+
+	if lookup == nil {
+		return nil
+	}
+
+	now := time.Now()
+	if cached && !now.After(kl.expiresAt) {
+		return kl
+	}
+
+	// Looked up outside limitsMu so a slow query doesn't block other keys
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	userID, limit, err := lookup(ctx, identifier)
+	cancel()
+	if err != nil {
+		log.Printf("Failed to look up rate limit for %s: %v", identifier, err)
+		return kl
+	}
+
+	kl = &keyLimit{userID: userID, limit: limit, expiresAt: now.Add(rl.limitTTL)}
+	// Keys that don't belong to a user, such as made-up webhook tokens, aren't
+	// cached so they can't grow the cache
+	if userID != 0 {
+		rl.limitsMu.Lock()
+		rl.limits[identifier] = kl
+		rl.limitsMu.Unlock()
+	}
+	return kl
+}
+
+// Window returns the rate limiting window
+func (rl *RateLimiter) Window() time.Duration {
+	return rl.window
+}
+
 func (rl *RateLimiter) cleanup() {
 	ticker := time.NewTicker(5 * time.Minute)
 	defer ticker.Stop()
@@ -53,51 +176,158 @@ func (rl *RateLimiter) cleanup() {
 			}
 		}
 		rl.mu.Unlock()
+
+		rl.limitsMu.Lock()
+		now := time.Now()
+		for key, kl := range rl.limits {
+			if now.After(kl.expiresAt) {
+				delete(rl.limits, key)
+			}
+		}
+		rl.limitsMu.Unlock()
 	}
 }
 
 func (rl *RateLimiter) Allow(identifier string) bool {
+	return rl.allow(identifier, time.Now())
+}
+
+// allow records a request from identifier at now if it fits in the limit
+func (rl *RateLimiter) allow(identifier string, now time.Time) bool {
+	limit := rl.LimitFor(identifier)
+
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	now := time.Now()
 	v, exists := rl.visitors[identifier]
-
 	if !exists {
-		rl.visitors[identifier] = &Visitor{
-			lastSeen: now,
-			count:    1,
-		}
-		return true
+		v = &Visitor{}
+		rl.visitors[identifier] = v
 	}
 
-	if now.Sub(v.lastSeen) > rl.window {
-		v.count = 1
-		v.lastSeen = now
-		return true
-	}
-
-	if v.count >= rl.limit {
+	v.advance(now, rl.window)
+	if v.estimate(now, rl.window) >= float64(limit) {
 		return false
 	}
 
-	v.count++
+	v.current++
 	v.lastSeen = now
 	return true
 }
 
+// advance moves the visitor's counters to the fixed window containing now
+func (v *Visitor) advance(now time.Time, window time.Duration) {
+	start := now.Truncate(window)
+	switch {
+	case start.Equal(v.windowStart):
+		return
+	case start.Sub(v.windowStart) == window:
+		v.previous = v.current
+	default:
+		v.previous = 0
+	}
+	v.current = 0
+	v.windowStart = start
+}
+
+// estimate approximates the number of requests in the window-long span
+// ending at now. Callers must advance the visitor to now first.
+func (v *Visitor) estimate(now time.Time, window time.Duration) float64 {
+	overlap := 1 - float64(now.Sub(v.windowStart))/float64(window)
+	return float64(v.previous)*overlap + float64(v.current)
+}
+
+// RetryAfter returns how long identifier must wait before another request
+// fits in the window
+func (rl *RateLimiter) RetryAfter(identifier string) time.Duration {
+	return rl.retryAfter(identifier, time.Now())
+}
+
+// retryAfter returns how long after now identifier's next request fits
+func (rl *RateLimiter) retryAfter(identifier string, now time.Time) time.Duration {
+	limit := rl.LimitFor(identifier)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	v, exists := rl.visitors[identifier]
+	if !exists {
+		return 0
+	}
+
+	v.advance(now, rl.window)
+	if v.estimate(now, rl.window) < float64(limit) {
+		return 0
+	}
+	if limit <= 0 {
+		return rl.window
+	}
+
+	window := float64(rl.window)
+	elapsed := now.Sub(v.windowStart)
+	if v.current < limit {
+		// The previous window's weight has to fall until the current
+		// count plus its share is below the limit
+		fits := window * (1 - float64(limit-v.current)/float64(v.previous))
+		return time.Duration(fits) - elapsed
+	}
+
+	// Nothing fits in this window; in the next one the current count
+	// becomes the previous and fades out the same way
+	fits := window * (1 - float64(limit)/float64(v.current))
+	return rl.window - elapsed + time.Duration(fits)
+}
+
+// RateLimitKey identifies who a request is rate limited as: the webhook
+// token for webhook routes, so senders sharing an IP behind a proxy don't
+// throttle each other, then the JWT user, then the client IP
+func RateLimitKey(c *fiber.Ctx) string {
+	if token := c.Params("token"); token != "" {
+		return "token:" + token
+	}
+	if userID, ok := c.Locals("user_id").(int); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return "ip:" + c.IP()
+}
+
+// identify returns the key a request is limited under. A webhook token only
+// gets its own bucket once it resolves to a user; otherwise sending a new
+// random token with each request would get a fresh bucket every time.
+func (rl *RateLimiter) identify(c *fiber.Ctx) string {
+	identifier := RateLimitKey(c)
+	if !strings.HasPrefix(identifier, "token:") {
+		return identifier
+	}
+
+	// Tokens that aren't known to belong to a user each cost a lookup, so
+	// they're only looked up while the IP's own bucket has room; a flood of
+	// made-up tokens is then stopped before it reaches the database
+	ipKey := "ip:" + c.IP()
+	if !rl.knownUser(identifier) && rl.retryAfter(ipKey, time.Now()) > 0 {
+		return ipKey
+	}
+	if kl := rl.resolve(identifier); kl != nil && kl.userID != 0 {
+		return identifier
+	}
+	return ipKey
+}
+
+// knownUser reports whether a key has been resolved to a user, even if the
+// cached entry has since expired
+func (rl *RateLimiter) knownUser(identifier string) bool {
+	rl.limitsMu.Lock()
+	defer rl.limitsMu.Unlock()
+	kl, ok := rl.limits[identifier]
+	return ok && kl.userID != 0
+}
+
 func (rl *RateLimiter) Middleware() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		// Use user_id from JWT if available, otherwise use IP
-		identifier := c.IP()
-		if userID := c.Locals("user_id"); userID != nil {
-			identifier = strconv.Itoa(userID.(int))
-		}
+		identifier := rl.identify(c)
 
 		if !rl.Allow(identifier) {
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"error": "rate limit exceeded, please try again later",
-			})
+			return TooManyRequests(c, rl.RetryAfter(identifier), "rate limit exceeded, please try again later")
 		}
 
 		return c.Next()
@@ -0,0 +1,174 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimiterBurstAcrossWindowBoundary(t *testing.T) {
+	const limit = 10
+	rl := newRateLimiter(limit, time.Minute)
+	boundary := time.Date(2026, 1, 1, 12, 1, 0, 0, time.UTC)
+
+	allowed := func(at time.Time, n int) int {
+		got := 0
+		for i := 0; i < n; i++ {
+			if rl.allow("ip:1.2.3.4", at) {
+				got++
+			}
+		}
+		return got
+	}
+
+	if got := allowed(boundary.Add(-time.Second), limit); got != limit {
+		t.Fatalf("burst before the boundary: %d allowed, want %d", got, limit)
+	}
+	if got := allowed(boundary, limit); got != 0 {
+		t.Fatalf("burst right after the boundary: %d allowed, want 0", got)
+	}
+	// The earlier burst starts fading out straight away, so the next request
+	// fits within a second
+	if wait := rl.retryAfter("ip:1.2.3.4", boundary); wait < 0 || wait > time.Second {
+		t.Fatalf("retry after %s, want under a second", wait)
+	}
+
+	// Halfway through the next window half of the earlier burst still counts
+	if got := allowed(boundary.Add(30*time.Second), limit); got != limit/2 {
+		t.Fatalf("halfway through the window: %d allowed, want %d", got, limit/2)
+	}
+
+	// Two windows on, the earlier requests no longer count
+	if got := allowed(boundary.Add(2*time.Minute), limit); got != limit {
+		t.Fatalf("two windows later: %d allowed, want %d", got, limit)
+	}
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	rl := newRateLimiter(4, time.Minute)
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 4; i++ {
+		if !rl.allow("user:1", start) {
+			t.Fatalf("request %d rejected", i+1)
+		}
+	}
+	if rl.allow("user:1", start) {
+		t.Fatal("request over the limit allowed")
+	}
+
+	wait := rl.retryAfter("user:1", start)
+	if wait <= 0 {
+		t.Fatalf("retry after %s, want a positive wait", wait)
+	}
+	if rl.allow("user:1", start.Add(wait-time.Second)) {
+		t.Fatal("request allowed before the retry time")
+	}
+	if !rl.allow("user:1", start.Add(wait+time.Second)) {
+		t.Fatal("request rejected after the retry time")
+	}
+
+	if wait := rl.retryAfter("user:2", start); wait != 0 {
+		t.Fatalf("unseen key must wait %s, want 0", wait)
+	}
+}
+
+func TestRateLimiterUnknownTokensShareIPBucket(t *testing.T) {
+	const limit = 3
+	rl := newRateLimiter(limit, time.Minute)
+	rl.SetLimitLookup(func(ctx context.Context, key string) (int, int, error) {
+		if key == "token:known" {
+			return 7, 0, nil
+		}
+		return 0, 0, nil
+	})
+
+	app := fiber.New()
+	app.Post("/webhook/:token", rl.Middleware(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	status := func(token string) int {
+		resp, err := app.Test(httptest.NewRequest("POST", "/webhook/"+token, nil))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if got := status("known"); got != fiber.StatusOK {
+		t.Fatalf("known token got %d, want 200", got)
+	}
+	for i := 0; i < limit; i++ {
+		if got := status(fmt.Sprintf("random-%d", i)); got != fiber.StatusOK {
+			t.Fatalf("request %d got %d, want 200", i+1, got)
+		}
+	}
+	if got := status("random-new"); got != fiber.StatusTooManyRequests {
+		t.Fatalf("fresh unknown token got %d, want 429 from the shared IP bucket", got)
+	}
+
+	// A real token has its own bucket, and unknown tokens aren't cached
+	if got := status("known"); got != fiber.StatusOK {
+		t.Fatalf("known token got %d, want 200", got)
+	}
+	rl.limitsMu.Lock()
+	defer rl.limitsMu.Unlock()
+	for key := range rl.limits {
+		if strings.HasPrefix(key, "token:random") {
+			t.Fatalf("unknown token %s was cached", key)
+		}
+	}
+}
+
+func TestRateLimiterSkipsLookupsOnceIPIsLimited(t *testing.T) {
+	const limit = 3
+	rl := newRateLimiter(limit, time.Minute)
+	lookups := 0
+	rl.SetLimitLookup(func(ctx context.Context, key string) (int, int, error) {
+		if strings.HasPrefix(key, "token:") {
+			lookups++
+		}
+		if key == "token:known" {
+			return 7, 0, nil
+		}
+		return 0, 0, nil
+	})
+
+	app := fiber.New()
+	app.Post("/webhook/:token", rl.Middleware(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	status := func(token string) int {
+		resp, err := app.Test(httptest.NewRequest("POST", "/webhook/"+token, nil))
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		return resp.StatusCode
+	}
+
+	if got := status("known"); got != fiber.StatusOK {
+		t.Fatalf("known token got %d, want 200", got)
+	}
+	for i := 0; i < limit; i++ {
+		status(fmt.Sprintf("random-%d", i))
+	}
+	before := lookups
+	for i := 0; i < 20; i++ {
+		if got := status(fmt.Sprintf("flood-%d", i)); got != fiber.StatusTooManyRequests {
+			t.Fatalf("flood request %d got %d, want 429", i+1, got)
+		}
+	}
+	if lookups != before {
+		t.Fatalf("%d token lookups while the IP was limited, want 0", lookups-before)
+	}
+
+	// A token already known to belong to a user keeps its own bucket
+	if got := status("known"); got != fiber.StatusOK {
+		t.Fatalf("known token got %d after the flood, want 200", got)
+	}
+}
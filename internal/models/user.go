@@ -12,8 +12,20 @@ type User struct {
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"`
 	WebhookToken uuid.UUID `json:"webhook_token"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	// Set by an admin to hold or drop this user's alerts
+	ProcessingPaused bool `json:"processing_paused"`
+	// Webhooks must be signed with this HMAC key when set
+	WebhookSecret string    `json:"-"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// UserResourceCounts summarises what a user has configured
+type UserResourceCounts struct {
+	Bots           int `json:"bots"`
+	Channels       int `json:"channels"`
+	ActiveChannels int `json:"active_channels"`
+	Schedules      int `json:"schedules"`
 }
 
 type WebhookLog struct {
@@ -36,16 +48,80 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
 type LoginResponse struct {
 	Token        string    `json:"token"`
+	ExpiresIn    int       `json:"expires_in"` // Access token lifetime in seconds
+	RefreshToken string    `json:"refresh_token"`
 	User         User      `json:"user"`
 	WebhookToken uuid.UUID `json:"webhook_token"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+	All          bool   `json:"all"` // Revoke every session of the user
+}
+
 type WebhookPayload struct {
-	Message  string                 `json:"message"`
-	Data     map[string]interface{} `json:"data,omitempty"`
-	Priority int                    `json:"priority,omitempty"` // 1=urgent, 2=high, 3=normal, 4=low
+	Message       string                 `json:"message"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+	Priority      int                    `json:"priority,omitempty"`       // 1=urgent, 2=high, 3=normal, 4=low
+	Format        string                 `json:"format,omitempty"`         // "html", "markdown", "markdownv2" or "plain"; overrides the channel default
+	ParseMode     string                 `json:"parse_mode,omitempty"`     // Telegram name for format: "HTML", "Markdown", "MarkdownV2" or "none"
+	CorrelationID string                 `json:"correlation_id,omitempty"` // Groups rapid updates the channel may coalesce
+	Debug         bool                   `json:"debug,omitempty"`          // Include resolved routing in the response
+	DryRun        bool                   `json:"dry_run,omitempty"`        // Resolve routing and rules without sending
+	MaxRetries    *int                   `json:"max_retries,omitempty"`    // Overrides the user default, then the queue default of 3; 0 disables retries
+	Raw           bool                   `json:"raw,omitempty"`            // Send the message verbatim: no data block, template, notice or escaping
+	// Repeats with the same key within the TTL return the original alert;
+	// the Idempotency-Key header takes precedence
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// Link buttons shown under the message, one per row
+	Buttons []WebhookButton `json:"buttons,omitempty"`
+	// Send a photo or document fetched from this URL, with the message as
+	// its caption; at most one of the two may be set
+	PhotoURL    string `json:"photo_url,omitempty"`
+	DocumentURL string `json:"document_url,omitempty"`
+	Silent      bool   `json:"silent,omitempty"` // Deliver without a notification sound
+	// Forum topic to post in, overriding the channel's; an identifier such
+	// as "alerts:42" overrides both
+	ThreadID *int `json:"thread_id,omitempty"`
+	// Deliver later: at an RFC 3339 time or after a number of seconds, at
+	// most 24 hours ahead; at most one of the two may be set
+	SendAt       string `json:"send_at,omitempty"`
+	DelaySeconds *int   `json:"delay_seconds,omitempty"`
+}
+
+// WebhookButton is an inline keyboard button opening a URL
+type WebhookButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// WebhookBatchRequest submits several alerts in one webhook call
+type WebhookBatchRequest struct {
+	Alerts []WebhookPayload `json:"alerts"`
 }
 
 type QueueStats struct {
@@ -54,65 +130,173 @@ type QueueStats struct {
 	Retried     int64 `json:"retried"`
 	Batched     int64 `json:"batched"`
 	CurrentSize int   `json:"current_size"`
+	// CurrentSize broken down by priority
+	UrgentSize int `json:"urgent_size"`
+	HighSize   int `json:"high_size"`
+	NormalSize int `json:"normal_size"`
+	LowSize    int `json:"low_size"`
+	Held       int `json:"held"`    // Alerts of paused users, not counted in CurrentSize
+	Delayed    int `json:"delayed"` // Alerts scheduled for later, not counted in CurrentSize
+	// Fill of the fullest priority queue, and the highest warning threshold
+	// it has crossed (0 when below all of them)
+	FillPercent     float64 `json:"fill_percent"`
+	CapacityWarning float64 `json:"capacity_warning"`
+	// Worker pool health
+	BusyWorkers        int     `json:"busy_workers"`
+	LongestBusySeconds float64 `json:"longest_busy_seconds"`
+	Stalled            bool    `json:"stalled"` // Every worker busy beyond the stall threshold
+	Paused             bool    `json:"paused"`  // New alerts rejected while stalled
+	EmergencyWorkers   int     `json:"emergency_workers"`
 }
 
 // TelegramBot represents a user's Telegram bot configuration
 type TelegramBot struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	BotToken    string    `json:"bot_token"`
-	BotUsername string    `json:"bot_username,omitempty"`
-	IsDefault   bool      `json:"is_default"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 int       `json:"id"`
+	UserID             int       `json:"user_id"`
+	BotToken           string    `json:"-"` // Never serialized; responses use TelegramBotResponse
+	BotUsername        string    `json:"bot_username,omitempty"`
+	IsDefault          bool      `json:"is_default"`
+	MessageTemplate    string    `json:"message_template"`      // Inherited by channels without their own template
+	RateLimitPerSecond *int      `json:"rate_limit_per_second"` // Sends per second across the bot's channels; null uses the default
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// MaskedToken returns the bot token with all but its last 4 characters
+// hidden, e.g. "****:****1234"
+func (b TelegramBot) MaskedToken() string {
+	if len(b.BotToken) <= 8 {
+		return "****:****"
+	}
+	return "****:****" + b.BotToken[len(b.BotToken)-4:]
+}
+
+// Response returns the bot as sent to clients, with its token masked
+func (b TelegramBot) Response() TelegramBotResponse {
+	return TelegramBotResponse{TelegramBot: b, BotToken: b.MaskedToken()}
+}
+
+// TelegramBotResponse is a bot as returned by the API; BotToken holds the
+// masked token
+type TelegramBotResponse struct {
+	TelegramBot
+	BotToken string `json:"bot_token"`
+}
+
+// RateLimitOverride is a bot's own sends per second (ChannelID empty) or a
+// channel's own sends per minute
+type RateLimitOverride struct {
+	BotToken  string
+	ChannelID string
+	Limit     int
 }
 
 // TelegramChannel represents a user's channel/group configuration with identifier
 type TelegramChannel struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	BotID       int       `json:"bot_id"`
-	Identifier  string    `json:"identifier"`  // Custom identifier like "tg", "alerts", "vip"
-	ChannelID   string    `json:"channel_id"`  // Telegram channel ID or username
-	ChannelName string    `json:"channel_name,omitempty"`
-	Description string    `json:"description,omitempty"`
-	IsActive    bool      `json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                    int       `json:"id"`
+	UserID                int       `json:"user_id"`
+	BotID                 int       `json:"bot_id"`
+	Identifier            string    `json:"identifier"` // Custom identifier like "tg", "alerts", "vip"
+	ChannelID             string    `json:"channel_id"` // Telegram channel ID or username
+	ChannelName           string    `json:"channel_name,omitempty"`
+	Description           string    `json:"description,omitempty"`
+	ParseMode             string    `json:"parse_mode"`              // Default message format: "markdown", "markdownv2", "html" or "plain"
+	MessageTemplate       string    `json:"message_template"`        // Overrides the bot's template when set
+	CoalesceWindowSeconds int       `json:"coalesce_window_seconds"` // Updates sharing a correlation_id within this window are coalesced
+	DedupWindowSeconds    *int      `json:"dedup_window_seconds"`    // Overrides the user's dedup window when set; 0 disables deduplication
+	ThreadID              int       `json:"thread_id"`               // Forum topic to post in; 0 is the main chat
+	RateLimitPerMinute    *int      `json:"rate_limit_per_minute"`   // Sends per minute to this chat; null uses CHANNEL_RATE_PER_MIN
+	IsDefault             bool      `json:"is_default"`              // Receives webhooks without an identifier
+	WebhookSecret         string    `json:"-"`                       // Webhooks routed here must be signed with this HMAC key when set
+	IsActive              bool      `json:"is_active"`
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
 }
 
 // Request/Response models for bot and channel management
 
 type CreateBotRequest struct {
-	BotToken  string `json:"bot_token" validate:"required"`
-	IsDefault bool   `json:"is_default"`
+	BotToken           string `json:"bot_token" validate:"required"`
+	IsDefault          bool   `json:"is_default"`
+	MessageTemplate    string `json:"message_template,omitempty"`
+	RateLimitPerSecond *int   `json:"rate_limit_per_second,omitempty"`
+}
+
+// ValidateBotTokenRequest checks a bot token without saving it
+type ValidateBotTokenRequest struct {
+	BotToken string `json:"bot_token"`
 }
 
 type UpdateBotRequest struct {
-	BotToken  string `json:"bot_token,omitempty"`
-	IsDefault bool   `json:"is_default"`
+	BotToken           string  `json:"bot_token,omitempty"`
+	IsDefault          bool    `json:"is_default"`
+	MessageTemplate    *string `json:"message_template,omitempty"`      // "" clears the template
+	RateLimitPerSecond *int    `json:"rate_limit_per_second,omitempty"` // 0 clears the override
 }
 
 type CreateChannelRequest struct {
-	BotID       int    `json:"bot_id" validate:"required"`
-	Identifier  string `json:"identifier" validate:"required"`
-	ChannelID   string `json:"channel_id" validate:"required"`
-	ChannelName string `json:"channel_name,omitempty"`
-	Description string `json:"description,omitempty"`
+	BotID                 int    `json:"bot_id" validate:"required"`
+	Identifier            string `json:"identifier" validate:"required"`
+	ChannelID             string `json:"channel_id" validate:"required"`
+	ChannelName           string `json:"channel_name,omitempty"`
+	Description           string `json:"description,omitempty"`
+	ParseMode             string `json:"parse_mode,omitempty"`
+	MessageTemplate       string `json:"message_template,omitempty"`
+	CoalesceWindowSeconds int    `json:"coalesce_window_seconds,omitempty"`
+	DedupWindowSeconds    *int   `json:"dedup_window_seconds,omitempty"`
+	ThreadID              int    `json:"thread_id,omitempty"`
+	RateLimitPerMinute    *int   `json:"rate_limit_per_minute,omitempty"`
+	IsDefault             bool   `json:"is_default"`
+	SkipValidation        bool   `json:"skip_validation,omitempty"` // Don't check the bot can post to the channel
+}
+
+// BulkCreateChannelsRequest creates several channels in one transaction
+type BulkCreateChannelsRequest struct {
+	Channels []CreateChannelRequest `json:"channels"`
+	// "abort" (the default) creates nothing when any channel fails; "skip"
+	// creates the rest and reports the failures
+	OnError string `json:"on_error,omitempty"`
 }
 
 type UpdateChannelRequest struct {
-	BotID       int    `json:"bot_id,omitempty"`
-	Identifier  string `json:"identifier,omitempty"`
-	ChannelID   string `json:"channel_id,omitempty"`
-	ChannelName string `json:"channel_name,omitempty"`
-	Description string `json:"description,omitempty"`
-	IsActive    *bool  `json:"is_active,omitempty"`
+	BotID                 int     `json:"bot_id,omitempty"`
+	Identifier            string  `json:"identifier,omitempty"`
+	ChannelID             string  `json:"channel_id,omitempty"`
+	ChannelName           string  `json:"channel_name,omitempty"`
+	Description           string  `json:"description,omitempty"`
+	ParseMode             string  `json:"parse_mode,omitempty"`
+	MessageTemplate       *string `json:"message_template,omitempty"` // "" clears the template
+	CoalesceWindowSeconds *int    `json:"coalesce_window_seconds,omitempty"`
+	DedupWindowSeconds    *int    `json:"dedup_window_seconds,omitempty"`  // -1 clears the override
+	ThreadID              *int    `json:"thread_id,omitempty"`             // 0 posts to the main chat
+	RateLimitPerMinute    *int    `json:"rate_limit_per_minute,omitempty"` // 0 clears the override
+	IsActive              *bool   `json:"is_active,omitempty"`
+	IsDefault             *bool   `json:"is_default,omitempty"`
+	SkipValidation        bool    `json:"skip_validation,omitempty"` // Don't check the bot can post to the channel
+}
+
+// ChannelPreviewRequest renders a sample payload with a channel's formatting,
+// optionally overriding settings that have not been saved yet
+type ChannelPreviewRequest struct {
+	Payload         WebhookPayload `json:"payload"`
+	ParseMode       *string        `json:"parse_mode,omitempty"`
+	MessageTemplate *string        `json:"message_template,omitempty"`
 }
 
 type BotWithChannels struct {
-	Bot      TelegramBot       `json:"bot"`
-	Channels []TelegramChannel `json:"channels"`
+	Bot      TelegramBotResponse `json:"bot"`
+	Channels []TelegramChannel   `json:"channels"`
+}
+
+// ConfigExport is a user's complete bot and channel configuration, as
+// returned by the config export and accepted back by the import
+type ConfigExport struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Bot tokens are masked unless the export was given a passphrase, in
+	// which case they are encrypted with it
+	TokensEncrypted bool              `json:"tokens_encrypted"`
+	Bots            []BotWithChannels `json:"bots"`
 }
 
 // ============================================================================
@@ -126,12 +310,19 @@ type AnalyticsSummary struct {
 	FailedCount      int     `json:"failed_count"`
 	FilteredCount    int     `json:"filtered_count"`
 	PendingCount     int     `json:"pending_count"`
+	StatusCounts     map[string]int `json:"status_counts"` // Every status, including the ones above
 	SuccessRate      float64 `json:"success_rate"`
 	AvgPerHour       float64 `json:"avg_per_hour"`
 	AvgPerDay        float64 `json:"avg_per_day"`
 	PeakHour         int     `json:"peak_hour"`          // 0-23
 	PeakHourCount    int     `json:"peak_hour_count"`
 	LastMessageAt    *time.Time `json:"last_message_at,omitempty"`
+	// Delivery latency of successful sends, from queueing to Telegram
+	// accepting the message, in milliseconds
+	LatencyAvgMs float64 `json:"latency_avg_ms"`
+	LatencyP50Ms float64 `json:"latency_p50_ms"`
+	LatencyP95Ms float64 `json:"latency_p95_ms"`
+	LatencyP99Ms float64 `json:"latency_p99_ms"`
 }
 
 // TimelineDataPoint represents messages at a specific time
@@ -143,6 +334,22 @@ type TimelineDataPoint struct {
 	TotalCount   int       `json:"total_count"`
 }
 
+// AnalyticsFilter narrows analytics to one bot's channels and/or one
+// channel identifier; zero values match everything
+type AnalyticsFilter struct {
+	BotID      int
+	Identifier string
+}
+
+// WebhookLogExportRow is one webhook log as exported to CSV
+type WebhookLogExportRow struct {
+	SentAt     time.Time
+	Status     string
+	Identifier string
+	Priority   int
+	Message    string // Truncated
+}
+
 // StatusDistribution shows breakdown by status
 type StatusDistribution struct {
 	Status string `json:"status"`
@@ -150,6 +357,13 @@ type StatusDistribution struct {
 	Percentage float64 `json:"percentage"`
 }
 
+// FailureReasonDistribution shows breakdown of failed sends by category
+type FailureReasonDistribution struct {
+	Category   string  `json:"category"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
 // ChannelDistribution shows messages per channel
 type ChannelDistribution struct {
 	ChannelIdentifier string `json:"channel_identifier"`
@@ -168,10 +382,407 @@ type PriorityDistribution struct {
 
 // AnalyticsResponse combines all analytics data
 type AnalyticsResponse struct {
-	Summary              AnalyticsSummary        `json:"summary"`
-	Timeline             []TimelineDataPoint     `json:"timeline"`
-	StatusDistribution   []StatusDistribution    `json:"status_distribution"`
-	ChannelDistribution  []ChannelDistribution   `json:"channel_distribution,omitempty"`
-	PriorityDistribution []PriorityDistribution  `json:"priority_distribution,omitempty"`
-	TimeRange            string                  `json:"time_range"` // "24h", "7d", "30d"
+	Summary              AnalyticsSummary            `json:"summary"`
+	Timeline             []TimelineDataPoint         `json:"timeline"`
+	StatusDistribution   []StatusDistribution        `json:"status_distribution"`
+	ChannelDistribution  []ChannelDistribution       `json:"channel_distribution,omitempty"`
+	PriorityDistribution []PriorityDistribution      `json:"priority_distribution,omitempty"`
+	FailureReasons       []FailureReasonDistribution `json:"failure_reasons,omitempty"`
+	TimeRange            string                      `json:"time_range"` // "24h", "7d", "30d" or "custom"
+	From                 time.Time                   `json:"from"`
+	To                   time.Time                   `json:"to"`
+	// Partial is set when some sections failed to load; FailedSections names them
+	Partial        bool     `json:"partial,omitempty"`
+	FailedSections []string `json:"failed_sections,omitempty"`
+}
+
+// ============================================================================
+// Scheduled Message Models
+// ============================================================================
+
+// ScheduledMessage is a recurring message sent to a channel on a cron schedule
+type ScheduledMessage struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"user_id"`
+	ChannelID      int        `json:"channel_id"` // Database channel ID
+	Name           string     `json:"name"`
+	CronExpression string     `json:"cron_expression"`
+	Timezone       string     `json:"timezone"`
+	Message        string     `json:"message"`
+	Priority       int        `json:"priority"`
+	IsActive       bool       `json:"is_active"`
+	NextRunAt      time.Time  `json:"next_run_at"`
+	LastRunAt      *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// DueScheduledMessage is a scheduled message joined with its routing details
+type DueScheduledMessage struct {
+	ScheduledMessage
+	Username          string
+	BotToken          string
+	TelegramChannelID string
+	Identifier        string
+	ParseMode         string
+	ChannelTemplate   string
+	BotTemplate       string
+}
+
+type CreateScheduleRequest struct {
+	ChannelID      int    `json:"channel_id" validate:"required"`
+	Name           string `json:"name" validate:"required"`
+	CronExpression string `json:"cron_expression" validate:"required"`
+	Timezone       string `json:"timezone,omitempty"`
+	Message        string `json:"message" validate:"required"`
+	Priority       int    `json:"priority,omitempty"`
+}
+
+type UpdateScheduleRequest struct {
+	ChannelID      int    `json:"channel_id,omitempty"`
+	Name           string `json:"name,omitempty"`
+	CronExpression string `json:"cron_expression,omitempty"`
+	Timezone       string `json:"timezone,omitempty"`
+	Message        string `json:"message,omitempty"`
+	Priority       int    `json:"priority,omitempty"`
+	IsActive       *bool  `json:"is_active,omitempty"`
+}
+
+// ============================================================================
+// Delivery Receipt Models
+// ============================================================================
+
+// ReceiptWebhook is a user's endpoint for periodic delivery receipts
+type ReceiptWebhook struct {
+	ID              int        `json:"id"`
+	UserID          int        `json:"user_id"`
+	URL             string     `json:"url"`
+	Secret          string     `json:"secret"`
+	IntervalMinutes int        `json:"interval_minutes"`
+	IsActive        bool       `json:"is_active"`
+	NextSendAt      time.Time  `json:"next_send_at"`
+	LastWindowEnd   *time.Time `json:"last_window_end,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// DueReceiptWebhook is a receipt webhook joined with the owning user's token
+type DueReceiptWebhook struct {
+	ReceiptWebhook
+	WebhookToken uuid.UUID
+}
+
+type UpsertReceiptWebhookRequest struct {
+	URL             string `json:"url" validate:"required"`
+	Secret          string `json:"secret,omitempty"`
+	IntervalMinutes int    `json:"interval_minutes,omitempty"`
+	IsActive        *bool  `json:"is_active,omitempty"`
+}
+
+// DeliveryReceipt is the aggregated summary POSTed to a receipt webhook
+type DeliveryReceipt struct {
+	WebhookToken uuid.UUID `json:"webhook_token"`
+	WindowStart  time.Time `json:"window_start"`
+	WindowEnd    time.Time `json:"window_end"`
+	Total        int       `json:"total"`
+	Delivered    int       `json:"delivered"`
+	Failed       int       `json:"failed"`
+	Filtered     int       `json:"filtered"`
+	Pending      int       `json:"pending"`
+}
+
+// ============================================================================
+// Escalation Models
+// ============================================================================
+
+// EscalationPolicy decides what happens when an urgent alert permanently
+// fails. A policy without ChannelID is the user's default; channel policies
+// override it.
+type EscalationPolicy struct {
+	ID                int       `json:"id"`
+	UserID            int       `json:"user_id"`
+	ChannelID         *int      `json:"channel_id,omitempty"`
+	FailoverChannelID *int      `json:"failover_channel_id,omitempty"`
+	MirrorURL         string    `json:"mirror_url,omitempty"`
+	DeadLetter        bool      `json:"dead_letter"`
+	IsActive          bool      `json:"is_active"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+type UpsertEscalationPolicyRequest struct {
+	ChannelID         *int   `json:"channel_id,omitempty"`
+	FailoverChannelID *int   `json:"failover_channel_id,omitempty"`
+	MirrorURL         string `json:"mirror_url,omitempty"`
+	DeadLetter        *bool  `json:"dead_letter,omitempty"`
+	IsActive          *bool  `json:"is_active,omitempty"`
+}
+
+// DeadLetterAlert is an alert that permanently failed delivery
+type DeadLetterAlert struct {
+	ID        int                    `json:"id"`
+	UserID    int                    `json:"user_id"`
+	AlertID   string                 `json:"alert_id"`
+	ChannelID *int                   `json:"channel_id,omitempty"`
+	Payload   map[string]interface{} `json:"payload"`
+	Priority  int                    `json:"priority"`
+	Retries   int                    `json:"retries"`
+	Error     string                 `json:"error"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// EscalationEvent is POSTed to an escalation policy's mirror URL
+type EscalationEvent struct {
+	AlertID   string                 `json:"alert_id"`
+	UserID    int                    `json:"user_id"`
+	ChannelID int                    `json:"channel_id"`
+	Priority  int                    `json:"priority"`
+	Payload   map[string]interface{} `json:"payload"`
+	Error     string                 `json:"error"`
+	Retries   int                    `json:"retries"`
+	FailedAt  time.Time              `json:"failed_at"`
+}
+
+// ============================================================================
+// Maintenance Notice Models
+// ============================================================================
+
+// MaintenanceNotice is prepended to outgoing messages between StartsAt and
+// EndsAt. A notice without UserID applies to every user.
+type MaintenanceNotice struct {
+	ID        int       `json:"id"`
+	UserID    *int      `json:"user_id,omitempty"`
+	Message   string    `json:"message"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type CreateMaintenanceNoticeRequest struct {
+	UserID   *int       `json:"user_id,omitempty"`
+	Message  string     `json:"message" validate:"required"`
+	StartsAt *time.Time `json:"starts_at,omitempty"` // defaults to now
+	EndsAt   time.Time  `json:"ends_at" validate:"required"`
+}
+
+// ============================================================================
+// Batch Submission Models
+// ============================================================================
+
+// BatchAlertStatus is the delivery outcome of one alert in a batch
+type BatchAlertStatus struct {
+	AlertID   string    `json:"alert_id"`
+	Status    string    `json:"status"` // "queued", "completed" or "failed"
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AlertBatch aggregates the statuses of the alerts in a batch submission
+type AlertBatch struct {
+	BatchID   string             `json:"batch_id"`
+	Total     int                `json:"total"`
+	Counts    map[string]int     `json:"counts"`
+	Done      bool               `json:"done"` // No alerts are still queued
+	Alerts    []BatchAlertStatus `json:"alerts"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// ============================================================================
+// Payload Transform Models
+// ============================================================================
+
+// PayloadTransform reshapes raw JSON posted to a user's webhook token into a
+// WebhookPayload before it is processed
+type PayloadTransform struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	Expression string    `json:"expression"`
+	IsActive   bool      `json:"is_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type UpsertPayloadTransformRequest struct {
+	Expression string `json:"expression" validate:"required"`
+	IsActive   *bool  `json:"is_active,omitempty"`
+}
+
+// ============================================================================
+// Quiet Hours Models
+// ============================================================================
+
+// QuietHours is a daily window in the user's timezone during which normal
+// and low priority alerts are filtered. A window whose end hour is not after
+// its start hour crosses midnight.
+type QuietHours struct {
+	UserID    int       `json:"user_id"`
+	StartHour int       `json:"start_hour"` // 0-23, inclusive
+	EndHour   int       `json:"end_hour"`   // 0-23, exclusive
+	Timezone  string    `json:"timezone"`   // IANA name, e.g. "Europe/Berlin"
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type UpsertQuietHoursRequest struct {
+	StartHour *int   `json:"start_hour" validate:"required"`
+	EndHour   *int   `json:"end_hour" validate:"required"`
+	Timezone  string `json:"timezone,omitempty"` // Defaults to UTC
+	IsActive  *bool  `json:"is_active,omitempty"`
+}
+
+// ============================================================================
+// User Settings Models
+// ============================================================================
+
+// UserSettings are account-level defaults for webhook alerts. Nil fields fall
+// back to the queue defaults; a payload's own max_retries always wins.
+type UserSettings struct {
+	UserID              int       `json:"user_id"`
+	DefaultMaxRetries   *int      `json:"default_max_retries"`
+	BackoffBaseSeconds  *int      `json:"backoff_base_seconds"`  // First retry delay, doubling each retry
+	BackoffMaxSeconds   *int      `json:"backoff_max_seconds"`   // Cap on the retry delay
+	DedupWindowSeconds  *int      `json:"dedup_window_seconds"`  // Identical messages within this window are dropped; 0 disables
+	DedupAcrossChannels bool      `json:"dedup_across_channels"` // Also drop identical messages sent to other channels
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// UpdateUserSettingsRequest replaces the user's settings; omitted or null
+// fields reset to the queue defaults
+type UpdateUserSettingsRequest struct {
+	DefaultMaxRetries   *int `json:"default_max_retries"`
+	BackoffBaseSeconds  *int `json:"backoff_base_seconds"`
+	BackoffMaxSeconds   *int `json:"backoff_max_seconds"`
+	DedupWindowSeconds  *int `json:"dedup_window_seconds"`
+	DedupAcrossChannels bool `json:"dedup_across_channels"`
+}
+
+// ============================================================================
+// Queued Alert Models
+// ============================================================================
+
+// QueuedAlert is a persisted queue alert, reloaded on startup while pending
+type QueuedAlert struct {
+	ID                  string
+	UserID              int
+	Username            string
+	Payload             map[string]interface{}
+	Priority            int
+	Retries             int
+	MaxRetries          int
+	ScheduledAt         time.Time
+	BotToken            string
+	TelegramChannelID   string
+	ChannelID           *int // nil in legacy mode
+	Format              string
+	ChannelTemplate     string
+	BotTemplate         string
+	CorrelationID       string
+	CoalesceWindowMs    int64
+	BatchID             *string
+	BackoffBaseMs       int64
+	BackoffMaxMs        int64
+	DedupWindowMs       *int64 // nil uses the server default
+	DedupAcrossChannels bool
+	Silent              bool
+	ThreadID            int
+	CreatedAt           time.Time
+}
+
+// ============================================================================
+// Webhook Fixture Models
+// ============================================================================
+
+// WebhookFixture is a named sample webhook payload saved for re-testing
+// formatting and routing
+type WebhookFixture struct {
+	ID        int                    `json:"id"`
+	UserID    int                    `json:"user_id"`
+	Name      string                 `json:"name"`
+	Payload   map[string]interface{} `json:"payload"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
+
+type SaveWebhookFixtureRequest struct {
+	Name    string                 `json:"name" validate:"required"`
+	Payload map[string]interface{} `json:"payload" validate:"required"`
+}
+
+// ============================================================================
+// Alert Rule Models
+// ============================================================================
+
+// Alert rule kinds
+const (
+	RuleKindFilter    = "filter"    // Drops alerts that fail the rule
+	RuleKindTransform = "transform" // Rewrites the message
+)
+
+// Alert rule types. Filters: block_keywords, require_keywords, min_length,
+// max_length and throttle. Transforms: max_length (truncates) and
+// redact_keywords.
+const (
+	RuleTypeBlockKeywords   = "block_keywords"
+	RuleTypeRequireKeywords = "require_keywords"
+	RuleTypeMinLength       = "min_length"
+	RuleTypeMaxLength       = "max_length"
+	RuleTypeThrottle        = "throttle"
+	RuleTypeRedactKeywords  = "redact_keywords"
+)
+
+// UserAlertRule is a dashboard-defined rule applied to a user's alerts in
+// addition to the global default rules
+type UserAlertRule struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"user_id"`
+	Name          string    `json:"name"`
+	Kind          string    `json:"kind"`
+	RuleType      string    `json:"rule_type"`
+	Keywords      []string  `json:"keywords"`                 // Matched case-insensitively
+	Value         *int      `json:"value,omitempty"`          // Length for min/max_length, alert count for throttle
+	WindowSeconds *int      `json:"window_seconds,omitempty"` // Throttle window
+	IsEnabled     bool      `json:"is_enabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type UserAlertRuleRequest struct {
+	Name          string   `json:"name" validate:"required"`
+	Kind          string   `json:"kind" validate:"required"`
+	RuleType      string   `json:"rule_type" validate:"required"`
+	Keywords      []string `json:"keywords,omitempty"`
+	Value         *int     `json:"value,omitempty"`
+	WindowSeconds *int     `json:"window_seconds,omitempty"`
+	IsEnabled     *bool    `json:"is_enabled,omitempty"`
+}
+
+// ============================================================================
+// Redaction Rule Models
+// ============================================================================
+
+// RedactionRule replaces matches of a regex in a user's alert messages and
+// data before they are sent to Telegram or written to the logs
+type RedactionRule struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	Name        string    `json:"name"`
+	Pattern     string    `json:"pattern"`     // RE2 syntax
+	Replacement string    `json:"replacement"` // May reference groups as $1
+	IsEnabled   bool      `json:"is_enabled"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type RedactionRuleRequest struct {
+	Name        string  `json:"name" validate:"required"`
+	Pattern     string  `json:"pattern" validate:"required"`
+	Replacement *string `json:"replacement,omitempty"` // Defaults to [REDACTED]
+	IsEnabled   *bool   `json:"is_enabled,omitempty"`
+}
+
+// SetUserRateLimitRequest overrides a user's webhook rate limit; null or 0
+// reverts to the RATE_LIMIT default
+type SetUserRateLimitRequest struct {
+	RateLimit *int `json:"rate_limit"`
 }
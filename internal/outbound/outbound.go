@@ -0,0 +1,73 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for URLs that resolve to an address on the
+// server's own network
+var ErrPrivateAddress = errors.New("url must not point to a private, loopback or link-local address")
+
+// NewClient returns an HTTP client for user-supplied URLs that refuses to
+// connect to private addresses. The check runs on the address actually
+// dialled, so a host that resolves differently after the URL was saved, or a
+// redirect, can't reach internal services.
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return ErrPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		// No proxy, so the dial check sees the target host itself
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+	}
+}
+
+// ValidateURL checks that rawURL is an absolute http(s) URL whose host only
+// resolves to public addresses
+func ValidateURL(ctx context.Context, rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return errors.New("url must be a valid http or https URL")
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, "ip", parsed.Hostname())
+	if err != nil || len(ips) == 0 {
+		return fmt.Errorf("could not resolve host %q", parsed.Hostname())
+	}
+	for _, ip := range ips {
+		if isPrivateIP(ip) {
+			return ErrPrivateAddress
+		}
+	}
+
+	return nil
+}
+
+// isPrivateIP reports whether ip is loopback, private, link-local, multicast
+// or unspecified
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
@@ -0,0 +1,58 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"http://93.184.216.34/hook", true},
+		{"https://[2606:2800:220:1:248:1893:25c8:1946]/hook", true},
+		{"ftp://93.184.216.34/hook", false},
+		{"/hook", false},
+		{"http://127.0.0.1:8080/hook", false},
+		{"http://localhost/hook", false},
+		{"http://10.0.0.5/hook", false},
+		{"http://172.16.0.1/hook", false},
+		{"http://192.168.1.1/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://[fe80::1]/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateURL(context.Background(), tt.url)
+		if tt.ok && err != nil {
+			t.Errorf("%s rejected: %v", tt.url, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s accepted, want an error", tt.url)
+		}
+	}
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	resp, err := NewClient(time.Second).Post(server.URL, "application/json", nil)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("request to a loopback address succeeded")
+	}
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Fatalf("got %v, want ErrPrivateAddress", err)
+	}
+}
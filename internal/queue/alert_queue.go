@@ -2,12 +2,19 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/metrics"
 	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/telegram"
 )
 
 // Alert represents a queued alert message
@@ -18,18 +25,60 @@ type Alert struct {
 	Payload     map[string]interface{}
 	Priority    int // 1=urgent, 2=high, 3=normal, 4=low
 	Retries     int
-	MaxRetries  int
+	MaxRetries  int // 0 disables retries; producers set DefaultMaxRetries explicitly
 	CreatedAt   time.Time
 	ScheduledAt time.Time
 	// Multi-channel routing fields
 	BotToken    string // User's bot token for this alert
 	ChannelID   string // Target channel ID
 	DBChannelID int    // Database channel ID for logging
+	Format      string // Message format: "markdown", "markdownv2", "html" or "plain"
+	// Message templates; the processor prefers the channel's over the bot's
+	ChannelTemplate string
+	BotTemplate     string
+	// Updates with the same CorrelationID arriving within CoalesceWindow of
+	// each other are coalesced into one message
+	CorrelationID  string
+	CoalesceWindow time.Duration
+	BatchID        string // Set for alerts submitted through the batch endpoint
+	// Retry delay starts at BackoffBase and doubles each retry, capped at
+	// BackoffMax; zero values use DefaultBackoffBase and MaxBackoff
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// DedupWindow overrides the rule engine's deduplication window when set;
+	// zero disables deduplication for the alert
+	DedupWindow *time.Duration
+	// DedupAcrossChannels treats the same message to different channels as
+	// a duplicate; by default each channel is deduplicated separately
+	DedupAcrossChannels bool
+	Silent              bool // Deliver without a notification sound
+	ThreadID            int  // Forum topic; 0 is the main chat
 }
 
+// priorityLevels is the number of alert priorities, from 1 (urgent) to 4 (low)
+const priorityLevels = 4
+
+// Queue-level retry defaults, used when neither the request nor the user's
+// settings specify otherwise
+const (
+	DefaultMaxRetries  = 3
+	DefaultBackoffBase = 2 * time.Second
+)
+
+// MaxBackoff caps the delay before any retry, whatever the user's backoff
+// settings
+const MaxBackoff = 24 * time.Hour
+
+// maxRateLimitWait caps how long a retry waits on Telegram's retry_after. An
+// alert retried while the bot is still limited fails again and waits anew.
+const maxRateLimitWait = 10 * time.Minute
+
 // AlertQueue manages the queue of alerts to be sent
 type AlertQueue struct {
-	queue         chan *Alert
+	// queues holds one channel per priority, most urgent first; workers
+	// always take from the most urgent non-empty one
+	queues        [priorityLevels]chan *Alert
+	capacity      int // Buffer size of each priority's channel
 	workers       int
 	wg            sync.WaitGroup
 	ctx           context.Context
@@ -40,9 +89,42 @@ type AlertQueue struct {
 	batchSize     int
 	batchInterval time.Duration
 	stats         *QueueStats
+	hooks         []CompletionHook
+	escalator     Escalator
+	failures      FailureRecorder
+	redactor      Redactor
+	retryOverflow string // RetryOverflow* action when the retry queue is full
 	mu            sync.RWMutex
+	// Worker stall detection; busySince holds each worker's current alert
+	// start time in Unix nanoseconds, or 0 when idle
+	busySince      []atomic.Int64
+	watchdog       watchdogConfig
+	stalled        atomic.Bool
+	paused         atomic.Bool
+	emergencyCount atomic.Int32
+	// Optional backing table for unfinished alerts, see NewPersistentAlertQueue
+	db *database.DB
+	// High-water marks checked as the queue grows; guarded by stats.mu
+	capacityWarnings *capacityWarnings
+	// Minimum fraction of a batch that must be delivered for its failures to
+	// be retried
+	batchSuccessThreshold float64
+	// Alerts of paused users, waiting for the user to be resumed
+	held   []*Alert
+	heldMu sync.Mutex
+	// Alerts scheduled for later, waiting to be due
+	delayed   []*Alert
+	delayedMu sync.Mutex
+	// Set by Stop before the priority channels are closed; senders check it
+	// under a read lock so nothing sends on a closed channel
+	stopped bool
+	stopMu  sync.RWMutex
 }
 
+// CompletionHook is called once an alert reaches a final state: delivered,
+// filtered, or failed with no retries left (err is non-nil on failure)
+type CompletionHook func(alert *Alert, err error)
+
 // QueueStats tracks queue statistics
 type QueueStats struct {
 	Processed   int64
@@ -56,32 +138,135 @@ type QueueStats struct {
 // AlertProcessor is the interface for processing alerts
 type AlertProcessor interface {
 	ProcessAlert(ctx context.Context, alert *Alert) error
-	ProcessBatch(ctx context.Context, alerts []*Alert) error
+	// ProcessBatch returns the alerts that failed to process, along with an
+	// error when none of them succeeded
+	ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error)
 }
 
+// Escalator handles urgent alerts that failed permanently
+type Escalator interface {
+	Escalate(ctx context.Context, alert *Alert, err error)
+}
+
+// FailureRecorder keeps a trace of alerts the queue gives up on outside the
+// normal retry path, such as retries turned away by a full retry queue
+type FailureRecorder interface {
+	RecordFailure(ctx context.Context, alert *Alert, cause error, deadLetter bool)
+}
+
+// Redactor removes content that must not be stored from an alert's payload
+type Redactor interface {
+	Redact(alert *Alert)
+}
+
+// Actions taken when a retry doesn't fit in the retry queue
+const (
+	RetryOverflowDeadLetter = "dead_letter" // Drop it, logging it as failed and recording a dead letter
+	RetryOverflowLog        = "log"         // Drop it, logging it as failed
+	RetryOverflowBlock      = "block"       // Wait for room, holding up the worker
+)
+
 // NewAlertQueue creates a new alert queue
 func NewAlertQueue(workers int, queueSize int, processor AlertProcessor) *AlertQueue {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	aq := &AlertQueue{
-		queue:         make(chan *Alert, queueSize),
+		capacity:      queueSize,
 		workers:       workers,
 		ctx:           ctx,
 		cancel:        cancel,
 		processor:     processor,
-		retryQueue:    make(chan *Alert, queueSize/2),
+		retryQueue:    make(chan *Alert, retryQueueSizeFromEnv(queueSize)),
+		retryOverflow: retryOverflowFromEnv(),
 		batchQueue:    make(chan []*Alert, 100),
-		batchSize:     10,
-		batchInterval: 5 * time.Second,
+		batchSize:     batchSizeFromEnv(),
+		batchInterval: batchIntervalFromEnv(),
 		stats:         &QueueStats{},
+		busySince:     make([]atomic.Int64, workers),
+		watchdog:      newWatchdogConfig(),
+
+		batchSuccessThreshold: batchSuccessThresholdFromEnv(),
+		capacityWarnings:      newCapacityWarnings(),
+	}
+	for i := range aq.queues {
+		aq.queues[i] = make(chan *Alert, queueSize)
 	}
 
 	return aq
 }
 
+// retryQueueSizeFromEnv reads how many alerts may wait for a retry from
+// RETRY_QUEUE_SIZE (default half the queue capacity)
+func retryQueueSizeFromEnv(queueSize int) int {
+	if v, err := strconv.Atoi(os.Getenv("RETRY_QUEUE_SIZE")); err == nil && v > 0 {
+		return v
+	}
+	return queueSize / 2
+}
+
+// retryOverflowFromEnv reads what happens to a retry that doesn't fit in the
+// retry queue from RETRY_OVERFLOW_ACTION (dead_letter, log or block; default
+// dead_letter)
+func retryOverflowFromEnv() string {
+	switch action := os.Getenv("RETRY_OVERFLOW_ACTION"); action {
+	case RetryOverflowDeadLetter, RetryOverflowLog, RetryOverflowBlock:
+		return action
+	case "":
+	default:
+		log.Printf("Unknown RETRY_OVERFLOW_ACTION %q, using %q", action, RetryOverflowDeadLetter)
+	}
+	return RetryOverflowDeadLetter
+}
+
+// batchSizeFromEnv reads how many alerts are sent together from BATCH_SIZE
+// (default 10)
+func batchSizeFromEnv() int {
+	if v, err := strconv.Atoi(os.Getenv("BATCH_SIZE")); err == nil && v > 0 {
+		return v
+	}
+	return 10
+}
+
+// batchIntervalFromEnv reads how often a partial batch is flushed from
+// BATCH_INTERVAL, as a duration ("5s") or whole seconds (default 5s)
+func batchIntervalFromEnv() time.Duration {
+	env := os.Getenv("BATCH_INTERVAL")
+	if d, err := time.ParseDuration(env); err == nil && d > 0 {
+		return d
+	}
+	if v, err := strconv.Atoi(env); err == nil && v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return 5 * time.Second
+}
+
+// batchSuccessThresholdFromEnv reads the fraction of a batch that must be
+// delivered for its failed alerts to be retried from BATCH_SUCCESS_THRESHOLD
+// (0-1, default 0 so failures are always retried)
+func batchSuccessThresholdFromEnv() float64 {
+	env := os.Getenv("BATCH_SUCCESS_THRESHOLD")
+	if env == "" {
+		return 0
+	}
+	if v, err := strconv.ParseFloat(env, 64); err == nil && v >= 0 && v <= 1 {
+		return v
+	}
+	log.Printf("Invalid BATCH_SUCCESS_THRESHOLD %q, using 0", env)
+	return 0
+}
+
 // Start initializes the worker pool
 func (aq *AlertQueue) Start() {
-	log.Printf("Starting alert queue with %d workers", aq.workers)
+	log.Printf("Starting alert queue with %d workers, capacity %d, retry capacity %d (overflow: %s), batch size %d, batch interval %s",
+		aq.workers, aq.capacity, cap(aq.retryQueue), aq.retryOverflow, aq.batchSize, aq.batchInterval)
+
+	// Reload alerts left pending by the previous run, and keep purging
+	// finished ones
+	if aq.db != nil {
+		aq.restore()
+		aq.wg.Add(1)
+		go aq.pruneLoop()
+	}
 
 	// Start regular workers
 	for i := 0; i < aq.workers; i++ {
@@ -97,20 +282,151 @@ func (aq *AlertQueue) Start() {
 	aq.wg.Add(1)
 	go aq.batchProcessor()
 
+	// Start stalled worker watchdog
+	aq.wg.Add(1)
+	go aq.watchdogLoop()
+
+	// Start releasing held alerts of resumed users
+	aq.wg.Add(1)
+	go aq.releaseLoop()
+
+	// Start queuing scheduled alerts once they're due
+	aq.wg.Add(1)
+	go aq.delayedLoop()
+
 	log.Println("Alert queue started successfully")
 }
 
-// Stop gracefully shuts down the queue
+// Stop gracefully shuts down the queue. The workers, the retry worker and
+// everything else that feeds the priority channels are stopped first; only
+// then are the channels closed, so a retry re-enqueued during shutdown can't
+// send on a closed channel.
 func (aq *AlertQueue) Stop() {
 	log.Println("Stopping alert queue...")
 	aq.cancel()
-	close(aq.queue)
 	aq.wg.Wait()
+
+	aq.stopMu.Lock()
+	aq.stopped = true
+	for _, q := range aq.queues {
+		close(q)
+	}
+	aq.stopMu.Unlock()
+
+	aq.saveUnprocessed()
 	log.Println("Alert queue stopped")
 }
 
+// Drain waits for the workers to finish the alerts already queued, up to
+// timeout, and reports whether the queue emptied. Call it before Stop, once
+// nothing else is enqueuing, so a shutdown doesn't drop queued alerts.
+// Alerts scheduled for later aren't waited for.
+func (aq *AlertQueue) Drain(timeout time.Duration) bool {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+
+	for {
+		pending := len(aq.batchQueue) + len(aq.retryQueue)
+		for _, q := range aq.queues {
+			pending += len(q)
+		}
+		busy, _, _ := aq.workerBusy()
+		if pending == 0 && busy == 0 {
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline:
+			log.Printf("Queue drain timed out with %d alerts queued and %d workers busy", pending, busy)
+			return false
+		}
+	}
+}
+
+// SetMetrics exports the queue's statistics through a Prometheus registry
+func (aq *AlertQueue) SetMetrics(prom *metrics.Prometheus) {
+	prom.RegisterQueue(aq.GetStats)
+}
+
+// SetEscalator sets the handler for urgent alerts that exhaust their retries
+func (aq *AlertQueue) SetEscalator(escalator Escalator) {
+	aq.mu.Lock()
+	defer aq.mu.Unlock()
+	aq.escalator = escalator
+}
+
+// SetFailureRecorder sets where alerts dropped by a full retry queue are
+// recorded
+func (aq *AlertQueue) SetFailureRecorder(failures FailureRecorder) {
+	aq.mu.Lock()
+	defer aq.mu.Unlock()
+	aq.failures = failures
+}
+
+// SetRedactor sets how alerts are redacted before being persisted, so the
+// queued_alerts table never holds content the user's redaction rules remove
+func (aq *AlertQueue) SetRedactor(redactor Redactor) {
+	aq.mu.Lock()
+	defer aq.mu.Unlock()
+	aq.redactor = redactor
+}
+
+// escalate hands a permanently failed urgent alert to the escalator
+func (aq *AlertQueue) escalate(alert *Alert, err error) {
+	aq.mu.RLock()
+	escalator := aq.escalator
+	aq.mu.RUnlock()
+
+	if escalator == nil || alert.Priority != 1 {
+		return
+	}
+
+	log.Printf("Escalating urgent alert %s after %d retries", alert.ID, alert.Retries)
+	escalator.Escalate(aq.ctx, alert, err)
+}
+
+// AddCompletionHook registers a hook called when alerts finish processing
+func (aq *AlertQueue) AddCompletionHook(hook CompletionHook) {
+	aq.mu.Lock()
+	defer aq.mu.Unlock()
+	aq.hooks = append(aq.hooks, hook)
+}
+
+// complete notifies completion hooks that an alert is finished
+func (aq *AlertQueue) complete(alert *Alert, err error) {
+	aq.mu.RLock()
+	hooks := aq.hooks
+	aq.mu.RUnlock()
+
+	aq.markFinished(alert, err)
+
+	for _, hook := range hooks {
+		hook(alert, err)
+	}
+}
+
 // Enqueue adds an alert to the queue
 func (aq *AlertQueue) Enqueue(alert *Alert) error {
+	if aq.paused.Load() {
+		return fmt.Errorf("queue is paused: all workers are stalled")
+	}
+
+	if time.Now().Before(alert.ScheduledAt) {
+		return aq.delay(alert)
+	}
+	return aq.push(alert)
+}
+
+// push adds an alert to the queue even while it is paused
+func (aq *AlertQueue) push(alert *Alert) error {
+	aq.stopMu.RLock()
+	defer aq.stopMu.RUnlock()
+	if aq.stopped {
+		return fmt.Errorf("queue is shutting down")
+	}
+
 	// Set defaults
 	if alert.CreatedAt.IsZero() {
 		alert.CreatedAt = time.Now()
@@ -118,33 +434,64 @@ func (aq *AlertQueue) Enqueue(alert *Alert) error {
 	if alert.ScheduledAt.IsZero() {
 		alert.ScheduledAt = time.Now()
 	}
-	if alert.MaxRetries == 0 {
-		alert.MaxRetries = 3
-	}
 	if alert.Priority == 0 {
 		alert.Priority = 3 // Default to normal priority
 	}
 
+	// Saved before sending so a worker cannot finish the alert first
+	aq.persist(alert)
+
 	select {
-	case aq.queue <- alert:
-		aq.updateCurrentSize(1)
+	case aq.queueFor(alert.Priority) <- alert:
+		aq.refreshCurrentSize()
 		return nil
 	case <-aq.ctx.Done():
 		return fmt.Errorf("queue is shutting down")
 	default:
-		return fmt.Errorf("queue is full")
+		err := fmt.Errorf("queue is full")
+		aq.markFinished(alert, err)
+		return err
 	}
 }
 
 // EnqueueBatch adds multiple alerts for batch processing
 func (aq *AlertQueue) EnqueueBatch(alerts []*Alert) error {
+	aq.stopMu.RLock()
+	defer aq.stopMu.RUnlock()
+	if aq.stopped {
+		return fmt.Errorf("queue is shutting down")
+	}
+
+	// Alerts scheduled for later are set aside and sent on their own once
+	// they're due
+	due := make([]*Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		aq.persist(alert)
+		if !time.Now().Before(alert.ScheduledAt) {
+			due = append(due, alert)
+			continue
+		}
+		if err := aq.addDelayed(alert); err != nil {
+			log.Printf("Failed to delay alert %s: %v", alert.ID, err)
+			aq.complete(alert, err)
+		}
+	}
+	if len(due) == 0 {
+		return nil
+	}
+	alerts = due
+
 	select {
 	case aq.batchQueue <- alerts:
 		return nil
 	case <-aq.ctx.Done():
 		return fmt.Errorf("queue is shutting down")
 	default:
-		return fmt.Errorf("batch queue is full")
+		err := fmt.Errorf("batch queue is full")
+		for _, alert := range alerts {
+			aq.markFinished(alert, err)
+		}
+		return err
 	}
 }
 
@@ -155,66 +502,144 @@ func (aq *AlertQueue) worker(id int) {
 	log.Printf("Worker %d started", id)
 
 	for {
-		select {
-		case alert, ok := <-aq.queue:
-			if !ok {
-				log.Printf("Worker %d stopping", id)
-				return
-			}
+		alert, ok := aq.next(aq.ctx)
+		if !ok {
+			log.Printf("Worker %d stopping", id)
+			return
+		}
+
+		aq.refreshCurrentSize()
+		aq.busySince[id].Store(time.Now().UnixNano())
+		aq.processAlert(alert, id)
+		aq.busySince[id].Store(0)
+	}
+}
 
-			aq.updateCurrentSize(-1)
-			aq.processAlert(alert, id)
+// queueFor returns the channel for a priority, treating out-of-range values
+// as the nearest valid priority
+func (aq *AlertQueue) queueFor(priority int) chan *Alert {
+	return aq.queues[min(max(priority, 1), priorityLevels)-1]
+}
 
-		case <-aq.ctx.Done():
-			log.Printf("Worker %d received shutdown signal", id)
-			return
+// next takes the next alert, preferring the most urgent priority with alerts
+// waiting and otherwise blocking until any priority has one. It returns false
+// once ctx is done or the queue is closed.
+func (aq *AlertQueue) next(ctx context.Context) (*Alert, bool) {
+	for _, q := range aq.queues {
+		select {
+		case alert, ok := <-q:
+			return alert, ok
+		default:
 		}
 	}
+
+	select {
+	case alert, ok := <-aq.queues[0]:
+		return alert, ok
+	case alert, ok := <-aq.queues[1]:
+		return alert, ok
+	case alert, ok := <-aq.queues[2]:
+		return alert, ok
+	case alert, ok := <-aq.queues[3]:
+		return alert, ok
+	case <-ctx.Done():
+		return nil, false
+	}
 }
 
 // processAlert handles individual alert processing
 func (aq *AlertQueue) processAlert(alert *Alert, workerID int) {
-	// Wait until scheduled time
+	// Alerts that aren't due yet go back to the delayed alerts rather than
+	// holding up the worker
 	if time.Now().Before(alert.ScheduledAt) {
-		time.Sleep(time.Until(alert.ScheduledAt))
+		if err := aq.delay(alert); err != nil {
+			log.Printf("Worker %d: Failed to delay alert %s: %v", workerID, alert.ID, err)
+			aq.complete(alert, err)
+		}
+		return
 	}
 
 	// Process the alert
 	err := aq.processor.ProcessAlert(aq.ctx, alert)
+	if errors.Is(err, ErrUserPaused) {
+		aq.hold(alert)
+		return
+	}
 	if err != nil {
 		log.Printf("Worker %d: Failed to process alert %s: %v", workerID, alert.ID, err)
 		aq.stats.IncrementFailed()
 
 		// Retry if possible
 		if alert.Retries < alert.MaxRetries {
-			aq.scheduleRetry(alert)
+			aq.scheduleRetry(alert, err)
 		} else {
 			log.Printf("Alert %s exceeded max retries (%d)", alert.ID, alert.MaxRetries)
+			aq.escalate(alert, err)
+			aq.complete(alert, err)
 		}
 	} else {
 		aq.stats.IncrementProcessed()
+		aq.complete(alert, nil)
 	}
 }
 
-// scheduleRetry schedules an alert for retry with exponential backoff
-func (aq *AlertQueue) scheduleRetry(alert *Alert) {
+// scheduleRetry schedules an alert for retry with exponential backoff, or
+// after the delay Telegram asked for when err is a rate limit response
+func (aq *AlertQueue) scheduleRetry(alert *Alert, err error) {
 	alert.Retries++
 	aq.stats.IncrementRetried()
 
-	// Exponential backoff: 2^retries seconds
-	backoffSeconds := 1 << alert.Retries // 2, 4, 8, 16...
-	alert.ScheduledAt = time.Now().Add(time.Duration(backoffSeconds) * time.Second)
+	// Exponential backoff: base, 2*base, 4*base... (2, 4, 8s by default)
+	base := alert.BackoffBase
+	if base <= 0 {
+		base = DefaultBackoffBase
+	}
+	backoff := base << min(alert.Retries-1, 20)
+	if alert.BackoffMax > 0 && backoff > alert.BackoffMax {
+		backoff = alert.BackoffMax
+	}
+	backoff = min(backoff, MaxBackoff)
+
+	var rateLimitErr *telegram.RateLimitError
+	if errors.As(err, &rateLimitErr) && rateLimitErr.RetryAfter > 0 {
+		backoff = min(rateLimitErr.RetryAfter, maxRateLimitWait)
+	}
+	alert.ScheduledAt = time.Now().Add(backoff)
 
-	log.Printf("Scheduling retry %d/%d for alert %s in %d seconds",
-		alert.Retries, alert.MaxRetries, alert.ID, backoffSeconds)
+	log.Printf("Scheduling retry %d/%d for alert %s in %s",
+		alert.Retries, alert.MaxRetries, alert.ID, backoff)
 
 	select {
 	case aq.retryQueue <- alert:
+		return
 	case <-aq.ctx.Done():
 		return
 	default:
-		log.Printf("Retry queue full, dropping alert %s", alert.ID)
 	}
+
+	if aq.retryOverflow == RetryOverflowBlock {
+		log.Printf("Retry queue full, waiting to schedule alert %s", alert.ID)
+		select {
+		case aq.retryQueue <- alert:
+		case <-aq.ctx.Done():
+		}
+		return
+	}
+
+	cause := fmt.Errorf("retry queue full")
+	if err != nil {
+		cause = fmt.Errorf("retry queue full, last error: %w", err)
+	}
+	log.Printf("Retry queue full, dropping alert %s", alert.ID)
+
+	aq.mu.RLock()
+	failures := aq.failures
+	aq.mu.RUnlock()
+	if failures != nil {
+		failures.RecordFailure(aq.ctx, alert, cause, aq.retryOverflow == RetryOverflowDeadLetter)
+	}
+
+	aq.complete(alert, cause)
 }
 
 // retryWorker handles retries
@@ -231,8 +656,10 @@ func (aq *AlertQueue) retryWorker() {
 				return
 			}
 
-			// Re-enqueue the alert
-			if err := aq.Enqueue(alert); err != nil {
+			// Re-enqueue the alert, setting it aside until its backoff
+			// has passed; retries already accepted are not turned away
+			// while the queue is paused
+			if err := aq.requeue(alert); err != nil {
 				log.Printf("Failed to re-enqueue alert %s: %v", alert.ID, err)
 			}
 
@@ -243,6 +670,15 @@ func (aq *AlertQueue) retryWorker() {
 	}
 }
 
+// requeue queues a retry, or adds it to the delayed alerts when it isn't due
+// yet
+func (aq *AlertQueue) requeue(alert *Alert) error {
+	if time.Now().Before(alert.ScheduledAt) {
+		return aq.delay(alert)
+	}
+	return aq.push(alert)
+}
+
 // batchProcessor handles batch processing
 func (aq *AlertQueue) batchProcessor() {
 	defer aq.wg.Done()
@@ -293,22 +729,62 @@ func (aq *AlertQueue) batchProcessor() {
 
 // processBatch processes a batch of alerts
 func (aq *AlertQueue) processBatch(alerts []*Alert) {
+	alerts = aq.holdPaused(alerts)
+	if len(alerts) == 0 {
+		return
+	}
+
 	log.Printf("Processing batch of %d alerts", len(alerts))
 
-	err := aq.processor.ProcessBatch(aq.ctx, alerts)
+	failed, err := aq.processor.ProcessBatch(aq.ctx, alerts)
 	if err != nil {
 		log.Printf("Batch processing failed: %v", err)
+	}
+
+	succeeded := len(alerts) - len(failed)
+	if succeeded > 0 {
+		aq.stats.AddBatched(int64(succeeded))
+		aq.stats.AddProcessed(int64(succeeded))
+	}
+
+	failedIDs := make(map[string]bool, len(failed))
+	for _, alert := range failed {
+		failedIDs[alert.ID] = true
+	}
+	for _, alert := range alerts {
+		if !failedIDs[alert.ID] {
+			aq.complete(alert, nil)
+		}
+	}
+
+	// A batch delivered below the success threshold points to a problem
+	// retrying won't fix, so its failures are final
+	var batchErr error
+	if len(failed) > 0 {
+		if rate := float64(succeeded) / float64(len(alerts)); rate < aq.batchSuccessThreshold {
+			batchErr = fmt.Errorf("batch success rate %.0f%% below threshold %.0f%%",
+				rate*100, aq.batchSuccessThreshold*100)
+			log.Printf("Not retrying %d failed alerts: %v", len(failed), batchErr)
+		}
+	}
+
+	// Only retry the alerts that actually failed; the rest were already
+	// delivered and re-enqueuing them would send duplicates
+	for _, alert := range failed {
 		aq.stats.IncrementFailed()
 
-		// Fall back to individual processing
-		for _, alert := range alerts {
-			if err := aq.Enqueue(alert); err != nil {
-				log.Printf("Failed to re-enqueue alert from batch: %v", err)
-			}
+		switch {
+		case batchErr != nil:
+			aq.escalate(alert, batchErr)
+			aq.complete(alert, batchErr)
+		case alert.Retries < alert.MaxRetries:
+			aq.scheduleRetry(alert, nil)
+		default:
+			log.Printf("Alert %s exceeded max retries (%d)", alert.ID, alert.MaxRetries)
+			err := fmt.Errorf("exceeded max retries")
+			aq.escalate(alert, err)
+			aq.complete(alert, err)
 		}
-	} else {
-		aq.stats.AddBatched(int64(len(alerts)))
-		aq.stats.AddProcessed(int64(len(alerts)))
 	}
 }
 
@@ -317,23 +793,48 @@ func (aq *AlertQueue) GetStats() models.QueueStats {
 	aq.stats.mu.RLock()
 	defer aq.stats.mu.RUnlock()
 
+	busy, _, longest := aq.workerBusy()
+
 	return models.QueueStats{
-		Processed:   aq.stats.Processed,
-		Failed:      aq.stats.Failed,
-		Retried:     aq.stats.Retried,
-		Batched:     aq.stats.Batched,
-		CurrentSize: aq.stats.CurrentSize,
+		Processed:          aq.stats.Processed,
+		Failed:             aq.stats.Failed,
+		Retried:            aq.stats.Retried,
+		Batched:            aq.stats.Batched,
+		CurrentSize:        aq.queuedCount(),
+		UrgentSize:         len(aq.queues[0]),
+		HighSize:           len(aq.queues[1]),
+		NormalSize:         len(aq.queues[2]),
+		LowSize:            len(aq.queues[3]),
+		Held:               aq.heldCount(),
+		Delayed:            aq.delayedCount(),
+		FillPercent:        aq.fillPercent(),
+		CapacityWarning:    aq.capacityWarningLevel(),
+		BusyWorkers:        busy,
+		LongestBusySeconds: longest.Seconds(),
+		Stalled:            aq.stalled.Load(),
+		Paused:             aq.paused.Load(),
+		EmergencyWorkers:   int(aq.emergencyCount.Load()),
 	}
 }
 
-// updateCurrentSize updates the current queue size
-func (aq *AlertQueue) updateCurrentSize(delta int) {
+// queuedCount returns how many alerts wait in the priority queues
+func (aq *AlertQueue) queuedCount() int {
+	queued := 0
+	for _, q := range aq.queues {
+		queued += len(q)
+	}
+	return queued
+}
+
+// refreshCurrentSize records the current queue size after alerts are added
+// or taken. It is read from the channels rather than counted, so a worker
+// taking an alert before its sender records it can't make the size drift.
+func (aq *AlertQueue) refreshCurrentSize() {
 	aq.stats.mu.Lock()
 	defer aq.stats.mu.Unlock()
-	aq.stats.CurrentSize += delta
-	if aq.stats.CurrentSize < 0 {
-		aq.stats.CurrentSize = 0
-	}
+	aq.stats.CurrentSize = aq.queuedCount()
+	metrics.Gauge("queue.current_size", int64(aq.stats.CurrentSize))
+	aq.checkCapacity()
 }
 
 // Stats methods
@@ -341,28 +842,33 @@ func (qs *QueueStats) IncrementProcessed() {
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	qs.Processed++
+	metrics.Count("queue.processed", 1)
 }
 
 func (qs *QueueStats) IncrementFailed() {
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	qs.Failed++
+	metrics.Count("queue.failed", 1)
 }
 
 func (qs *QueueStats) IncrementRetried() {
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	qs.Retried++
+	metrics.Count("queue.retried", 1)
 }
 
 func (qs *QueueStats) AddBatched(count int64) {
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	qs.Batched += count
+	metrics.Count("queue.batched", count)
 }
 
 func (qs *QueueStats) AddProcessed(count int64) {
 	qs.mu.Lock()
 	defer qs.mu.Unlock()
 	qs.Processed += count
+	metrics.Count("queue.processed", count)
 }
@@ -0,0 +1,384 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/thenaveensharma/telehook/internal/telegram"
+)
+
+// flakyProcessor runs alerts through a rule engine like TelegramProcessor
+// does, then fails the first failures sends that get past the rules
+type flakyProcessor struct {
+	rules    *RuleEngine
+	failures int
+
+	mu       sync.Mutex
+	attempts int
+}
+
+func (p *flakyProcessor) ProcessAlert(ctx context.Context, alert *Alert) error {
+	if allowed, _ := p.rules.ProcessAlert(alert); !allowed {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts++
+	if p.attempts <= p.failures {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func (p *flakyProcessor) ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error) {
+	return nil, nil
+}
+
+func (p *flakyProcessor) sends() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.attempts
+}
+
+// completions registers a hook on aq and returns a channel receiving the
+// error each alert finished with
+func completions(aq *AlertQueue) <-chan error {
+	done := make(chan error, 100)
+	aq.AddCompletionHook(func(alert *Alert, err error) {
+		done <- err
+	})
+	return done
+}
+
+func TestRetryIsNotFilteredAsDuplicate(t *testing.T) {
+	proc := &flakyProcessor{rules: NewRuleEngine(30 * time.Second), failures: 1}
+	aq := NewAlertQueue(1, 10, proc)
+	done := completions(aq)
+	aq.Start()
+	defer aq.Stop()
+
+	err := aq.Enqueue(&Alert{
+		ID:          "flaky",
+		UserID:      1,
+		Payload:     map[string]interface{}{"message": "disk full"},
+		MaxRetries:  3,
+		BackoffBase: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("alert finished with error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("alert did not finish")
+	}
+
+	if got := proc.sends(); got != 2 {
+		t.Fatalf("got %d sends, want the failed one and its retry", got)
+	}
+}
+
+// failingProcessor fails every alert, keeping the retry path busy
+type failingProcessor struct{}
+
+func (failingProcessor) ProcessAlert(ctx context.Context, alert *Alert) error {
+	return errors.New("send failed")
+}
+
+func (failingProcessor) ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error) {
+	return alerts, errors.New("send failed")
+}
+
+// Run with -race: Stop closes the priority channels while producers and the
+// retry worker may still be sending on them
+func TestStopDuringEnqueueAndRetries(t *testing.T) {
+	aq := NewAlertQueue(4, 50, failingProcessor{})
+	aq.Start()
+
+	stop := make(chan struct{})
+	var producers sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		producers.Add(1)
+		go func() {
+			defer producers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = aq.Enqueue(&Alert{
+					UserID:      1,
+					Payload:     map[string]interface{}{"message": "disk full"},
+					MaxRetries:  100,
+					BackoffBase: time.Millisecond,
+				})
+			}
+		}()
+	}
+
+	// Let retries pile up before stopping underneath the producers
+	time.Sleep(50 * time.Millisecond)
+	aq.Stop()
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	producers.Wait()
+
+	if err := aq.Enqueue(&Alert{UserID: 1}); err == nil {
+		t.Fatal("Enqueue after Stop succeeded")
+	}
+}
+
+// firstAttemptFails fails each alert's first attempt and accepts its retry
+type firstAttemptFails struct{}
+
+func (firstAttemptFails) ProcessAlert(ctx context.Context, alert *Alert) error {
+	if alert.Retries == 0 {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func (firstAttemptFails) ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error) {
+	return nil, nil
+}
+
+func TestCurrentSizeReturnsToZeroAfterRetries(t *testing.T) {
+	const burst = 40
+	aq := NewAlertQueue(4, 2*burst, firstAttemptFails{})
+	done := completions(aq)
+	aq.Start()
+	defer aq.Stop()
+
+	for i := 0; i < burst; i++ {
+		err := aq.Enqueue(&Alert{
+			UserID:      1,
+			Priority:    i%priorityLevels + 1,
+			MaxRetries:  1,
+			BackoffBase: time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("Enqueue %d: %v", i, err)
+		}
+	}
+
+	for i := 0; i < burst; i++ {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("alert finished with error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d alerts finished", i, burst)
+		}
+	}
+
+	if got := aq.GetStats(); got.CurrentSize != 0 || got.Retried != burst {
+		t.Fatalf("got current size %d after %d retries, want 0 after %d", got.CurrentSize, got.Retried, burst)
+	}
+	aq.stats.mu.RLock()
+	recorded := aq.stats.CurrentSize
+	aq.stats.mu.RUnlock()
+	if recorded != 0 {
+		t.Fatalf("recorded current size is %d, want 0", recorded)
+	}
+}
+
+// failsAlert fails every attempt at the alert with the given ID
+type failsAlert string
+
+func (id failsAlert) ProcessAlert(ctx context.Context, alert *Alert) error {
+	if alert.ID == string(id) {
+		return errors.New("send failed")
+	}
+	return nil
+}
+
+func (failsAlert) ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error) {
+	return nil, nil
+}
+
+func TestLongBackoffDoesNotHoldWorkers(t *testing.T) {
+	aq := NewAlertQueue(1, 10, failsAlert("failing"))
+	done := completions(aq)
+	aq.Start()
+
+	err := aq.Enqueue(&Alert{
+		ID:          "failing",
+		UserID:      1,
+		MaxRetries:  3,
+		BackoffBase: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for aq.GetStats().Delayed != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("retry was not set aside until its backoff passed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The only worker must still be free for other alerts
+	if err := aq.Enqueue(&Alert{ID: "other", UserID: 2}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("alert finished with error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("alert queued behind a retry's backoff did not finish")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		aq.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop waited for a retry's backoff")
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	aq := NewAlertQueue(1, 10, failingProcessor{})
+	alert := &Alert{ID: "failing", MaxRetries: 30, BackoffBase: time.Hour, Retries: 10}
+
+	aq.scheduleRetry(alert, errors.New("send failed"))
+	if wait := time.Until(alert.ScheduledAt); wait > MaxBackoff {
+		t.Fatalf("retry scheduled in %s, want at most %s", wait, MaxBackoff)
+	}
+}
+
+// rateLimited fails every alert with Telegram's rate limit response
+type rateLimited time.Duration
+
+func (wait rateLimited) ProcessAlert(ctx context.Context, alert *Alert) error {
+	return &telegram.RateLimitError{RetryAfter: time.Duration(wait)}
+}
+
+func (rateLimited) ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error) {
+	return alerts, errors.New("send failed")
+}
+
+func TestRateLimitRetryAfterIsClamped(t *testing.T) {
+	tests := []struct {
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{30 * time.Second, 30 * time.Second},
+		{48 * time.Hour, maxRateLimitWait},
+	}
+
+	for _, tt := range tests {
+		aq := NewAlertQueue(1, 10, rateLimited(tt.retryAfter))
+		aq.Start()
+
+		err := aq.Enqueue(&Alert{ID: "limited", UserID: 1, MaxRetries: 3})
+		if err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+
+		deadline := time.Now().Add(5 * time.Second)
+		for aq.delayedCount() != 1 {
+			if time.Now().After(deadline) {
+				t.Fatal("rate limited retry was not set aside until it was due")
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		aq.delayedMu.Lock()
+		wait := time.Until(aq.delayed[0].ScheduledAt)
+		aq.delayedMu.Unlock()
+		aq.Stop()
+
+		if wait > tt.want || wait < tt.want-5*time.Second {
+			t.Errorf("retry_after %s: retry scheduled in %s, want %s", tt.retryAfter, wait, tt.want)
+		}
+	}
+}
+
+// failsInBatch fails the batched alerts whose IDs it lists
+type failsInBatch map[string]bool
+
+func (failsInBatch) ProcessAlert(ctx context.Context, alert *Alert) error {
+	return nil
+}
+
+func (ids failsInBatch) ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error) {
+	var failed []*Alert
+	for _, alert := range alerts {
+		if ids[alert.ID] {
+			failed = append(failed, alert)
+		}
+	}
+	return failed, nil
+}
+
+func TestBatchSuccessThreshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		failing failsInBatch
+		retried int64
+	}{
+		{"above threshold", failsInBatch{"a": true}, 1},
+		{"below threshold", failsInBatch{"a": true, "b": true, "c": true}, 0},
+	}
+
+	for _, tt := range tests {
+		aq := NewAlertQueue(1, 10, tt.failing)
+		aq.batchSize = 4
+		aq.batchSuccessThreshold = 0.5
+		done := completions(aq)
+		aq.Start()
+
+		var batch []*Alert
+		for _, id := range []string{"a", "b", "c", "d"} {
+			batch = append(batch, &Alert{ID: id, UserID: 1, MaxRetries: 3, BackoffBase: time.Hour})
+		}
+		if err := aq.EnqueueBatch(batch); err != nil {
+			t.Fatalf("%s: EnqueueBatch: %v", tt.name, err)
+		}
+
+		// Delivered alerts finish, and failed ones too unless they're retried
+		want := len(batch) - int(tt.retried)
+		failed := 0
+		for i := 0; i < want; i++ {
+			select {
+			case err := <-done:
+				if err != nil {
+					failed++
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s: only %d of %d alerts finished", tt.name, i, want)
+			}
+		}
+		// Retries are scheduled after the delivered alerts finish
+		deadline := time.Now().Add(time.Second)
+		for aq.GetStats().Retried < tt.retried && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		stats := aq.GetStats()
+		aq.Stop()
+
+		if stats.Retried != tt.retried {
+			t.Errorf("%s: %d alerts retried, want %d", tt.name, stats.Retried, tt.retried)
+		}
+		if wantFailed := len(tt.failing) - int(tt.retried); failed != wantFailed {
+			t.Errorf("%s: %d alerts failed outright, want %d", tt.name, failed, wantFailed)
+		}
+	}
+}
@@ -3,8 +3,13 @@ package queue
 import (
 	"crypto/sha256"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 // AlertRule defines a rule for processing alerts
@@ -21,28 +26,37 @@ type RuleEngine struct {
 	rules            []*AlertRule
 	deduplication    *DeduplicationCache
 	throttle         *ThrottleManager
+	userRules        *userRuleCache // Users' own rules, applied after the global ones
+	quietHours       *quietHoursCache
 	mu               sync.RWMutex
 }
 
-// DeduplicationCache tracks seen alerts to prevent duplicates
+// DeduplicationCache tracks seen alerts to prevent duplicates. Entries hold
+// the time their dedup window ends, since alerts may carry their own window.
 type DeduplicationCache struct {
 	cache  map[string]time.Time
-	window time.Duration
+	window time.Duration // Default window for alerts without their own
 	mu     sync.RWMutex
 }
 
-// ThrottleManager tracks alert rates per user
+// ThrottleManager rate limits alerts per user and priority with token
+// buckets: a burst of up to Burst alerts is accepted at once, then alerts
+// are admitted at the sustained PerMinute rate as the bucket refills
 type ThrottleManager struct {
-	counters map[int]*ThrottleCounter // userID -> counter
-	mu       sync.RWMutex
+	buckets map[throttleKey]*rate.Limiter
+	limits  map[int]ThrottleLimit // priority -> limit
+	mu      sync.RWMutex
+}
+
+type throttleKey struct {
+	userID   int
+	priority int
 }
 
-// ThrottleCounter tracks alerts for a specific user
-type ThrottleCounter struct {
-	count      int
-	windowEnd  time.Time
-	maxPerWindow int
-	mu         sync.Mutex
+// ThrottleLimit configures the token bucket for one priority
+type ThrottleLimit struct {
+	PerMinute int // Sustained refill rate
+	Burst     int // Bucket capacity
 }
 
 // NewRuleEngine creates a new rule engine
@@ -68,6 +82,19 @@ func (re *RuleEngine) AddRule(rule *AlertRule) {
 
 // ProcessAlert applies all rules to an alert
 func (re *RuleEngine) ProcessAlert(alert *Alert) (bool, string) {
+	// Quiet hours come first so suppressed alerts aren't recorded for
+	// deduplication or counted against the throttle
+	if re.quietHours != nil && re.quietHours.Suppresses(alert, time.Now()) {
+		return false, "quiet hours"
+	}
+
+	// Retries passed the rules on their first attempt, which also recorded
+	// them for deduplication and throttling and applied the user's
+	// transforms; checking again would filter a retry as a duplicate of itself
+	if alert.Retries > 0 {
+		return true, ""
+	}
+
 	// Check deduplication first
 	if re.deduplication.IsDuplicate(alert) {
 		return false, "duplicate alert filtered"
@@ -92,11 +119,74 @@ func (re *RuleEngine) ProcessAlert(alert *Alert) (bool, string) {
 		}
 	}
 
+	if re.userRules != nil {
+		if allowed, reason, _ := re.userRules.Apply(alert, true); !allowed {
+			return false, reason
+		}
+	}
+
 	return true, ""
 }
 
+// Evaluate reports whether an alert would pass the rules, without recording
+// it for deduplication or counting it against the throttle. It also returns
+// the names of the enabled rules that were checked.
+func (re *RuleEngine) Evaluate(alert *Alert) (bool, string, []string) {
+	re.mu.RLock()
+	defer re.mu.RUnlock()
+
+	checked := make([]string, 0, len(re.rules)+3)
+
+	if re.quietHours != nil {
+		checked = append(checked, "Quiet Hours")
+		if re.quietHours.Suppresses(alert, time.Now()) {
+			return false, "quiet hours", checked
+		}
+	}
+
+	checked = append(checked, "Deduplication")
+	if re.deduplication.Seen(alert) {
+		return false, "duplicate alert filtered", checked
+	}
+
+	checked = append(checked, "Priority Throttle")
+	if !re.throttle.WouldAllow(alert.UserID, alert.Priority) {
+		return false, "rate limit exceeded", checked
+	}
+
+	for _, rule := range re.rules {
+		if !rule.Enabled {
+			continue
+		}
+
+		checked = append(checked, rule.Name)
+		if rule.FilterFunc != nil && !rule.FilterFunc(alert) {
+			return false, fmt.Sprintf("filtered by rule: %s", rule.Name), checked
+		}
+	}
+
+	if re.userRules != nil {
+		allowed, reason, userChecked := re.userRules.Apply(alert, false)
+		checked = append(checked, userChecked...)
+		if !allowed {
+			return false, reason, checked
+		}
+	}
+
+	return true, "", checked
+}
+
 // DeduplicationCache methods
 
+// DedupWindowFromEnv reads the server's default deduplication window from
+// DEDUPE_WINDOW_SECONDS (default 30); 0 disables deduplication by default
+func DedupWindowFromEnv() time.Duration {
+	if v, err := strconv.Atoi(os.Getenv("DEDUPE_WINDOW_SECONDS")); err == nil && v >= 0 {
+		return time.Duration(v) * time.Second
+	}
+	return 30 * time.Second
+}
+
 // NewDeduplicationCache creates a new deduplication cache
 func NewDeduplicationCache(window time.Duration) *DeduplicationCache {
 	return &DeduplicationCache{
@@ -107,30 +197,67 @@ func NewDeduplicationCache(window time.Duration) *DeduplicationCache {
 
 // IsDuplicate checks if an alert is a duplicate
 func (dc *DeduplicationCache) IsDuplicate(alert *Alert) bool {
+	window := dc.windowFor(alert)
+	if window <= 0 {
+		return false
+	}
+
 	key := dc.generateKey(alert)
 
 	dc.mu.Lock()
 	defer dc.mu.Unlock()
 
-	if lastSeen, exists := dc.cache[key]; exists {
-		if time.Since(lastSeen) < dc.window {
-			return true
-		}
+	now := time.Now()
+	if expires, exists := dc.cache[key]; exists && now.Before(expires) {
+		return true
 	}
 
-	dc.cache[key] = time.Now()
+	dc.cache[key] = now.Add(window)
 	return false
 }
 
+// Seen reports whether an alert would be a duplicate, without recording it
+func (dc *DeduplicationCache) Seen(alert *Alert) bool {
+	if dc.windowFor(alert) <= 0 {
+		return false
+	}
+
+	key := dc.generateKey(alert)
+
+	dc.mu.RLock()
+	defer dc.mu.RUnlock()
+
+	expires, exists := dc.cache[key]
+	return exists && time.Now().Before(expires)
+}
+
+// windowFor returns the alert's own dedup window, resolved from its channel
+// or user settings, or the cache default
+func (dc *DeduplicationCache) windowFor(alert *Alert) time.Duration {
+	if alert.DedupWindow != nil {
+		return *alert.DedupWindow
+	}
+	return dc.window
+}
+
 // generateKey creates a unique key for an alert
 func (dc *DeduplicationCache) generateKey(alert *Alert) string {
-	// Create hash based on user and message content
+	// Create hash based on user, channel and message content, so the same
+	// message fanned out to several channels isn't dropped as a duplicate
+	// unless the user asked for deduplication across channels
 	message := ""
 	if msg, ok := alert.Payload["message"].(string); ok {
 		message = msg
 	}
 
-	data := fmt.Sprintf("%d:%s", alert.UserID, message)
+	// The Telegram chat ID covers destinations without a database channel,
+	// such as the legacy env-configured bot
+	dbChannelID, chatID := alert.DBChannelID, alert.ChannelID
+	if alert.DedupAcrossChannels {
+		dbChannelID, chatID = 0, ""
+	}
+
+	data := fmt.Sprintf("%d:%d:%s:%s", alert.UserID, dbChannelID, chatID, message)
 	hash := sha256.Sum256([]byte(data))
 	return fmt.Sprintf("%x", hash[:16]) // Use first 16 bytes
 }
@@ -143,8 +270,8 @@ func (dc *DeduplicationCache) cleanup() {
 	for range ticker.C {
 		dc.mu.Lock()
 		now := time.Now()
-		for key, lastSeen := range dc.cache {
-			if now.Sub(lastSeen) > dc.window {
+		for key, expires := range dc.cache {
+			if now.After(expires) {
 				delete(dc.cache, key)
 			}
 		}
@@ -154,68 +281,75 @@ func (dc *DeduplicationCache) cleanup() {
 
 // ThrottleManager methods
 
-// NewThrottleManager creates a new throttle manager
+// NewThrottleManager creates a new throttle manager. Limits default to the
+// sustained rates below with an equal burst, overridable per priority with
+// THROTTLE_RATE_P<n> (alerts per minute) and THROTTLE_BURST_P<n>.
 func NewThrottleManager() *ThrottleManager {
+	defaults := map[int]int{
+		1: 100, // Urgent
+		2: 60,  // High
+		3: 30,  // Normal
+		4: 10,  // Low
+	}
+
+	limits := make(map[int]ThrottleLimit, len(defaults))
+	for priority, perMinute := range defaults {
+		limit := ThrottleLimit{PerMinute: perMinute, Burst: perMinute}
+		if v, err := strconv.Atoi(os.Getenv(fmt.Sprintf("THROTTLE_RATE_P%d", priority))); err == nil && v > 0 {
+			limit.PerMinute = v
+		}
+		if v, err := strconv.Atoi(os.Getenv(fmt.Sprintf("THROTTLE_BURST_P%d", priority))); err == nil && v > 0 {
+			limit.Burst = v
+		}
+		limits[priority] = limit
+	}
+
 	return &ThrottleManager{
-		counters: make(map[int]*ThrottleCounter),
+		buckets: make(map[throttleKey]*rate.Limiter),
+		limits:  limits,
 	}
 }
 
 // AllowAlert checks if an alert is allowed based on rate limits
 func (tm *ThrottleManager) AllowAlert(userID int, priority int) bool {
-	tm.mu.Lock()
-	counter, exists := tm.counters[userID]
-	if !exists {
-		counter = &ThrottleCounter{
-			count:        0,
-			windowEnd:    time.Now().Add(1 * time.Minute),
-			maxPerWindow: tm.getMaxForPriority(priority),
-		}
-		tm.counters[userID] = counter
-	}
-	tm.mu.Unlock()
-
-	return counter.increment()
+	return tm.bucket(userID, priority).Allow()
 }
 
-// getMaxForPriority returns max alerts per minute based on priority
-func (tm *ThrottleManager) getMaxForPriority(priority int) int {
-	switch priority {
-	case 1: // Urgent
-		return 100
-	case 2: // High
-		return 60
-	case 3: // Normal
-		return 30
-	case 4: // Low
-		return 10
-	default:
-		return 30
-	}
+// WouldAllow reports whether an alert would be allowed, without counting it
+func (tm *ThrottleManager) WouldAllow(userID int, priority int) bool {
+	return tm.bucket(userID, priority).Tokens() >= 1
 }
 
-// ThrottleCounter methods
+// bucket returns the token bucket for a user and priority, creating it full
+func (tm *ThrottleManager) bucket(userID int, priority int) *rate.Limiter {
+	key := throttleKey{userID: userID, priority: priority}
 
-// increment increments the counter and checks limit
-func (tc *ThrottleCounter) increment() bool {
-	tc.mu.Lock()
-	defer tc.mu.Unlock()
+	tm.mu.RLock()
+	limiter, exists := tm.buckets[key]
+	tm.mu.RUnlock()
+	if exists {
+		return limiter
+	}
 
-	now := time.Now()
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 
-	// Reset if window expired
-	if now.After(tc.windowEnd) {
-		tc.count = 0
-		tc.windowEnd = now.Add(1 * time.Minute)
+	if limiter, exists := tm.buckets[key]; exists {
+		return limiter
 	}
 
-	// Check if limit exceeded
-	if tc.count >= tc.maxPerWindow {
-		return false
-	}
+	limit := tm.getLimitForPriority(priority)
+	limiter = rate.NewLimiter(rate.Limit(float64(limit.PerMinute)/60), limit.Burst)
+	tm.buckets[key] = limiter
+	return limiter
+}
 
-	tc.count++
-	return true
+// getLimitForPriority returns the bucket configuration for a priority
+func (tm *ThrottleManager) getLimitForPriority(priority int) ThrottleLimit {
+	if limit, exists := tm.limits[priority]; exists {
+		return limit
+	}
+	return tm.limits[3] // Normal
 }
 
 // DefaultRules returns a set of default alert rules
@@ -249,8 +383,7 @@ func DefaultRules() []*AlertRule {
 	}
 }
 
-// Helper function to check if string contains substring (case-insensitive)
+// contains reports whether s contains substr, ignoring case
 func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr ||
-		len(s) > len(substr) && (s[:len(substr)] == substr || contains(s[1:], substr)))
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
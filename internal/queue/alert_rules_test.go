@@ -0,0 +1,129 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestContains(t *testing.T) {
+	tests := []struct {
+		s, substr string
+		want      bool
+	}{
+		{"Win the LOTTERY today", "lottery", true},
+		{"win the lottery today", "LoTtErY", true},
+		{"visit our casino", "casino", true},
+		{"Visit our CASINO", "casino", true},
+		{"casinos", "casino", true},
+		{"cas ino", "casino", false},
+		{"", "casino", false},
+		{"anything", "", true},
+	}
+
+	for _, tt := range tests {
+		if got := contains(tt.s, tt.substr); got != tt.want {
+			t.Errorf("contains(%q, %q) = %v, want %v", tt.s, tt.substr, got, tt.want)
+		}
+	}
+}
+
+func TestSpamKeywordRule(t *testing.T) {
+	var spam *AlertRule
+	for _, rule := range DefaultRules() {
+		if rule.Name == "Block Spam Keywords" {
+			spam = rule
+		}
+	}
+	if spam == nil {
+		t.Fatal("Block Spam Keywords rule not found")
+	}
+
+	tests := []struct {
+		message string
+		allowed bool
+	}{
+		{"Disk usage at 91% on db-1", true},
+		{"Cheap VIAGRA", false},
+		{"the prize is in the Lottery", false},
+		{"viagra and casino and lottery", false},
+		{"Casino night, then the lottery", false},
+	}
+
+	for _, tt := range tests {
+		alert := &Alert{Payload: map[string]interface{}{"message": tt.message}}
+		if got := spam.FilterFunc(alert); got != tt.allowed {
+			t.Errorf("message %q allowed = %v, want %v", tt.message, got, tt.allowed)
+		}
+	}
+}
+
+func TestSameMessageToTwoChannelsIsNotDuplicate(t *testing.T) {
+	re := NewRuleEngine(30 * time.Second)
+	alertTo := func(dbChannelID int, chatID string, acrossChannels bool) *Alert {
+		return &Alert{
+			UserID:              1,
+			Priority:            3,
+			Payload:             map[string]interface{}{"message": "disk full"},
+			DBChannelID:         dbChannelID,
+			ChannelID:           chatID,
+			DedupAcrossChannels: acrossChannels,
+		}
+	}
+
+	for _, alert := range []*Alert{alertTo(10, "@ops", false), alertTo(11, "@oncall", false)} {
+		if allowed, reason := re.ProcessAlert(alert); !allowed {
+			t.Fatalf("alert to %s blocked: %s", alert.ChannelID, reason)
+		}
+	}
+
+	if allowed, _ := re.ProcessAlert(alertTo(10, "@ops", false)); allowed {
+		t.Fatal("repeat to the same channel was not deduplicated")
+	}
+
+	// Legacy alerts have no database channel and differ only by chat
+	if allowed, reason := re.ProcessAlert(alertTo(0, "-1001", false)); !allowed {
+		t.Fatalf("legacy alert blocked: %s", reason)
+	}
+	if allowed, reason := re.ProcessAlert(alertTo(0, "-1002", false)); !allowed {
+		t.Fatalf("legacy alert to a second chat blocked: %s", reason)
+	}
+
+	if allowed, reason := re.ProcessAlert(alertTo(20, "@a", true)); !allowed {
+		t.Fatalf("first cross-channel alert blocked: %s", reason)
+	}
+	if allowed, _ := re.ProcessAlert(alertTo(21, "@b", true)); allowed {
+		t.Fatal("cross-channel dedup let the same message through to a second channel")
+	}
+}
+
+func TestSameMessageToTwoChannelsIsDeliveredTwice(t *testing.T) {
+	proc := &flakyProcessor{rules: NewRuleEngine(30 * time.Second)}
+	aq := NewAlertQueue(1, 10, proc)
+	done := completions(aq)
+	aq.Start()
+	defer aq.Stop()
+
+	for i, chatID := range []string{"@ops", "@oncall"} {
+		err := aq.Enqueue(&Alert{
+			UserID:      1,
+			Payload:     map[string]interface{}{"message": "disk full"},
+			DBChannelID: i + 1,
+			ChannelID:   chatID,
+		})
+		if err != nil {
+			t.Fatalf("Enqueue to %s: %v", chatID, err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("alert did not finish")
+		}
+	}
+
+	if got := proc.sends(); got != 2 {
+		t.Fatalf("got %d sends, want one per channel", got)
+	}
+}
@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"sync"
+
+	"github.com/thenaveensharma/telehook/internal/telegram"
+)
+
+// botCacheSize bounds how many bot/channel pairs the processor keeps
+const botCacheSize = 1000
+
+// botCache keeps a telegram.Bot per bot token and channel so repeated alerts
+// to the same destination reuse it instead of building one per alert. It is
+// safe for concurrent use by the queue workers.
+type botCache struct {
+	bots    map[string]*telegram.Bot
+	maxSize int
+	mu      sync.Mutex
+}
+
+func newBotCache(maxSize int) *botCache {
+	return &botCache{bots: make(map[string]*telegram.Bot), maxSize: maxSize}
+}
+
+// Get returns the cached bot for token and channelID, creating it on first use
+func (bc *botCache) Get(token, channelID string) (*telegram.Bot, error) {
+	key := token + "|" + channelID
+
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+
+	if bot, ok := bc.bots[key]; ok {
+		return bot, nil
+	}
+
+	bot, err := telegram.NewBotWithToken(token, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Start over rather than track recency; the bots are cheap to rebuild
+	if len(bc.bots) >= bc.maxSize {
+		clear(bc.bots)
+	}
+	bc.bots[key] = bot
+	return bot, nil
+}
@@ -0,0 +1,124 @@
+package queue
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/thenaveensharma/telehook/internal/metrics"
+)
+
+// capacityHysteresis is how far (in percentage points) the queue must drain
+// below a threshold before that threshold can fire again, so a queue hovering
+// around a threshold doesn't flood the logs
+const capacityHysteresis = 5.0
+
+// capacityWarnings tracks which high-water mark the queue has crossed.
+// Fill is measured on the fullest priority queue, since a full priority
+// queue is what starts rejecting alerts.
+type capacityWarnings struct {
+	thresholds []float64 // Percentages, ascending
+	level      int       // Number of thresholds currently crossed
+	notify     func(message string)
+}
+
+// newCapacityWarnings reads QUEUE_WARNING_THRESHOLDS, a comma-separated list
+// of fill percentages (default "70,90"). "off" disables warnings.
+func newCapacityWarnings() *capacityWarnings {
+	env := strings.TrimSpace(os.Getenv("QUEUE_WARNING_THRESHOLDS"))
+	if env == "" {
+		env = "70,90"
+	}
+	if env == "off" {
+		return &capacityWarnings{}
+	}
+
+	var thresholds []float64
+	for _, field := range strings.Split(env, ",") {
+		pct, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(field), "%")), 64)
+		if err != nil || pct <= 0 || pct > 100 {
+			log.Printf("Ignoring invalid QUEUE_WARNING_THRESHOLDS entry %q", field)
+			continue
+		}
+		thresholds = append(thresholds, pct)
+	}
+	sort.Float64s(thresholds)
+
+	return &capacityWarnings{thresholds: thresholds}
+}
+
+// SetCapacityNotifier sends a message when the queue crosses a warning
+// threshold or drains below the first one, e.g. to an admin channel
+func (aq *AlertQueue) SetCapacityNotifier(notify func(message string)) {
+	aq.stats.mu.Lock()
+	defer aq.stats.mu.Unlock()
+	aq.capacityWarnings.notify = notify
+}
+
+// fillPercent reports how full the fullest priority queue is
+func (aq *AlertQueue) fillPercent() float64 {
+	if aq.capacity <= 0 {
+		return 0
+	}
+	fullest := 0
+	for i := range aq.queues {
+		if n := len(aq.queues[i]); n > fullest {
+			fullest = n
+		}
+	}
+	return float64(fullest) * 100 / float64(aq.capacity)
+}
+
+// checkCapacity warns when the queue crosses a threshold on the way up and
+// logs recovery once it drains below the first one. Callers must hold
+// aq.stats.mu.
+func (aq *AlertQueue) checkCapacity() {
+	cw := aq.capacityWarnings
+	if len(cw.thresholds) == 0 {
+		return
+	}
+
+	fill := aq.fillPercent()
+
+	level := cw.level
+	for level < len(cw.thresholds) && fill >= cw.thresholds[level] {
+		level++
+	}
+	for level > 0 && fill < cw.thresholds[level-1]-capacityHysteresis {
+		level--
+	}
+	if level == cw.level {
+		return
+	}
+
+	var message string
+	if level > cw.level {
+		threshold := cw.thresholds[level-1]
+		message = fmt.Sprintf("Alert queue is %.0f%% full (%d queued, warning threshold %.0f%%); alerts will be rejected once a priority queue is full",
+			fill, aq.stats.CurrentSize, threshold)
+		log.Printf("WARNING: %s", message)
+		metrics.Count("queue.capacity_warning", 1)
+	} else if level == 0 {
+		message = fmt.Sprintf("Alert queue drained to %.0f%% full (%d queued)", fill, aq.stats.CurrentSize)
+		log.Println(message)
+	}
+	cw.level = level
+
+	if message != "" && cw.notify != nil {
+		// Notify outside the stats lock; senders may be slow
+		go cw.notify(message)
+	}
+}
+
+// capacityWarningLevel returns the highest warning threshold currently
+// crossed, or 0. Callers must hold aq.stats.mu.
+func (aq *AlertQueue) capacityWarningLevel() float64 {
+	cw := aq.capacityWarnings
+	if cw.level == 0 {
+		return 0
+	}
+	return cw.thresholds[cw.level-1]
+}
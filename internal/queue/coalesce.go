@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// coalesceBuffer holds alerts that share a channel and correlation id for a
+// window, so a burst of progress-style updates produces one message with the
+// latest content. The window starts at the first update and is not extended
+// by later ones, so a steady stream still flushes regularly.
+type coalesceBuffer struct {
+	pending map[string]*Alert
+	flush   func(alert *Alert)
+	mu      sync.Mutex
+}
+
+func newCoalesceBuffer(flush func(alert *Alert)) *coalesceBuffer {
+	return &coalesceBuffer{
+		pending: make(map[string]*Alert),
+		flush:   flush,
+	}
+}
+
+func coalesceKey(alert *Alert) string {
+	return fmt.Sprintf("%d|%d|%s", alert.UserID, alert.DBChannelID, alert.CorrelationID)
+}
+
+// Add buffers an alert. If an update for the same key is already pending it
+// is replaced and returned so the caller can record it as superseded.
+func (cb *coalesceBuffer) Add(alert *Alert, window time.Duration) *Alert {
+	key := coalesceKey(alert)
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if previous, exists := cb.pending[key]; exists {
+		cb.pending[key] = alert
+		return previous
+	}
+
+	cb.pending[key] = alert
+	time.AfterFunc(window, func() {
+		cb.mu.Lock()
+		latest, exists := cb.pending[key]
+		delete(cb.pending, key)
+		cb.mu.Unlock()
+
+		if exists {
+			cb.flush(latest)
+		}
+	})
+
+	return nil
+}
+
+// FlushAll sends every pending alert immediately, e.g. on shutdown
+func (cb *coalesceBuffer) FlushAll() {
+	cb.mu.Lock()
+	pending := cb.pending
+	cb.pending = make(map[string]*Alert)
+	cb.mu.Unlock()
+
+	for _, alert := range pending {
+		cb.flush(alert)
+	}
+}
@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// delayedRecheckInterval is how often alerts scheduled for later are checked
+// for being due
+const delayedRecheckInterval = time.Second
+
+// delay sets aside an alert scheduled for later. Delayed alerts stay out of
+// the priority queues so no worker sleeps until they're due.
+func (aq *AlertQueue) delay(alert *Alert) error {
+	aq.stopMu.RLock()
+	defer aq.stopMu.RUnlock()
+	if aq.stopped {
+		return fmt.Errorf("queue is shutting down")
+	}
+
+	aq.persist(alert)
+	return aq.addDelayed(alert)
+}
+
+// addDelayed adds an already persisted alert to the delayed alerts, failing
+// it when they're at capacity
+func (aq *AlertQueue) addDelayed(alert *Alert) error {
+	aq.delayedMu.Lock()
+	full := len(aq.delayed) >= aq.capacity
+	if !full {
+		aq.delayed = append(aq.delayed, alert)
+	}
+	aq.delayedMu.Unlock()
+
+	if full {
+		err := fmt.Errorf("scheduled alert limit reached")
+		aq.markFinished(alert, err)
+		return err
+	}
+	return nil
+}
+
+// delayedCount returns how many alerts wait for their scheduled time
+func (aq *AlertQueue) delayedCount() int {
+	aq.delayedMu.Lock()
+	defer aq.delayedMu.Unlock()
+	return len(aq.delayed)
+}
+
+// delayedLoop periodically queues delayed alerts that are due
+func (aq *AlertQueue) delayedLoop() {
+	defer aq.wg.Done()
+
+	ticker := time.NewTicker(delayedRecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			aq.releaseDue(time.Now())
+		case <-aq.ctx.Done():
+			return
+		}
+	}
+}
+
+// releaseDue queues delayed alerts scheduled at or before now. Alerts that
+// don't fit in the queue stay delayed for the next check.
+func (aq *AlertQueue) releaseDue(now time.Time) {
+	aq.delayedMu.Lock()
+	kept := aq.delayed[:0]
+	released := 0
+	for _, alert := range aq.delayed {
+		if now.Before(alert.ScheduledAt) {
+			kept = append(kept, alert)
+			continue
+		}
+
+		select {
+		case aq.queueFor(alert.Priority) <- alert:
+			released++
+		default:
+			kept = append(kept, alert)
+		}
+	}
+	clear(aq.delayed[len(kept):])
+	aq.delayed = kept
+	aq.delayedMu.Unlock()
+
+	// Updated outside delayedMu, like releaseHeld
+	if released > 0 {
+		aq.refreshCurrentSize()
+		log.Printf("Queued %d scheduled alerts that are now due", released)
+	}
+}
@@ -0,0 +1,180 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/outbound"
+	"github.com/thenaveensharma/telehook/internal/telegram"
+)
+
+// EscalationDispatcher applies a user's escalation policy to urgent alerts
+// that failed permanently: resend through a failover channel, mirror the
+// failure to an external endpoint (e.g. a pager) and record a dead letter
+type EscalationDispatcher struct {
+	db     *database.DB
+	client *http.Client
+}
+
+// NewEscalationDispatcher creates a new escalation dispatcher
+func NewEscalationDispatcher(db *database.DB) *EscalationDispatcher {
+	return &EscalationDispatcher{
+		db:     db,
+		client: outbound.NewClient(10 * time.Second),
+	}
+}
+
+// Escalate runs every action of the policy that applies to the alert's channel
+func (ed *EscalationDispatcher) Escalate(ctx context.Context, alert *Alert, cause error) {
+	policy, err := ed.db.GetEffectiveEscalationPolicy(ctx, alert.UserID, alert.DBChannelID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Escalation: failed to load policy for alert %s: %v", alert.ID, err)
+		}
+		return
+	}
+
+	if policy.FailoverChannelID != nil && *policy.FailoverChannelID != alert.DBChannelID {
+		if err := ed.failover(ctx, alert, *policy.FailoverChannelID); err != nil {
+			log.Printf("Escalation: failover for alert %s failed: %v", alert.ID, err)
+		}
+	}
+
+	if policy.MirrorURL != "" {
+		if err := ed.mirror(ctx, alert, policy.MirrorURL, cause); err != nil {
+			log.Printf("Escalation: mirror for alert %s failed: %v", alert.ID, err)
+		}
+	}
+
+	if policy.DeadLetter {
+		if err := ed.deadLetter(ctx, alert, cause); err != nil {
+			log.Printf("Escalation: dead-letter for alert %s failed: %v", alert.ID, err)
+		}
+	}
+}
+
+// RecordFailure logs an alert the queue dropped as failed and, when
+// deadLetter is set, keeps it in the dead-letter store for replay
+func (ed *EscalationDispatcher) RecordFailure(ctx context.Context, alert *Alert, cause error, deadLetter bool) {
+	code, category := telegram.ClassifyError(cause)
+	if err := ed.db.CreateFailedWebhookLog(ctx, alert.UserID, alert.Payload, cause.Error(), code, category); err != nil {
+		log.Printf("Failed to log dropped alert %s: %v", alert.ID, err)
+	}
+
+	if deadLetter {
+		if err := ed.deadLetter(ctx, alert, cause); err != nil {
+			log.Printf("Failed to dead-letter dropped alert %s: %v", alert.ID, err)
+		}
+	}
+}
+
+// failover resends the alert once through another of the user's channels
+func (ed *EscalationDispatcher) failover(ctx context.Context, alert *Alert, channelID int) error {
+	channel, err := ed.db.GetTelegramChannel(ctx, channelID, alert.UserID)
+	if err != nil {
+		return err
+	}
+	if !channel.IsActive {
+		return fmt.Errorf("failover channel %d is inactive", channelID)
+	}
+
+	bot, err := ed.db.GetBotByID(ctx, channel.BotID)
+	if err != nil {
+		return err
+	}
+
+	botInstance, err := telegram.NewBotWithToken(bot.BotToken, channel.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	template := channel.MessageTemplate
+	if template == "" {
+		template = bot.MessageTemplate
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, SendTimeoutFromEnv())
+	defer cancel()
+	response, err := botInstance.SendFormattedWebhookMessage(sendCtx, alert.Username, alert.Payload, telegram.MessageOptions{
+		Format:   alert.Format,
+		Template: template,
+		Silent:   alert.Silent,
+		ThreadID: channel.ThreadID, // The alert's topic belongs to its original chat
+	})
+	if err != nil {
+		logFailedSend(ctx, ed.db, alert, err)
+		return err
+	}
+
+	_ = ed.db.CreateWebhookLog(ctx, alert.UserID, alert.Payload, response, "escalated")
+	log.Printf("Escalation: alert %s delivered via failover channel %d", alert.ID, channelID)
+	return nil
+}
+
+// mirror POSTs the failure to the policy's external endpoint
+func (ed *EscalationDispatcher) mirror(ctx context.Context, alert *Alert, url string, cause error) error {
+	event := models.EscalationEvent{
+		AlertID:   alert.ID,
+		UserID:    alert.UserID,
+		ChannelID: alert.DBChannelID,
+		Priority:  alert.Priority,
+		Payload:   alert.Payload,
+		Retries:   alert.Retries,
+		FailedAt:  time.Now().UTC(),
+	}
+	if cause != nil {
+		event.Error = cause.Error()
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal escalation event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := ed.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// deadLetter records the alert in the dead-letter store
+func (ed *EscalationDispatcher) deadLetter(ctx context.Context, alert *Alert, cause error) error {
+	entry := &models.DeadLetterAlert{
+		UserID:   alert.UserID,
+		AlertID:  alert.ID,
+		Payload:  alert.Payload,
+		Priority: alert.Priority,
+		Retries:  alert.Retries,
+	}
+	if alert.DBChannelID != 0 {
+		channelID := alert.DBChannelID
+		entry.ChannelID = &channelID
+	}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+
+	return ed.db.CreateDeadLetterAlert(ctx, entry)
+}
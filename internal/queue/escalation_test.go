@@ -0,0 +1,28 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/thenaveensharma/telehook/internal/outbound"
+)
+
+func TestMirrorRefusesPrivateAddresses(t *testing.T) {
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	ed := NewEscalationDispatcher(nil)
+	err := ed.mirror(context.Background(), &Alert{ID: "urgent", Priority: 1}, server.URL, errors.New("send failed"))
+	if !errors.Is(err, outbound.ErrPrivateAddress) {
+		t.Fatalf("got %v, want ErrPrivateAddress", err)
+	}
+	if reached {
+		t.Fatal("mirror reached a loopback address")
+	}
+}
@@ -0,0 +1,49 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+// noticeCache keeps maintenance notices in memory, reloaded every refresh
+// interval. Windows are checked on each lookup so a notice stops applying
+// the moment it ends.
+type noticeCache struct {
+	notices *reloadCache[[]models.MaintenanceNotice]
+}
+
+func newNoticeCache(db *database.DB, refresh time.Duration) *noticeCache {
+	return &noticeCache{notices: newReloadCache("maintenance notices", refresh,
+		func(ctx context.Context) ([]models.MaintenanceNotice, error) {
+			return db.GetMaintenanceNotices(ctx, time.Now().UTC())
+		})}
+}
+
+// ActiveNotice returns the notice to show a user now: their own notice if
+// one is active, otherwise the active global notice, otherwise ""
+func (nc *noticeCache) ActiveNotice(userID int) string {
+	now := time.Now().UTC()
+
+	global := ""
+	for _, notice := range nc.notices.Get() {
+		if now.Before(notice.StartsAt) || !now.Before(notice.EndsAt) {
+			continue
+		}
+		if notice.UserID != nil && *notice.UserID == userID {
+			return notice.Message
+		}
+		if notice.UserID == nil && global == "" {
+			global = notice.Message
+		}
+	}
+
+	return global
+}
+
+// Invalidate forces the next lookup to reload notices
+func (nc *noticeCache) Invalidate() {
+	nc.notices.Invalidate()
+}
@@ -0,0 +1,267 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+// finishedAlertRetention is how long processed and failed rows are kept in
+// queued_alerts before being purged, at startup and every
+// finishedAlertPruneInterval after
+const (
+	finishedAlertRetention     = 24 * time.Hour
+	finishedAlertPruneInterval = time.Hour
+)
+
+// NewPersistentAlertQueue creates an alert queue that records every enqueued
+// alert in the queued_alerts table until it finishes, and reloads unfinished
+// alerts on Start, so pending alerts survive restarts. Delivery is at least
+// once: an alert being sent when the process dies is sent again.
+func NewPersistentAlertQueue(db *database.DB, workers int, queueSize int, processor AlertProcessor) *AlertQueue {
+	aq := NewAlertQueue(workers, queueSize, processor)
+	aq.db = db
+	return aq
+}
+
+// persist saves an alert's current state as pending, redacted the way it
+// will be sent
+func (aq *AlertQueue) persist(alert *Alert) {
+	if aq.db == nil {
+		return
+	}
+
+	if err := aq.db.SaveQueuedAlert(context.Background(), aq.redactedQueuedAlert(alert)); err != nil {
+		log.Printf("Failed to persist alert %s: %v", alert.ID, err)
+	}
+}
+
+// redactedQueuedAlert returns the row saved for an alert. A copy is redacted,
+// leaving the queued alert to be redacted when it's processed.
+func (aq *AlertQueue) redactedQueuedAlert(alert *Alert) *models.QueuedAlert {
+	aq.mu.RLock()
+	redactor := aq.redactor
+	aq.mu.RUnlock()
+
+	if redactor == nil {
+		return toQueuedAlert(alert)
+	}
+	redacted := *alert
+	redactor.Redact(&redacted)
+	return toQueuedAlert(&redacted)
+}
+
+// markFinished records that an alert reached a final state
+func (aq *AlertQueue) markFinished(alert *Alert, err error) {
+	if aq.db == nil {
+		return
+	}
+
+	status := "processed"
+	if err != nil {
+		status = "failed"
+	}
+	if err := aq.db.UpdateQueuedAlertStatus(context.Background(), alert.ID, status); err != nil {
+		log.Printf("Failed to mark persisted alert %s %s: %v", alert.ID, status, err)
+	}
+}
+
+// pruneFinished deletes finished rows older than finishedAlertRetention
+func (aq *AlertQueue) pruneFinished() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if purged, err := aq.db.DeleteFinishedQueuedAlerts(ctx, time.Now().Add(-finishedAlertRetention)); err != nil {
+		log.Printf("Failed to purge finished queued alerts: %v", err)
+	} else if purged > 0 {
+		log.Printf("Purged %d finished queued alerts", purged)
+	}
+}
+
+// pruneLoop periodically purges finished rows while the queue runs
+func (aq *AlertQueue) pruneLoop() {
+	defer aq.wg.Done()
+
+	ticker := time.NewTicker(finishedAlertPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			aq.pruneFinished()
+		case <-aq.ctx.Done():
+			return
+		}
+	}
+}
+
+// restore purges old finished rows and re-enqueues pending alerts
+func (aq *AlertQueue) restore() {
+	ctx := context.Background()
+
+	aq.pruneFinished()
+
+	pending, err := aq.db.GetPendingQueuedAlerts(ctx)
+	if err != nil {
+		log.Printf("Failed to load pending alerts: %v", err)
+		return
+	}
+
+	// Send straight to the channel, or set aside alerts scheduled for later;
+	// the rows are already saved
+	restored := 0
+	now := time.Now()
+	for i := range pending {
+		alert := fromQueuedAlert(&pending[i])
+		if now.Before(alert.ScheduledAt) {
+			if err := aq.addDelayed(alert); err != nil {
+				log.Printf("Failed to restore scheduled alert %s: %v", alert.ID, err)
+			}
+			restored++
+			continue
+		}
+
+		select {
+		case aq.queueFor(pending[i].Priority) <- alert:
+			aq.refreshCurrentSize()
+			restored++
+			continue
+		default:
+		}
+		// Rows left pending are picked up on the next start
+		log.Printf("Queue full, %d persisted alerts left for the next start", len(pending)-restored)
+		break
+	}
+
+	if len(pending) > 0 {
+		log.Printf("Restored %d pending alerts from previous run", restored)
+	}
+}
+
+// saveUnprocessed persists the latest state of alerts still buffered when the
+// queue stops, such as retries waiting for their backoff
+func (aq *AlertQueue) saveUnprocessed() {
+	if aq.db == nil {
+		return
+	}
+
+	saved := 0
+	for _, q := range aq.queues {
+		for alert := range q {
+			aq.persist(alert)
+			saved++
+		}
+	}
+drain:
+	for {
+		select {
+		case alert := <-aq.retryQueue:
+			aq.persist(alert)
+			saved++
+		case alerts := <-aq.batchQueue:
+			for _, alert := range alerts {
+				aq.persist(alert)
+				saved++
+			}
+		default:
+			break drain
+		}
+	}
+
+	aq.heldMu.Lock()
+	for _, alert := range aq.held {
+		aq.persist(alert)
+		saved++
+	}
+	aq.heldMu.Unlock()
+
+	aq.delayedMu.Lock()
+	for _, alert := range aq.delayed {
+		aq.persist(alert)
+		saved++
+	}
+	aq.delayedMu.Unlock()
+
+	if saved > 0 {
+		log.Printf("Saved %d unprocessed alerts for the next start", saved)
+	}
+}
+
+func toQueuedAlert(alert *Alert) *models.QueuedAlert {
+	queued := &models.QueuedAlert{
+		ID:                  alert.ID,
+		UserID:              alert.UserID,
+		Username:            alert.Username,
+		Payload:             alert.Payload,
+		Priority:            alert.Priority,
+		Retries:             alert.Retries,
+		MaxRetries:          alert.MaxRetries,
+		ScheduledAt:         alert.ScheduledAt,
+		BotToken:            alert.BotToken,
+		TelegramChannelID:   alert.ChannelID,
+		Format:              alert.Format,
+		ChannelTemplate:     alert.ChannelTemplate,
+		BotTemplate:         alert.BotTemplate,
+		CorrelationID:       alert.CorrelationID,
+		CoalesceWindowMs:    alert.CoalesceWindow.Milliseconds(),
+		BackoffBaseMs:       alert.BackoffBase.Milliseconds(),
+		BackoffMaxMs:        alert.BackoffMax.Milliseconds(),
+		DedupAcrossChannels: alert.DedupAcrossChannels,
+		Silent:              alert.Silent,
+		ThreadID:            alert.ThreadID,
+		CreatedAt:           alert.CreatedAt,
+	}
+	if alert.DBChannelID != 0 {
+		channelID := alert.DBChannelID
+		queued.ChannelID = &channelID
+	}
+	if alert.BatchID != "" {
+		batchID := alert.BatchID
+		queued.BatchID = &batchID
+	}
+	if alert.DedupWindow != nil {
+		dedupMs := alert.DedupWindow.Milliseconds()
+		queued.DedupWindowMs = &dedupMs
+	}
+	return queued
+}
+
+func fromQueuedAlert(queued *models.QueuedAlert) *Alert {
+	alert := &Alert{
+		ID:                  queued.ID,
+		UserID:              queued.UserID,
+		Username:            queued.Username,
+		Payload:             queued.Payload,
+		Priority:            queued.Priority,
+		Retries:             queued.Retries,
+		MaxRetries:          queued.MaxRetries,
+		CreatedAt:           queued.CreatedAt,
+		ScheduledAt:         queued.ScheduledAt,
+		BotToken:            queued.BotToken,
+		ChannelID:           queued.TelegramChannelID,
+		Format:              queued.Format,
+		ChannelTemplate:     queued.ChannelTemplate,
+		BotTemplate:         queued.BotTemplate,
+		CorrelationID:       queued.CorrelationID,
+		CoalesceWindow:      time.Duration(queued.CoalesceWindowMs) * time.Millisecond,
+		BackoffBase:         time.Duration(queued.BackoffBaseMs) * time.Millisecond,
+		BackoffMax:          time.Duration(queued.BackoffMaxMs) * time.Millisecond,
+		DedupAcrossChannels: queued.DedupAcrossChannels,
+		Silent:              queued.Silent,
+		ThreadID:            queued.ThreadID,
+	}
+	if queued.ChannelID != nil {
+		alert.DBChannelID = *queued.ChannelID
+	}
+	if queued.BatchID != nil {
+		alert.BatchID = *queued.BatchID
+	}
+	if queued.DedupWindowMs != nil {
+		dedupWindow := time.Duration(*queued.DedupWindowMs) * time.Millisecond
+		alert.DedupWindow = &dedupWindow
+	}
+	return alert
+}
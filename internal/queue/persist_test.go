@@ -0,0 +1,43 @@
+package queue
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestPersistedAlertIsRedacted(t *testing.T) {
+	rules := map[int][]redaction{
+		1: {{pattern: regexp.MustCompile(`sk-[a-z0-9]+`), replacement: "[REDACTED]"}},
+	}
+	proc := &TelegramProcessor{redactions: &redactionCache{
+		rules: newReloadCache("redaction rules", time.Minute, func(ctx context.Context) (map[int][]redaction, error) {
+			return rules, nil
+		}),
+	}}
+
+	aq := NewAlertQueue(1, 10, proc)
+	aq.SetRedactor(proc)
+
+	alert := &Alert{
+		ID:     "secret",
+		UserID: 1,
+		Payload: map[string]interface{}{
+			"message": "key sk-abc123 leaked",
+			"data":    map[string]interface{}{"token": "sk-def456"},
+		},
+	}
+	queued := aq.redactedQueuedAlert(alert)
+
+	if got := queued.Payload["message"]; got != "key [REDACTED] leaked" {
+		t.Fatalf("persisted message %q, want it redacted", got)
+	}
+	if got := queued.Payload["data"].(map[string]interface{})["token"]; got != "[REDACTED]" {
+		t.Fatalf("persisted data token %q, want it redacted", got)
+	}
+	// The queued alert itself is redacted when it's processed
+	if got := alert.Payload["message"]; got != "key sk-abc123 leaked" {
+		t.Fatalf("queued alert's message changed to %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/thenaveensharma/telehook/internal/database"
+)
+
+// quietPriority is the most urgent priority quiet hours suppress; urgent and
+// high priority alerts always get through
+const quietPriority = 3 // Normal
+
+// quietWindow is a user's quiet hours with the timezone resolved
+type quietWindow struct {
+	start, end int // Hours; end is exclusive
+	loc        *time.Location
+}
+
+// contains reports whether t falls in the window, in the window's timezone.
+// A window whose end is not after its start crosses midnight.
+func (w quietWindow) contains(t time.Time) bool {
+	hour := t.In(w.loc).Hour()
+	if w.start < w.end {
+		return hour >= w.start && hour < w.end
+	}
+	return hour >= w.start || hour < w.end
+}
+
+// quietHoursCache keeps users' quiet hours in memory, reloaded every refresh
+// interval
+type quietHoursCache struct {
+	windows *reloadCache[map[int]quietWindow]
+}
+
+func newQuietHoursCache(db *database.DB, refresh time.Duration) *quietHoursCache {
+	var load func(ctx context.Context) (map[int]quietWindow, error)
+	if db != nil {
+		load = func(ctx context.Context) (map[int]quietWindow, error) {
+			all, err := db.GetActiveQuietHours(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			windows := make(map[int]quietWindow, len(all))
+			for _, quiet := range all {
+				loc, err := time.LoadLocation(quiet.Timezone)
+				if err != nil {
+					log.Printf("Invalid quiet hours timezone %q for user %d, using UTC", quiet.Timezone, quiet.UserID)
+					loc = time.UTC
+				}
+				windows[quiet.UserID] = quietWindow{start: quiet.StartHour, end: quiet.EndHour, loc: loc}
+			}
+			return windows, nil
+		}
+	}
+	return &quietHoursCache{windows: newReloadCache("quiet hours", refresh, load)}
+}
+
+// Suppresses reports whether an alert falls in its user's quiet hours and
+// is not urgent enough to get through
+func (qc *quietHoursCache) Suppresses(alert *Alert, now time.Time) bool {
+	if alert.Priority < quietPriority {
+		return false
+	}
+
+	window, ok := qc.windows.Get()[alert.UserID]
+	return ok && window.contains(now)
+}
+
+// Invalidate forces the next lookup to reload quiet hours
+func (qc *quietHoursCache) Invalidate() {
+	qc.windows.Invalidate()
+}
@@ -0,0 +1,103 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/thenaveensharma/telehook/internal/database"
+)
+
+// redaction is a user's redaction rule with its pattern compiled
+type redaction struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// redactionCache keeps users' compiled redaction rules in memory so patterns
+// are compiled once per refresh rather than for every alert
+type redactionCache struct {
+	rules *reloadCache[map[int][]redaction]
+}
+
+func newRedactionCache(db *database.DB, refresh time.Duration) *redactionCache {
+	var load func(ctx context.Context) (map[int][]redaction, error)
+	if db != nil {
+		load = func(ctx context.Context) (map[int][]redaction, error) {
+			all, err := db.GetEnabledRedactionRules(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			rules := make(map[int][]redaction)
+			for _, rule := range all {
+				pattern, err := regexp.Compile(rule.Pattern)
+				if err != nil {
+					log.Printf("Skipping redaction rule %d with invalid pattern: %v", rule.ID, err)
+					continue
+				}
+				rules[rule.UserID] = append(rules[rule.UserID], redaction{pattern: pattern, replacement: rule.Replacement})
+			}
+			return rules, nil
+		}
+	}
+	return &redactionCache{rules: newReloadCache("redaction rules", refresh, load)}
+}
+
+// Apply replaces the alert's payload with a copy in which the message and
+// every string in data have the user's redactions applied. The payload is
+// copied so the caller's map is never modified.
+func (rc *redactionCache) Apply(alert *Alert) {
+	rules := rc.rules.Get()[alert.UserID]
+	if len(rules) == 0 {
+		return
+	}
+
+	payload := make(map[string]interface{}, len(alert.Payload))
+	for k, v := range alert.Payload {
+		payload[k] = v
+	}
+	if message, ok := payload["message"].(string); ok {
+		payload["message"] = redactString(message, rules)
+	}
+	if data, ok := payload["data"]; ok {
+		payload["data"] = redactValue(data, rules)
+	}
+	alert.Payload = payload
+}
+
+// Invalidate forces the next lookup to reload redaction rules
+func (rc *redactionCache) Invalidate() {
+	rc.rules.Invalidate()
+}
+
+func redactString(s string, rules []redaction) string {
+	for _, rule := range rules {
+		s = rule.pattern.ReplaceAllString(s, rule.replacement)
+	}
+	return s
+}
+
+// redactValue redacts the strings in a decoded JSON value, copying maps and
+// slices rather than modifying them
+func redactValue(value interface{}, rules []redaction) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redactString(v, rules)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = redactValue(item, rules)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item, rules)
+		}
+		return out
+	default:
+		return value
+	}
+}
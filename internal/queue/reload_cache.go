@@ -0,0 +1,72 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// reloadCache keeps a value loaded from the database in memory so the
+// processor doesn't query it for every alert. The value is reloaded once it
+// is older than the refresh interval, by whichever caller finds it stale
+// first; the query runs without holding the lock, so other callers keep
+// reading the previous value meanwhile instead of waiting on the database.
+// A failed reload keeps the previous value until the next refresh.
+type reloadCache[T any] struct {
+	name    string // What is cached, for logs
+	refresh time.Duration
+	load    func(ctx context.Context) (T, error) // nil never reloads
+
+	mu        sync.Mutex
+	value     T
+	loadedAt  time.Time
+	reloading bool
+	// Bumped by Invalidate so a reload that started before it doesn't
+	// count as fresh
+	generation int
+}
+
+func newReloadCache[T any](name string, refresh time.Duration, load func(ctx context.Context) (T, error)) *reloadCache[T] {
+	return &reloadCache[T]{name: name, refresh: refresh, load: load}
+}
+
+// Get returns the cached value, reloading it first when it is stale and no
+// other caller is already reloading it
+func (rc *reloadCache[T]) Get() T {
+	rc.mu.Lock()
+	now := time.Now()
+	if rc.load == nil || rc.reloading || now.Sub(rc.loadedAt) < rc.refresh {
+		value := rc.value
+		rc.mu.Unlock()
+		return value
+	}
+	rc.reloading = true
+	generation := rc.generation
+	rc.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	loaded, err := rc.load(ctx)
+	cancel()
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.reloading = false
+	if err != nil {
+		log.Printf("Failed to refresh %s: %v", rc.name, err)
+	} else {
+		rc.value = loaded
+	}
+	if rc.generation == generation {
+		rc.loadedAt = now
+	}
+	return rc.value
+}
+
+// Invalidate forces the next Get to reload the value
+func (rc *reloadCache[T]) Invalidate() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.loadedAt = time.Time{}
+	rc.generation++
+}
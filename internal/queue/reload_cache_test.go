@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestReloadCacheServesPreviousValueDuringReload(t *testing.T) {
+	release := make(chan struct{})
+	loads := 0
+	rc := newReloadCache("test values", time.Hour, func(ctx context.Context) (int, error) {
+		loads++
+		if loads == 1 {
+			return 1, nil
+		}
+		<-release
+		return 2, nil
+	})
+
+	if got := rc.Get(); got != 1 {
+		t.Fatalf("first Get returned %d, want 1", got)
+	}
+
+	rc.Invalidate()
+	reloaded := make(chan int)
+	go func() { reloaded <- rc.Get() }()
+
+	// Wait for the slow reload to start, then read alongside it
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rc.mu.Lock()
+		reloading := rc.reloading
+		rc.mu.Unlock()
+		if reloading {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("reload did not start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	read := make(chan int)
+	go func() { read <- rc.Get() }()
+	select {
+	case got := <-read:
+		if got != 1 {
+			t.Fatalf("Get during the reload returned %d, want the previous value 1", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get blocked on another caller's reload")
+	}
+
+	close(release)
+	if got := <-reloaded; got != 2 {
+		t.Fatalf("reloading Get returned %d, want 2", got)
+	}
+}
+
+func TestReloadCacheKeepsValueWhenReloadFails(t *testing.T) {
+	fail := false
+	rc := newReloadCache("test values", time.Hour, func(ctx context.Context) (string, error) {
+		if fail {
+			return "", errors.New("database unavailable")
+		}
+		return "loaded", nil
+	})
+
+	rc.Get()
+	fail = true
+	rc.Invalidate()
+	if got := rc.Get(); got != "loaded" {
+		t.Fatalf("Get after a failed reload returned %q, want the previous value", got)
+	}
+}
+
+func TestReloadCacheInvalidateDuringReload(t *testing.T) {
+	loads := 0
+	var rc *reloadCache[int]
+	rc = newReloadCache("test values", time.Hour, func(ctx context.Context) (int, error) {
+		loads++
+		if loads == 1 {
+			// A change lands while the first load is running
+			rc.Invalidate()
+		}
+		return loads, nil
+	})
+
+	rc.Get()
+	if got := rc.Get(); got != 2 {
+		t.Fatalf("Get after an invalidation during the reload returned %d, want a fresh load", got)
+	}
+}
@@ -4,30 +4,86 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 	"time"
 
 	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/metrics"
 	"github.com/thenaveensharma/telehook/internal/telegram"
 )
 
 // TelegramProcessor implements AlertProcessor for Telegram
 type TelegramProcessor struct {
-	bot *telegram.Bot
-	db  *database.DB
-	ruleEngine *RuleEngine
+	bot          *telegram.Bot
+	db           *database.DB
+	ruleEngine   *RuleEngine
+	notices      *noticeCache
+	pausedUsers  *pausedUserCache
+	redactions   *redactionCache
+	pausedAction string // PausedActionHold or PausedActionDrop
+	coalescer    *coalesceBuffer
+	prom         *metrics.Prometheus
+	sendTimeout  time.Duration // Per-send limit so a hung request can't hold a worker
+	bots         *botCache
 }
 
 // NewTelegramProcessor creates a new Telegram alert processor
 func NewTelegramProcessor(bot *telegram.Bot, db *database.DB) *TelegramProcessor {
-	return &TelegramProcessor{
-		bot:        bot,
-		db:         db,
-		ruleEngine: NewRuleEngine(30 * time.Second), // 30 second dedup window
+	// PAUSED_USER_ACTION decides what happens to alerts of a paused user
+	pausedAction := PausedActionHold
+	switch action := os.Getenv("PAUSED_USER_ACTION"); action {
+	case PausedActionHold, PausedActionDrop:
+		pausedAction = action
+	case "":
+	default:
+		log.Printf("Unknown PAUSED_USER_ACTION %q, using %q", action, PausedActionHold)
 	}
+
+	tp := &TelegramProcessor{
+		bot:          bot,
+		db:           db,
+		ruleEngine:   NewRuleEngine(DedupWindowFromEnv()),
+		notices:      newNoticeCache(db, 30*time.Second),
+		pausedUsers:  newPausedUserCache(db, 30*time.Second),
+		redactions:   newRedactionCache(db, 30*time.Second),
+		pausedAction: pausedAction,
+		sendTimeout:  SendTimeoutFromEnv(),
+		bots:         newBotCache(botCacheSize),
+	}
+	tp.ruleEngine.userRules = newUserRuleCache(db, 30*time.Second)
+	tp.ruleEngine.quietHours = newQuietHoursCache(db, 30*time.Second)
+
+	// Coalesced alerts are sent once their window closes, outside the queue's
+	// retry loop, so failures are logged rather than retried
+	tp.coalescer = newCoalesceBuffer(func(alert *Alert) {
+		if err := tp.deliver(context.Background(), alert); err != nil {
+			log.Printf("Coalesced alert %s failed: %v", alert.ID, err)
+		}
+	})
+
+	return tp
+}
+
+// SetMetrics records Telegram send latency in a Prometheus registry
+func (tp *TelegramProcessor) SetMetrics(prom *metrics.Prometheus) {
+	tp.prom = prom
 }
 
 // ProcessAlert processes a single alert
 func (tp *TelegramProcessor) ProcessAlert(ctx context.Context, alert *Alert) error {
+	// Redact first so nothing below sends or logs the original content
+	tp.redactions.Apply(alert)
+
+	// Paused users' alerts are held by the queue or dropped
+	if tp.pausedUsers.IsPaused(alert.UserID) {
+		if tp.pausedAction == PausedActionHold {
+			return ErrUserPaused
+		}
+		log.Printf("Alert %s dropped: processing paused for user %d", alert.ID, alert.UserID)
+		_ = tp.db.CreateWebhookLog(ctx, alert.UserID, alert.Payload, "processing paused", "paused")
+		return nil
+	}
+
 	// Apply rules
 	allowed, reason := tp.ruleEngine.ProcessAlert(alert)
 	if !allowed {
@@ -36,16 +92,31 @@ func (tp *TelegramProcessor) ProcessAlert(ctx context.Context, alert *Alert) err
 		return nil // Not an error, just filtered
 	}
 
+	// Hold back rapid updates sharing a correlation id; only the latest one in
+	// the window is sent
+	if alert.CorrelationID != "" && alert.CoalesceWindow > 0 {
+		if superseded := tp.coalescer.Add(alert, alert.CoalesceWindow); superseded != nil {
+			log.Printf("Alert %s superseded by %s (correlation id %s)", superseded.ID, alert.ID, alert.CorrelationID)
+			_ = tp.db.CreateWebhookLog(ctx, superseded.UserID, superseded.Payload, "coalesced: superseded by alert "+alert.ID, "filtered")
+		}
+		return nil
+	}
+
+	return tp.deliver(ctx, alert)
+}
+
+// deliver sends an alert to Telegram and records the outcome
+func (tp *TelegramProcessor) deliver(ctx context.Context, alert *Alert) error {
 	// Use per-alert bot token and channel if provided (multi-channel mode)
 	var botInstance *telegram.Bot
 	var err error
 
 	if alert.BotToken != "" && alert.ChannelID != "" {
-		// Multi-channel mode: create bot instance with alert's token and channel
-		botInstance, err = telegram.NewBotWithToken(alert.BotToken, alert.ChannelID)
+		// Multi-channel mode: reuse the bot instance for the alert's token and channel
+		botInstance, err = tp.bots.Get(alert.BotToken, alert.ChannelID)
 		if err != nil {
 			log.Printf("Failed to create bot instance for alert %s: %v", alert.ID, err)
-			_ = tp.db.CreateWebhookLog(ctx, alert.UserID, alert.Payload, err.Error(), "failed")
+			logFailedSend(ctx, tp.db, alert, err)
 			return fmt.Errorf("failed to create bot instance: %w", err)
 		}
 	} else {
@@ -57,46 +128,142 @@ func (tp *TelegramProcessor) ProcessAlert(ctx context.Context, alert *Alert) err
 	}
 
 	// Send to Telegram
-	response, err := botInstance.SendFormattedWebhookMessage(alert.Username, alert.Payload)
+	// Format the message, prepending any active maintenance notice
+	sendStart := time.Now()
+	sendCtx, cancel := context.WithTimeout(ctx, tp.sendTimeout)
+	defer cancel()
+	response, err := botInstance.SendFormattedWebhookMessage(sendCtx, alert.Username, alert.Payload, telegram.MessageOptions{
+		Format:   alert.Format,
+		Template: resolveTemplate(alert),
+		Notice:   tp.notices.ActiveNotice(alert.UserID),
+		Silent:   alert.Silent,
+		ThreadID: alert.ThreadID,
+	})
+	sendDuration := time.Since(sendStart)
+	metrics.Timing("telegram.send", sendDuration)
+	tp.prom.ObserveTelegramSend(sendDuration)
 	if err != nil {
-		_ = tp.db.CreateWebhookLog(ctx, alert.UserID, alert.Payload, err.Error(), "failed")
+		logFailedSend(ctx, tp.db, alert, err)
 		return err
 	}
 
-	// Log success
-	_ = tp.db.CreateWebhookLog(ctx, alert.UserID, alert.Payload, response, "success")
+	// Log success, with the time since the alert was queued: queue wait,
+	// retries and the Telegram round trip
+	_ = tp.db.CreateDeliveredWebhookLog(ctx, alert.UserID, alert.Payload, response, time.Since(alert.CreatedAt))
 	log.Printf("Alert %s processed successfully for user %d to channel %s", alert.ID, alert.UserID, alert.ChannelID)
 
 	return nil
 }
 
-// ProcessBatch processes multiple alerts in a batch
-func (tp *TelegramProcessor) ProcessBatch(ctx context.Context, alerts []*Alert) error {
+// SendTimeoutFromEnv reads how long a single Telegram send may take from
+// TELEGRAM_SEND_TIMEOUT (default 30s). A send that times out fails like any
+// other and is retried.
+func SendTimeoutFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("TELEGRAM_SEND_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// logFailedSend logs a failed send along with why Telegram rejected it
+func logFailedSend(ctx context.Context, db *database.DB, alert *Alert, err error) {
+	code, category := telegram.ClassifyError(err)
+	_ = db.CreateFailedWebhookLog(ctx, alert.UserID, alert.Payload, err.Error(), code, category)
+}
+
+// resolveTemplate picks the message template for an alert: the channel's own
+// template, then the bot's, then "" for the built-in layout
+func resolveTemplate(alert *Alert) string {
+	if alert.ChannelTemplate != "" {
+		return alert.ChannelTemplate
+	}
+	return alert.BotTemplate
+}
+
+// ProcessBatch processes multiple alerts in a batch and returns the alerts
+// that failed, so the caller only retries those rather than the whole batch
+func (tp *TelegramProcessor) ProcessBatch(ctx context.Context, alerts []*Alert) ([]*Alert, error) {
 	if len(alerts) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	log.Printf("Processing batch of %d alerts", len(alerts))
 
 	successCount := 0
-	errorCount := 0
+	var failed []*Alert
 
 	for _, alert := range alerts {
 		if err := tp.ProcessAlert(ctx, alert); err != nil {
-			errorCount++
+			failed = append(failed, alert)
 			log.Printf("Batch: Failed to process alert %s: %v", alert.ID, err)
 		} else {
 			successCount++
 		}
 	}
 
-	log.Printf("Batch complete: %d succeeded, %d failed", successCount, errorCount)
+	log.Printf("Batch complete: %d succeeded, %d failed", successCount, len(failed))
 
-	if errorCount > 0 && successCount == 0 {
-		return fmt.Errorf("all alerts in batch failed")
+	if len(failed) == 0 {
+		return nil, nil
 	}
 
-	return nil
+	if successCount == 0 {
+		return failed, fmt.Errorf("all alerts in batch failed")
+	}
+
+	return failed, nil
+}
+
+// FlushCoalesced sends any alerts still held in coalesce windows
+func (tp *TelegramProcessor) FlushCoalesced() {
+	tp.coalescer.FlushAll()
+}
+
+// InvalidateNotices makes newly created or deleted maintenance notices take
+// effect immediately instead of at the next refresh
+func (tp *TelegramProcessor) InvalidateNotices() {
+	tp.notices.Invalidate()
+}
+
+// InvalidateUserRules makes changes to users' alert rules take effect
+// immediately instead of at the next refresh
+func (tp *TelegramProcessor) InvalidateUserRules() {
+	tp.ruleEngine.userRules.Invalidate()
+}
+
+// InvalidateQuietHours makes changes to users' quiet hours take effect
+// immediately instead of at the next refresh
+func (tp *TelegramProcessor) InvalidateQuietHours() {
+	tp.ruleEngine.quietHours.Invalidate()
+}
+
+// Redact applies the user's redaction rules to the alert's payload
+func (tp *TelegramProcessor) Redact(alert *Alert) {
+	tp.redactions.Apply(alert)
+}
+
+// InvalidateRedactions makes changes to users' redaction rules take effect
+// immediately instead of at the next refresh
+func (tp *TelegramProcessor) InvalidateRedactions() {
+	tp.redactions.Invalidate()
+}
+
+// HoldAlerts reports whether a user's alerts should be held by the queue
+// rather than processed
+func (tp *TelegramProcessor) HoldAlerts(userID int) bool {
+	return tp.pausedAction == PausedActionHold && tp.pausedUsers.IsPaused(userID)
+}
+
+// InvalidatePausedUsers makes pausing or resuming a user take effect
+// immediately instead of at the next refresh
+func (tp *TelegramProcessor) InvalidatePausedUsers() {
+	tp.pausedUsers.Invalidate()
+}
+
+// EvaluateRules reports how the rules would treat an alert without affecting
+// deduplication or throttling state, for dry runs and debugging
+func (tp *TelegramProcessor) EvaluateRules(alert *Alert) (bool, string, []string) {
+	return tp.ruleEngine.Evaluate(alert)
 }
 
 // AddCustomRule adds a custom rule to the processor
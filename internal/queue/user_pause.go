@@ -0,0 +1,159 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/thenaveensharma/telehook/internal/database"
+)
+
+// ErrUserPaused is returned by a processor for an alert whose user has
+// processing paused; the queue holds the alert until the user is resumed
+var ErrUserPaused = errors.New("alert processing is paused for this user")
+
+// Actions taken on alerts of a paused user
+const (
+	PausedActionHold = "hold" // Keep alerts until the user is resumed
+	PausedActionDrop = "drop" // Log alerts as paused without sending them
+)
+
+// heldRecheckInterval is how often held alerts are checked for release
+const heldRecheckInterval = 15 * time.Second
+
+// UserPauser is implemented by processors that can report which users'
+// alerts should be held rather than processed
+type UserPauser interface {
+	HoldAlerts(userID int) bool
+}
+
+// pausedUserCache keeps the set of paused users in memory, reloaded every
+// refresh interval
+type pausedUserCache struct {
+	paused *reloadCache[map[int]bool]
+}
+
+func newPausedUserCache(db *database.DB, refresh time.Duration) *pausedUserCache {
+	var load func(ctx context.Context) (map[int]bool, error)
+	if db != nil {
+		load = func(ctx context.Context) (map[int]bool, error) {
+			ids, err := db.GetPausedUserIDs(ctx)
+			if err != nil {
+				return nil, err
+			}
+			paused := make(map[int]bool, len(ids))
+			for _, id := range ids {
+				paused[id] = true
+			}
+			return paused, nil
+		}
+	}
+	return &pausedUserCache{paused: newReloadCache("paused users", refresh, load)}
+}
+
+// IsPaused reports whether a user's alert processing is paused
+func (pc *pausedUserCache) IsPaused(userID int) bool {
+	return pc.paused.Get()[userID]
+}
+
+// Invalidate forces the next lookup to reload paused users
+func (pc *pausedUserCache) Invalidate() {
+	pc.paused.Invalidate()
+}
+
+// hold sets aside an alert of a paused user. Held alerts stay out of the
+// priority queues so they can't crowd out other users' alerts.
+func (aq *AlertQueue) hold(alert *Alert) {
+	aq.heldMu.Lock()
+	full := len(aq.held) >= aq.capacity
+	if !full {
+		aq.held = append(aq.held, alert)
+	}
+	aq.heldMu.Unlock()
+
+	if full {
+		log.Printf("Held alert limit reached, dropping alert %s for paused user %d", alert.ID, alert.UserID)
+		aq.complete(alert, fmt.Errorf("held alert limit reached"))
+	}
+}
+
+// heldCount returns how many alerts are held for paused users
+func (aq *AlertQueue) heldCount() int {
+	aq.heldMu.Lock()
+	defer aq.heldMu.Unlock()
+	return len(aq.held)
+}
+
+// releaseLoop periodically requeues held alerts whose user is no longer
+// paused
+func (aq *AlertQueue) releaseLoop() {
+	defer aq.wg.Done()
+
+	pauser, ok := aq.processor.(UserPauser)
+	if !ok {
+		return
+	}
+
+	ticker := time.NewTicker(heldRecheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			aq.releaseHeld(pauser)
+		case <-aq.ctx.Done():
+			return
+		}
+	}
+}
+
+// releaseHeld requeues held alerts of resumed users. Alerts that don't fit
+// in the queue stay held for the next check.
+func (aq *AlertQueue) releaseHeld(pauser UserPauser) {
+	aq.heldMu.Lock()
+	kept := aq.held[:0]
+	released := 0
+	for _, alert := range aq.held {
+		if pauser.HoldAlerts(alert.UserID) {
+			kept = append(kept, alert)
+			continue
+		}
+
+		select {
+		case aq.queueFor(alert.Priority) <- alert:
+			released++
+		default:
+			kept = append(kept, alert)
+		}
+	}
+	clear(aq.held[len(kept):])
+	aq.held = kept
+	aq.heldMu.Unlock()
+
+	// Updated outside heldMu; GetStats takes the locks in the other order
+	if released > 0 {
+		aq.refreshCurrentSize()
+		log.Printf("Released %d held alerts of resumed users", released)
+	}
+}
+
+// holdPaused splits out the alerts of a batch whose user is paused and holds
+// them, returning the rest
+func (aq *AlertQueue) holdPaused(alerts []*Alert) []*Alert {
+	pauser, ok := aq.processor.(UserPauser)
+	if !ok {
+		return alerts
+	}
+
+	ready := make([]*Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if pauser.HoldAlerts(alert.UserID) {
+			aq.hold(alert)
+		} else {
+			ready = append(ready, alert)
+		}
+	}
+	return ready
+}
@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+// userRuleCache keeps users' own alert rules in memory, reloaded every
+// refresh interval. It also holds the recent send times each throttle rule
+// counts against.
+type userRuleCache struct {
+	rules *reloadCache[*userRuleSet]
+	seen  *userRuleSet        // The set sent was last pruned against
+	sent  map[int][]time.Time // throttle rule ID -> send times in its window
+	mu    sync.Mutex
+}
+
+// userRuleSet is one load of the enabled rules
+type userRuleSet struct {
+	byUser map[int][]models.UserAlertRule
+	live   map[int]bool // Rule IDs
+}
+
+func newUserRuleCache(db *database.DB, refresh time.Duration) *userRuleCache {
+	var load func(ctx context.Context) (*userRuleSet, error)
+	if db != nil {
+		load = func(ctx context.Context) (*userRuleSet, error) {
+			rules, err := db.GetEnabledAlertRules(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			set := &userRuleSet{byUser: make(map[int][]models.UserAlertRule), live: make(map[int]bool)}
+			for _, rule := range rules {
+				set.byUser[rule.UserID] = append(set.byUser[rule.UserID], rule)
+				set.live[rule.ID] = true
+			}
+			return set, nil
+		}
+	}
+	return &userRuleCache{
+		rules: newReloadCache("user alert rules", refresh, load),
+		sent:  make(map[int][]time.Time),
+	}
+}
+
+// Apply checks an alert against its user's rules. Filters run first, then
+// throttles, so an alert dropped by a filter doesn't use up throttle
+// allowance. When record is false the alert is only evaluated: throttles
+// aren't counted and transforms aren't applied. It returns whether the alert
+// passes, the reason if not, and the names of the rules checked.
+func (uc *userRuleCache) Apply(alert *Alert, record bool) (bool, string, []string) {
+	set := uc.rules.Get()
+	if set == nil {
+		return true, "", nil
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	now := time.Now()
+	uc.pruneSent(set)
+
+	rules := set.byUser[alert.UserID]
+	if len(rules) == 0 {
+		return true, "", nil
+	}
+
+	message, _ := alert.Payload["message"].(string)
+	checked := make([]string, 0, len(rules))
+
+	for _, rule := range rules {
+		if rule.Kind != models.RuleKindFilter || rule.RuleType == models.RuleTypeThrottle {
+			continue
+		}
+		checked = append(checked, rule.Name)
+		if !passesFilter(rule, message) {
+			return false, fmt.Sprintf("filtered by rule: %s", rule.Name), checked
+		}
+	}
+
+	for _, rule := range rules {
+		if rule.Kind != models.RuleKindFilter || rule.RuleType != models.RuleTypeThrottle {
+			continue
+		}
+		checked = append(checked, rule.Name)
+		if uc.throttled(rule, now) {
+			return false, fmt.Sprintf("throttled by rule: %s", rule.Name), checked
+		}
+	}
+
+	if record {
+		for _, rule := range rules {
+			if rule.RuleType == models.RuleTypeThrottle {
+				uc.sent[rule.ID] = append(uc.sent[rule.ID], now)
+			}
+		}
+	}
+
+	transformed := message
+	for _, rule := range rules {
+		if rule.Kind != models.RuleKindTransform {
+			continue
+		}
+		checked = append(checked, rule.Name)
+		transformed = transform(rule, transformed)
+	}
+
+	if record && transformed != message {
+		// Copy the payload so other holders of the map keep the original
+		payload := make(map[string]interface{}, len(alert.Payload))
+		for k, v := range alert.Payload {
+			payload[k] = v
+		}
+		payload["message"] = transformed
+		alert.Payload = payload
+	}
+
+	return true, "", checked
+}
+
+// Invalidate forces the next lookup to reload rules
+func (uc *userRuleCache) Invalidate() {
+	uc.rules.Invalidate()
+}
+
+// pruneSent drops send times for throttle rules that were deleted or
+// disabled, once per load of the rules. Callers must hold uc.mu.
+func (uc *userRuleCache) pruneSent(set *userRuleSet) {
+	if set == uc.seen {
+		return
+	}
+	uc.seen = set
+
+	for id := range uc.sent {
+		if !set.live[id] {
+			delete(uc.sent, id)
+		}
+	}
+}
+
+// throttled prunes send times outside the rule's window and reports whether
+// the rule's limit has been reached. Callers must hold uc.mu.
+func (uc *userRuleCache) throttled(rule models.UserAlertRule, now time.Time) bool {
+	if rule.Value == nil || rule.WindowSeconds == nil {
+		return false
+	}
+
+	cutoff := now.Add(-time.Duration(*rule.WindowSeconds) * time.Second)
+	times := uc.sent[rule.ID]
+	kept := times[:0]
+	for _, t := range times {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	uc.sent[rule.ID] = kept
+
+	return len(kept) >= *rule.Value
+}
+
+// passesFilter reports whether a message gets through a filter rule
+func passesFilter(rule models.UserAlertRule, message string) bool {
+	switch rule.RuleType {
+	case models.RuleTypeBlockKeywords:
+		lower := strings.ToLower(message)
+		for _, keyword := range rule.Keywords {
+			if strings.Contains(lower, strings.ToLower(keyword)) {
+				return false
+			}
+		}
+		return true
+	case models.RuleTypeRequireKeywords:
+		lower := strings.ToLower(message)
+		for _, keyword := range rule.Keywords {
+			if strings.Contains(lower, strings.ToLower(keyword)) {
+				return true
+			}
+		}
+		return false
+	case models.RuleTypeMinLength:
+		return rule.Value == nil || utf8.RuneCountInString(message) >= *rule.Value
+	case models.RuleTypeMaxLength:
+		return rule.Value == nil || utf8.RuneCountInString(message) <= *rule.Value
+	}
+	return true
+}
+
+// transform applies a transform rule to a message
+func transform(rule models.UserAlertRule, message string) string {
+	switch rule.RuleType {
+	case models.RuleTypeMaxLength:
+		if rule.Value != nil && utf8.RuneCountInString(message) > *rule.Value {
+			runes := []rune(message)
+			return string(runes[:*rule.Value])
+		}
+	case models.RuleTypeRedactKeywords:
+		for _, keyword := range rule.Keywords {
+			if keyword == "" {
+				continue
+			}
+			re := regexp.MustCompile("(?i)" + regexp.QuoteMeta(keyword))
+			message = re.ReplaceAllString(message, "***")
+		}
+	}
+	return message
+}
@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Actions the watchdog can take when every worker is stuck
+const (
+	StallActionLog   = "log"   // Log a critical warning only
+	StallActionSpawn = "spawn" // Start temporary emergency workers
+	StallActionPause = "pause" // Reject new alerts until a worker frees up
+)
+
+// watchdogConfig controls detection of a fully stalled worker pool
+type watchdogConfig struct {
+	threshold        time.Duration // How long every worker must be busy
+	action           string
+	emergencyWorkers int
+}
+
+// newWatchdogConfig reads WORKER_STALL_THRESHOLD_SECONDS (default 30),
+// WORKER_STALL_ACTION (log, spawn or pause; default log) and
+// WORKER_EMERGENCY_COUNT (default 5) from the environment
+func newWatchdogConfig() watchdogConfig {
+	cfg := watchdogConfig{
+		threshold:        30 * time.Second,
+		action:           StallActionLog,
+		emergencyWorkers: 5,
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("WORKER_STALL_THRESHOLD_SECONDS")); err == nil && v > 0 {
+		cfg.threshold = time.Duration(v) * time.Second
+	}
+	switch action := os.Getenv("WORKER_STALL_ACTION"); action {
+	case StallActionLog, StallActionSpawn, StallActionPause:
+		cfg.action = action
+	case "":
+	default:
+		log.Printf("Unknown WORKER_STALL_ACTION %q, using %q", action, StallActionLog)
+	}
+	if v, err := strconv.Atoi(os.Getenv("WORKER_EMERGENCY_COUNT")); err == nil && v > 0 {
+		cfg.emergencyWorkers = v
+	}
+
+	return cfg
+}
+
+// workerBusy reports how many workers are processing an alert, how many of
+// them have been at it longer than the stall threshold, and the longest time
+// any worker has been busy
+func (aq *AlertQueue) workerBusy() (busy int, stuck int, longest time.Duration) {
+	now := time.Now()
+	for i := range aq.busySince {
+		since := aq.busySince[i].Load()
+		if since == 0 {
+			continue
+		}
+		busy++
+		d := now.Sub(time.Unix(0, since))
+		if d > aq.watchdog.threshold {
+			stuck++
+		}
+		if d > longest {
+			longest = d
+		}
+	}
+	return busy, stuck, longest
+}
+
+// watchdogLoop checks the worker pool and reacts when every worker is stuck
+func (aq *AlertQueue) watchdogLoop() {
+	defer aq.wg.Done()
+
+	interval := aq.watchdog.threshold / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var cancelEmergency context.CancelFunc
+	stalled := false
+
+	for {
+		select {
+		case <-ticker.C:
+			_, stuck, longest := aq.workerBusy()
+			nowStalled := stuck == aq.workers
+
+			if nowStalled && !stalled {
+				log.Printf("CRITICAL: all %d workers busy for over %s (longest %s); taking action %q",
+					aq.workers, aq.watchdog.threshold, longest.Round(time.Second), aq.watchdog.action)
+
+				switch aq.watchdog.action {
+				case StallActionSpawn:
+					cancelEmergency = aq.startEmergencyWorkers()
+				case StallActionPause:
+					aq.paused.Store(true)
+				}
+			} else if !nowStalled && stalled {
+				log.Println("Worker pool recovered from stall")
+				if cancelEmergency != nil {
+					cancelEmergency()
+					cancelEmergency = nil
+				}
+				aq.paused.Store(false)
+			}
+			stalled = nowStalled
+			aq.stalled.Store(stalled)
+
+		case <-aq.ctx.Done():
+			if cancelEmergency != nil {
+				cancelEmergency()
+			}
+			return
+		}
+	}
+}
+
+// startEmergencyWorkers starts temporary workers that run until the returned
+// cancel function is called
+func (aq *AlertQueue) startEmergencyWorkers() context.CancelFunc {
+	ctx, cancel := context.WithCancel(aq.ctx)
+
+	for i := 0; i < aq.watchdog.emergencyWorkers; i++ {
+		aq.wg.Add(1)
+		go aq.emergencyWorker(ctx, aq.workers+i)
+	}
+
+	return cancel
+}
+
+// emergencyWorker processes alerts like a regular worker until ctx is done
+func (aq *AlertQueue) emergencyWorker(ctx context.Context, id int) {
+	defer aq.wg.Done()
+
+	aq.emergencyCount.Add(1)
+	defer aq.emergencyCount.Add(-1)
+
+	log.Printf("Emergency worker %d started", id)
+
+	for {
+		alert, ok := aq.next(ctx)
+		if !ok {
+			log.Printf("Emergency worker %d stopping", id)
+			return
+		}
+
+		aq.refreshCurrentSize()
+		aq.processAlert(alert, id)
+	}
+}
@@ -0,0 +1,175 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed standard 5-field cron expression
+// (minute hour day-of-month month day-of-week)
+type CronSchedule struct {
+	minutes     map[int]bool
+	hours       map[int]bool
+	daysOfMonth map[int]bool
+	months      map[int]bool
+	daysOfWeek  map[int]bool
+	// Standard cron semantics: when both day fields are restricted, a day
+	// matches if EITHER field matches. As in Vixie cron, a field starting
+	// with "*" (including steps such as "*/2") isn't restricted.
+	domRestricted bool
+	dowRestricted bool
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// ParseCron parses a 5-field cron expression. Each field supports "*",
+// single values, ranges ("1-5"), lists ("1,15") and steps ("*/10", "0-30/5").
+// Day of week 7 is accepted as an alias for Sunday.
+func ParseCron(expr string) (*CronSchedule, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression must have 5 fields, got %d", len(parts))
+	}
+
+	sets := make([]map[int]bool, len(cronFields))
+	for i, part := range parts {
+		field := cronFields[i]
+		max := field.max
+		if i == 4 {
+			max = 7 // allow 7 for Sunday
+		}
+
+		set, err := parseCronField(part, field.min, max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s field %q: %w", field.name, part, err)
+		}
+		sets[i] = set
+	}
+
+	// Normalise Sunday
+	if sets[4][7] {
+		sets[4][0] = true
+		delete(sets[4], 7)
+	}
+
+	return &CronSchedule{
+		minutes:       sets[0],
+		hours:         sets[1],
+		daysOfMonth:   sets[2],
+		months:        sets[3],
+		daysOfWeek:    sets[4],
+		domRestricted: !strings.HasPrefix(parts[2], "*"),
+		dowRestricted: !strings.HasPrefix(parts[4], "*"),
+	}, nil
+}
+
+// parseCronField expands a single cron field into the set of matching values
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(item, "/"); idx != -1 {
+			s, err := strconv.Atoi(item[idx+1:])
+			if err != nil || s <= 0 {
+				return nil, fmt.Errorf("invalid step %q", item[idx+1:])
+			}
+			step = s
+			item = item[:idx]
+		}
+
+		lo, hi := min, max
+		switch {
+		case item == "*":
+		case strings.Contains(item, "-"):
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid range start %q", bounds[0])
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid range end %q", bounds[1])
+			}
+		default:
+			v, err := strconv.Atoi(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", item)
+			}
+			lo, hi = v, v
+			if step > 1 {
+				hi = max
+			}
+		}
+
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("value out of range %d-%d", min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+
+	return set, nil
+}
+
+// Next returns the first time strictly after t that matches the schedule,
+// evaluated in t's location so schedules follow local wall-clock time
+func (cs *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Five years is enough to find any valid schedule (e.g. Feb 29)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !cs.months[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !cs.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !cs.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if !cs.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches applies cron's day-of-month / day-of-week rules
+func (cs *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := cs.daysOfMonth[t.Day()]
+	dowMatch := cs.daysOfWeek[int(t.Weekday())]
+
+	if cs.domRestricted && cs.dowRestricted {
+		return domMatch || dowMatch
+	}
+	return domMatch && dowMatch
+}
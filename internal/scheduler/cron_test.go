@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+// matchingDays returns the days of January 2024 (which starts on a Monday)
+// on which expr fires
+func matchingDays(t *testing.T, expr string) []int {
+	t.Helper()
+	cs, err := ParseCron(expr)
+	if err != nil {
+		t.Fatalf("ParseCron(%q): %v", expr, err)
+	}
+
+	var days []int
+	next := time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC)
+	for {
+		next = cs.Next(next)
+		if next.Month() != time.January {
+			return days
+		}
+		days = append(days, next.Day())
+	}
+}
+
+func equalDays(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCronDayFields(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []int
+	}{
+		// Both day fields restricted: either may match
+		{"0 9 1 * 1", []int{1, 8, 15, 22, 29}},
+		{"0 9 2 * 1", []int{1, 2, 8, 15, 22, 29}},
+		// A step starting with "*" isn't a restriction, so both must match:
+		// odd days that are Mondays
+		{"0 9 */2 * 1", []int{1, 15, 29}},
+		{"0 9 2 * */3", []int{}},
+		{"0 9 1 * */3", []int{}},
+		// A stepped range is a restriction
+		{"0 9 1-31/14 * 2", []int{1, 2, 9, 15, 16, 23, 29, 30}},
+		// Only one field restricted
+		{"0 9 * * 1", []int{1, 8, 15, 22, 29}},
+		{"0 9 10 * *", []int{10}},
+	}
+
+	for _, tt := range tests {
+		if got := matchingDays(t, tt.expr); !equalDays(got, tt.want) {
+			t.Errorf("%q fires on %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
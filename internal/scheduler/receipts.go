@@ -0,0 +1,202 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/outbound"
+)
+
+// ReceiptSignatureHeader carries "sha256=" followed by the hex HMAC-SHA256 of
+// the receipt body, keyed by the webhook's secret
+const ReceiptSignatureHeader = "X-Telehook-Signature"
+
+// ReceiptDispatcher periodically POSTs aggregated delivery counts to each
+// user's configured receipt webhook
+type ReceiptDispatcher struct {
+	db         *database.DB
+	client     *http.Client
+	interval   time.Duration
+	maxRetries int
+	ctx        context.Context
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// NewReceiptDispatcher creates a new receipt dispatcher
+func NewReceiptDispatcher(db *database.DB) *ReceiptDispatcher {
+	interval := time.Minute
+	if envInterval := os.Getenv("RECEIPTS_CHECK_INTERVAL_SECONDS"); envInterval != "" {
+		if seconds, err := strconv.Atoi(envInterval); err == nil && seconds > 0 {
+			interval = time.Duration(seconds) * time.Second
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &ReceiptDispatcher{
+		db:         db,
+		client:     outbound.NewClient(10 * time.Second),
+		interval:   interval,
+		maxRetries: 3,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Start launches the dispatcher loop
+func (rd *ReceiptDispatcher) Start() {
+	rd.wg.Add(1)
+	go rd.run()
+	log.Println("Receipt dispatcher started")
+}
+
+// Stop shuts down the dispatcher and waits for in-flight deliveries
+func (rd *ReceiptDispatcher) Stop() {
+	rd.cancel()
+	rd.wg.Wait()
+	log.Println("Receipt dispatcher stopped")
+}
+
+func (rd *ReceiptDispatcher) run() {
+	defer rd.wg.Done()
+
+	ticker := time.NewTicker(rd.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rd.tick()
+		case <-rd.ctx.Done():
+			return
+		}
+	}
+}
+
+// tick claims and sends every due receipt
+func (rd *ReceiptDispatcher) tick() {
+	now := time.Now()
+
+	due, err := rd.db.GetDueReceiptWebhooks(rd.ctx, now)
+	if err != nil {
+		log.Printf("Receipts: failed to load due webhooks: %v", err)
+		return
+	}
+
+	for _, webhook := range due {
+		interval := time.Duration(webhook.IntervalMinutes) * time.Minute
+
+		windowStart := webhook.NextSendAt.Add(-interval)
+		if webhook.LastWindowEnd != nil {
+			windowStart = *webhook.LastWindowEnd
+		}
+
+		claimed, err := rd.db.ClaimReceiptWindow(rd.ctx, webhook.ID, webhook.NextSendAt, now.Add(interval), now)
+		if err != nil {
+			log.Printf("Receipts: failed to claim webhook %d: %v", webhook.ID, err)
+			continue
+		}
+		if !claimed {
+			// Another replica is sending this window
+			continue
+		}
+
+		rd.wg.Add(1)
+		go func(webhook models.DueReceiptWebhook, windowStart time.Time) {
+			defer rd.wg.Done()
+			rd.deliver(webhook, windowStart, now)
+		}(webhook, windowStart)
+	}
+}
+
+// deliver builds the receipt for a window and POSTs it, retrying with
+// exponential backoff on failure
+func (rd *ReceiptDispatcher) deliver(webhook models.DueReceiptWebhook, windowStart, windowEnd time.Time) {
+	summary, err := rd.db.GetAnalyticsSummary(rd.ctx, webhook.UserID, windowStart.Local(), windowEnd.Local())
+	if err != nil {
+		log.Printf("Receipts: failed to aggregate for user %d: %v", webhook.UserID, err)
+		return
+	}
+
+	receipt := models.DeliveryReceipt{
+		WebhookToken: webhook.WebhookToken,
+		WindowStart:  windowStart.UTC(),
+		WindowEnd:    windowEnd.UTC(),
+		Total:        summary.TotalMessages,
+		Delivered:    summary.SuccessCount,
+		Failed:       summary.FailedCount,
+		Filtered:     summary.FilteredCount,
+		Pending:      summary.PendingCount,
+	}
+
+	body, err := json.Marshal(receipt)
+	if err != nil {
+		log.Printf("Receipts: failed to marshal receipt for user %d: %v", webhook.UserID, err)
+		return
+	}
+
+	for attempt := 0; attempt <= rd.maxRetries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(1<<attempt) * time.Second
+			select {
+			case <-time.After(backoff):
+			case <-rd.ctx.Done():
+				return
+			}
+		}
+
+		err = rd.post(webhook.URL, webhook.Secret, body)
+		if err == nil {
+			log.Printf("Receipts: delivered receipt for user %d (%d delivered, %d failed)",
+				webhook.UserID, receipt.Delivered, receipt.Failed)
+			return
+		}
+
+		log.Printf("Receipts: attempt %d/%d for user %d failed: %v",
+			attempt+1, rd.maxRetries+1, webhook.UserID, err)
+	}
+}
+
+// post sends a signed receipt body to url
+func (rd *ReceiptDispatcher) post(url, secret string, body []byte) error {
+	req, err := http.NewRequestWithContext(rd.ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ReceiptSignatureHeader, SignReceipt(secret, body))
+
+	resp, err := rd.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// SignReceipt returns the signature header value for body keyed by secret
+func SignReceipt(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"text/template"
+	"time"
+	_ "time/tzdata" // embed zoneinfo so timezones work in minimal containers
+
+	"github.com/google/uuid"
+	"github.com/thenaveensharma/telehook/internal/database"
+	"github.com/thenaveensharma/telehook/internal/models"
+	"github.com/thenaveensharma/telehook/internal/queue"
+)
+
+// Scheduler periodically enqueues alerts for scheduled messages that are due
+type Scheduler struct {
+	db       *database.DB
+	queue    *queue.AlertQueue
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewScheduler creates a new scheduler for recurring messages
+func NewScheduler(db *database.DB, alertQueue *queue.AlertQueue) *Scheduler {
+	interval := 30 * time.Second
+	if envInterval := os.Getenv("SCHEDULER_INTERVAL_SECONDS"); envInterval != "" {
+		if seconds, err := strconv.Atoi(envInterval); err == nil && seconds > 0 {
+			interval = time.Duration(seconds) * time.Second
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		db:       db,
+		queue:    alertQueue,
+		interval: interval,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start launches the scheduler loop
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go s.run()
+	log.Printf("Scheduler started (checking every %s)", s.interval)
+}
+
+// Stop shuts down the scheduler loop
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+	log.Println("Scheduler stopped")
+}
+
+func (s *Scheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.tick()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// tick fires every schedule that is due
+func (s *Scheduler) tick() {
+	now := time.Now()
+
+	due, err := s.db.GetDueScheduledMessages(s.ctx, now)
+	if err != nil {
+		log.Printf("Scheduler: failed to load due schedules: %v", err)
+		return
+	}
+
+	for _, schedule := range due {
+		// Missed occurrences (e.g. while the server was down) are collapsed
+		// into a single run by computing the next run from now
+		next, err := NextRun(schedule.CronExpression, schedule.Timezone, now)
+		if err != nil {
+			log.Printf("Scheduler: schedule %d has invalid cron: %v", schedule.ID, err)
+			continue
+		}
+
+		claimed, err := s.db.ClaimScheduledRun(s.ctx, schedule.ID, schedule.NextRunAt, next)
+		if err != nil {
+			log.Printf("Scheduler: failed to claim schedule %d: %v", schedule.ID, err)
+			continue
+		}
+		if !claimed {
+			// Another replica already fired this occurrence
+			continue
+		}
+
+		s.fire(schedule, now)
+	}
+}
+
+// fire enqueues the alert for a single scheduled occurrence
+func (s *Scheduler) fire(schedule models.DueScheduledMessage, now time.Time) {
+	message, err := RenderMessage(schedule.Message, schedule.Name, schedule.Timezone, now)
+	if err != nil {
+		log.Printf("Scheduler: failed to render schedule %d: %v", schedule.ID, err)
+		return
+	}
+
+	alert := &queue.Alert{
+		ID:       uuid.New().String(),
+		UserID:   schedule.UserID,
+		Username: schedule.Username,
+		Payload: map[string]interface{}{
+			"message":     message,
+			"priority":    schedule.Priority,
+			"identifier":  schedule.Identifier,
+			"schedule_id": schedule.ID,
+		},
+		Priority:        schedule.Priority,
+		MaxRetries:      queue.DefaultMaxRetries,
+		CreatedAt:       now,
+		BotToken:        schedule.BotToken,
+		ChannelID:       schedule.TelegramChannelID,
+		DBChannelID:     schedule.ChannelID,
+		Format:          schedule.ParseMode,
+		ChannelTemplate: schedule.ChannelTemplate,
+		BotTemplate:     schedule.BotTemplate,
+	}
+
+	if err := s.queue.Enqueue(alert); err != nil {
+		log.Printf("Scheduler: failed to enqueue schedule %d: %v", schedule.ID, err)
+		return
+	}
+
+	log.Printf("Scheduler: queued schedule %d (%s) as alert %s", schedule.ID, schedule.Name, alert.ID)
+}
+
+// NextRun returns the next occurrence of a cron expression after the given
+// time, evaluated in the named IANA timezone
+func NextRun(cronExpr, timezone string, after time.Time) (time.Time, error) {
+	cron, err := ParseCron(cronExpr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+
+	next := cron.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression never matches")
+	}
+
+	return next, nil
+}
+
+// RenderMessage executes a schedule's message template. Templates may use
+// {{.name}}, {{.date}}, {{.time}} and {{.weekday}}, in the schedule's timezone.
+func RenderMessage(message, name, timezone string, now time.Time) (string, error) {
+	tmpl, err := template.New("schedule").Option("missingkey=zero").Parse(message)
+	if err != nil {
+		return "", fmt.Errorf("invalid message template: %w", err)
+	}
+
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return "", fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	local := now.In(loc)
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]string{
+		"name":    name,
+		"date":    local.Format("2006-01-02"),
+		"time":    local.Format("15:04"),
+		"weekday": local.Weekday().String(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render message template: %w", err)
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,43 @@
+package telegram
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// CheckAccess verifies the bot can post to its channel: the chat must exist
+// and the bot must be a member allowed to send messages, which for channels
+// means an administrator with permission to post
+func (b *Bot) CheckAccess() error {
+	chat, err := b.api.GetChat(tgbotapi.ChatInfoConfig{
+		ChatConfig: b.chatConfig(),
+	})
+	if err != nil {
+		return err
+	}
+
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: chat.ID, UserID: b.api.Self.ID},
+	})
+	if err != nil {
+		return err
+	}
+
+	if member.HasLeft() || member.WasKicked() {
+		return fmt.Errorf("bot not a member: add the bot to the chat")
+	}
+
+	if chat.IsChannel() {
+		if !member.IsCreator() && !(member.IsAdministrator() && member.CanPostMessages) {
+			return fmt.Errorf("bot not admin: make the bot an administrator of the channel with permission to post messages")
+		}
+		return nil
+	}
+
+	if member.Status == "restricted" && !member.CanSendMessages {
+		return fmt.Errorf("bot is restricted: allow the bot to send messages in the chat")
+	}
+
+	return nil
+}
@@ -3,10 +3,14 @@ package telegram
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"golang.org/x/time/rate"
@@ -16,21 +20,35 @@ type Bot struct {
 	api            *tgbotapi.BotAPI
 	channelID      string
 	botLimiter     *rate.Limiter // Per-bot rate limiter (30 msg/sec)
-	channelLimiter *rate.Limiter // Per-channel rate limiter (20 msg/min)
+	channelLimiter *rate.Limiter // Per-channel rate limiter (CHANNEL_RATE_PER_MIN, default 20 msg/min)
 }
 
 // BotManager manages multiple bot instances per user
 type BotManager struct {
 	bots            map[string]*tgbotapi.BotAPI // token -> bot instance
 	botLimiters     map[string]*rate.Limiter    // token -> rate limiter (30 msg/sec per bot)
-	channelLimiters map[string]*rate.Limiter    // channelID -> rate limiter (20 msg/min per channel)
-	mu              sync.RWMutex
+	channelLimiters map[string]*rate.Limiter    // token+channelID -> rate limiter (CHANNEL_RATE_PER_MIN per channel)
+	// Per-bot sends per second and per-channel sends per minute set by
+	// users, keyed like the limiters; see SetBotRateLimit
+	botRates     map[string]int
+	channelRates map[string]int
+	mu           sync.RWMutex
 }
 
+// Message formats accepted in webhook payloads and channel configuration
+const (
+	FormatMarkdown   = "markdown"
+	FormatMarkdownV2 = "markdownv2"
+	FormatHTML       = "html"
+	FormatPlain      = "plain"
+)
+
 var globalBotManager = &BotManager{
 	bots:            make(map[string]*tgbotapi.BotAPI),
 	botLimiters:     make(map[string]*rate.Limiter),
 	channelLimiters: make(map[string]*rate.Limiter),
+	botRates:        make(map[string]int),
+	channelRates:    make(map[string]int),
 }
 
 // NewBot creates a bot instance using environment variables (legacy support)
@@ -45,7 +63,7 @@ func NewBot() (*Bot, error) {
 		return nil, fmt.Errorf("TELEGRAM_CHANNEL_ID not set in environment")
 	}
 
-	botAPI, err := tgbotapi.NewBotAPI(token)
+	botAPI, err := tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, httpClient)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create bot: %w", err)
 	}
@@ -81,6 +99,16 @@ func NewBotWithToken(token, channelID string) (*Bot, error) {
 	}, nil
 }
 
+// ChannelID returns the channel this bot sends to
+func (b *Bot) ChannelID() string {
+	return b.channelID
+}
+
+// Username returns the bot's Telegram username
+func (b *Bot) Username() string {
+	return b.api.Self.UserName
+}
+
 // GetOrCreateBot retrieves or creates a bot instance with rate limiters
 func (bm *BotManager) GetOrCreateBot(token string, channelID string) (*tgbotapi.BotAPI, *rate.Limiter, *rate.Limiter, error) {
 	bm.mu.Lock()
@@ -90,7 +118,7 @@ func (bm *BotManager) GetOrCreateBot(token string, channelID string) (*tgbotapi.
 	bot, exists := bm.bots[token]
 	if !exists {
 		var err error
-		bot, err = tgbotapi.NewBotAPI(token)
+		bot, err = tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, httpClient)
 		if err != nil {
 			return nil, nil, nil, fmt.Errorf("failed to create bot API: %w", err)
 		}
@@ -98,26 +126,51 @@ func (bm *BotManager) GetOrCreateBot(token string, channelID string) (*tgbotapi.
 		log.Printf("New Telegram bot authorized: %s", bot.Self.UserName)
 	}
 
-	// Get or create bot rate limiter (30 messages per second)
+	// Get or create bot rate limiter (30 messages per second unless the
+	// bot has its own rate)
 	botLimiter, exists := bm.botLimiters[token]
 	if !exists {
-		// Allow 30 requests per second with burst of 5
-		botLimiter = rate.NewLimiter(rate.Limit(30), 5)
+		botLimiter = rate.NewLimiter(bm.botRate(token), botBurst)
 		bm.botLimiters[token] = botLimiter
 	}
 
-	// Get or create channel rate limiter (60 messages per minute = 1 per second)
-	channelLimiter, exists := bm.channelLimiters[channelID]
+	// Get or create channel rate limiter (see channelRateFromEnv, unless the
+	// channel has its own rate). Keyed on the bot token as well so two users
+	// pointing different bots at the same channel ID never share a bucket.
+	limiterKey := channelLimiterKey(token, channelID)
+	channelLimiter, exists := bm.channelLimiters[limiterKey]
 	if !exists {
-		// Allow 1 message per second (60/min) with burst of 5
-		// This is conservative and safe, well below bot limit of 30/sec
-		channelLimiter = rate.NewLimiter(rate.Limit(1), 5)
-		bm.channelLimiters[channelID] = channelLimiter
+		_, burst := channelRateFromEnv()
+		channelLimiter = rate.NewLimiter(bm.channelRate(limiterKey), burst)
+		bm.channelLimiters[limiterKey] = channelLimiter
 	}
 
 	return bot, botLimiter, channelLimiter, nil
 }
 
+// channelRateFromEnv reads the per-channel send rate from
+// CHANNEL_RATE_PER_MIN (default 20, Telegram's limit for posts to one group
+// or channel) and how many sends may go out back to back from CHANNEL_BURST
+// (default 5). A higher burst delivers short spikes without delay, but
+// sustained bursts beyond Telegram's limit come back as 429s and are
+// retried later; a lower one spaces messages out evenly.
+func channelRateFromEnv() (float64, int) {
+	perMinute := 20.0
+	if v, err := strconv.ParseFloat(os.Getenv("CHANNEL_RATE_PER_MIN"), 64); err == nil && v > 0 {
+		perMinute = v
+	}
+	burst := 5
+	if v, err := strconv.Atoi(os.Getenv("CHANNEL_BURST")); err == nil && v > 0 {
+		burst = v
+	}
+	return perMinute, burst
+}
+
+// channelLimiterKey builds the channelLimiters map key for a bot/channel pair
+func channelLimiterKey(token, channelID string) string {
+	return token + "|" + channelID
+}
+
 // GetBotUsername retrieves the username of a bot by token
 func GetBotUsername(token string) (string, error) {
 	botAPI, _, _, err := globalBotManager.GetOrCreateBot(token, "")
@@ -127,47 +180,342 @@ func GetBotUsername(token string) (string, error) {
 	return botAPI.Self.UserName, nil
 }
 
-func (b *Bot) SendMessage(text string) (string, error) {
+// IsValidFormat reports whether format is a supported message format
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatMarkdown, FormatMarkdownV2, FormatHTML, FormatPlain:
+		return true
+	}
+	return false
+}
+
+// FormatForParseMode maps a Telegram parse mode name ("HTML", "Markdown",
+// "MarkdownV2" or "none", case-insensitive) to the matching message format
+func FormatForParseMode(parseMode string) (string, bool) {
+	switch strings.ToLower(parseMode) {
+	case "html":
+		return FormatHTML, true
+	case "markdown":
+		return FormatMarkdown, true
+	case "markdownv2":
+		return FormatMarkdownV2, true
+	case "none", "plain":
+		return FormatPlain, true
+	}
+	return "", false
+}
+
+// parseModeForFormat maps a message format to the Telegram parse mode,
+// defaulting to Markdown when no format is given
+func parseModeForFormat(format string) string {
+	switch format {
+	case FormatHTML:
+		return tgbotapi.ModeHTML
+	case FormatMarkdownV2:
+		return tgbotapi.ModeMarkdownV2
+	case FormatPlain:
+		return ""
+	default:
+		return tgbotapi.ModeMarkdown
+	}
+}
+
+func (b *Bot) SendMessage(ctx context.Context, text string) (string, error) {
+	return b.SendMessageWithFormat(ctx, text, FormatMarkdown)
+}
+
+// SendMessageWithFormat sends text using the parse mode for the given format.
+// Text over Telegram's length limit is sent as several sequential messages;
+// the response lists every message ID. If a later part fails, the parts
+// already sent are not recalled. The send gives up once ctx is done.
+func (b *Bot) SendMessageWithFormat(ctx context.Context, text string, format string) (string, error) {
+	return b.sendMessage(ctx, text, format, sendOptions{})
+}
+
+// sendOptions are per-send settings beyond the text and format
+type sendOptions struct {
+	keyboard *tgbotapi.InlineKeyboardMarkup // Attached to the last message part
+	silent   bool                           // Sets DisableNotification
+	threadID int                            // Forum topic; 0 is the main chat
+}
+
+// sendMessage sends text as SendMessageWithFormat does, attaching the
+// keyboard, if any, to the last part so the buttons sit under the whole
+// message
+func (b *Bot) sendMessage(ctx context.Context, text string, format string, opts sendOptions) (string, error) {
+	parts := SplitMessage(text, format)
+
+	messageIDs := make([]int, 0, len(parts))
+	var first tgbotapi.Message
+	for i, part := range parts {
+		partOpts := sendOptions{silent: opts.silent, threadID: opts.threadID}
+		if i == len(parts)-1 {
+			partOpts.keyboard = opts.keyboard
+		}
+		sentMsg, err := b.sendPart(ctx, part, format, partOpts)
+		if err != nil {
+			if i > 0 {
+				return "", fmt.Errorf("sent %d of %d message parts: %w", i, len(parts), err)
+			}
+			return "", err
+		}
+		if i == 0 {
+			first = sentMsg
+		}
+		messageIDs = append(messageIDs, sentMsg.MessageID)
+	}
+
+	response := map[string]interface{}{
+		"message_id":  first.MessageID,
+		"message_ids": messageIDs,
+		"chat_id":     first.Chat.ID,
+		"date":        first.Date,
+	}
+
+	responseJSON, _ := json.Marshal(response)
+	return string(responseJSON), nil
+}
+
+// sendPart sends a single message that fits Telegram's length limit, waiting
+// on the bot and channel rate limiters first
+func (b *Bot) sendPart(ctx context.Context, text string, format string, opts sendOptions) (tgbotapi.Message, error) {
+	if err := b.waitForLimiters(ctx); err != nil {
+		return tgbotapi.Message{}, err
+	}
+
+	var sentMsg tgbotapi.Message
+	var err error
+	if opts.threadID != 0 {
+		params := tgbotapi.Params{"text": text}
+		params.AddNonEmpty("parse_mode", parseModeForFormat(format))
+		params.AddBool("disable_web_page_preview", true)
+		sentMsg, err = b.sendInThread(ctx, "sendMessage", params, opts)
+	} else {
+		msg := b.newMessage(text)
+		msg.ParseMode = parseModeForFormat(format)
+		msg.DisableWebPagePreview = true
+		msg.DisableNotification = opts.silent
+		if opts.keyboard != nil {
+			msg.ReplyMarkup = *opts.keyboard
+		}
+		sentMsg, err = request(ctx, func() (tgbotapi.Message, error) {
+			return b.api.Send(msg)
+		})
+	}
+	if err != nil {
+		if rateErr := asRateLimitError(err); rateErr != nil {
+			return tgbotapi.Message{}, rateErr
+		}
+		return tgbotapi.Message{}, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	return sentMsg, nil
+}
+
+// waitForLimiters blocks until both the bot and channel rate limiters allow
+// another send, or ctx is done
+func (b *Bot) waitForLimiters(ctx context.Context) error {
 	// Wait for bot-level rate limit (30 msg/sec)
 	if b.botLimiter != nil {
-		if err := b.botLimiter.Wait(context.Background()); err != nil {
-			return "", fmt.Errorf("bot rate limit error: %w", err)
+		if err := b.botLimiter.Wait(ctx); err != nil {
+			return fmt.Errorf("bot rate limit error: %w", err)
 		}
 	}
 
-	// Wait for channel-level rate limit (20 msg/min)
+	// Wait for channel-level rate limit (CHANNEL_RATE_PER_MIN)
 	if b.channelLimiter != nil {
-		if err := b.channelLimiter.Wait(context.Background()); err != nil {
-			return "", fmt.Errorf("channel rate limit error: %w", err)
+		if err := b.channelLimiter.Wait(ctx); err != nil {
+			return fmt.Errorf("channel rate limit error: %w", err)
 		}
 	}
 
-	msg := tgbotapi.NewMessageToChannel(b.channelID, text)
-	msg.ParseMode = "Markdown"
-	msg.DisableWebPagePreview = true
+	return nil
+}
+
+// request runs a Telegram API call, returning early once ctx is done. The
+// library can't cancel an HTTP request in flight, so an abandoned call still
+// finishes in the background, bounded by the HTTP client's timeout, and may
+// yet deliver the message.
+func request(ctx context.Context, call func() (tgbotapi.Message, error)) (tgbotapi.Message, error) {
+	type result struct {
+		msg tgbotapi.Message
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		msg, err := call()
+		done <- result{msg, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.msg, r.err
+	case <-ctx.Done():
+		return tgbotapi.Message{}, fmt.Errorf("telegram request abandoned: %w", ctx.Err())
+	}
+}
+
+// asRateLimitError converts Telegram's HTTP 429 into a RateLimitError, or
+// returns nil for any other error
+func asRateLimitError(err error) *RateLimitError {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && (apiErr.Code == 429 || apiErr.RetryAfter > 0) {
+		return &RateLimitError{RetryAfter: time.Duration(apiErr.RetryAfter) * time.Second, err: apiErr}
+	}
+	return nil
+}
+
+// RateLimitError is returned when Telegram rejects a send with HTTP 429;
+// RetryAfter is how long Telegram asked us to wait before trying again
+type RateLimitError struct {
+	RetryAfter time.Duration
+	err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("telegram rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.err
+}
+
+// DescribeError explains a failed Telegram call in terms a user can act on,
+// falling back to the error itself
+func DescribeError(err error) string {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return err.Error()
+	}
+
+	message := strings.ToLower(apiErr.Message)
+	switch {
+	case apiErr.Code == 401 || strings.Contains(message, "unauthorized"):
+		return "bad token: Telegram rejected the bot token, check it with @BotFather"
+	case strings.Contains(message, "chat not found"):
+		return "chat not found: check the channel ID and that the bot has been added to the channel"
+	case apiErr.Code == 403 || strings.Contains(message, "not enough rights") ||
+		strings.Contains(message, "administrator") || strings.Contains(message, "not a member"):
+		return "bot not admin: make the bot an administrator of the channel with permission to post messages"
+	default:
+		return apiErr.Message
+	}
+}
+
+// Failure categories recorded for failed sends, so failures caused by
+// configuration can be told apart from transient ones
+const (
+	ErrorCategoryChatNotFound   = "chat_not_found"
+	ErrorCategoryBotBlocked     = "bot_blocked"
+	ErrorCategoryRateLimited    = "rate_limited"
+	ErrorCategoryMessageTooLong = "message_too_long"
+	ErrorCategoryBadToken       = "bad_token"
+	ErrorCategoryTimeout        = "timeout"
+	ErrorCategoryUnknown        = "unknown"
+)
+
+// ClassifyError returns the Telegram API error code of a failed send (0 if
+// the failure didn't come from the API) and its failure category
+func ClassifyError(err error) (int, string) {
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) {
+		return 429, ErrorCategoryRateLimited
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return 0, ErrorCategoryTimeout
+	}
+
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, ErrorCategoryUnknown
+	}
+
+	message := strings.ToLower(apiErr.Message)
+	switch {
+	case apiErr.Code == 429 || apiErr.RetryAfter > 0:
+		return apiErr.Code, ErrorCategoryRateLimited
+	case apiErr.Code == 401 || strings.Contains(message, "unauthorized"):
+		return apiErr.Code, ErrorCategoryBadToken
+	case strings.Contains(message, "chat not found"):
+		return apiErr.Code, ErrorCategoryChatNotFound
+	case strings.Contains(message, "too long"):
+		return apiErr.Code, ErrorCategoryMessageTooLong
+	case apiErr.Code == 403 || strings.Contains(message, "blocked") ||
+		strings.Contains(message, "not enough rights") || strings.Contains(message, "not a member"):
+		return apiErr.Code, ErrorCategoryBotBlocked
+	default:
+		return apiErr.Code, ErrorCategoryUnknown
+	}
+}
+
+// MessageOptions control how a webhook payload is rendered
+type MessageOptions struct {
+	Format   string // "markdown", "markdownv2", "html" or "plain"
+	Template string // Message template; empty uses the built-in layout
+	Notice   string // Plain-text notice prepended to the message, if any
+	Silent   bool   // Deliver without a notification sound
+	ThreadID int    // Forum topic to post in; 0 is the main chat
+}
+
+// BuildMessage renders a webhook payload into the text that would be sent.
+// A non-nil error means the template failed and the built-in layout was used.
+// Raw payloads ("raw": true) are returned as their message, untouched.
+func BuildMessage(username string, payload map[string]interface{}, opts MessageOptions) (string, error) {
+	if raw, _ := payload["raw"].(bool); raw {
+		message, _ := payload["message"].(string)
+		return message, nil
+	}
+
+	message, err := renderMessage(opts.Template, username, payload, opts.Format)
+
+	if opts.Notice != "" {
+		message = escapeText(opts.Notice, opts.Format) + "\n\n" + message
+	}
+
+	return message, err
+}
 
-	sentMsg, err := b.api.Send(msg)
+// SendFormattedWebhookMessage renders a webhook payload and sends it, giving
+// up once ctx is done
+func (b *Bot) SendFormattedWebhookMessage(ctx context.Context, username string, payload map[string]interface{}, opts MessageOptions) (string, error) {
+	message, err := BuildMessage(username, payload, opts)
 	if err != nil {
-		return "", fmt.Errorf("failed to send message: %w", err)
+		// Fall back to the built-in layout rather than dropping the alert
+		log.Printf("Message template failed, using default layout: %v", err)
 	}
 
-	response := map[string]interface{}{
-		"message_id": sentMsg.MessageID,
-		"chat_id":    sentMsg.Chat.ID,
-		"date":       sentMsg.Date,
+	sendOpts := sendOptions{keyboard: inlineKeyboard(payload), silent: opts.Silent, threadID: opts.ThreadID}
+	if photoURL, _ := payload["photo_url"].(string); photoURL != "" {
+		return b.sendMedia(ctx, mediaPhoto, photoURL, message, opts.Format, sendOpts)
+	}
+	if documentURL, _ := payload["document_url"].(string); documentURL != "" {
+		return b.sendMedia(ctx, mediaDocument, documentURL, message, opts.Format, sendOpts)
 	}
 
-	responseJSON, _ := json.Marshal(response)
-	return string(responseJSON), nil
+	return b.sendMessage(ctx, message, opts.Format, sendOpts)
 }
 
-func (b *Bot) SendFormattedWebhookMessage(username string, payload map[string]interface{}) (string, error) {
-	// Just send the message as-is, nothing extra
-	message := ""
+// inlineKeyboard builds a keyboard of link buttons, one per row, from a
+// payload's "buttons" list of {text, url} objects. It returns nil when the
+// payload has no buttons.
+func inlineKeyboard(payload map[string]interface{}) *tgbotapi.InlineKeyboardMarkup {
+	buttons, _ := payload["buttons"].([]interface{})
 
-	if msg, ok := payload["message"].(string); ok && msg != "" {
-		message = msg
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, item := range buttons {
+		button, _ := item.(map[string]interface{})
+		text, _ := button["text"].(string)
+		url, _ := button["url"].(string)
+		if text == "" || url == "" {
+			continue
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(tgbotapi.NewInlineKeyboardButtonURL(text, url)))
 	}
 
-	return b.SendMessage(message)
+	if len(rows) == 0 {
+		return nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &keyboard
 }
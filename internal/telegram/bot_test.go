@@ -0,0 +1,50 @@
+package telegram
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"golang.org/x/time/rate"
+)
+
+func TestChannelLimiterNotSharedAcrossBots(t *testing.T) {
+	// Bots are registered up front so GetOrCreateBot doesn't call Telegram
+	bm := &BotManager{
+		bots: map[string]*tgbotapi.BotAPI{
+			"token-a": {},
+			"token-b": {},
+		},
+		botLimiters:     make(map[string]*rate.Limiter),
+		channelLimiters: make(map[string]*rate.Limiter),
+		botRates:        make(map[string]int),
+		channelRates:    make(map[string]int),
+	}
+
+	const channelID = "-1001234567890"
+	_, _, limiterA, err := bm.GetOrCreateBot("token-a", channelID)
+	if err != nil {
+		t.Fatalf("GetOrCreateBot(token-a): %v", err)
+	}
+	_, _, limiterB, err := bm.GetOrCreateBot("token-b", channelID)
+	if err != nil {
+		t.Fatalf("GetOrCreateBot(token-b): %v", err)
+	}
+	if limiterA == limiterB {
+		t.Fatal("two bots on the same channel ID share a limiter")
+	}
+
+	// Draining one bot's bucket leaves the other's untouched
+	for limiterA.Allow() {
+	}
+	if !limiterB.Allow() {
+		t.Fatal("draining one bot's channel bucket throttled the other bot")
+	}
+
+	_, _, again, err := bm.GetOrCreateBot("token-a", channelID)
+	if err != nil {
+		t.Fatalf("GetOrCreateBot(token-a) again: %v", err)
+	}
+	if again != limiterA {
+		t.Fatal("the same bot and channel got a new limiter")
+	}
+}
@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// chatUsernamePattern matches a public chat's @username: 5-32 letters, digits
+// and underscores, starting with a letter
+var chatUsernamePattern = regexp.MustCompile(`^@[A-Za-z][A-Za-z0-9_]{4,31}$`)
+
+// ValidateChatID checks a channel_id is either a numeric chat ID, such as
+// -1001234567890 for a private channel, or a public chat's @username
+func ValidateChatID(chatID string) error {
+	if _, ok := numericChatID(chatID); ok {
+		return nil
+	}
+	if chatUsernamePattern.MatchString(chatID) {
+		return nil
+	}
+
+	const rules = "channel_id must be a numeric chat ID such as -1001234567890 or a public @username"
+	switch {
+	case chatID != strings.TrimSpace(chatID):
+		return fmt.Errorf("invalid channel_id %q: remove the surrounding spaces; %s", chatID, rules)
+	case chatUsernamePattern.MatchString("@" + chatID):
+		return fmt.Errorf("invalid channel_id %q: did you mean @%s? %s", chatID, chatID, rules)
+	case strings.HasPrefix(chatID, "https://t.me/") || strings.HasPrefix(chatID, "t.me/"):
+		return fmt.Errorf("invalid channel_id %q: use the chat's @username rather than its link; %s", chatID, rules)
+	default:
+		return fmt.Errorf("invalid channel_id %q: %s", chatID, rules)
+	}
+}
+
+// numericChatID parses a chat ID configured as a number rather than an
+// @username
+func numericChatID(chatID string) (int64, bool) {
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	return id, err == nil
+}
+
+// newMessage creates a message to the bot's channel, which may be configured
+// as a numeric chat ID or an @username
+func (b *Bot) newMessage(text string) tgbotapi.MessageConfig {
+	if chatID, ok := numericChatID(b.channelID); ok {
+		return tgbotapi.NewMessage(chatID, text)
+	}
+	return tgbotapi.NewMessageToChannel(b.channelID, text)
+}
+
+// baseChat targets the bot's channel, which may be configured as a numeric
+// chat ID or an @username
+func (b *Bot) baseChat() tgbotapi.BaseChat {
+	if chatID, ok := numericChatID(b.channelID); ok {
+		return tgbotapi.BaseChat{ChatID: chatID}
+	}
+	return tgbotapi.BaseChat{ChannelUsername: b.channelID}
+}
+
+// chatConfig identifies the bot's channel in chat lookups
+func (b *Bot) chatConfig() tgbotapi.ChatConfig {
+	if chatID, ok := numericChatID(b.channelID); ok {
+		return tgbotapi.ChatConfig{ChatID: chatID}
+	}
+	return tgbotapi.ChatConfig{SuperGroupUsername: b.channelID}
+}
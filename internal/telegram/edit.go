@@ -0,0 +1,64 @@
+package telegram
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// EditOptions control how a previously sent message is edited
+type EditOptions struct {
+	Format string // "markdown", "markdownv2", "html" or "plain"
+	// ClearReplyMarkup removes any inline keyboard (e.g. an "Acknowledge"
+	// button) from the message along with the text change
+	ClearReplyMarkup bool
+}
+
+// EditMessage replaces the text of a message this bot sent to its channel.
+// The text must fit in a single message.
+func (b *Bot) EditMessage(messageID int, text string, opts EditOptions) error {
+	edit := tgbotapi.EditMessageTextConfig{
+		BaseEdit:              b.baseEdit(messageID),
+		Text:                  text,
+		ParseMode:             parseModeForFormat(opts.Format),
+		DisableWebPagePreview: true,
+	}
+	if opts.ClearReplyMarkup {
+		edit.ReplyMarkup = emptyInlineKeyboard()
+	}
+
+	if _, err := b.api.Send(edit); err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+	return nil
+}
+
+// ClearReplyMarkup removes the inline keyboard from a message this bot sent
+// to its channel, leaving the text unchanged
+func (b *Bot) ClearReplyMarkup(messageID int) error {
+	edit := tgbotapi.EditMessageReplyMarkupConfig{BaseEdit: b.baseEdit(messageID)}
+	edit.ReplyMarkup = emptyInlineKeyboard()
+
+	if _, err := b.api.Send(edit); err != nil {
+		return fmt.Errorf("failed to clear reply markup: %w", err)
+	}
+	return nil
+}
+
+// baseEdit targets a message in the bot's channel, which may be configured
+// as a numeric chat ID or an @username
+func (b *Bot) baseEdit(messageID int) tgbotapi.BaseEdit {
+	edit := tgbotapi.BaseEdit{MessageID: messageID}
+	if chatID, ok := numericChatID(b.channelID); ok {
+		edit.ChatID = chatID
+	} else {
+		edit.ChannelUsername = b.channelID
+	}
+	return edit
+}
+
+// emptyInlineKeyboard serializes as {"inline_keyboard":[]}, which Telegram
+// treats as "remove the keyboard"
+func emptyInlineKeyboard() *tgbotapi.InlineKeyboardMarkup {
+	return &tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}}
+}
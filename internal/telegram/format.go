@@ -0,0 +1,134 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// dataListItemLimit caps how many array items are rendered before "+N more"
+var dataListItemLimit = loadDataListItemLimit()
+
+func loadDataListItemLimit() int {
+	limit := 10
+	if envLimit := os.Getenv("DATA_LIST_ITEM_LIMIT"); envLimit != "" {
+		if l, err := strconv.Atoi(envLimit); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	return limit
+}
+
+// formatData renders a webhook's data map as readable lines. Scalars render
+// as "key: value", arrays as a compact bulleted list and nested objects as
+// single-line JSON. Keys are sorted so output is stable.
+func formatData(data map[string]interface{}, format string) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		label := boldText(escapeText(key, format), format)
+
+		switch value := data[key].(type) {
+		case []interface{}:
+			b.WriteString(label + ":")
+			shown := len(value)
+			if shown > dataListItemLimit {
+				shown = dataListItemLimit
+			}
+			for _, item := range value[:shown] {
+				b.WriteString("\n• " + escapeText(formatValue(item), format))
+			}
+			if more := len(value) - shown; more > 0 {
+				b.WriteString(fmt.Sprintf("\n• +%d more", more))
+			}
+		default:
+			b.WriteString(label + ": " + escapeText(formatValue(value), format))
+		}
+	}
+
+	return b.String()
+}
+
+// formatValue renders a single data value on one line
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// escapeText escapes text so it renders literally in the given format
+func escapeText(text, format string) string {
+	switch format {
+	case FormatHTML:
+		return html.EscapeString(text)
+	case FormatMarkdownV2:
+		return escapeMarkdownV2(text)
+	case FormatPlain:
+		return text
+	default:
+		return escapeMarkdown(text)
+	}
+}
+
+// escapeMarkdown escapes the characters legacy Markdown treats as entities
+func escapeMarkdown(text string) string {
+	replacer := strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+	return replacer.Replace(text)
+}
+
+// markdownV2Reserved are the characters MarkdownV2 requires escaping anywhere
+// outside an entity
+const markdownV2Reserved = "\\_*[]()~`>#+-=|{}.!"
+
+// escapeMarkdownV2 escapes every MarkdownV2 reserved character, including the
+// backslash itself
+func escapeMarkdownV2(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		if strings.ContainsRune(markdownV2Reserved, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// boldText wraps already-escaped text in bold markup. Legacy Markdown is not
+// bolded: it can't contain escaped characters inside an entity, and keys
+// commonly contain underscores.
+func boldText(text, format string) string {
+	switch format {
+	case FormatHTML:
+		return "<b>" + text + "</b>"
+	case FormatMarkdownV2:
+		return "*" + text + "*"
+	}
+	return text
+}
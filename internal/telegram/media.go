@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// MaxCaptionLength is Telegram's limit on a photo or document caption, in
+// characters
+const MaxCaptionLength = 1024
+
+// Kinds of media a webhook can send by URL
+const (
+	mediaPhoto    = "photo"
+	mediaDocument = "document"
+)
+
+// SendPhoto sends the image at fileURL with caption, which Telegram fetches
+// itself. The response has the same shape as SendMessageWithFormat's.
+func (b *Bot) SendPhoto(ctx context.Context, fileURL, caption, format string) (string, error) {
+	return b.sendMedia(ctx, mediaPhoto, fileURL, caption, format, sendOptions{})
+}
+
+// SendDocument sends the file at fileURL with caption, which Telegram fetches
+// itself. The response has the same shape as SendMessageWithFormat's.
+func (b *Bot) SendDocument(ctx context.Context, fileURL, caption, format string) (string, error) {
+	return b.sendMedia(ctx, mediaDocument, fileURL, caption, format, sendOptions{})
+}
+
+// sendMedia sends a photo or document by URL. A caption too long for Telegram
+// is sent as a message after the file, carrying the keyboard, rather than
+// being cut short.
+func (b *Bot) sendMedia(ctx context.Context, kind, fileURL, caption, format string, opts sendOptions) (string, error) {
+	followUp := ""
+	if utf8.RuneCountInString(caption) > MaxCaptionLength {
+		followUp, caption = caption, ""
+	}
+
+	if err := b.waitForLimiters(ctx); err != nil {
+		return "", err
+	}
+
+	// The keyboard goes on the follow-up message when there is one
+	mediaOpts := opts
+	if followUp != "" {
+		mediaOpts.keyboard = nil
+	}
+
+	var sentMsg tgbotapi.Message
+	var err error
+	if opts.threadID != 0 {
+		params := tgbotapi.Params{kind: fileURL}
+		params.AddNonEmpty("caption", caption)
+		params.AddNonEmpty("parse_mode", parseModeForFormat(format))
+		method := "sendPhoto"
+		if kind == mediaDocument {
+			method = "sendDocument"
+		}
+		sentMsg, err = b.sendInThread(ctx, method, params, mediaOpts)
+	} else {
+		base := tgbotapi.BaseFile{BaseChat: b.baseChat(), File: tgbotapi.FileURL(fileURL)}
+		base.DisableNotification = opts.silent
+		if mediaOpts.keyboard != nil {
+			base.ReplyMarkup = *mediaOpts.keyboard
+		}
+
+		var config tgbotapi.Chattable
+		if kind == mediaPhoto {
+			config = tgbotapi.PhotoConfig{BaseFile: base, Caption: caption, ParseMode: parseModeForFormat(format)}
+		} else {
+			config = tgbotapi.DocumentConfig{BaseFile: base, Caption: caption, ParseMode: parseModeForFormat(format)}
+		}
+		sentMsg, err = request(ctx, func() (tgbotapi.Message, error) {
+			return b.api.Send(config)
+		})
+	}
+	if err != nil {
+		if rateErr := asRateLimitError(err); rateErr != nil {
+			return "", rateErr
+		}
+		if isFetchError(err) {
+			return "", fmt.Errorf("telegram could not fetch the %s from %s, check that the URL is publicly reachable: %w", kind, fileURL, err)
+		}
+		return "", fmt.Errorf("failed to send %s: %w", kind, err)
+	}
+
+	messageIDs := []int{sentMsg.MessageID}
+	if followUp != "" {
+		text, err := b.sendMessage(ctx, followUp, format, opts)
+		if err != nil {
+			return "", fmt.Errorf("sent %s but not its caption: %w", kind, err)
+		}
+		var sent struct {
+			MessageIDs []int `json:"message_ids"`
+		}
+		_ = json.Unmarshal([]byte(text), &sent)
+		messageIDs = append(messageIDs, sent.MessageIDs...)
+	}
+
+	response := map[string]interface{}{
+		"message_id":  sentMsg.MessageID,
+		"message_ids": messageIDs,
+		"chat_id":     sentMsg.Chat.ID,
+		"date":        sentMsg.Date,
+	}
+
+	responseJSON, _ := json.Marshal(response)
+	return string(responseJSON), nil
+}
+
+// isFetchError reports whether Telegram rejected a send because it couldn't
+// download the file URL
+func isFetchError(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	message := strings.ToLower(apiErr.Message)
+	return strings.Contains(message, "http url") ||
+		strings.Contains(message, "wrong file identifier") ||
+		strings.Contains(message, "wrong type of the web page content")
+}
@@ -0,0 +1,129 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxMessageLength is Telegram's limit on message text, in characters
+const MaxMessageLength = 4096
+
+// htmlTagPattern matches opening and closing HTML tags
+var htmlTagPattern = regexp.MustCompile(`<(/?)([a-zA-Z][a-zA-Z0-9-]*)[^>]*>`)
+
+// supportedHTMLTags are the tags Telegram's HTML parse mode accepts
+var supportedHTMLTags = map[string]bool{
+	"b": true, "strong": true, "i": true, "em": true, "u": true, "ins": true,
+	"s": true, "strike": true, "del": true, "span": true, "tg-spoiler": true,
+	"a": true, "code": true, "pre": true, "blockquote": true, "tg-emoji": true,
+}
+
+// FormattingWarnings returns problems likely to make Telegram reject or
+// mangle a rendered message. It is a best-effort check, not a full parser.
+func FormattingWarnings(text, format string) []string {
+	var warnings []string
+
+	if length := utf8.RuneCountInString(text); length > MaxMessageLength {
+		warnings = append(warnings, fmt.Sprintf("message is %d characters, over Telegram's %d limit; it will be sent as %d messages",
+			length, MaxMessageLength, len(SplitMessage(text, format))))
+	}
+
+	switch format {
+	case FormatHTML:
+		warnings = append(warnings, htmlWarnings(text)...)
+	case FormatMarkdownV2:
+		warnings = append(warnings, markdownV2Warnings(text)...)
+	case FormatPlain:
+	default:
+		warnings = append(warnings, markdownWarnings(text)...)
+	}
+
+	return warnings
+}
+
+// markdownWarnings flags legacy Markdown entity characters that are not
+// closed, which Telegram rejects with "can't parse entities"
+func markdownWarnings(text string) []string {
+	var warnings []string
+
+	for _, marker := range []string{"*", "_", "`"} {
+		// Escaped markers are literal and don't open entities
+		unescaped := strings.Count(text, marker) - strings.Count(text, "\\"+marker)
+		if unescaped%2 != 0 {
+			warnings = append(warnings, fmt.Sprintf("unbalanced %q in Markdown; escape it as \\%s or close the entity", marker, marker))
+		}
+	}
+
+	return warnings
+}
+
+// markdownV2Warnings flags reserved characters that never start an entity but
+// are left unescaped, which Telegram rejects in MarkdownV2, along with
+// unbalanced entity markers
+func markdownV2Warnings(text string) []string {
+	var warnings []string
+
+	unescaped := make(map[rune]bool)
+	var order []rune
+	escaped := false
+	for _, r := range text {
+		if escaped {
+			escaped = false
+			continue
+		}
+		if r == '\\' {
+			escaped = true
+			continue
+		}
+		if strings.ContainsRune(".!-=+#|{}", r) && !unescaped[r] {
+			unescaped[r] = true
+			order = append(order, r)
+		}
+	}
+	for _, r := range order {
+		warnings = append(warnings, fmt.Sprintf("unescaped %q in MarkdownV2; escape it as \\%c", string(r), r))
+	}
+
+	for _, marker := range []string{"*", "_", "~", "`"} {
+		count := strings.Count(text, marker) - strings.Count(text, "\\"+marker)
+		if count%2 != 0 {
+			warnings = append(warnings, fmt.Sprintf("unbalanced %q in MarkdownV2; escape it as \\%s or close the entity", marker, marker))
+		}
+	}
+
+	return warnings
+}
+
+// htmlWarnings flags unsupported and unbalanced tags
+func htmlWarnings(text string) []string {
+	var warnings []string
+	var open []string
+
+	for _, match := range htmlTagPattern.FindAllStringSubmatch(text, -1) {
+		closing, tag := match[1] == "/", strings.ToLower(match[2])
+
+		if !supportedHTMLTags[tag] {
+			warnings = append(warnings, fmt.Sprintf("tag <%s> is not supported by Telegram HTML", tag))
+			continue
+		}
+
+		if !closing {
+			open = append(open, tag)
+			continue
+		}
+
+		if len(open) == 0 || open[len(open)-1] != tag {
+			warnings = append(warnings, fmt.Sprintf("closing </%s> does not match an open tag", tag))
+			continue
+		}
+		open = open[:len(open)-1]
+	}
+
+	for _, tag := range open {
+		warnings = append(warnings, fmt.Sprintf("tag <%s> is never closed", tag))
+	}
+
+	return warnings
+}
@@ -0,0 +1,57 @@
+package telegram
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// httpClient is shared by every Telegram API client; ConfigureProxy replaces
+// it when an outbound proxy is configured
+var httpClient = &http.Client{Timeout: 60 * time.Second}
+
+// ConfigureProxy routes Telegram API traffic through TELEGRAM_PROXY_URL, or
+// HTTPS_PROXY when that is unset. http, https, socks5 and socks5h proxies are
+// supported. It must be called before any bot is created.
+func ConfigureProxy() error {
+	rawURL := os.Getenv("TELEGRAM_PROXY_URL")
+	if rawURL == "" {
+		rawURL = os.Getenv("HTTPS_PROXY")
+	}
+	if rawURL == "" {
+		rawURL = os.Getenv("https_proxy")
+	}
+	if rawURL == "" {
+		return nil
+	}
+
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("unsupported proxy scheme %q (use http, https, socks5 or socks5h)", proxyURL.Scheme)
+	}
+
+	if proxyURL.Host == "" {
+		return fmt.Errorf("proxy URL has no host")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+
+	httpClient = &http.Client{
+		Transport: transport,
+		Timeout:   60 * time.Second,
+	}
+
+	// Log host only so proxy credentials never reach the logs
+	log.Printf("Telegram API proxy enabled: %s://%s", proxyURL.Scheme, proxyURL.Host)
+	return nil
+}
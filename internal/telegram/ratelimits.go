@@ -0,0 +1,68 @@
+package telegram
+
+import (
+	"golang.org/x/time/rate"
+)
+
+// Default send rate of a bot across all of its chats, and how many sends may
+// go out back to back
+const (
+	defaultBotRatePerSecond = 30
+	botBurst                = 5
+)
+
+// SetBotRateLimit overrides how many messages per second a bot sends across
+// its chats, for bots with a higher limit approved by Telegram or users who
+// want to stay well below it. 0 reverts to the default. A limiter already in
+// use is changed in place.
+func SetBotRateLimit(token string, perSecond int) {
+	globalBotManager.mu.Lock()
+	defer globalBotManager.mu.Unlock()
+
+	if perSecond > 0 {
+		globalBotManager.botRates[token] = perSecond
+	} else {
+		delete(globalBotManager.botRates, token)
+	}
+
+	if limiter, ok := globalBotManager.botLimiters[token]; ok {
+		limiter.SetLimit(globalBotManager.botRate(token))
+	}
+}
+
+// SetChannelRateLimit overrides how many messages per minute a bot sends to
+// one chat. 0 reverts to CHANNEL_RATE_PER_MIN. A limiter already in use is
+// changed in place.
+func SetChannelRateLimit(token, channelID string, perMinute int) {
+	globalBotManager.mu.Lock()
+	defer globalBotManager.mu.Unlock()
+
+	key := channelLimiterKey(token, channelID)
+	if perMinute > 0 {
+		globalBotManager.channelRates[key] = perMinute
+	} else {
+		delete(globalBotManager.channelRates, key)
+	}
+
+	if limiter, ok := globalBotManager.channelLimiters[key]; ok {
+		limiter.SetLimit(globalBotManager.channelRate(key))
+	}
+}
+
+// botRate returns a bot's send rate. Callers must hold bm.mu.
+func (bm *BotManager) botRate(token string) rate.Limit {
+	if perSecond, ok := bm.botRates[token]; ok {
+		return rate.Limit(perSecond)
+	}
+	return rate.Limit(defaultBotRatePerSecond)
+}
+
+// channelRate returns the send rate for a channelLimiterKey. Callers must
+// hold bm.mu.
+func (bm *BotManager) channelRate(key string) rate.Limit {
+	if perMinute, ok := bm.channelRates[key]; ok {
+		return rate.Limit(float64(perMinute) / 60)
+	}
+	perMinute, _ := channelRateFromEnv()
+	return rate.Limit(perMinute / 60)
+}
@@ -0,0 +1,150 @@
+package telegram
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SplitMessage breaks text longer than MaxMessageLength into parts that each
+// fit in one Telegram message. It splits on newlines where possible and hard
+// cuts lines that are too long on their own. A code block (<pre> in HTML,
+// ``` in Markdown) cut across parts is closed at the end of one part and
+// reopened at the start of the next so each part parses on its own.
+func SplitMessage(text, format string) []string {
+	if utf8.RuneCountInString(text) <= MaxMessageLength {
+		return []string{text}
+	}
+
+	open, close := codeFence(format)
+
+	var parts []string
+	var current strings.Builder
+	currentLen := 0
+	baseLen := 0 // Length of a part holding only a reopened fence
+	inBlock := false
+
+	// Room kept free so a part can always be closed and the next reopened
+	reserve := utf8.RuneCountInString(open) + utf8.RuneCountInString(close) + 1
+	limit := MaxMessageLength - reserve
+
+	flush := func() {
+		part := current.String()
+		if inBlock {
+			part += close
+		}
+		parts = append(parts, strings.TrimRight(part, "\n"))
+
+		current.Reset()
+		baseLen = 0
+		if inBlock {
+			current.WriteString(open)
+			baseLen = utf8.RuneCountInString(open)
+		}
+		currentLen = baseLen
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		for line != "" {
+			lineLen := utf8.RuneCountInString(line)
+			if currentLen+lineLen <= limit {
+				current.WriteString(line)
+				currentLen += lineLen
+				if open != "" {
+					inBlock = fenceState(line, open, close, inBlock)
+				}
+				line = ""
+				continue
+			}
+
+			if currentLen > baseLen {
+				// Start a new part before this line
+				flush()
+				continue
+			}
+
+			// The line alone is too long: hard cut it
+			head, tail := cutRunes(line, limit-currentLen, format)
+			current.WriteString(head)
+			currentLen += utf8.RuneCountInString(head)
+			if open != "" {
+				inBlock = fenceState(head, open, close, inBlock)
+			}
+			flush()
+			line = tail
+		}
+	}
+
+	if currentLen > baseLen {
+		parts = append(parts, current.String())
+	}
+
+	return parts
+}
+
+// codeFence returns the markup that opens and closes a code block in format
+func codeFence(format string) (string, string) {
+	switch format {
+	case FormatHTML:
+		return "<pre>", "</pre>"
+	case FormatPlain:
+		return "", ""
+	default:
+		return "```\n", "\n```"
+	}
+}
+
+// fenceState reports whether a code block is open after text, given whether
+// one was open before it
+func fenceState(text, open, close string, inBlock bool) bool {
+	if open == "<pre>" {
+		opens := strings.Count(text, "<pre>") + strings.Count(text, "<pre ")
+		closes := strings.Count(text, "</pre>")
+		if opens > closes {
+			return true
+		}
+		if closes > opens {
+			return false
+		}
+		return inBlock
+	}
+
+	// Markdown fences toggle
+	if strings.Count(text, "```")%2 == 1 {
+		return !inBlock
+	}
+	return inBlock
+}
+
+// cutRunes splits s after at most n runes, backing off so an escape sequence,
+// HTML tag or HTML entity is not split in two
+func cutRunes(s string, n int, format string) (string, string) {
+	if n < 1 {
+		n = 1
+	}
+
+	i := 0
+	for pos := range s {
+		if i == n {
+			head := s[:pos]
+			switch format {
+			case FormatHTML:
+				if lt := strings.LastIndex(head, "<"); lt > 0 && !strings.Contains(head[lt:], ">") {
+					head = head[:lt]
+				}
+				if amp := strings.LastIndex(head, "&"); amp > 0 && !strings.Contains(head[amp:], ";") {
+					head = head[:amp]
+				}
+			case FormatPlain:
+			default:
+				// An odd run of trailing backslashes ends in an unfinished escape
+				trailing := len(head) - len(strings.TrimRight(head, "\\"))
+				if trailing%2 == 1 && len(head) > 1 {
+					head = head[:len(head)-1]
+				}
+			}
+			return head, s[len(head):]
+		}
+		i++
+	}
+	return s, ""
+}
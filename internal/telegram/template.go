@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// ValidateTemplate checks that a message template parses and renders a
+// sample alert, so mistakes such as indexing into the data block are caught
+// when the template is saved rather than on every alert. Templates may use
+// {{.message}}, {{.data}} (the formatted data block), {{.username}},
+// {{.identifier}} and {{.priority}}.
+func ValidateTemplate(tmpl string) error {
+	_, err := executeTemplate(tmpl, map[string]interface{}{
+		"message":    "Test alert",
+		"data":       "",
+		"username":   "user",
+		"identifier": "",
+		"priority":   3,
+	})
+	return err
+}
+
+// renderMessage builds the outgoing text for a webhook payload. An empty
+// template uses the built-in layout: the message followed by any data. If the
+// template fails, the built-in layout is returned along with the error so the
+// alert is still delivered.
+func renderMessage(tmpl, username string, payload map[string]interface{}, format string) (string, error) {
+	message, _ := payload["message"].(string)
+
+	dataBlock := ""
+	if data, ok := payload["data"].(map[string]interface{}); ok && len(data) > 0 {
+		dataBlock = formatData(data, format)
+	}
+
+	var tmplErr error
+	if tmpl != "" {
+		rendered, err := executeTemplate(tmpl, map[string]interface{}{
+			"message":    message,
+			"data":       dataBlock,
+			"username":   username,
+			"identifier": payload["identifier"],
+			"priority":   payload["priority"],
+		})
+		if err == nil {
+			return rendered, nil
+		}
+		tmplErr = err
+	}
+
+	if dataBlock != "" {
+		message += "\n\n" + dataBlock
+	}
+	return message, tmplErr
+}
+
+func executeTemplate(tmpl string, vars map[string]interface{}) (string, error) {
+	t, err := template.New("message").Option("missingkey=zero").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid message template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render message template: %w", err)
+	}
+
+	return buf.String(), nil
+}
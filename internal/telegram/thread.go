@@ -0,0 +1,34 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// sendInThread calls a send method with message_thread_id set, which the
+// library's config types don't support, so params carries the method's own
+// fields and the common ones are added here
+func (b *Bot) sendInThread(ctx context.Context, method string, params tgbotapi.Params, opts sendOptions) (tgbotapi.Message, error) {
+	params["chat_id"] = b.channelID
+	params.AddNonZero("message_thread_id", opts.threadID)
+	params.AddBool("disable_notification", opts.silent)
+	if opts.keyboard != nil {
+		if err := params.AddInterface("reply_markup", *opts.keyboard); err != nil {
+			return tgbotapi.Message{}, fmt.Errorf("failed to encode reply markup: %w", err)
+		}
+	}
+
+	return request(ctx, func() (tgbotapi.Message, error) {
+		resp, err := b.api.MakeRequest(method, params)
+		if err != nil {
+			return tgbotapi.Message{}, err
+		}
+
+		var message tgbotapi.Message
+		err = json.Unmarshal(resp.Result, &message)
+		return message, err
+	})
+}
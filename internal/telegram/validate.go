@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// tokenInfoTTL is how long a getMe answer for a token is reused
+const tokenInfoTTL = time.Minute
+
+// TokenInfo is what Telegram's getMe reports about a bot token. Valid is
+// false when Telegram rejected the token, with Error saying why.
+type TokenInfo struct {
+	Valid                   bool   `json:"valid"`
+	Error                   string `json:"error,omitempty"`
+	BotID                   int64  `json:"bot_id,omitempty"`
+	Username                string `json:"username,omitempty"`
+	FirstName               string `json:"first_name,omitempty"`
+	CanJoinGroups           bool   `json:"can_join_groups"`
+	CanReadAllGroupMessages bool   `json:"can_read_all_group_messages"`
+	SupportsInlineQueries   bool   `json:"supports_inline_queries"`
+}
+
+type cachedTokenInfo struct {
+	info      TokenInfo
+	expiresAt time.Time
+}
+
+var (
+	tokenInfoCache   = make(map[string]cachedTokenInfo)
+	tokenInfoCacheMu sync.Mutex
+)
+
+// ValidateToken calls getMe with a bot token without registering the bot,
+// so a dashboard can check a token as it is typed. Answers, including
+// rejections, are cached for a minute; an error means Telegram couldn't be
+// reached and nothing was cached.
+func ValidateToken(token string) (TokenInfo, error) {
+	tokenInfoCacheMu.Lock()
+	cached, ok := tokenInfoCache[token]
+	tokenInfoCacheMu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.info, nil
+	}
+
+	var info TokenInfo
+	botAPI, err := tgbotapi.NewBotAPIWithClient(token, tgbotapi.APIEndpoint, httpClient)
+	if err != nil {
+		var apiErr *tgbotapi.Error
+		if !errors.As(err, &apiErr) {
+			return TokenInfo{}, err
+		}
+		info = TokenInfo{Valid: false, Error: DescribeError(err)}
+	} else {
+		self := botAPI.Self
+		info = TokenInfo{
+			Valid:                   true,
+			BotID:                   self.ID,
+			Username:                self.UserName,
+			FirstName:               self.FirstName,
+			CanJoinGroups:           self.CanJoinGroups,
+			CanReadAllGroupMessages: self.CanReadAllGroupMessages,
+			SupportsInlineQueries:   self.SupportsInlineQueries,
+		}
+	}
+
+	tokenInfoCacheMu.Lock()
+	defer tokenInfoCacheMu.Unlock()
+	now := time.Now()
+	for key, entry := range tokenInfoCache {
+		if now.After(entry.expiresAt) {
+			delete(tokenInfoCache, key)
+		}
+	}
+	tokenInfoCache[token] = cachedTokenInfo{info: info, expiresAt: now.Add(tokenInfoTTL)}
+
+	return info, nil
+}
@@ -0,0 +1,507 @@
+// Package transform implements a small, jq-like expression language used to
+// reshape incoming webhook JSON into a telehook payload.
+//
+// Supported syntax:
+//
+//	.                  the whole input
+//	.a.b[0]."c d"      object fields and array indexes; missing values are null
+//	"text" 42 true null
+//	{message: expr, "data": expr}
+//	a + b              numbers add, null is ignored, anything else concatenates as text
+//	a // b             a unless it is null or false, otherwise b
+//	( expr )
+//
+// Evaluation is a single pass over the parsed expression, so its cost is
+// bounded by MaxExpressionLength; results are further capped by MaxOutputBytes.
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MaxExpressionLength bounds the size of an expression
+	MaxExpressionLength = 2048
+	// MaxDepth bounds how deeply expressions may nest
+	MaxDepth = 32
+	// MaxOutputBytes bounds the length of any string built while evaluating
+	MaxOutputBytes = 64 * 1024
+)
+
+// Expression is a compiled transformation
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses and validates an expression
+func Compile(source string) (*Expression, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+	if len(source) > MaxExpressionLength {
+		return nil, fmt.Errorf("expression exceeds %d characters", MaxExpressionLength)
+	}
+
+	p := &parser{src: source}
+	root, err := p.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", string(p.src[p.pos]))
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the expression's source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Apply evaluates the expression against decoded JSON input. A string result
+// is treated as the message; otherwise the result must be an object.
+func (e *Expression) Apply(input interface{}) (map[string]interface{}, error) {
+	result, err := e.root.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r := result.(type) {
+	case map[string]interface{}:
+		return r, nil
+	case string:
+		return map[string]interface{}{"message": r}, nil
+	default:
+		return nil, fmt.Errorf("expression must produce an object or string, got %s", typeName(result))
+	}
+}
+
+// ============================================================================
+// Evaluation
+// ============================================================================
+
+type node interface {
+	eval(input interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n literalNode) eval(interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+// pathStep is either a field name or an array index
+type pathStep struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+type pathNode struct {
+	steps []pathStep
+}
+
+func (n pathNode) eval(input interface{}) (interface{}, error) {
+	current := input
+	for _, step := range n.steps {
+		if current == nil {
+			return nil, nil
+		}
+		if step.isIndex {
+			arr, ok := current.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("cannot index %s with [%d]", typeName(current), step.index)
+			}
+			idx := step.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, nil
+			}
+			current = arr[idx]
+			continue
+		}
+		obj, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot get field %q of %s", step.field, typeName(current))
+		}
+		current = obj[step.field]
+	}
+	return current, nil
+}
+
+type objectField struct {
+	key   string
+	value node
+}
+
+type objectNode struct {
+	fields []objectField
+}
+
+func (n objectNode) eval(input interface{}) (interface{}, error) {
+	obj := make(map[string]interface{}, len(n.fields))
+	for _, field := range n.fields {
+		v, err := field.value.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		obj[field.key] = v
+	}
+	return obj, nil
+}
+
+type addNode struct {
+	left, right node
+}
+
+func (n addNode) eval(input interface{}) (interface{}, error) {
+	l, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if l == nil {
+		return r, nil
+	}
+	if r == nil {
+		return l, nil
+	}
+	if lf, ok := l.(float64); ok {
+		if rf, ok := r.(float64); ok {
+			return lf + rf, nil
+		}
+	}
+
+	ls, err := toText(l)
+	if err != nil {
+		return nil, err
+	}
+	rs, err := toText(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(ls)+len(rs) > MaxOutputBytes {
+		return nil, fmt.Errorf("result exceeds %d bytes", MaxOutputBytes)
+	}
+	return ls + rs, nil
+}
+
+type alternativeNode struct {
+	left, right node
+}
+
+func (n alternativeNode) eval(input interface{}) (interface{}, error) {
+	l, err := n.left.eval(input)
+	if err == nil && l != nil && l != false {
+		return l, nil
+	}
+	return n.right.eval(input)
+}
+
+// toText renders a value for string concatenation
+func toText(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	default:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return "", fmt.Errorf("cannot convert %s to text: %w", typeName(v), err)
+		}
+		if len(b) > MaxOutputBytes {
+			return "", fmt.Errorf("result exceeds %d bytes", MaxOutputBytes)
+		}
+		return string(b), nil
+	}
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// ============================================================================
+// Parsing
+// ============================================================================
+
+type parser struct {
+	src string
+	pos int
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("at position %d: %s", p.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// consume skips whitespace and then tok if it is next
+func (p *parser) consume(tok string) bool {
+	p.skipSpace()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// parseExpr parses alternatives: concat ('//' concat)*
+func (p *parser) parseExpr(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, p.errorf("expression nested more than %d levels", MaxDepth)
+	}
+
+	left, err := p.parseConcat(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("//") {
+		right, err := p.parseConcat(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = alternativeNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseConcat parses term ('+' term)*
+func (p *parser) parseConcat(depth int) (node, error) {
+	left, err := p.parseTerm(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.consume("+") {
+		right, err := p.parseTerm(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = addNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseTerm(depth int) (node, error) {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of expression")
+	}
+
+	switch c := p.src[p.pos]; {
+	case c == '.':
+		return p.parsePath()
+	case c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return literalNode{value: s}, nil
+	case c == '{':
+		return p.parseObject(depth + 1)
+	case c == '(':
+		p.pos++
+		inner, err := p.parseExpr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return inner, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	case isIdentStart(c):
+		switch word := p.parseIdent(); word {
+		case "null":
+			return literalNode{value: nil}, nil
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		default:
+			return nil, p.errorf("unknown keyword %q", word)
+		}
+	default:
+		return nil, p.errorf("unexpected %q", string(c))
+	}
+}
+
+// parsePath parses '.' followed by any number of .field, ."field" and [n]
+func (p *parser) parsePath() (node, error) {
+	var steps []pathStep
+	p.pos++ // leading '.'
+
+	// A bare field may follow the leading dot directly
+	if p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || p.src[p.pos] == '"') {
+		p.pos--
+	}
+
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '.':
+			p.pos++
+			if p.pos < len(p.src) && p.src[p.pos] == '"' {
+				field, err := p.parseString()
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, pathStep{field: field})
+			} else if p.pos < len(p.src) && isIdentStart(p.src[p.pos]) {
+				steps = append(steps, pathStep{field: p.parseIdent()})
+			} else {
+				return nil, p.errorf("expected field name after '.'")
+			}
+		case '[':
+			p.pos++
+			start := p.pos
+			if p.pos < len(p.src) && p.src[p.pos] == '-' {
+				p.pos++
+			}
+			for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+				p.pos++
+			}
+			idx, err := strconv.Atoi(p.src[start:p.pos])
+			if err != nil {
+				return nil, p.errorf("expected array index")
+			}
+			if p.pos >= len(p.src) || p.src[p.pos] != ']' {
+				return nil, p.errorf("expected ]")
+			}
+			p.pos++
+			steps = append(steps, pathStep{index: idx, isIndex: true})
+		default:
+			return pathNode{steps: steps}, nil
+		}
+	}
+	return pathNode{steps: steps}, nil
+}
+
+func (p *parser) parseObject(depth int) (node, error) {
+	if depth > MaxDepth {
+		return nil, p.errorf("expression nested more than %d levels", MaxDepth)
+	}
+	p.pos++ // '{'
+
+	obj := objectNode{}
+	if p.consume("}") {
+		return obj, nil
+	}
+
+	for {
+		p.skipSpace()
+		var key string
+		switch {
+		case p.pos < len(p.src) && p.src[p.pos] == '"':
+			s, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			key = s
+		case p.pos < len(p.src) && isIdentStart(p.src[p.pos]):
+			key = p.parseIdent()
+		default:
+			return nil, p.errorf("expected object key")
+		}
+
+		if !p.consume(":") {
+			return nil, p.errorf("expected : after key %q", key)
+		}
+		value, err := p.parseExpr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		obj.fields = append(obj.fields, objectField{key: key, value: value})
+
+		if p.consume("}") {
+			return obj, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or }")
+		}
+	}
+}
+
+// parseString parses a JSON string literal
+func (p *parser) parseString() (string, error) {
+	start := p.pos
+	p.pos++ // opening quote
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			var s string
+			if err := json.Unmarshal([]byte(p.src[start:p.pos]), &s); err != nil {
+				return "", fmt.Errorf("at position %d: invalid string literal", start+1)
+			}
+			return s, nil
+		default:
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("at position %d: unterminated string", start+1)
+}
+
+func (p *parser) parseNumber() (node, error) {
+	start := p.pos
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("at position %d: invalid number", start+1)
+	}
+	return literalNode{value: f}, nil
+}
+
+func (p *parser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || p.src[p.pos] >= '0' && p.src[p.pos] <= '9') {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
@@ -0,0 +1,100 @@
+package vendors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+// ParseDatadog converts a Datadog webhook into a payload. Datadog payloads are
+// user-defined templates, so the fields recognized are those of the default
+// template (title, body, id, date) plus the common additions: alert_type
+// ($ALERT_TYPE), alert_transition ($ALERT_TRANSITION), priority ($PRIORITY),
+// link ($LINK), tags ($TAGS), hostname ($HOSTNAME) and aggreg_key
+// ($AGGREG_KEY). Updates sharing an aggregation key or alert ID share a
+// correlation ID so channels can coalesce them.
+func ParseDatadog(body []byte) (*models.WebhookPayload, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil, fmt.Errorf("invalid Datadog payload: %w", err)
+	}
+
+	title := stringValue(raw["title"])
+	text := stringValue(raw["body"])
+	if title == "" && text == "" {
+		return nil, fmt.Errorf("invalid Datadog payload: title or body is required")
+	}
+
+	alertType := strings.ToLower(stringValue(raw["alert_type"]))
+	transition := stringValue(raw["alert_transition"])
+
+	heading := datadogIcon(alertType)
+	if transition != "" {
+		heading += " " + transition + ":"
+	}
+	if title != "" {
+		heading += " " + title
+	}
+
+	message := heading
+	if text != "" {
+		message += "\n\n" + text
+	}
+
+	data := map[string]interface{}{"source": "Datadog"}
+	setIfPresent(data, "alert_type", alertType)
+	setIfPresent(data, "host", stringValue(raw["hostname"]))
+	setIfPresent(data, "link", stringValue(raw["link"]))
+	setIfPresent(data, "date", stringValue(raw["date"]))
+	if tags := tagList(raw["tags"]); len(tags) > 0 {
+		data["tags"] = strings.Join(tags, ", ")
+	}
+
+	correlationID := stringValue(raw["aggreg_key"])
+	if correlationID == "" {
+		correlationID = stringValue(raw["alert_id"])
+	}
+	if correlationID != "" {
+		correlationID = "datadog:" + correlationID
+	}
+
+	return &models.WebhookPayload{
+		Message:       message,
+		Data:          data,
+		Priority:      datadogPriority(alertType, strings.ToLower(stringValue(raw["priority"]))),
+		CorrelationID: correlationID,
+	}, nil
+}
+
+// datadogPriority maps Datadog's alert type to a priority, lowering it one
+// level for monitors Datadog marks as low priority
+func datadogPriority(alertType, priority string) int {
+	p := 3
+	switch alertType {
+	case "error":
+		p = 1
+	case "warning":
+		p = 2
+	case "success":
+		p = 4
+	}
+	if priority == "low" && p < 4 {
+		p++
+	}
+	return p
+}
+
+func datadogIcon(alertType string) string {
+	switch alertType {
+	case "error":
+		return "🔴"
+	case "warning":
+		return "🟠"
+	case "success":
+		return "✅"
+	default:
+		return "ℹ️"
+	}
+}
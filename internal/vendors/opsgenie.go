@@ -0,0 +1,147 @@
+package vendors
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/thenaveensharma/telehook/internal/models"
+)
+
+// opsgenieWebhook is the body of an Opsgenie outgoing webhook integration
+type opsgenieWebhook struct {
+	Action string `json:"action"` // "Create", "Close", "Acknowledge", "AddNote", ...
+	Alert  struct {
+		AlertID     string                 `json:"alertId"`
+		TinyID      string                 `json:"tinyId"`
+		Alias       string                 `json:"alias"`
+		Message     string                 `json:"message"`
+		Description string                 `json:"description"`
+		Entity      string                 `json:"entity"`
+		Source      string                 `json:"source"`
+		Priority    string                 `json:"priority"` // "P1" (critical) to "P5" (informational)
+		Tags        []string               `json:"tags"`
+		Details     map[string]interface{} `json:"details"`
+		Username    string                 `json:"username"`
+	} `json:"alert"`
+}
+
+// ParseOpsgenie converts an Opsgenie outgoing webhook into a payload. Only
+// newly created alerts keep their full priority; closes, acknowledgements
+// and other actions are sent at normal priority or lower. Every action on an
+// alert shares a correlation ID so channels can coalesce them.
+func ParseOpsgenie(body []byte) (*models.WebhookPayload, error) {
+	var hook opsgenieWebhook
+	if err := json.Unmarshal(body, &hook); err != nil {
+		return nil, fmt.Errorf("invalid Opsgenie payload: %w", err)
+	}
+
+	alert := hook.Alert
+	if alert.Message == "" {
+		return nil, fmt.Errorf("invalid Opsgenie payload: alert.message is required")
+	}
+
+	action := hook.Action
+	if action == "" {
+		action = "Create"
+	}
+
+	heading := opsgenieIcon(action, alert.Priority)
+	if alert.Priority != "" {
+		heading += " [" + strings.ToUpper(alert.Priority) + "]"
+	}
+	heading += " " + opsgenieActionLabel(action) + ": " + alert.Message
+
+	message := heading
+	if description := strings.TrimSpace(alert.Description); description != "" {
+		message += "\n\n" + description
+	}
+
+	data := map[string]interface{}{"source": "Opsgenie"}
+	setIfPresent(data, "alert", alert.TinyID)
+	setIfPresent(data, "entity", alert.Entity)
+	setIfPresent(data, "origin", alert.Source)
+	if action != "Create" {
+		setIfPresent(data, "by", alert.Username)
+	}
+	if len(alert.Tags) > 0 {
+		data["tags"] = strings.Join(alert.Tags, ", ")
+	}
+	for key, value := range alert.Details {
+		if _, taken := data[key]; !taken {
+			data[key] = value
+		}
+	}
+
+	priority := opsgeniePriority(alert.Priority)
+	if action != "Create" && priority < 3 {
+		priority = 3
+	}
+
+	correlationID := alert.Alias
+	if correlationID == "" {
+		correlationID = alert.AlertID
+	}
+	if correlationID != "" {
+		correlationID = "opsgenie:" + correlationID
+	}
+
+	return &models.WebhookPayload{
+		Message:       message,
+		Data:          data,
+		Priority:      priority,
+		CorrelationID: correlationID,
+	}, nil
+}
+
+// opsgeniePriority maps P1-P5 onto telehook priorities; P4 and P5 are both low
+func opsgeniePriority(priority string) int {
+	switch strings.ToUpper(priority) {
+	case "P1":
+		return 1
+	case "P2":
+		return 2
+	case "P4", "P5":
+		return 4
+	default:
+		return 3
+	}
+}
+
+// opsgenieActionLabel describes an action in past tense for the message
+func opsgenieActionLabel(action string) string {
+	switch action {
+	case "Create":
+		return "Alert"
+	case "Close":
+		return "Closed"
+	case "Acknowledge":
+		return "Acknowledged"
+	case "UnAcknowledge":
+		return "Unacknowledged"
+	case "Escalate":
+		return "Escalated"
+	case "AddNote":
+		return "Note added"
+	default:
+		return action
+	}
+}
+
+func opsgenieIcon(action, priority string) string {
+	switch action {
+	case "Close":
+		return "✅"
+	case "Acknowledge":
+		return "👀"
+	case "Create", "Escalate":
+		switch opsgeniePriority(priority) {
+		case 1:
+			return "🔴"
+		case 2:
+			return "🟠"
+		}
+		return "🟡"
+	}
+	return "ℹ️"
+}
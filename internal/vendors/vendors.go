@@ -0,0 +1,52 @@
+// Package vendors turns webhook payloads from third-party alerting tools into
+// telehook webhook payloads. Each vendor's parser is self-contained: it reads
+// the vendor's JSON, renders a readable message and maps the vendor's
+// severity onto telehook priorities (1=urgent, 2=high, 3=normal, 4=low).
+package vendors
+
+import (
+	"fmt"
+	"strings"
+)
+
+// stringValue reads a JSON value as a trimmed string, accepting numbers since
+// vendors are inconsistent about ID types
+func stringValue(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return strings.TrimSpace(s)
+	case float64:
+		return strings.TrimSpace(fmt.Sprintf("%.0f", s))
+	case nil:
+		return ""
+	default:
+		return strings.TrimSpace(fmt.Sprint(s))
+	}
+}
+
+// tagList reads tags sent either as a JSON array or a comma-separated string
+func tagList(v interface{}) []string {
+	var tags []string
+	switch t := v.(type) {
+	case []interface{}:
+		for _, tag := range t {
+			if s := stringValue(tag); s != "" {
+				tags = append(tags, s)
+			}
+		}
+	case string:
+		for _, tag := range strings.Split(t, ",") {
+			if s := strings.TrimSpace(tag); s != "" {
+				tags = append(tags, s)
+			}
+		}
+	}
+	return tags
+}
+
+// setIfPresent adds a non-empty value to data
+func setIfPresent(data map[string]interface{}, key, value string) {
+	if value != "" {
+		data[key] = value
+	}
+}
@@ -0,0 +1,7 @@
+-- Migration: Per-channel default message format
+-- Created: 2026-10-16
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS parse_mode VARCHAR(20) NOT NULL DEFAULT 'markdown';
+
+COMMENT ON COLUMN telegram_channels.parse_mode IS 'Default message format (markdown, html, plain), overridable per webhook';
@@ -0,0 +1,24 @@
+-- Migration: Scheduled recurring messages
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS scheduled_messages (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_id INTEGER NOT NULL REFERENCES telegram_channels(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    cron_expression VARCHAR(100) NOT NULL, -- e.g., "0 9 * * 1-5"
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA timezone the cron is evaluated in
+    message TEXT NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 3,
+    is_active BOOLEAN DEFAULT true,
+    next_run_at TIMESTAMP NOT NULL, -- stored in UTC
+    last_run_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_user_id ON scheduled_messages(user_id);
+CREATE INDEX IF NOT EXISTS idx_scheduled_messages_due ON scheduled_messages(next_run_at) WHERE is_active = true;
+
+COMMENT ON TABLE scheduled_messages IS 'Recurring messages sent on a cron schedule';
+COMMENT ON COLUMN scheduled_messages.next_run_at IS 'Next occurrence; replicas claim a run by advancing it with a compare-and-swap update';
@@ -0,0 +1,19 @@
+-- Migration: Aggregated delivery receipts posted to an external endpoint
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS receipt_webhooks (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret VARCHAR(128) NOT NULL, -- HMAC-SHA256 key used to sign receipts
+    interval_minutes INTEGER NOT NULL DEFAULT 60,
+    is_active BOOLEAN DEFAULT true,
+    next_send_at TIMESTAMP NOT NULL, -- stored in UTC
+    last_window_end TIMESTAMP, -- end of the last reported window, in UTC
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_receipt_webhooks_due ON receipt_webhooks(next_send_at) WHERE is_active = true;
+
+COMMENT ON TABLE receipt_webhooks IS 'Per-user endpoint that periodically receives signed delivery count summaries';
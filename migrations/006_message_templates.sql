@@ -0,0 +1,11 @@
+-- Migration: Message templates with bot-level inheritance
+-- Created: 2026-10-16
+
+ALTER TABLE telegram_bots
+ADD COLUMN IF NOT EXISTS message_template TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS message_template TEXT NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN telegram_bots.message_template IS 'Default message template for the bot''s channels; empty uses the built-in layout';
+COMMENT ON COLUMN telegram_channels.message_template IS 'Channel message template; empty inherits the bot''s template';
@@ -0,0 +1,15 @@
+-- Migration: Case-insensitive channel identifiers
+-- Created: 2026-10-16
+
+-- Lowercased identifier, kept in sync by Postgres, used for case-insensitive routing
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS identifier_normalized VARCHAR(50) GENERATED ALWAYS AS (LOWER(identifier)) STORED;
+
+-- Reject identifiers that differ only in case. If this fails, find the
+-- conflicting rows with:
+--   SELECT user_id, LOWER(identifier) FROM telegram_channels
+--   GROUP BY 1, 2 HAVING COUNT(*) > 1;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_channels_identifier_normalized
+    ON telegram_channels(user_id, identifier_normalized);
+
+COMMENT ON COLUMN telegram_channels.identifier_normalized IS 'Lowercased identifier for case-insensitive matching';
@@ -0,0 +1,35 @@
+-- Migration: Escalation policies and dead-letter store for urgent alerts
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS escalation_policies (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel_id INTEGER REFERENCES telegram_channels(id) ON DELETE CASCADE, -- NULL = user default
+    failover_channel_id INTEGER REFERENCES telegram_channels(id) ON DELETE SET NULL,
+    mirror_url TEXT NOT NULL DEFAULT '',
+    dead_letter BOOLEAN NOT NULL DEFAULT true,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One default policy per user plus at most one per channel
+CREATE UNIQUE INDEX IF NOT EXISTS idx_escalation_policies_scope
+    ON escalation_policies(user_id, (COALESCE(channel_id, 0)));
+
+CREATE TABLE IF NOT EXISTS dead_letter_alerts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    alert_id VARCHAR(64) NOT NULL,
+    channel_id INTEGER REFERENCES telegram_channels(id) ON DELETE SET NULL,
+    payload JSONB NOT NULL,
+    priority INTEGER NOT NULL,
+    retries INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_dead_letter_alerts_user ON dead_letter_alerts(user_id, created_at DESC);
+
+COMMENT ON TABLE escalation_policies IS 'What to do when an urgent (priority 1) alert exhausts its retries';
+COMMENT ON TABLE dead_letter_alerts IS 'Alerts that permanently failed delivery, kept for inspection and replay';
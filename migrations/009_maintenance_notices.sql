@@ -0,0 +1,16 @@
+-- Migration: Time-bounded maintenance notices prepended to outgoing messages
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS maintenance_notices (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE, -- NULL = all users
+    message TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL, -- stored in UTC
+    ends_at TIMESTAMP NOT NULL, -- stored in UTC
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_notices_window ON maintenance_notices(ends_at, starts_at);
+
+COMMENT ON TABLE maintenance_notices IS 'Admin-set notices prepended to messages while their window is active';
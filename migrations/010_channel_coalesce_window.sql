@@ -0,0 +1,7 @@
+-- Migration: Per-channel coalesce window for rapid updates sharing a correlation id
+-- Created: 2026-10-16
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS coalesce_window_seconds INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN telegram_channels.coalesce_window_seconds IS 'Messages with the same correlation_id within this window replace each other; 0 disables coalescing';
@@ -0,0 +1,16 @@
+-- Migration: Track per-alert delivery status for batch webhook submissions
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS batch_alerts (
+    alert_id UUID PRIMARY KEY,
+    batch_id UUID NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued', -- queued, completed, failed
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_batch_alerts_batch ON batch_alerts(batch_id, user_id);
+
+COMMENT ON TABLE batch_alerts IS 'Outcome of each alert submitted through the batch webhook endpoint';
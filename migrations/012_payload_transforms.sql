@@ -0,0 +1,13 @@
+-- Migration: Per-token expressions reshaping incoming webhook payloads
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS payload_transforms (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expression TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE payload_transforms IS 'jq-like expression applied to raw webhook JSON to produce the message and data fields';
@@ -0,0 +1,14 @@
+-- Migration: Account-level defaults for webhook retries and backoff
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    default_max_retries INTEGER, -- NULL = queue default
+    backoff_base_seconds INTEGER, -- NULL = queue default
+    backoff_max_seconds INTEGER, -- NULL = uncapped
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE user_settings IS 'Per-user defaults applied when a webhook payload does not set them';
+COMMENT ON COLUMN user_settings.default_max_retries IS 'Precedence: payload max_retries, then this, then the queue default';
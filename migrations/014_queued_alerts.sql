@@ -0,0 +1,32 @@
+-- Migration: Persist queued alerts so pending alerts survive restarts
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS queued_alerts (
+    id UUID PRIMARY KEY, -- queue alert ID
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    username VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    priority INTEGER NOT NULL,
+    retries INTEGER NOT NULL DEFAULT 0,
+    max_retries INTEGER NOT NULL DEFAULT 0,
+    scheduled_at TIMESTAMP NOT NULL, -- stored in UTC
+    bot_token VARCHAR(255) NOT NULL DEFAULT '', -- empty = legacy env bot
+    telegram_channel_id VARCHAR(255) NOT NULL DEFAULT '',
+    channel_id INTEGER, -- telegram_channels.id, NULL in legacy mode
+    format VARCHAR(20) NOT NULL DEFAULT '',
+    channel_template TEXT NOT NULL DEFAULT '',
+    bot_template TEXT NOT NULL DEFAULT '',
+    correlation_id VARCHAR(255) NOT NULL DEFAULT '',
+    coalesce_window_ms BIGINT NOT NULL DEFAULT 0,
+    batch_id UUID,
+    backoff_base_ms BIGINT NOT NULL DEFAULT 0,
+    backoff_max_ms BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, processed, failed
+    created_at TIMESTAMP NOT NULL, -- stored in UTC
+    updated_at TIMESTAMP NOT NULL -- stored in UTC
+);
+
+CREATE INDEX IF NOT EXISTS idx_queued_alerts_pending ON queued_alerts(priority, created_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_queued_alerts_finished ON queued_alerts(updated_at) WHERE status <> 'pending';
+
+COMMENT ON TABLE queued_alerts IS 'Alerts accepted by a persistent queue; pending rows are reloaded on startup';
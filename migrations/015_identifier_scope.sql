@@ -0,0 +1,14 @@
+-- Migration: Per-bot channel identifier scope
+-- Created: 2026-10-16
+
+-- Identifiers only have to be unique per bot at the database level, so a user
+-- with two bots can have "alerts" on each. When IDENTIFIER_SCOPE=user (the
+-- default) the application still rejects an identifier used on any of the
+-- user's bots.
+ALTER TABLE telegram_channels DROP CONSTRAINT IF EXISTS telegram_channels_user_id_identifier_key;
+DROP INDEX IF EXISTS idx_telegram_channels_identifier_normalized;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_channels_bot_identifier_normalized
+    ON telegram_channels(user_id, bot_id, identifier_normalized);
+
+COMMENT ON INDEX idx_telegram_channels_bot_identifier_normalized IS 'Identifiers are unique per bot; per-user uniqueness is enforced by the application when IDENTIFIER_SCOPE=user';
@@ -0,0 +1,14 @@
+-- Migration: Named sample webhook payloads per user
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS webhook_fixtures (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, name)
+);
+
+COMMENT ON TABLE webhook_fixtures IS 'Saved sample webhook payloads that can be fired through the dry-run or real webhook path by name';
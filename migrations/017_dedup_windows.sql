@@ -0,0 +1,14 @@
+-- Migration: Per-user and per-channel deduplication windows
+-- Created: 2026-10-16
+
+ALTER TABLE user_settings
+ADD COLUMN IF NOT EXISTS dedup_window_seconds INTEGER; -- NULL = server default
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS dedup_window_seconds INTEGER; -- NULL = user setting
+
+ALTER TABLE queued_alerts
+ADD COLUMN IF NOT EXISTS dedup_window_ms BIGINT;
+
+COMMENT ON COLUMN user_settings.dedup_window_seconds IS 'Identical messages within this many seconds are dropped; 0 disables deduplication';
+COMMENT ON COLUMN telegram_channels.dedup_window_seconds IS 'Overrides the user dedup window for alerts routed to this channel; 0 disables deduplication';
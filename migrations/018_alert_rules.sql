@@ -0,0 +1,21 @@
+-- Migration: User-defined alert rules
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('filter', 'transform')),
+    rule_type VARCHAR(30) NOT NULL CHECK (rule_type IN ('block_keywords', 'require_keywords', 'min_length', 'max_length', 'throttle', 'redact_keywords')),
+    keywords TEXT[] NOT NULL DEFAULT '{}',
+    value INTEGER, -- Length for min/max_length, alert count for throttle
+    window_seconds INTEGER, -- Throttle window
+    is_enabled BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user ON alert_rules(user_id);
+
+COMMENT ON TABLE alert_rules IS 'Per-user filter and transform rules applied after the global default rules';
+COMMENT ON COLUMN alert_rules.kind IS 'filter rules drop alerts; transform rules rewrite the message';
@@ -0,0 +1,9 @@
+-- Migration: Per-user processing pause
+-- Created: 2026-10-16
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS processing_paused BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_users_processing_paused ON users(id) WHERE processing_paused;
+
+COMMENT ON COLUMN users.processing_paused IS 'Set by an admin to stop delivering this user''s alerts; other users are unaffected';
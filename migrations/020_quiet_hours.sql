@@ -0,0 +1,16 @@
+-- Migration: Per-user quiet hours
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS quiet_hours (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    start_hour SMALLINT NOT NULL CHECK (start_hour BETWEEN 0 AND 23),
+    end_hour SMALLINT NOT NULL CHECK (end_hour BETWEEN 0 AND 23),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE quiet_hours IS 'Daily window in which normal and low priority alerts are filtered';
+COMMENT ON COLUMN quiet_hours.start_hour IS 'First quiet hour in the user''s timezone; a window with end_hour <= start_hour crosses midnight';
+COMMENT ON COLUMN quiet_hours.end_hour IS 'Hour at which alerts resume, exclusive';
@@ -0,0 +1,10 @@
+-- Migration: Optional cross-channel deduplication
+-- Created: 2026-10-16
+
+ALTER TABLE user_settings
+ADD COLUMN IF NOT EXISTS dedup_across_channels BOOLEAN NOT NULL DEFAULT false;
+
+ALTER TABLE queued_alerts
+ADD COLUMN IF NOT EXISTS dedup_across_channels BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN user_settings.dedup_across_channels IS 'When true, the same message sent to different channels within the dedup window is a duplicate';
@@ -0,0 +1,18 @@
+-- Migration: Per-user regex redaction of alert content
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS redaction_rules (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    pattern TEXT NOT NULL,
+    replacement TEXT NOT NULL DEFAULT '[REDACTED]',
+    is_enabled BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_redaction_rules_user_id ON redaction_rules(user_id);
+
+COMMENT ON TABLE redaction_rules IS 'Regexes applied to alert messages and data before they are sent or logged';
+COMMENT ON COLUMN redaction_rules.pattern IS 'RE2 regular expression; replacement may reference groups as $1';
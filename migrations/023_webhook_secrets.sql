@@ -0,0 +1,11 @@
+-- Migration: Optional HMAC signing secrets for incoming webhooks
+-- Created: 2026-10-16
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS webhook_secret VARCHAR(128) NOT NULL DEFAULT '';
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS webhook_secret VARCHAR(128) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN users.webhook_secret IS 'When set, webhooks must carry an X-Telehook-Signature HMAC-SHA256 of the body keyed by this secret';
+COMMENT ON COLUMN telegram_channels.webhook_secret IS 'When set, webhooks routed to this channel must be signed with this secret';
@@ -0,0 +1,16 @@
+-- Migration: Idempotency keys for webhook requests
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    idempotency_key VARCHAR(255) NOT NULL,
+    alert_id VARCHAR(64) NOT NULL,
+    created_at TIMESTAMP NOT NULL, -- stored in UTC
+    expires_at TIMESTAMP NOT NULL, -- stored in UTC
+    PRIMARY KEY (user_id, idempotency_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Webhook idempotency keys; a repeat within the TTL returns the original alert instead of queuing again';
+COMMENT ON COLUMN idempotency_keys.alert_id IS 'First alert queued by the original request';
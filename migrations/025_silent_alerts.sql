@@ -0,0 +1,7 @@
+-- Migration: Silent (no notification) alerts
+-- Created: 2026-10-16
+
+ALTER TABLE queued_alerts
+ADD COLUMN IF NOT EXISTS silent BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON COLUMN queued_alerts.silent IS 'Deliver the alert without a notification sound';
@@ -0,0 +1,10 @@
+-- Migration: Forum topic routing
+-- Created: 2026-10-16
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS thread_id INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE queued_alerts
+ADD COLUMN IF NOT EXISTS thread_id INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN telegram_channels.thread_id IS 'Forum topic (message_thread_id) alerts are posted in; 0 is the main chat';
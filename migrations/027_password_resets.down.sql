@@ -0,0 +1,4 @@
+-- Rollback: Password reset tokens
+-- Created: 2026-10-16
+
+DROP TABLE IF EXISTS password_resets;
@@ -0,0 +1,16 @@
+-- Migration: Password reset tokens
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS password_resets (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL, -- stored in UTC
+    used_at TIMESTAMP, -- stored in UTC
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets(user_id);
+
+COMMENT ON TABLE password_resets IS 'Single-use password reset tokens';
+COMMENT ON COLUMN password_resets.token_hash IS 'SHA-256 of the token sent by email; the token itself is never stored';
@@ -0,0 +1,4 @@
+-- Rollback: Refresh tokens
+-- Created: 2026-10-16
+
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,16 @@
+-- Migration: Refresh tokens
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL, -- stored in UTC
+    revoked_at TIMESTAMP, -- stored in UTC
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id);
+
+COMMENT ON TABLE refresh_tokens IS 'Long-lived tokens used to mint new access tokens; revoked on logout';
+COMMENT ON COLUMN refresh_tokens.token_hash IS 'SHA-256 of the refresh token; the token itself is never stored';
@@ -0,0 +1,4 @@
+-- Rollback: Revoked access tokens
+-- Created: 2026-10-16
+
+DROP TABLE IF EXISTS revoked_tokens;
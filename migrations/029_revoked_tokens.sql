@@ -0,0 +1,14 @@
+-- Migration: Revoked access tokens
+-- Created: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL, -- stored in UTC
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+COMMENT ON TABLE revoked_tokens IS 'Access tokens revoked by logout, kept until they would have expired';
+COMMENT ON COLUMN revoked_tokens.jti IS 'The token''s jti claim';
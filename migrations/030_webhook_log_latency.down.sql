@@ -0,0 +1,5 @@
+-- Rollback: Delivery latency on webhook logs
+-- Created: 2026-10-16
+
+ALTER TABLE webhook_logs
+DROP COLUMN IF EXISTS latency_ms;
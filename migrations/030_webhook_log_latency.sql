@@ -0,0 +1,7 @@
+-- Migration: Delivery latency on webhook logs
+-- Created: 2026-10-16
+
+ALTER TABLE webhook_logs
+ADD COLUMN IF NOT EXISTS latency_ms INTEGER;
+
+COMMENT ON COLUMN webhook_logs.latency_ms IS 'Milliseconds from the alert being queued to Telegram accepting it, including retries; set on successful sends';
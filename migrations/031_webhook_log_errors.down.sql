@@ -0,0 +1,8 @@
+-- Rollback: Failure reasons on webhook logs
+-- Created: 2026-10-16
+
+DROP INDEX IF EXISTS idx_webhook_logs_error_category;
+
+ALTER TABLE webhook_logs
+DROP COLUMN IF EXISTS error_category,
+DROP COLUMN IF EXISTS error_code;
@@ -0,0 +1,11 @@
+-- Migration: Failure reasons on webhook logs
+-- Created: 2026-10-16
+
+ALTER TABLE webhook_logs
+ADD COLUMN IF NOT EXISTS error_code INTEGER,
+ADD COLUMN IF NOT EXISTS error_category VARCHAR(32);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_logs_error_category ON webhook_logs(user_id, error_category) WHERE error_category IS NOT NULL;
+
+COMMENT ON COLUMN webhook_logs.error_code IS 'Telegram API error code of a failed send, if any';
+COMMENT ON COLUMN webhook_logs.error_category IS 'Failure category: chat_not_found, bot_blocked, rate_limited, message_too_long, bad_token or unknown';
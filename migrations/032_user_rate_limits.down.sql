@@ -0,0 +1,5 @@
+-- Rollback: Per-user webhook rate limits
+-- Created: 2026-10-16
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS rate_limit;
@@ -0,0 +1,7 @@
+-- Migration: Per-user webhook rate limits
+-- Created: 2026-10-16
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS rate_limit INTEGER;
+
+COMMENT ON COLUMN users.rate_limit IS 'Webhook requests per minute for this user; NULL uses the RATE_LIMIT default';
@@ -0,0 +1,8 @@
+-- Rollback: Per-bot and per-channel send rates
+-- Created: 2026-10-16
+
+ALTER TABLE telegram_channels
+DROP COLUMN IF EXISTS rate_limit_per_minute;
+
+ALTER TABLE telegram_bots
+DROP COLUMN IF EXISTS rate_limit_per_second;
@@ -0,0 +1,11 @@
+-- Migration: Per-bot and per-channel send rates
+-- Created: 2026-10-16
+
+ALTER TABLE telegram_bots
+ADD COLUMN IF NOT EXISTS rate_limit_per_second INTEGER;
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS rate_limit_per_minute INTEGER;
+
+COMMENT ON COLUMN telegram_bots.rate_limit_per_second IS 'Messages per second the bot sends across its chats; NULL uses the default of 30';
+COMMENT ON COLUMN telegram_channels.rate_limit_per_minute IS 'Messages per minute sent to this chat; NULL uses CHANNEL_RATE_PER_MIN';
@@ -0,0 +1,7 @@
+-- Rollback: Explicit default channel
+-- Created: 2026-10-16
+
+DROP INDEX IF EXISTS idx_telegram_channels_user_default;
+
+ALTER TABLE telegram_channels
+DROP COLUMN IF EXISTS is_default;
@@ -0,0 +1,11 @@
+-- Migration: Explicit default channel
+-- Created: 2026-10-16
+
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS is_default BOOLEAN NOT NULL DEFAULT false;
+
+-- At most one default channel per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_channels_user_default
+ON telegram_channels (user_id) WHERE is_default;
+
+COMMENT ON COLUMN telegram_channels.is_default IS 'Channel used by webhooks without an identifier; without one the oldest active channel is used';
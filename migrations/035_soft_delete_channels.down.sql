@@ -0,0 +1,15 @@
+-- Rollback: Soft-deleted channels
+-- Created: 2026-10-16
+
+-- Deleted channels could clash with live ones once identifiers are unique
+-- across all rows again
+DELETE FROM telegram_channels WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_telegram_channels_deleted_at;
+
+DROP INDEX IF EXISTS idx_telegram_channels_bot_identifier_normalized;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_channels_bot_identifier_normalized
+    ON telegram_channels(user_id, bot_id, identifier_normalized);
+
+ALTER TABLE telegram_channels
+DROP COLUMN IF EXISTS deleted_at;
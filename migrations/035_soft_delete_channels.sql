@@ -0,0 +1,20 @@
+-- Migration: Soft-deleted channels
+-- Created: 2026-10-16
+
+-- Deleted channels are kept so historical logs can still be labeled with
+-- their names until they are purged
+ALTER TABLE telegram_channels
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+-- A deleted channel's identifier may be reused
+DROP INDEX IF EXISTS idx_telegram_channels_bot_identifier_normalized;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_telegram_channels_bot_identifier_normalized
+    ON telegram_channels(user_id, bot_id, identifier_normalized)
+    WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_telegram_channels_deleted_at
+    ON telegram_channels(deleted_at)
+    WHERE deleted_at IS NOT NULL;
+
+COMMENT ON COLUMN telegram_channels.deleted_at IS 'When the channel was deleted; NULL for live channels. Deleted channels only resolve names in analytics';
+COMMENT ON INDEX idx_telegram_channels_bot_identifier_normalized IS 'Identifiers are unique per bot among live channels; per-user uniqueness is enforced by the application when IDENTIFIER_SCOPE=user';
@@ -50,21 +50,10 @@ else
     echo "Creating database '$DB_NAME'..."
     psql -h "$DB_HOST" -p "$DB_PORT" -U "$DB_USER" -c "CREATE DATABASE $DB_NAME;"
 fi
-echo ""
-
-# Run migrations
-echo "Running database migrations..."
-psql -h "$DB_HOST" -p "$DB_PORT" -U "$DB_USER" -d "$DB_NAME" -f migrations/001_init_schema.sql
 
-if [ $? -eq 0 ]; then
-    echo ""
-    echo "=== Database setup completed successfully! ==="
-    echo ""
-    echo "You can now start the server with:"
-    echo "  go run cmd/server/main.go"
-    echo ""
-else
-    echo ""
-    echo "Error: Migration failed!"
-    exit 1
-fi
+echo ""
+echo "=== Database setup completed successfully! ==="
+echo ""
+echo "Migrations are applied when the server starts:"
+echo "  go run cmd/server/main.go"
+echo ""
@@ -55,8 +55,13 @@ window.loadAnalytics = async function(timeRange = '24h') {
         const data = await response.json();
         console.log('Analytics data received:', data);
 
-        // Check if there's any data
-        if (data.summary.total_messages === 0) {
+        if (data.partial) {
+            console.warn('Analytics partially loaded, failed sections:', data.failed_sections);
+        }
+
+        // Check if there's any data (a failed summary reads as zero, so
+        // still render whatever sections did load)
+        if (data.summary.total_messages === 0 && !data.partial) {
             if (loadingEl) loadingEl.style.display = 'none';
             if (noDataEl) noDataEl.style.display = 'block';
             return;
@@ -90,10 +95,10 @@ function displayAnalytics(data) {
     document.getElementById('peakHour').textContent = `${peakHourFormatted} (${data.summary.peak_hour_count})`;
 
     // Create charts
-    createTimelineChart(data.timeline);
-    createStatusChart(data.status_distribution);
-    createPriorityChart(data.priority_distribution);
-    createChannelChart(data.channel_distribution);
+    createTimelineChart(data.timeline || []);
+    createStatusChart(data.status_distribution || []);
+    createPriorityChart(data.priority_distribution || []);
+    createChannelChart(data.channel_distribution || []);
 }
 
 // Create timeline chart (line chart)
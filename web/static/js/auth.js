@@ -64,8 +64,8 @@ if (signupForm) {
         errorMessage.style.display = 'none';
 
         // Validate password length
-        if (password.length < 6) {
-            errorMessage.textContent = 'Password must be at least 6 characters';
+        if (password.length < 8) {
+            errorMessage.textContent = 'Password must be at least 8 characters';
             errorMessage.style.display = 'block';
             return;
         }
@@ -90,7 +90,65 @@ if (signupForm) {
                 // Redirect to dashboard
                 window.location.href = '/dashboard';
             } else {
-                errorMessage.textContent = data.error || 'Signup failed';
+                errorMessage.textContent = data.requirements
+                    ? `Password ${data.requirements.join(', ')}`
+                    : (data.error || 'Signup failed');
+                errorMessage.style.display = 'block';
+            }
+        } catch (error) {
+            errorMessage.textContent = 'Network error. Please try again.';
+            errorMessage.style.display = 'block';
+        }
+    });
+}
+
+// Reset Password Form Handler
+const resetPasswordForm = document.getElementById('resetPasswordForm');
+if (resetPasswordForm) {
+    const token = new URLSearchParams(window.location.search).get('token');
+    const errorMessage = document.getElementById('errorMessage');
+    const successMessage = document.getElementById('successMessage');
+
+    if (!token) {
+        errorMessage.textContent = 'This reset link is missing its token. Request a new one.';
+        errorMessage.style.display = 'block';
+    }
+
+    resetPasswordForm.addEventListener('submit', async (e) => {
+        e.preventDefault();
+
+        const newPassword = document.getElementById('password').value;
+        const confirmPassword = document.getElementById('confirmPassword').value;
+
+        errorMessage.style.display = 'none';
+        successMessage.style.display = 'none';
+
+        if (newPassword !== confirmPassword) {
+            errorMessage.textContent = 'Passwords do not match';
+            errorMessage.style.display = 'block';
+            return;
+        }
+
+        try {
+            const response = await fetch(`${API_BASE}/auth/reset-password`, {
+                method: 'POST',
+                headers: {
+                    'Content-Type': 'application/json'
+                },
+                body: JSON.stringify({ token, new_password: newPassword })
+            });
+
+            const data = await response.json();
+
+            if (response.ok) {
+                resetPasswordForm.reset();
+                successMessage.textContent = 'Your password has been reset. Redirecting to login...';
+                successMessage.style.display = 'block';
+                setTimeout(() => { window.location.href = '/login'; }, 2000);
+            } else {
+                errorMessage.textContent = data.requirements
+                    ? `Password ${data.requirements.join(', ')}`
+                    : (data.error || 'Password reset failed');
                 errorMessage.style.display = 'block';
             }
         } catch (error) {